@@ -0,0 +1,77 @@
+// auditverify checks owners' audit trails for missing, altered or reordered
+// entries. It checks every owner, or one with -owner, and exits with status 1
+// if any chain is broken.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/service"
+)
+
+func main() {
+	owner := flag.String("owner", "", "only verify this owner's audit trail")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	db, err := postgres.NewConnection(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	auditService := service.NewAuditService(postgres.NewAuditRepository(db))
+	ctx := context.Background()
+
+	var results []service.AuditVerification
+	if *owner != "" {
+		ownerID, err := uuid.Parse(*owner)
+		if err != nil {
+			log.Fatalf("Invalid owner ID: %v", err)
+		}
+
+		result, err := auditService.Verify(ctx, ownerID)
+		if err != nil {
+			log.Fatalf("Failed to verify audit trail: %v", err)
+		}
+		results = append(results, *result)
+	} else {
+		results, err = auditService.VerifyAll(ctx)
+		if err != nil {
+			log.Fatalf("Failed to verify audit trails: %v", err)
+		}
+	}
+
+	broken := 0
+	for _, result := range results {
+		if result.Valid {
+			fmt.Printf("%s ok (%d entries)\n", result.OwnerID, result.Entries)
+			continue
+		}
+
+		broken++
+		fmt.Printf("%s BROKEN at seq %d: %s\n", result.OwnerID, result.FirstInvalidSeq, result.Problem)
+	}
+
+	fmt.Printf("%d audit trail(s) checked, %d broken\n", len(results), broken)
+	if broken > 0 {
+		db.Close()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,88 @@
+// keygen writes a new JWT signing key to JWT_KEYS_DIR (or -dir) as <kid>.pem.
+//
+// Rotate keys by generating a new one, setting JWT_ACTIVE_KID to its kid and
+// restarting. Keep the old file until tokens it signed have expired; it can be
+// replaced with its public half (see -public) so it only verifies.
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sampatti/internal/util"
+)
+
+func main() {
+	dir := flag.String("dir", os.Getenv("JWT_KEYS_DIR"), "directory to write the key to")
+	alg := flag.String("alg", util.AlgEdDSA, "key algorithm: EdDSA or RS256")
+	kid := flag.String("kid", time.Now().UTC().Format("20060102-150405"), "key ID")
+	public := flag.String("public", "", "instead of generating a key, replace this kid's private key with its public key")
+	flag.Parse()
+
+	if *dir == "" {
+		log.Fatal("set -dir or JWT_KEYS_DIR")
+	}
+
+	if *public != "" {
+		if err := retireKey(*dir, *public); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Key %s is now verification-only\n", *public)
+		return
+	}
+
+	data, err := util.GenerateKeyPEM(*alg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		log.Fatalf("Failed to create key directory: %v", err)
+	}
+
+	path := filepath.Join(*dir, *kid+".pem")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		log.Fatalf("Failed to write key: %v", err)
+	}
+
+	fmt.Printf("Wrote %s key %s to %s\n", *alg, *kid, path)
+}
+
+// retireKey rewrites a private key file as its public key
+func retireKey(dir, kid string) error {
+	path := filepath.Join(dir, kid+".pem")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return fmt.Errorf("%s is not a PKCS#8 private key", path)
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse key: %w", err)
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("%s has no public key", path)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644)
+}
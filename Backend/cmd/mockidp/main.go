@@ -0,0 +1,246 @@
+// mockidp is a minimal OpenID Connect provider for trying single sign-on locally.
+//
+// It signs in whoever fills in its form, so never expose it. Point the API at it with
+//
+//	OIDC_ISSUER=http://localhost:9090 OIDC_CLIENT_ID=sampatti OIDC_CLIENT_SECRET=secret
+//
+// The authorization code flow requires PKCE (S256), as the API always sends it.
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sampatti/internal/util"
+)
+
+const codeTTL = time.Minute
+
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	email         string
+	name          string
+	emailVerified bool
+	expiresAt     time.Time
+}
+
+type provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	keys         *util.KeyManager
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<title>Mock identity provider</title>
+<h1>Mock identity provider</h1>
+<form method="post" action="/authorize">
+  {{range $name, $value := .}}<input type="hidden" name="{{$name}}" value="{{index $value 0}}">
+  {{end}}
+  <p><label>Email <input name="email" type="email" required></label></p>
+  <p><label>Name <input name="name"></label></p>
+  <p><label><input name="email_verified" type="checkbox" value="true" checked> Email verified</label></p>
+  <p><button type="submit">Sign in</button></p>
+</form>
+`))
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9090", "issuer URL the API is configured with")
+	clientID := flag.String("client-id", "sampatti", "accepted client ID")
+	clientSecret := flag.String("client-secret", "secret", "accepted client secret; empty accepts public clients")
+	flag.Parse()
+
+	keys, err := util.NewKeyManager("", "")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	p := &provider{
+		issuer:       *issuer,
+		clientID:     *clientID,
+		clientSecret: *clientSecret,
+		keys:         keys,
+		codes:        make(map[string]authorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+
+	log.Printf("Mock identity provider for client %q listening on %s with issuer %s", *clientID, *addr, *issuer)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func (p *provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{util.AlgEdDSA},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *provider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, p.keys.JWKS())
+}
+
+// authorize shows the sign-in form on GET and issues a code on POST
+func (p *provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	params := url.Values{}
+	for _, name := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge", "code_challenge_method", "scope", "response_type"} {
+		params.Set(name, r.Form.Get(name))
+	}
+
+	if params.Get("client_id") != p.clientID || params.Get("response_type") != "code" {
+		http.Error(w, "unknown client or unsupported response type", http.StatusBadRequest)
+		return
+	}
+	if params.Get("code_challenge") == "" || params.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(params.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := loginPage.Execute(w, params); err != nil {
+			log.Printf("Failed to render login page: %v", err)
+		}
+		return
+	}
+
+	code, err := util.GenerateSecureToken(24)
+	if err != nil {
+		http.Error(w, "failed to issue code", http.StatusInternalServerError)
+		return
+	}
+
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		clientID:      params.Get("client_id"),
+		redirectURI:   params.Get("redirect_uri"),
+		codeChallenge: params.Get("code_challenge"),
+		nonce:         params.Get("nonce"),
+		email:         r.Form.Get("email"),
+		name:          r.Form.Get("name"),
+		emailVerified: r.Form.Get("email_verified") == "true",
+		expiresAt:     time.Now().Add(codeTTL),
+	}
+	p.mu.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", params.Get("state"))
+	redirectURI.RawQuery = query.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// token redeems a code for an ID token
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.Form.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.Form.Get("client_id"), r.Form.Get("client_secret")
+	}
+
+	if clientID != p.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(p.clientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	// Codes are single use
+	code := r.Form.Get("code")
+	p.mu.Lock()
+	auth, found := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	if !found || time.Now().After(auth.expiresAt) ||
+		auth.clientID != clientID ||
+		auth.redirectURI != r.Form.Get("redirect_uri") ||
+		util.PKCEChallenge(r.Form.Get("code_verifier")) != auth.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	// The subject stays the same for an email, like a real provider's account ID
+	sum := sha256.Sum256([]byte(auth.email))
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.issuer,
+		"sub":            "mock-" + hex.EncodeToString(sum[:8]),
+		"aud":            clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          auth.nonce,
+		"email":          auth.email,
+		"email_verified": auth.emailVerified,
+		"name":           auth.name,
+	}
+
+	key := p.keys.SigningKey()
+	idToken := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	idToken.Header["kid"] = key.ID
+
+	signed, err := idToken.SignedString(key.Private)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": code,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
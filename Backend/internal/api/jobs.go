@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// job is work the server repeats in the background while it runs
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+}
+
+func (s *Server) addJob(name string, interval time.Duration, run func(ctx context.Context) error) {
+	if interval <= 0 {
+		log.Printf("Background job %s is disabled: interval must be positive", name)
+		return
+	}
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+// startJobs runs every job once and then on its interval until ctx is done
+func (s *Server) startJobs(ctx context.Context) {
+	for _, j := range s.jobs {
+		go func(j job) {
+			ticker := time.NewTicker(j.interval)
+			defer ticker.Stop()
+
+			for {
+				if err := j.run(ctx); err != nil {
+					log.Printf("Background job %s failed: %v", j.name, err)
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(j)
+	}
+}
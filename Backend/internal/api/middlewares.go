@@ -4,16 +4,20 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/service"
 	"github.com/sampatti/internal/types"
 	"github.com/sampatti/internal/util"
 )
 
 type AuthMiddleware struct {
-	jwtUtil *util.JWTUtil
+	jwtUtil         *util.JWTUtil
+	tokenService    *service.PersonalAccessTokenService
+	nomineeSessions *service.NomineeSessionService
 }
 
-func NewAuthMiddleware(jwtUtil *util.JWTUtil) *AuthMiddleware {
-	return &AuthMiddleware{jwtUtil: jwtUtil}
+func NewAuthMiddleware(jwtUtil *util.JWTUtil, tokenService *service.PersonalAccessTokenService, nomineeSessions *service.NomineeSessionService) *AuthMiddleware {
+	return &AuthMiddleware{jwtUtil: jwtUtil, tokenService: tokenService, nomineeSessions: nomineeSessions}
 }
 
 func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
@@ -28,6 +32,11 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 			return
 		}
 
+		if service.IsAccessToken(tokenString) {
+			m.authenticateAccessToken(c, tokenString)
+			return
+		}
+
 		// Validate token
 		claims, err := m.jwtUtil.ValidateToken(tokenString)
 		if err != nil {
@@ -36,49 +45,48 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 			return
 		}
 
-		// Set user ID and access info in context
-		c.Set(string(types.UserIDKey), claims.UserID)
+		// Nominee sessions can be revoked at any time, so each request checks the store
+		if claims.IsNominee {
+			if err := m.nomineeSessions.Validate(c.Request.Context(), claims.SessionID, claims.UserID); err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "nominee session has ended"})
+				c.Abort()
+				return
+			}
+		}
+
+		// Nominee tokens never populate UserIDKey, so owner handlers can't
+		// mistake a nominee for the account they were granted access to
+		if claims.IsNominee {
+			c.Set(string(types.NomineeIDKey), claims.UserID)
+			c.Set(string(types.OwnerIDKey), claims.OwnerID)
+		} else {
+			c.Set(string(types.UserIDKey), claims.UserID)
+		}
 		c.Set(string(types.IsNomineeKey), claims.IsNominee)
 		c.Set(string(types.AccessTypeKey), claims.AccessType)
 		c.Set(string(types.AccessLevelKey), claims.AccessLevel)
+		c.Set(string(types.SessionIDKey), claims.SessionID)
 
 		c.Next()
 	}
 }
 
-// RequireUserAccess ensures only regular users (not nominees) can access a route
-func (m *AuthMiddleware) RequireUserAccess() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		isNominee, exists := c.Get(string(types.IsNomineeKey))
-		if !exists || isNominee.(bool) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "message": "requires user access"})
-			c.Abort()
-			return
-		}
-
-		c.Next()
+// authenticateAccessToken signs the request in as the owner of a personal
+// access token. Its scopes are checked by Authorize.
+func (m *AuthMiddleware) authenticateAccessToken(c *gin.Context, rawToken string) {
+	token, err := m.tokenService.Authenticate(c.Request.Context(), rawToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "invalid token"})
+		c.Abort()
+		return
 	}
-}
 
-// RequireFullAccess ensures only users with full access (not limited nominees) can access a route
-func (m *AuthMiddleware) RequireFullAccess() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		isNominee, exists := c.Get(string(types.IsNomineeKey))
-		if !exists {
-			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "message": "access information missing"})
-			c.Abort()
-			return
-		}
+	c.Set(string(types.UserIDKey), token.UserID)
+	c.Set(string(types.IsNomineeKey), false)
+	c.Set(string(types.AccessTypeKey), types.PrincipalUser)
+	c.Set(string(types.AccessLevelKey), "")
+	c.Set(string(types.SessionIDKey), uuid.Nil)
+	c.Set(string(types.TokenScopesKey), token.Scopes)
 
-		if isNominee.(bool) {
-			accessLevel, _ := c.Get(string(types.AccessLevelKey))
-			if accessLevel.(string) != "Full" {
-				c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "message": "requires full access"})
-				c.Abort()
-				return
-			}
-		}
-
-		c.Next()
-	}
+	c.Next()
 }
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+// RoutePolicy declares who may call a route
+type RoutePolicy struct {
+	// Public routes don't require a token at all
+	Public bool
+	// Principals lists the token types allowed on the route
+	Principals []string
+	// AccessLevels restricts nominee principals to these access levels; empty allows any level
+	AccessLevels []string
+	// OwnerParam names a path parameter that must match the owner a nominee token acts for
+	OwnerParam string
+	// Scope is the scope a personal access token needs for the route; routes
+	// without one can't be called with personal access tokens at all
+	Scope string
+}
+
+type policyRule struct {
+	// Method is an HTTP method, or "*" for any method
+	Method string
+	// Path is a full route path as registered with gin; a trailing "/*" also
+	// matches the path itself and everything below it
+	Path   string
+	Policy RoutePolicy
+}
+
+var (
+	publicRoute = RoutePolicy{Public: true}
+	ownerOnly   = RoutePolicy{Principals: []string{types.PrincipalUser}}
+	// nomineeOfOwner lets a nominee read data only for the owner who named them
+	nomineeOfOwner = RoutePolicy{Principals: []string{types.PrincipalNominee}, OwnerParam: "userID"}
+	// nomineeOnly is for nominee routes where the token alone names the owner
+	nomineeOnly = RoutePolicy{Principals: []string{types.PrincipalNominee}}
+)
+
+// ownerWithScope is ownerOnly, also reachable by personal access tokens holding scope
+func ownerWithScope(scope string) RoutePolicy {
+	return RoutePolicy{Principals: []string{types.PrincipalUser}, Scope: scope}
+}
+
+// routePolicies is the single source of truth for route authorization.
+// Rules are checked in order and the first match wins. A route without a
+// matching rule is denied, and the server refuses to start if one exists.
+var routePolicies = []policyRule{
+	// Authenticator registration sits under /auth but needs a signed-in owner
+	{"POST", "/api/v1/auth/webauthn/register/*", ownerOnly},
+
+	// Unauthenticated endpoints
+	{"GET", "/.well-known/jwks.json", publicRoute},
+	{"GET", "/api/v1/health", publicRoute},
+	{"*", "/api/v1/auth/*", publicRoute},
+
+	// Owner data that scripts may reach with a scoped personal access token
+	{"GET", "/api/v1/assets/*", ownerWithScope(service.ScopeAssetsRead)},
+	{"*", "/api/v1/assets/*", ownerWithScope(service.ScopeAssetsWrite)},
+	{"GET", "/api/v1/documents/*", ownerWithScope(service.ScopeDocumentsRead)},
+
+	// Owner account management and owner data
+	{"*", "/api/v1/users/*", ownerOnly},
+	{"*", "/api/v1/nominees/*", ownerOnly},
+	{"*", "/api/v1/documents/*", ownerOnly},
+	{"*", "/api/v1/alerts/*", ownerOnly},
+
+	// A signed-in user acting as a nominee of other accounts
+	{"GET", "/api/v1/nominee-access/users", ownerOnly},
+	{"GET", "/api/v1/nominee-access/requests", ownerOnly},
+	{"POST", "/api/v1/nominee-access/invitations/accept", ownerOnly},
+	{"POST", "/api/v1/nominee-access/access/:userID", ownerOnly},
+
+	// Nominee tokens reading the data they were granted
+	{"GET", "/api/v1/nominee-access/data/:userID", nomineeOfOwner},
+	{"GET", "/api/v1/nominee-access/quorum/:userID", nomineeOfOwner},
+	{"GET", "/api/v1/nominee-access/documents", nomineeOnly},
+	{"GET", "/api/v1/nominee-access/documents/:id/download", nomineeOnly},
+	{"*", "/api/v1/nominee-access/claims/*", nomineeOnly},
+	{"*", "/api/v1/nominee-access/vault/*", nomineeOnly},
+}
+
+// lookupPolicy finds the policy for a registered route
+func lookupPolicy(method, path string) (RoutePolicy, bool) {
+	for _, rule := range routePolicies {
+		if rule.Method != "*" && rule.Method != method {
+			continue
+		}
+
+		if prefix, ok := strings.CutSuffix(rule.Path, "/*"); ok {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return rule.Policy, true
+			}
+			continue
+		}
+
+		if rule.Path == path {
+			return rule.Policy, true
+		}
+	}
+
+	return RoutePolicy{}, false
+}
+
+// validateRoutePolicies panics if any registered route has no policy, so a
+// new endpoint can't be shipped without an explicit authorization decision
+func validateRoutePolicies(routes gin.RoutesInfo) {
+	missing := make([]string, 0)
+	for _, route := range routes {
+		if _, ok := lookupPolicy(route.Method, route.Path); !ok {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+
+	if len(missing) > 0 {
+		panic(fmt.Sprintf("routes without an authorization policy: %s", strings.Join(missing, ", ")))
+	}
+}
+
+// Authorize enforces routePolicies for authenticated routes. It must run after Authenticate.
+func (m *AuthMiddleware) Authorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy, ok := lookupPolicy(c.Request.Method, c.FullPath())
+		if !ok || policy.Public {
+			// Public routes are never mounted behind Authenticate, so reaching one here is a wiring mistake
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "message": "no access policy for this route"})
+			c.Abort()
+			return
+		}
+
+		if err := policy.check(c); err != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "message": err})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// check returns a reason when the request's principal doesn't satisfy the policy
+func (p RoutePolicy) check(c *gin.Context) string {
+	principal := c.GetString(string(types.AccessTypeKey))
+	if !contains(p.Principals, principal) {
+		return fmt.Sprintf("%s tokens can't access this route", principal)
+	}
+
+	// Personal access tokens only reach routes their scopes cover
+	if scopes, isAccessToken := c.Get(string(types.TokenScopesKey)); isAccessToken {
+		granted, _ := scopes.([]string)
+		if p.Scope == "" || !contains(granted, p.Scope) {
+			return "token scope doesn't allow this route"
+		}
+	}
+
+	if principal != types.PrincipalNominee {
+		return ""
+	}
+
+	if len(p.AccessLevels) > 0 && !contains(p.AccessLevels, c.GetString(string(types.AccessLevelKey))) {
+		return "access level doesn't allow this route"
+	}
+
+	if p.OwnerParam != "" {
+		_, ownerID, ok := types.ExtractNomineeFromGin(c)
+		if !ok || c.Param(p.OwnerParam) != ownerID.String() {
+			return "nominee can only access the account that named them"
+		}
+	}
+
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
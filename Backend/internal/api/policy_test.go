@@ -0,0 +1,222 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+var allScopes = []string{service.ScopeAssetsRead, service.ScopeAssetsWrite, service.ScopeDocumentsRead}
+
+// expectedAccess is who each route should admit, written out independently
+// of routePolicies so a wrong or too broad rule there fails the tests
+type expectedAccess struct {
+	public    bool
+	principal string
+	// scope is what a personal access token needs; empty means none may call the route
+	scope string
+	// ownerParam means a nominee may only pass their own owner's ID
+	ownerParam bool
+}
+
+func expectedAccessFor(method, path string) expectedAccess {
+	owner := expectedAccess{principal: types.PrincipalUser}
+
+	switch {
+	case path == "/.well-known/jwks.json", path == "/api/v1/health":
+		return expectedAccess{public: true}
+	case strings.HasPrefix(path, "/api/v1/auth/webauthn/register/"):
+		return owner
+	case strings.HasPrefix(path, "/api/v1/auth/"), path == "/api/v1/auth":
+		return expectedAccess{public: true}
+	case strings.HasPrefix(path, "/api/v1/assets"):
+		owner.scope = service.ScopeAssetsWrite
+		if method == http.MethodGet {
+			owner.scope = service.ScopeAssetsRead
+		}
+		return owner
+	case strings.HasPrefix(path, "/api/v1/documents"):
+		if method == http.MethodGet {
+			owner.scope = service.ScopeDocumentsRead
+		}
+		return owner
+	case strings.HasPrefix(path, "/api/v1/nominee-access/"):
+		switch path {
+		case "/api/v1/nominee-access/users",
+			"/api/v1/nominee-access/requests",
+			"/api/v1/nominee-access/invitations/accept",
+			"/api/v1/nominee-access/access/:userID":
+			return owner
+		}
+		return expectedAccess{principal: types.PrincipalNominee, ownerParam: strings.Contains(path, ":userID")}
+	}
+
+	// Everything else is the owner's own account and data
+	return owner
+}
+
+// principal sets up the request context the way Authenticate would
+type principal struct {
+	name    string
+	kind    string
+	scopes  []string
+	isToken bool
+}
+
+func (p principal) middleware(ownerID uuid.UUID) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(string(types.AccessTypeKey), p.kind)
+		c.Set(string(types.IsNomineeKey), p.kind == types.PrincipalNominee)
+		c.Set(string(types.AccessLevelKey), "")
+		c.Set(string(types.SessionIDKey), uuid.New())
+
+		if p.kind == types.PrincipalNominee {
+			c.Set(string(types.NomineeIDKey), uuid.New())
+			c.Set(string(types.OwnerIDKey), ownerID)
+		} else {
+			c.Set(string(types.UserIDKey), uuid.New())
+		}
+
+		if p.isToken {
+			c.Set(string(types.TokenScopesKey), p.scopes)
+		}
+
+		c.Next()
+	}
+}
+
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	cfg.Mail.OutputDir = t.TempDir()
+
+	return NewServer(cfg, &sqlx.DB{}).router
+}
+
+// authorize runs Authorize for one route as the principal, with any :userID
+// in the path set to userParam, and reports whether the request got through
+func authorize(t *testing.T, method, path string, p principal, ownerID, userParam uuid.UUID) bool {
+	t.Helper()
+
+	engine := gin.New()
+	engine.Handle(method, path, p.middleware(ownerID), (&AuthMiddleware{}).Authorize(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == ":userID":
+			segments[i] = userParam.String()
+		case strings.HasPrefix(segment, ":"):
+			segments[i] = uuid.New().String()
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(method, strings.Join(segments, "/"), nil))
+
+	switch recorder.Code {
+	case http.StatusNoContent:
+		return true
+	case http.StatusForbidden:
+		return false
+	}
+
+	t.Fatalf("%s %s as %s: unexpected status %d", method, path, p.name, recorder.Code)
+	return false
+}
+
+func TestEveryRouteHasTheExpectedPolicy(t *testing.T) {
+	for _, route := range newTestRouter(t).Routes() {
+		policy, ok := lookupPolicy(route.Method, route.Path)
+		if !ok {
+			t.Errorf("%s %s has no policy", route.Method, route.Path)
+			continue
+		}
+
+		want := expectedAccessFor(route.Method, route.Path)
+		if policy.Public != want.public {
+			t.Errorf("%s %s public = %v, want %v", route.Method, route.Path, policy.Public, want.public)
+		}
+	}
+}
+
+func TestRoutesRejectTheWrongPrincipal(t *testing.T) {
+	owner := principal{name: "owner session", kind: types.PrincipalUser}
+	nominee := principal{name: "nominee session", kind: types.PrincipalNominee}
+	noScopes := principal{name: "access token without scopes", kind: types.PrincipalUser, isToken: true, scopes: []string{}}
+
+	ownerID := uuid.New()
+	otherOwnerID := uuid.New()
+
+	for _, route := range newTestRouter(t).Routes() {
+		want := expectedAccessFor(route.Method, route.Path)
+		if want.public {
+			continue
+		}
+
+		t.Run(route.Method+" "+route.Path, func(t *testing.T) {
+			check := func(p principal, userParam uuid.UUID, allowed bool) {
+				t.Helper()
+				if got := authorize(t, route.Method, route.Path, p, ownerID, userParam); got != allowed {
+					t.Errorf("%s allowed = %v, want %v", p.name, got, allowed)
+				}
+			}
+
+			check(owner, ownerID, want.principal == types.PrincipalUser)
+			check(nominee, ownerID, want.principal == types.PrincipalNominee)
+			check(noScopes, ownerID, false)
+
+			if strings.Contains(route.Path, ":userID") {
+				other := principal{name: "nominee of another owner", kind: types.PrincipalNominee}
+				check(other, otherOwnerID, want.principal == types.PrincipalNominee && !want.ownerParam)
+			}
+
+			if want.scope != "" {
+				scoped := principal{name: "access token with " + want.scope, kind: types.PrincipalUser, isToken: true, scopes: []string{want.scope}}
+				check(scoped, ownerID, true)
+			}
+
+			// Every other scope together still isn't enough
+			otherScopes := make([]string, 0, len(allScopes))
+			for _, scope := range allScopes {
+				if scope != want.scope {
+					otherScopes = append(otherScopes, scope)
+				}
+			}
+			wrongScopes := principal{name: "access token with other scopes", kind: types.PrincipalUser, isToken: true, scopes: otherScopes}
+			check(wrongScopes, ownerID, false)
+		})
+	}
+}
+
+func TestAuthorizeRejectsRoutesWithoutPolicy(t *testing.T) {
+	owner := principal{name: "owner session", kind: types.PrincipalUser}
+	if authorize(t, http.MethodGet, "/api/v1/unlisted", owner, uuid.New(), uuid.New()) {
+		t.Fatal("route without a policy was allowed")
+	}
+}
+
+func TestValidateRoutePoliciesPanicsOnUnlistedRoute(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("validateRoutePolicies accepted a route without a policy")
+		}
+	}()
+
+	validateRoutePolicies(gin.RoutesInfo{{Method: http.MethodGet, Path: "/api/v1/unlisted"}})
+}
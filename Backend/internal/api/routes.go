@@ -27,38 +27,130 @@ func (s *Server) setupRoutes() {
 	nomineeRepo := postgres.NewNomineeRepository(s.db)
 	documentRepo := postgres.NewDocumentRepository(s.db)
 	alertRepo := postgres.NewAlertRepository(s.db)
+	resetRepo := postgres.NewPasswordResetRepository(s.db)
+	twoFactorRepo := postgres.NewTwoFactorRepository(s.db)
+	sessionRepo := postgres.NewSessionRepository(s.db)
+	securityEventRepo := postgres.NewSecurityEventRepository(s.db)
+	verificationRepo := postgres.NewEmailVerificationRepository(s.db)
+	webauthnRepo := postgres.NewWebAuthnRepository(s.db)
+	accessTokenRepo := postgres.NewPersonalAccessTokenRepository(s.db)
+	oidcRepo := postgres.NewOIDCRepository(s.db)
+	inactivityRepo := postgres.NewInactivitySwitchRepository(s.db)
+	emergencyRepo := postgres.NewEmergencyAccessRepository(s.db)
+	quorumRepo := postgres.NewQuorumRepository(s.db)
+	beneficiaryRepo := postgres.NewBeneficiaryRepository(s.db)
+	invitationRepo := postgres.NewNomineeInvitationRepository(s.db)
+	nomineeSessionRepo := postgres.NewNomineeSessionRepository(s.db)
+	permissionProfileRepo := postgres.NewPermissionProfileRepository(s.db)
+	revocationRepo := postgres.NewNomineeRevocationRepository(s.db)
+	webhookRepo := postgres.NewNotificationWebhookRepository(s.db)
+	messageRepo := postgres.NewNomineeMessageRepository(s.db)
+	claimRepo := postgres.NewClaimRepository(s.db)
+	claimInstructionRepo := postgres.NewClaimInstructionRepository(s.db)
+	auditRepo := postgres.NewAuditRepository(s.db)
+	anomalyRepo := postgres.NewNomineeAnomalyRepository(s.db)
+	vaultRepo := postgres.NewVaultRepository(s.db)
 
 	passwordUtil := util.NewPasswordUtil(10)
-	jwtUtil := util.NewJWTUtil(s.cfg.JWT.Secret)
+
+	keyManager, err := util.NewKeyManager(s.cfg.JWT.KeysDir, s.cfg.JWT.ActiveKeyID)
+	if err != nil {
+		panic(err)
+	}
+	jwtUtil := util.NewJWTUtil(keyManager, s.cfg.JWT.Issuer)
+
+	// Without a key, features that seal data at rest stay disabled
+	var sealer *util.Sealer
+	if s.cfg.Encryption.Key != "" {
+		sealer, err = util.NewSealer(s.cfg.Encryption.Key)
+		if err != nil {
+			panic(err)
+		}
+	}
 
 	storageService, err := service.NewStorageService(convertR2Config(&s.cfg.R2))
 	if err != nil {
 		panic(err)
 	}
 
-	authService := service.NewAuthService(userRepo, nomineeRepo, &s.cfg.JWT, passwordUtil)
+	mailer, err := service.NewMailer(&s.cfg.Mail)
+	if err != nil {
+		panic(err)
+	}
+
+	// Postgres keeps attempt counters consistent across replicas; memory is for single-node setups
+	var attemptStore service.AttemptStore = postgres.NewAuthAttemptRepository(s.db)
+	if s.cfg.Security.AttemptStore == "memory" {
+		attemptStore = service.NewMemoryAttemptStore()
+	}
+
+	auditService := service.NewAuditService(auditRepo)
+	attemptLimiter := service.NewAttemptLimiter(attemptStore, securityEventRepo, &s.cfg.Security)
+	twoFactorService := service.NewTwoFactorService(twoFactorRepo, userRepo, passwordUtil, auditService)
+	webauthnService := service.NewWebAuthnService(webauthnRepo, userRepo, passwordUtil, &s.cfg.WebAuthn)
+	authService := service.NewAuthService(userRepo, nomineeRepo, resetRepo, sessionRepo, &s.cfg.JWT, &s.cfg.App, passwordUtil, jwtUtil, mailer, twoFactorService, webauthnService, attemptLimiter, auditService)
 	userService := service.NewUserService(userRepo)
-	assetService := service.NewAssetService(assetRepo)
-	nomineeService := service.NewNomineeService(nomineeRepo, userRepo, authService)
-	documentService := service.NewDocumentService(documentRepo, storageService)
+	verificationService := service.NewEmailVerificationService(verificationRepo, userRepo, &s.cfg.App, mailer)
+	assetService := service.NewAssetService(assetRepo, auditService)
+	beneficiaryService := service.NewBeneficiaryService(beneficiaryRepo, assetRepo, nomineeRepo)
 	alertService := service.NewAlertService(alertRepo)
+	nomineeSessionService := service.NewNomineeSessionService(nomineeSessionRepo, jwtUtil)
+	anomalyService := service.NewNomineeAnomalyService(anomalyRepo, nomineeRepo, alertService, nomineeSessionService, auditService, &s.cfg.Anomaly)
+	accessLogger := service.NewNomineeAccessLogger(nomineeRepo, anomalyService)
+	emergencyService := service.NewEmergencyAccessService(emergencyRepo, nomineeRepo, userRepo, alertService, mailer, &s.cfg.Emergency, &s.cfg.App, auditService, accessLogger)
+	quorumService := service.NewQuorumService(quorumRepo, nomineeRepo, alertService, accessLogger)
+	vaultService := service.NewVaultService(vaultRepo, nomineeRepo, userRepo, emergencyService, mailer, auditService, sealer)
+	invitationService := service.NewNomineeInvitationService(invitationRepo, nomineeRepo, userRepo, passwordUtil, alertService, mailer, &s.cfg.App, vaultService)
+	permissionProfileService := service.NewPermissionProfileService(permissionProfileRepo, nomineeRepo)
+	webhookService := service.NewWebhookService(webhookRepo)
+	accessNotifier := service.NewNomineeAccessNotifier(revocationRepo, nomineeRepo, userRepo, alertService, mailer, webhookService, nomineeSessionService, &s.cfg.App, auditService)
+	nomineeService := service.NewNomineeService(nomineeRepo, userRepo, authService, alertService, attemptLimiter, emergencyService, quorumService, nomineeSessionService, permissionProfileService, accessNotifier, auditService, accessLogger)
+	messageService := service.NewNomineeMessageService(messageRepo, nomineeRepo, documentRepo, emergencyService, sealer)
+	nomineeDataService := service.NewNomineeDataService(nomineeRepo, assetRepo, documentRepo, storageService, permissionProfileService, messageService, accessNotifier, accessLogger, vaultService)
+	claimService := service.NewClaimService(claimRepo, claimInstructionRepo, assetRepo, nomineeRepo, nomineeDataService, emergencyService, storageService, accessLogger)
+	documentService := service.NewDocumentService(documentRepo, storageService, auditService, vaultService)
+	accessTokenService := service.NewPersonalAccessTokenService(accessTokenRepo)
+	oidcService := service.NewOIDCService(oidcRepo, userRepo, authService, passwordUtil, &s.cfg.OIDC)
+	inactivityService := service.NewInactivityService(inactivityRepo, userRepo, nomineeRepo, alertService, mailer, &s.cfg.Inactivity, &s.cfg.App)
 
-	authHandler := handler.NewAuthHandler(authService, s.db)
+	authHandler := handler.NewAuthHandler(authService, verificationService)
 	userHandler := handler.NewUserHandler(userService)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorService)
+	webauthnHandler := handler.NewWebAuthnHandler(webauthnService, authService)
+	accessTokenHandler := handler.NewAccessTokenHandler(accessTokenService)
+	oidcHandler := handler.NewOIDCHandler(oidcService)
+	inactivityHandler := handler.NewInactivityHandler(inactivityService)
+	emergencyHandler := handler.NewEmergencyAccessHandler(emergencyService)
+	quorumHandler := handler.NewQuorumHandler(quorumService)
+	verificationHandler := handler.NewEmailVerificationHandler(verificationService)
 	assetHandler := handler.NewAssetHandler(assetService)
+	beneficiaryHandler := handler.NewBeneficiaryHandler(beneficiaryService)
+	invitationHandler := handler.NewNomineeInvitationHandler(invitationService)
+	permissionProfileHandler := handler.NewPermissionProfileHandler(permissionProfileService)
+	revocationHandler := handler.NewNomineeRevocationHandler(accessNotifier)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	messageHandler := handler.NewNomineeMessageHandler(messageService)
+	claimHandler := handler.NewClaimHandler(claimService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	anomalyHandler := handler.NewNomineeAnomalyHandler(anomalyService)
+	vaultHandler := handler.NewVaultHandler(vaultService)
 	nomineeHandler := handler.NewNomineeHandler(
 		nomineeService,
 		userService,
-		assetService,
-		documentService,
+		nomineeDataService,
 		authService,
+		verificationService,
+		invitationService,
 	)
-	documentHandler := handler.NewDocumentHandler(documentService)
+	documentHandler := handler.NewDocumentHandler(documentService, verificationService)
 	alertHandler := handler.NewAlertHandler(alertService)
 
-	authHandler.SetNomineeService(nomineeService)
+	authHandler.SetNomineeService(nomineeService, nomineeDataService)
+
+	s.addJob("inactivity checks", s.cfg.Inactivity.CheckInterval, inactivityService.RunChecks)
+	s.addJob("emergency access requests", s.cfg.Emergency.CheckInterval, emergencyService.RunChecks)
 
-	authMiddleware := NewAuthMiddleware(jwtUtil)
+	authMiddleware := NewAuthMiddleware(jwtUtil, accessTokenService, nomineeSessionService)
 
 	s.router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
@@ -69,24 +161,64 @@ func (s *Server) setupRoutes() {
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Public keys for verifying tokens issued by this API
+	s.router.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, jwtUtil.JWKS())
+	})
+
 	v1 := s.router.Group("/api/v1")
 
 	v1.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok", "timestamp": time.Now().Unix()})
+		c.JSON(200, gin.H{"status": "ok", "timestamp": time.Now().Unix(), "audit_failures": auditService.Failures()})
 	})
 
 	auth := v1.Group("/auth")
 	{
 		auth.POST("/register", authHandler.Register)
 		auth.POST("/login", authHandler.Login)
+		auth.POST("/login/verify", authHandler.VerifyLogin)
 		auth.POST("/refresh-token", authHandler.RefreshToken)
+		auth.POST("/logout", authHandler.Logout)
 		auth.POST("/forgot-password", authHandler.ForgotPassword)
 		auth.POST("/reset-password", authHandler.ResetPassword)
+		auth.POST("/verify-email", verificationHandler.Verify)
 		auth.POST("/emergency-access", authHandler.EmergencyAccess)
+		auth.POST("/emergency-access/deny", emergencyHandler.DenyWithToken)
+		auth.POST("/nominee-access/revoke", revocationHandler.Revoke)
+	}
+
+	invitations := auth.Group("/nominee-invitations")
+	{
+		invitations.POST("/view", invitationHandler.Get)
+		invitations.POST("/accept", invitationHandler.Accept)
+		invitations.POST("/decline", invitationHandler.Decline)
+	}
+
+	oidc := auth.Group("/oidc")
+	{
+		oidc.GET("", oidcHandler.GetProvider)
+		oidc.POST("/authorize", oidcHandler.Authorize)
+		oidc.POST("/callback", oidcHandler.Callback)
+	}
+
+	webauthn := auth.Group("/webauthn")
+	{
+		webauthn.POST("/login/begin", webauthnHandler.BeginLogin)
+		webauthn.POST("/login/finish", webauthnHandler.FinishLogin)
+		webauthn.POST("/2fa/begin", webauthnHandler.BeginSecondFactor)
+		webauthn.POST("/2fa/finish", webauthnHandler.FinishSecondFactor)
+	}
+
+	// Registering an authenticator needs a signed-in user
+	webauthnRegister := webauthn.Group("/register", authMiddleware.Authenticate(), authMiddleware.Authorize())
+	{
+		webauthnRegister.POST("/begin", webauthnHandler.BeginRegistration)
+		webauthnRegister.POST("/finish", webauthnHandler.FinishRegistration)
 	}
 
 	api := v1.Group("")
-	api.Use(authMiddleware.Authenticate())
+	api.Use(authMiddleware.Authenticate(), authMiddleware.Authorize())
 
 	users := api.Group("/users")
 	{
@@ -94,6 +226,32 @@ func (s *Server) setupRoutes() {
 		users.PUT("/profile", userHandler.UpdateProfile)
 		users.PATCH("/settings", userHandler.UpdateSettings)
 		users.POST("/change-password", authHandler.ChangePassword)
+		users.POST("/email-verification", verificationHandler.Resend)
+		users.GET("/sessions", authHandler.ListSessions)
+		users.DELETE("/sessions", authHandler.RevokeOtherSessions)
+		users.DELETE("/sessions/:id", authHandler.RevokeSession)
+		users.GET("/2fa", twoFactorHandler.GetStatus)
+		users.POST("/2fa/enroll", twoFactorHandler.Enroll)
+		users.POST("/2fa/confirm", twoFactorHandler.Confirm)
+		users.POST("/2fa/disable", twoFactorHandler.Disable)
+		users.POST("/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+		users.GET("/webauthn/credentials", webauthnHandler.ListCredentials)
+		users.DELETE("/webauthn/credentials/:id", webauthnHandler.RemoveCredential)
+		users.GET("/tokens", accessTokenHandler.List)
+		users.POST("/tokens", accessTokenHandler.Create)
+		users.DELETE("/tokens/:id", accessTokenHandler.Revoke)
+		users.POST("/oidc/link", oidcHandler.Link)
+		users.POST("/oidc/link/callback", oidcHandler.LinkCallback)
+		users.GET("/oidc/identities", oidcHandler.ListIdentities)
+		users.DELETE("/oidc/identities/:id", oidcHandler.Unlink)
+		users.GET("/inactivity", inactivityHandler.GetStatus)
+		users.PUT("/inactivity", inactivityHandler.UpdateSettings)
+		users.POST("/check-in", inactivityHandler.CheckIn)
+		users.GET("/webhook", webhookHandler.Get)
+		users.PUT("/webhook", webhookHandler.Set)
+		users.DELETE("/webhook", webhookHandler.Delete)
+		users.GET("/audit-log", auditHandler.List)
+		users.GET("/audit-log/verify", auditHandler.Verify)
 	}
 
 	assets := api.Group("/assets")
@@ -107,6 +265,10 @@ func (s *Server) setupRoutes() {
 		assets.GET("/types/:type", assetHandler.GetByType)
 		assets.GET("/summary", assetHandler.GetSummary)
 		assets.GET("/:id/history", assetHandler.GetHistory)
+		assets.GET("/:id/beneficiaries", beneficiaryHandler.GetAll)
+		assets.PUT("/:id/beneficiaries", beneficiaryHandler.Replace)
+		assets.DELETE("/:id/beneficiaries", beneficiaryHandler.Delete)
+		assets.GET("/estate-report", beneficiaryHandler.EstateReport)
 	}
 
 	nominees := api.Group("/nominees")
@@ -117,14 +279,53 @@ func (s *Server) setupRoutes() {
 		nominees.PUT("/:id", nomineeHandler.Update)
 		nominees.DELETE("/:id", nomineeHandler.Delete)
 		nominees.POST("/:id/send-invitation", nomineeHandler.SendInvitation)
+		nominees.POST("/:id/revoke", nomineeHandler.Revoke)
+		nominees.POST("/:id/reinstate", anomalyHandler.Reinstate)
+		nominees.GET("/sessions", nomineeHandler.ListSessions)
+		nominees.DELETE("/sessions/:id", nomineeHandler.RevokeSession)
+		nominees.GET("/permission-profiles", permissionProfileHandler.List)
+		nominees.POST("/permission-profiles", permissionProfileHandler.Create)
+		nominees.PUT("/permission-profiles/:id", permissionProfileHandler.Update)
+		nominees.DELETE("/permission-profiles/:id", permissionProfileHandler.Delete)
+		nominees.GET("/messages", messageHandler.List)
+		nominees.POST("/messages", messageHandler.Create)
+		nominees.GET("/messages/:id", messageHandler.GetByID)
+		nominees.PUT("/messages/:id", messageHandler.Update)
+		nominees.DELETE("/messages/:id", messageHandler.Delete)
+		nominees.GET("/claim-instructions", claimHandler.ListInstructions)
+		nominees.POST("/claim-instructions", claimHandler.CreateInstruction)
+		nominees.PUT("/claim-instructions/:id", claimHandler.UpdateInstruction)
+		nominees.DELETE("/claim-instructions/:id", claimHandler.DeleteInstruction)
 		nominees.GET("/access-log", nomineeHandler.GetAccessLogs)
+		nominees.GET("/access-rules", anomalyHandler.GetRules)
+		nominees.PUT("/access-rules", anomalyHandler.UpdateRules)
+		nominees.GET("/anomalies", anomalyHandler.ListAnomalies)
+		nominees.GET("/vault", vaultHandler.GetStatus)
+		nominees.POST("/vault/split", vaultHandler.Split)
+		nominees.GET("/access-requests", emergencyHandler.ListForOwner)
+		nominees.POST("/access-requests/:id/deny", emergencyHandler.Deny)
+		nominees.GET("/quorum", quorumHandler.GetPolicy)
+		nominees.PUT("/quorum", quorumHandler.SetPolicy)
+		nominees.DELETE("/quorum", quorumHandler.DeletePolicy)
 	}
 
 	nomineeAccess := api.Group("/nominee-access")
 	{
 		nomineeAccess.GET("/users", nomineeHandler.GetUsersForNominee)
+		nomineeAccess.GET("/requests", emergencyHandler.ListForNominee)
+		nomineeAccess.POST("/invitations/accept", invitationHandler.AcceptWithAccount)
 		nomineeAccess.POST("/access/:userID", nomineeHandler.AccessUserData)
 		nomineeAccess.GET("/data/:userID", nomineeHandler.GetUserData)
+		nomineeAccess.GET("/documents", nomineeHandler.ListDocuments)
+		nomineeAccess.GET("/documents/:id/download", nomineeHandler.DownloadDocument)
+		nomineeAccess.GET("/claims", claimHandler.Settlement)
+		nomineeAccess.PATCH("/claims/steps/:id", claimHandler.UpdateStep)
+		nomineeAccess.POST("/claims/steps/:id/documents", claimHandler.UploadDocument)
+		nomineeAccess.GET("/claims/documents/:id/download", claimHandler.DownloadDocument)
+		nomineeAccess.DELETE("/claims/documents/:id", claimHandler.DeleteDocument)
+		nomineeAccess.GET("/quorum/:userID", quorumHandler.GetNomineeView)
+		nomineeAccess.GET("/vault", vaultHandler.GetProgress)
+		nomineeAccess.POST("/vault/shares", vaultHandler.SubmitShare)
 	}
 
 	documents := api.Group("/documents")
@@ -143,4 +344,6 @@ func (s *Server) setupRoutes() {
 		alerts.GET("", alertHandler.GetAll)
 		alerts.PATCH("/:id/read", alertHandler.MarkAsRead)
 	}
+
+	validateRoutePolicies(s.router.Routes())
 }
@@ -14,6 +14,8 @@ type Server struct {
 	httpServer *http.Server
 	cfg        *config.Config
 	db         *sqlx.DB
+	jobs       []job
+	stopJobs   context.CancelFunc
 }
 
 func NewServer(cfg *config.Config, db *sqlx.DB) *Server {
@@ -38,9 +40,16 @@ func NewServer(cfg *config.Config, db *sqlx.DB) *Server {
 }
 
 func (s *Server) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopJobs = cancel
+	s.startJobs(ctx)
+
 	return s.httpServer.ListenAndServe()
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.stopJobs != nil {
+		s.stopJobs()
+	}
 	return s.httpServer.Shutdown(ctx)
 }
@@ -3,14 +3,24 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	R2       R2Config
+	Server     ServerConfig
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	R2         R2Config
+	App        AppConfig
+	Mail       MailConfig
+	Security   SecurityConfig
+	WebAuthn   WebAuthnConfig
+	OIDC       OIDCConfig
+	Inactivity InactivityConfig
+	Emergency  EmergencyAccessConfig
+	Encryption EncryptionConfig
+	Anomaly    AnomalyConfig
 }
 
 type ServerConfig struct {
@@ -28,13 +38,110 @@ type DatabaseConfig struct {
 	SSLMode  string
 }
 
+// JWTConfig configures token signing. KeysDir holds one PEM file per key,
+// named <kid>.pem; ActiveKeyID picks the one new tokens are signed with.
+// To rotate, add a new key, make it active, and remove the old file once
+// the tokens it signed have expired.
 type JWTConfig struct {
-	Secret        string
+	KeysDir       string
+	ActiveKeyID   string
+	Issuer        string
 	ExpiryMinutes int
-	RefreshSecret string
 	RefreshExpiry int
 }
 
+type AppConfig struct {
+	FrontendURL             string
+	PasswordResetExpiry     time.Duration
+	EmailVerificationExpiry time.Duration
+	NomineeInvitationExpiry time.Duration
+	// NomineeRevocationExpiry is how long the revoke link in a nominee access notice works
+	NomineeRevocationExpiry time.Duration
+	// UnverifiedRestrictions lists actions blocked until the user verifies
+	// their email, e.g. "nominee_invitations", "nominee_lookup", "document_upload"
+	UnverifiedRestrictions []string
+}
+
+type MailConfig struct {
+	Driver       string // "smtp" or "file"
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	OutputDir    string
+}
+
+// SecurityConfig controls brute-force protection on credential endpoints.
+// Failures beyond the free attempts are answered with exponential backoff,
+// and reaching the lockout threshold blocks the key for LockoutDuration.
+type SecurityConfig struct {
+	AttemptStore       string // "postgres" or "memory"
+	AttemptWindow      time.Duration
+	FreeAttempts       int
+	LockoutThreshold   int
+	IPFreeAttempts     int
+	IPLockoutThreshold int
+	BaseBackoff        time.Duration
+	MaxBackoff         time.Duration
+	LockoutDuration    time.Duration
+}
+
+// WebAuthnConfig identifies this site to authenticators. RPID is the domain
+// credentials are scoped to and Origins lists the exact origins the frontend
+// is served from.
+type WebAuthnConfig struct {
+	RPID    string
+	RPName  string
+	Origins []string
+}
+
+// OIDCConfig configures sign-in with an external OpenID Connect provider.
+// It is disabled unless Issuer is set. RedirectURL is the frontend page the
+// provider returns to; it posts the code and state to /auth/oidc/callback.
+type OIDCConfig struct {
+	ProviderName string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// InactivityConfig drives the dead man's switch. Owners pick their own
+// inactivity period; once it lapses, reminders are sent during GracePeriod
+// and nominee access is released only if the owner never responds.
+type InactivityConfig struct {
+	CheckInterval time.Duration
+	DefaultDays   int
+	GracePeriod   time.Duration
+}
+
+// EmergencyAccessConfig controls emergency access requests. The owner can
+// deny a request during WaitingPeriod; otherwise it is granted and lasts for
+// GrantDuration. A denied nominee can ask again after another WaitingPeriod.
+type EmergencyAccessConfig struct {
+	WaitingPeriod time.Duration
+	GrantDuration time.Duration
+	CheckInterval time.Duration
+}
+
+// AnomalyConfig tunes the rules that score nominee access. An access scoring
+// AlertScore or more alerts the owner. BurstLimit accesses within BurstWindow
+// count as a burst of data views.
+type AnomalyConfig struct {
+	AlertScore  int
+	BurstWindow time.Duration
+	BurstLimit  int
+}
+
+// EncryptionConfig holds the key data is sealed with at rest, such as
+// letters to nominees. Key is a base64 encoded 32-byte AES-256 key; losing
+// it makes that data unreadable. Leaving it empty disables those features.
+type EncryptionConfig struct {
+	Key string
+}
+
 type R2Config struct {
 	AccountID       string
 	AccessKeyID     string
@@ -48,6 +155,28 @@ func Load() (*Config, error) {
 	writeTimeout, _ := strconv.Atoi(getEnv("SERVER_WRITE_TIMEOUT", "10"))
 	jwtExpiry, _ := strconv.Atoi(getEnv("JWT_EXPIRY_MINUTES", "15"))
 	refreshExpiry, _ := strconv.Atoi(getEnv("JWT_REFRESH_EXPIRY", "10080")) // 7 days
+	resetExpiry, _ := strconv.Atoi(getEnv("PASSWORD_RESET_EXPIRY_MINUTES", "30"))
+	verificationExpiry, _ := strconv.Atoi(getEnv("EMAIL_VERIFICATION_EXPIRY_HOURS", "24"))
+	invitationExpiry, _ := strconv.Atoi(getEnv("NOMINEE_INVITATION_EXPIRY_DAYS", "14"))
+	revocationExpiry, _ := strconv.Atoi(getEnv("NOMINEE_REVOCATION_EXPIRY_DAYS", "7"))
+	attemptWindow, _ := strconv.Atoi(getEnv("AUTH_ATTEMPT_WINDOW_MINUTES", "60"))
+	freeAttempts, _ := strconv.Atoi(getEnv("AUTH_FREE_ATTEMPTS", "3"))
+	lockoutThreshold, _ := strconv.Atoi(getEnv("AUTH_LOCKOUT_THRESHOLD", "10"))
+	ipFreeAttempts, _ := strconv.Atoi(getEnv("AUTH_IP_FREE_ATTEMPTS", "20"))
+	ipLockoutThreshold, _ := strconv.Atoi(getEnv("AUTH_IP_LOCKOUT_THRESHOLD", "100"))
+	baseBackoff, _ := strconv.Atoi(getEnv("AUTH_BACKOFF_BASE_SECONDS", "2"))
+	maxBackoff, _ := strconv.Atoi(getEnv("AUTH_BACKOFF_MAX_SECONDS", "300"))
+	lockoutDuration, _ := strconv.Atoi(getEnv("AUTH_LOCKOUT_MINUTES", "30"))
+	inactivityInterval, _ := strconv.Atoi(getEnv("INACTIVITY_CHECK_INTERVAL_MINUTES", "60"))
+	inactivityDays, _ := strconv.Atoi(getEnv("INACTIVITY_DEFAULT_DAYS", "90"))
+	inactivityGrace, _ := strconv.Atoi(getEnv("INACTIVITY_GRACE_DAYS", "14"))
+	emergencyWaiting, _ := strconv.Atoi(getEnv("EMERGENCY_ACCESS_WAITING_HOURS", "72"))
+	emergencyGrant, _ := strconv.Atoi(getEnv("EMERGENCY_ACCESS_GRANT_DAYS", "30"))
+	emergencyInterval, _ := strconv.Atoi(getEnv("EMERGENCY_ACCESS_CHECK_INTERVAL_MINUTES", "15"))
+	anomalyScore, _ := strconv.Atoi(getEnv("ANOMALY_ALERT_SCORE", "50"))
+	anomalyWindow, _ := strconv.Atoi(getEnv("ANOMALY_BURST_WINDOW_MINUTES", "10"))
+	anomalyBurst, _ := strconv.Atoi(getEnv("ANOMALY_BURST_LIMIT", "20"))
+	frontendURL := getEnv("FRONTEND_URL", "http://localhost:5173")
 
 	return &Config{
 		Server: ServerConfig{
@@ -64,9 +193,10 @@ func Load() (*Config, error) {
 			SSLMode:  getEnv("DB_SSLMODE", "require"), // Changed from "disable" to "require"
 		},
 		JWT: JWTConfig{
-			Secret:        getEnv("JWT_SECRET", "your-secret-key"),
+			KeysDir:       getEnv("JWT_KEYS_DIR", ""),
+			ActiveKeyID:   getEnv("JWT_ACTIVE_KID", ""),
+			Issuer:        getEnv("JWT_ISSUER", "sampatti"),
 			ExpiryMinutes: jwtExpiry,
-			RefreshSecret: getEnv("JWT_REFRESH_SECRET", "your-refresh-secret-key"),
 			RefreshExpiry: refreshExpiry,
 		},
 		R2: R2Config{
@@ -76,6 +206,65 @@ func Load() (*Config, error) {
 			BucketName:      getEnv("R2_BUCKET_NAME", "sampatti-documents"),
 			Endpoint:        getEnv("R2_ENDPOINT", ""),
 		},
+		App: AppConfig{
+			FrontendURL:             frontendURL,
+			PasswordResetExpiry:     time.Duration(resetExpiry) * time.Minute,
+			EmailVerificationExpiry: time.Duration(verificationExpiry) * time.Hour,
+			NomineeInvitationExpiry: time.Duration(invitationExpiry) * 24 * time.Hour,
+			NomineeRevocationExpiry: time.Duration(revocationExpiry) * 24 * time.Hour,
+			UnverifiedRestrictions:  getEnvList("UNVERIFIED_EMAIL_RESTRICTIONS", "nominee_invitations,nominee_lookup"),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "file"),
+			From:         getEnv("MAIL_FROM", "Sampatti <no-reply@sampatti.local>"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			OutputDir:    getEnv("MAIL_OUTPUT_DIR", "tmp/mail"),
+		},
+		Security: SecurityConfig{
+			AttemptStore:       getEnv("AUTH_ATTEMPT_STORE", "postgres"),
+			AttemptWindow:      time.Duration(attemptWindow) * time.Minute,
+			FreeAttempts:       freeAttempts,
+			LockoutThreshold:   lockoutThreshold,
+			IPFreeAttempts:     ipFreeAttempts,
+			IPLockoutThreshold: ipLockoutThreshold,
+			BaseBackoff:        time.Duration(baseBackoff) * time.Second,
+			MaxBackoff:         time.Duration(maxBackoff) * time.Second,
+			LockoutDuration:    time.Duration(lockoutDuration) * time.Minute,
+		},
+		WebAuthn: WebAuthnConfig{
+			RPID:    getEnv("WEBAUTHN_RP_ID", "localhost"),
+			RPName:  getEnv("WEBAUTHN_RP_NAME", "Sampatti"),
+			Origins: getEnvList("WEBAUTHN_ORIGINS", frontendURL),
+		},
+		OIDC: OIDCConfig{
+			ProviderName: getEnv("OIDC_PROVIDER_NAME", "Single sign-on"),
+			Issuer:       getEnv("OIDC_ISSUER", ""),
+			ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("OIDC_REDIRECT_URL", strings.TrimRight(frontendURL, "/")+"/auth/oidc/callback"),
+			Scopes:       getEnvList("OIDC_SCOPES", "openid,email,profile"),
+		},
+		Inactivity: InactivityConfig{
+			CheckInterval: time.Duration(inactivityInterval) * time.Minute,
+			DefaultDays:   inactivityDays,
+			GracePeriod:   time.Duration(inactivityGrace) * 24 * time.Hour,
+		},
+		Emergency: EmergencyAccessConfig{
+			WaitingPeriod: time.Duration(emergencyWaiting) * time.Hour,
+			GrantDuration: time.Duration(emergencyGrant) * 24 * time.Hour,
+			CheckInterval: time.Duration(emergencyInterval) * time.Minute,
+		},
+		Encryption: EncryptionConfig{
+			Key: getEnv("ENCRYPTION_KEY", ""),
+		},
+		Anomaly: AnomalyConfig{
+			AlertScore:  anomalyScore,
+			BurstWindow: time.Duration(anomalyWindow) * time.Minute,
+			BurstLimit:  anomalyBurst,
+		},
 	}, nil
 }
 
@@ -85,3 +274,14 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList reads a comma separated list, dropping empty entries
+func getEnvList(key, defaultValue string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type AccessTokenHandler struct {
+	tokenService *service.PersonalAccessTokenService
+}
+
+func NewAccessTokenHandler(tokenService *service.PersonalAccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{tokenService: tokenService}
+}
+
+// List returns the authenticated user's personal access tokens
+func (h *AccessTokenHandler) List(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tokens, err := h.tokenService.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch access tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Create issues a personal access token. The token is only ever shown in this response.
+func (h *AccessTokenHandler) Create(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays int      `json:"expires_in_days" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	rawToken, token, err := h.tokenService.Create(c.Request.Context(), userID, request.Name, request.Scopes, request.ExpiresInDays)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidScope) ||
+			errors.Is(err, service.ErrInvalidTokenExpiry) ||
+			errors.Is(err, service.ErrAccessTokenNameRequired) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":        rawToken,
+		"access_token": token,
+		"message":      "Copy this token now, it won't be shown again.",
+	})
+}
+
+// Revoke disables one of the user's personal access tokens
+func (h *AccessTokenHandler) Revoke(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tokenID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token ID"})
+		return
+	}
+
+	if err := h.tokenService.Revoke(c.Request.Context(), userID, tokenID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrAccessTokenNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "access token revoked"})
+}
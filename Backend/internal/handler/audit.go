@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// List returns a page of the user's audit trail, newest first. Pass the
+// lowest seq of a page as before to get the next one.
+func (h *AuditHandler) List(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var before int64
+	if value := c.Query("before"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before must be a positive sequence number"})
+			return
+		}
+		before = parsed
+	}
+
+	var limit int
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := h.auditService.List(c.Request.Context(), userID, before, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// Verify checks the user's audit trail for missing or altered entries
+func (h *AuditHandler) Verify(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	result, err := h.auditService.Verify(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Audit verification failed for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -2,33 +2,37 @@ package handler
 
 import (
 	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
+	"github.com/google/uuid"
 	"github.com/sampatti/internal/model"
-	"github.com/sampatti/internal/repository/postgres"
 	"github.com/sampatti/internal/service"
 	"github.com/sampatti/internal/types"
 )
 
 type AuthHandler struct {
-	authService    *service.AuthService
-	nomineeService *service.NomineeService
-	db             *sqlx.DB
+	authService         *service.AuthService
+	nomineeService      *service.NomineeService
+	verificationService *service.EmailVerificationService
+	nomineeData         *service.NomineeDataService
 }
 
-func NewAuthHandler(authService *service.AuthService, db *sqlx.DB) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, verificationService *service.EmailVerificationService) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		db:          db,
+		authService:         authService,
+		verificationService: verificationService,
 	}
 }
 
-// Set the nominee service after creation
-func (h *AuthHandler) SetNomineeService(nomineeService *service.NomineeService) {
+// Set the nominee services after creation
+func (h *AuthHandler) SetNomineeService(nomineeService *service.NomineeService, nomineeData *service.NomineeDataService) {
 	h.nomineeService = nomineeService
+	h.nomineeData = nomineeData
 }
 
 // Register handles new user registration
@@ -64,10 +68,17 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// The account exists either way; a failed email can be resent after logging in
+	if err := h.verificationService.SendVerification(c.Request.Context(), user); err != nil {
+		log.Printf("Failed to send verification email to %s: %v", user.Email, err)
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
-		"id":    user.ID,
-		"name":  user.Name,
-		"email": user.Email,
+		"id":             user.ID,
+		"name":           user.Name,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"message":        "check your inbox to verify your email address",
 	})
 }
 
@@ -83,7 +94,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Authenticate user and get tokens
-	accessToken, refreshToken, err := h.authService.Login(c.Request.Context(), request.Email, request.Password)
+	result, err := h.authService.Login(c.Request.Context(), request.Email, request.Password, clientInfo(c))
+	if respondTooManyAttempts(c, err) {
+		return
+	}
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, service.ErrInvalidCredentials) {
@@ -93,32 +107,95 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Fetch user profile after successful authentication
-	userRepo := postgres.NewUserRepository(h.db)
-	user, err := userRepo.GetByEmail(c.Request.Context(), request.Email)
+	// The password was correct but a second factor is still needed
+	if result.TwoFactorRequired() {
+		respondWithChallenge(c, result)
+		return
+	}
+
+	respondWithLogin(c, result)
+}
+
+// VerifyLogin completes a two-factor login
+func (h *AuthHandler) VerifyLogin(c *gin.Context) {
+	var request struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	result, err := h.authService.VerifyLogin(c.Request.Context(), request.ChallengeToken, request.Code, clientInfo(c))
+	if respondTooManyAttempts(c, err) {
+		return
+	}
 	if err != nil {
-		// Still return tokens even if user fetch fails
-		c.JSON(http.StatusOK, gin.H{
-			"access_token":  accessToken,
-			"refresh_token": refreshToken,
-			"token_type":    "Bearer",
-		})
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidToken) ||
+			errors.Is(err, service.ErrExpiredToken) ||
+			errors.Is(err, service.ErrInvalidTwoFactorCode) ||
+			errors.Is(err, service.ErrTwoFactorNotEnrolled) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
+	respondWithLogin(c, result)
+}
+
+// clientInfo describes the device making the request
+func clientInfo(c *gin.Context) service.ClientInfo {
+	return service.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// respondTooManyAttempts answers with 429 and a Retry-After header when err
+// says the caller is in backoff or locked out, and reports whether it did
+func respondTooManyAttempts(c *gin.Context, err error) bool {
+	var limitErr *service.AttemptLimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(limitErr.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": limitErr.Error(), "retry_after": retryAfter})
+	return true
+}
+
+// respondWithChallenge tells the client which second factors can finish the login
+func respondWithChallenge(c *gin.Context, result *service.LoginResult) {
+	c.JSON(http.StatusOK, gin.H{
+		"two_factor_required": true,
+		"challenge_token":     result.ChallengeToken,
+		"methods":             result.SecondFactors,
+	})
+}
+
+// respondWithLogin writes the tokens and a sanitized profile of a completed login
+func respondWithLogin(c *gin.Context, result *service.LoginResult) {
+	user := result.User
+
 	// Sanitize user data for response (remove sensitive fields)
 	userData := gin.H{
-		"id":           user.ID,
-		"name":         user.Name,
-		"email":        user.Email,
-		"phone_number": user.PhoneNumber,
-		"created_at":   user.CreatedAt,
+		"id":             user.ID,
+		"name":           user.Name,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"phone_number":   user.PhoneNumber,
+		"created_at":     user.CreatedAt,
 	}
 
 	// Return tokens and user data
 	c.JSON(http.StatusOK, gin.H{
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
+		"access_token":  result.AccessToken,
+		"refresh_token": result.RefreshToken,
 		"token_type":    "Bearer",
 		"user":          userData,
 	})
@@ -134,10 +211,12 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	accessToken, err := h.authService.RefreshToken(c.Request.Context(), request.RefreshToken)
+	accessToken, refreshToken, err := h.authService.RefreshToken(c.Request.Context(), request.RefreshToken, clientInfo(c))
 	if err != nil {
 		status := http.StatusInternalServerError
-		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrExpiredToken) {
+		if errors.Is(err, service.ErrInvalidToken) ||
+			errors.Is(err, service.ErrExpiredToken) ||
+			errors.Is(err, service.ErrRefreshTokenReused) {
 			status = http.StatusUnauthorized
 		}
 		c.JSON(status, gin.H{"error": err.Error()})
@@ -145,11 +224,99 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"access_token": accessToken,
-		"token_type":   "Bearer",
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
 	})
 }
 
+// Logout revokes the session behind a refresh token
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var request struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), request.RefreshToken); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidToken) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
+}
+
+// ListSessions returns the authenticated user's active sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	currentSessionID, _ := types.ExtractSessionIDFromGin(c)
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), userID, currentSessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch sessions", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession signs out one of the authenticated user's sessions
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	idParam := c.Param("id")
+	sessionID, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		return
+	}
+
+	if err := h.authService.RevokeSession(c.Request.Context(), userID, sessionID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrSessionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked successfully"})
+}
+
+// RevokeOtherSessions signs out all of the authenticated user's other sessions
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	currentSessionID, _ := types.ExtractSessionIDFromGin(c)
+
+	if err := h.authService.RevokeOtherSessions(c.Request.Context(), userID, currentSessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "other sessions revoked successfully"})
+}
+
 // ChangePassword handles password changes
 func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	userID, ok := types.ExtractUserIDFromGin(c)
@@ -168,7 +335,9 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	err := h.authService.ChangePassword(c.Request.Context(), userID, request.OldPassword, request.NewPassword)
+	currentSessionID, _ := types.ExtractSessionIDFromGin(c)
+
+	err := h.authService.ChangePassword(c.Request.Context(), userID, currentSessionID, request.OldPassword, request.NewPassword)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, service.ErrInvalidCredentials) {
@@ -192,6 +361,11 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 		return
 	}
 
+	if err := h.authService.RequestPasswordReset(c.Request.Context(), request.Email); err != nil {
+		// Respond the same way regardless, so the endpoint can't be used to probe accounts
+		log.Printf("Failed to process password reset for %s: %v", request.Email, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "if your email exists in our system, you will receive password reset instructions"})
 }
 
@@ -199,7 +373,7 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var request struct {
 		Token       string `json:"token" binding:"required"`
-		NewPassword string `json:"new_password" binding:"required,min:8"`
+		NewPassword string `json:"new_password" binding:"required,min=8"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -207,15 +381,26 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
+	if err := h.authService.ResetPassword(c.Request.Context(), request.Token, request.NewPassword); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidResetToken) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "password has been reset successfully"})
 }
 
-// EmergencyAccess handles nominee emergency access
-// Backend/internal/handler/auth.go - Modified EmergencyAccess function
+// EmergencyAccess signs a nominee in with the password they chose when
+// accepting their invitation
 func (h *AuthHandler) EmergencyAccess(c *gin.Context) {
 	var request struct {
-		Email               string `json:"email" binding:"required,email"`
-		EmergencyAccessCode string `json:"emergency_access_code" binding:"required"`
+		Email    string `json:"email" binding:"required,email"`
+		Password string `json:"password" binding:"required"`
+		// UserID picks the owner when the nominee was named by several
+		UserID *uuid.UUID `json:"user_id"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -227,22 +412,32 @@ func (h *AuthHandler) EmergencyAccess(c *gin.Context) {
 	nominee, user, err := h.nomineeService.VerifyNomineeAccess(
 		c.Request.Context(),
 		request.Email,
-		request.EmergencyAccessCode,
+		request.Password,
+		request.UserID,
+		clientInfo(c),
 	)
 
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+	if respondTooManyAttempts(c, err) {
 		return
 	}
-
-	// Check nominee status
-	if nominee.Status != "Active" && nominee.Status != "Pending" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "nominee access is not active"})
+	if respondAccessPending(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrOwnerRequired) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrNomineeSuspended) || errors.Is(err, service.ErrNomineeNotActive) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
 
-	// Generate access token for API usage
-	token, err := h.authService.GenerateNomineeToken(nominee.ID, user.ID, nominee.AccessLevel)
+	// Start a session for the duration the owner chose
+	token, session, err := h.nomineeService.StartSession(c.Request.Context(), nominee, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate access token"})
 		return
@@ -257,7 +452,9 @@ func (h *AuthHandler) EmergencyAccess(c *gin.Context) {
 		DeviceInfo: c.Request.UserAgent(),
 	}
 
-	h.nomineeService.LogNomineeAccess(c.Request.Context(), accessLog)
+	if err := h.nomineeService.LogNomineeAccess(c.Request.Context(), accessLog); err != nil {
+		log.Printf("Failed to log emergency access for nominee %s: %v", nominee.ID, err)
+	}
 
 	// Sanitize user data
 	userData := map[string]interface{}{
@@ -266,46 +463,33 @@ func (h *AuthHandler) EmergencyAccess(c *gin.Context) {
 		"email": user.Email,
 	}
 
-	// Collect data based on access level
-	var assets []model.Asset
-	var documents []model.Document
-
-	assetService := service.NewAssetService(postgres.NewAssetRepository(h.db))
-	documentService := service.NewDocumentService(
-		postgres.NewDocumentRepository(h.db),
-		nil, // No storage service needed for just fetching data
-	)
-
-	// Fetch assets if allowed by access level
-	if nominee.AccessLevel == "Full" || nominee.AccessLevel == "Limited" {
-		assets, _ = assetService.GetByUserID(c.Request.Context(), user.ID)
-
-		// For Limited access, filter out sensitive data
-		if nominee.AccessLevel == "Limited" {
-			for i := range assets {
-				// Redact sensitive fields for Limited access
-				assets[i].AccountNumber = "********" // Mask account number
-				// You could add more field masking here
-			}
-		}
+	// What the nominee sees is decided by their permission profile
+	view, err := h.nomineeData.View(c.Request.Context(), nominee.ID, nominee.AccessLevel)
+	if err != nil {
+		log.Printf("Failed to load nominee view for nominee %s: %v", nominee.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load data"})
+		return
 	}
 
-	// Fetch documents
-	if nominee.AccessLevel == "Full" || nominee.AccessLevel == "Limited" {
-		documents, _ = documentService.GetByUserID(c.Request.Context(), user.ID)
-	} else {
-		documents, _ = documentService.GetNomineeDocuments(c.Request.Context(), nominee.ID)
+	// Other nominees can see how close the owner's quorum is
+	quorum, err := h.nomineeService.QuorumStatus(c.Request.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to load quorum status for user %s: %v", user.ID, err)
 	}
 
 	// Prepare response with all data
 	response := gin.H{
 		"access_token": token,
 		"token_type":   "Bearer",
+		"expires_at":   session.ExpiresAt,
 		"user":         userData,
 		"access_level": nominee.AccessLevel,
+		"permissions":  view.Permissions,
 		"user_id":      user.ID,
-		"assets":       assets,
-		"documents":    documents,
+		"assets":       view.Assets,
+		"documents":    view.Documents,
+		"messages":     view.Messages,
+		"quorum":       quorum,
 	}
 
 	c.JSON(http.StatusOK, response)
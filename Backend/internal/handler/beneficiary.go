@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type BeneficiaryHandler struct {
+	beneficiaryService *service.BeneficiaryService
+}
+
+func NewBeneficiaryHandler(beneficiaryService *service.BeneficiaryService) *BeneficiaryHandler {
+	return &BeneficiaryHandler{beneficiaryService: beneficiaryService}
+}
+
+// GetAll returns an asset's beneficiaries and their shares
+func (h *BeneficiaryHandler) GetAll(c *gin.Context) {
+	userID, assetID, ok := assetRequest(c)
+	if !ok {
+		return
+	}
+
+	allocation, err := h.beneficiaryService.GetAllocation(c.Request.Context(), assetID, userID)
+	if err != nil {
+		respondBeneficiaryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, allocation)
+}
+
+// Replace sets an asset's beneficiaries. Shares must add up to 100 percent.
+func (h *BeneficiaryHandler) Replace(c *gin.Context) {
+	userID, assetID, ok := assetRequest(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Beneficiaries []service.BeneficiaryShare `json:"beneficiaries" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	allocation, err := h.beneficiaryService.SetAllocation(c.Request.Context(), assetID, userID, request.Beneficiaries)
+	if err != nil {
+		respondBeneficiaryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, allocation)
+}
+
+// Delete removes all of an asset's beneficiaries
+func (h *BeneficiaryHandler) Delete(c *gin.Context) {
+	userID, assetID, ok := assetRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.beneficiaryService.ClearAllocation(c.Request.Context(), assetID, userID); err != nil {
+		respondBeneficiaryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "beneficiaries removed"})
+}
+
+// EstateReport shows each nominee's expected inheritance at current values
+func (h *BeneficiaryHandler) EstateReport(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	report, err := h.beneficiaryService.EstateReport(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate estate report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// assetRequest reads the signed-in user and the :id asset parameter
+func assetRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	assetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid asset ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, assetID, true
+}
+
+func respondBeneficiaryError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrAssetNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrUnauthorized):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidShare),
+		errors.Is(err, service.ErrSharesNot100),
+		errors.Is(err, service.ErrDuplicateBeneficiary),
+		errors.Is(err, service.ErrBeneficiaryNotOwned):
+		status = http.StatusBadRequest
+	default:
+		c.JSON(status, gin.H{"error": "failed to update beneficiaries"})
+		return
+	}
+
+	c.JSON(status, gin.H{"error": err.Error()})
+}
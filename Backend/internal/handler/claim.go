@@ -0,0 +1,309 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type ClaimHandler struct {
+	claimService *service.ClaimService
+}
+
+func NewClaimHandler(claimService *service.ClaimService) *ClaimHandler {
+	return &ClaimHandler{claimService: claimService}
+}
+
+type claimInstructionRequest struct {
+	Institution  string                       `json:"institution" binding:"required"`
+	Instructions string                       `json:"instructions"`
+	Steps        []model.ClaimInstructionStep `json:"steps"`
+}
+
+func (r claimInstructionRequest) toModel(userID uuid.UUID) *model.ClaimInstruction {
+	return &model.ClaimInstruction{
+		UserID:       userID,
+		Institution:  r.Institution,
+		Instructions: r.Instructions,
+		Steps:        r.Steps,
+	}
+}
+
+// ListInstructions returns the owner's institution-specific claim instructions
+func (h *ClaimHandler) ListInstructions(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	instructions, err := h.claimService.ListInstructions(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch claim instructions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, instructions)
+}
+
+func (h *ClaimHandler) CreateInstruction(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request claimInstructionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	instruction := request.toModel(userID)
+	if err := h.claimService.CreateInstruction(c.Request.Context(), instruction); err != nil {
+		respondClaimError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, instruction)
+}
+
+func (h *ClaimHandler) UpdateInstruction(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	instructionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid claim instruction ID"})
+		return
+	}
+
+	var request claimInstructionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	instruction := request.toModel(userID)
+	instruction.ID = instructionID
+	if err := h.claimService.UpdateInstruction(c.Request.Context(), instruction); err != nil {
+		respondClaimError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, instruction)
+}
+
+func (h *ClaimHandler) DeleteInstruction(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	instructionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid claim instruction ID"})
+		return
+	}
+
+	if err := h.claimService.DeleteInstruction(c.Request.Context(), userID, instructionID); err != nil {
+		respondClaimError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "claim instruction deleted"})
+}
+
+// Settlement returns the nominee's claim checklists and overall estate settlement progress
+func (h *ClaimHandler) Settlement(c *gin.Context) {
+	nomineeID, _, ok := types.ExtractNomineeFromGin(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires nominee access"})
+		return
+	}
+
+	accessLevel := c.GetString(string(types.AccessLevelKey))
+
+	settlement, err := h.claimService.Settlement(c.Request.Context(), nomineeID, accessLevel)
+	if err != nil {
+		respondClaimError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settlement)
+}
+
+// UpdateStep records the nominee's progress on a claim step
+func (h *ClaimHandler) UpdateStep(c *gin.Context) {
+	nomineeID, _, ok := types.ExtractNomineeFromGin(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires nominee access"})
+		return
+	}
+
+	stepID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid claim step ID"})
+		return
+	}
+
+	var request struct {
+		Status string `json:"status" binding:"required"`
+		Notes  string `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	accessLevel := c.GetString(string(types.AccessLevelKey))
+
+	step, err := h.claimService.UpdateStep(c.Request.Context(), nomineeID, accessLevel, stepID, request.Status, request.Notes, clientInfo(c))
+	if err != nil {
+		respondClaimError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, step)
+}
+
+// UploadDocument attaches a file from the nominee to a claim step
+func (h *ClaimHandler) UploadDocument(c *gin.Context) {
+	nomineeID, _, ok := types.ExtractNomineeFromGin(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires nominee access"})
+		return
+	}
+
+	stepID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid claim step ID"})
+		return
+	}
+
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form data", "details": err.Error()})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file"})
+		return
+	}
+
+	accessLevel := c.GetString(string(types.AccessLevelKey))
+
+	document, err := h.claimService.UploadDocument(
+		c.Request.Context(),
+		nomineeID,
+		accessLevel,
+		stepID,
+		fileData,
+		header.Filename,
+		header.Size,
+		header.Header.Get("Content-Type"),
+	)
+	if err != nil {
+		respondClaimError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, document)
+}
+
+// DownloadDocument streams a file uploaded for one of the nominee's claims
+func (h *ClaimHandler) DownloadDocument(c *gin.Context) {
+	nomineeID, _, ok := types.ExtractNomineeFromGin(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires nominee access"})
+		return
+	}
+
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid claim document ID"})
+		return
+	}
+
+	accessLevel := c.GetString(string(types.AccessLevelKey))
+
+	fileData, document, err := h.claimService.DownloadDocument(c.Request.Context(), nomineeID, accessLevel, documentID)
+	if err != nil {
+		respondClaimError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+document.Filename)
+	c.Data(http.StatusOK, document.MimeType, fileData)
+}
+
+// DeleteDocument removes a claim document the nominee uploaded
+func (h *ClaimHandler) DeleteDocument(c *gin.Context) {
+	nomineeID, _, ok := types.ExtractNomineeFromGin(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires nominee access"})
+		return
+	}
+
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid claim document ID"})
+		return
+	}
+
+	accessLevel := c.GetString(string(types.AccessLevelKey))
+
+	if err := h.claimService.DeleteDocument(c.Request.Context(), nomineeID, accessLevel, documentID); err != nil {
+		respondClaimError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "claim document deleted"})
+}
+
+func respondClaimError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrClaimStepNotFound),
+		errors.Is(err, service.ErrClaimDocumentNotFound),
+		errors.Is(err, service.ErrClaimInstructionNotFound),
+		errors.Is(err, service.ErrNomineeNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidClaimStatus),
+		errors.Is(err, service.ErrClaimNotesTooLong),
+		errors.Is(err, service.ErrInvalidClaimInstruction),
+		errors.Is(err, service.ErrDocumentTooLarge):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrClaimInstructionExists):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrAccessNotReleased):
+		status = http.StatusForbidden
+	}
+
+	if status == http.StatusInternalServerError {
+		log.Printf("Claim request failed: %v", err)
+		c.JSON(status, gin.H{"error": "failed to process claim request"})
+		return
+	}
+
+	c.JSON(status, gin.H{"error": err.Error()})
+}
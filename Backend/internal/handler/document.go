@@ -15,10 +15,11 @@ import (
 
 type DocumentHandler struct {
 	documentService *service.DocumentService
+	verification    *service.EmailVerificationService
 }
 
-func NewDocumentHandler(documentService *service.DocumentService) *DocumentHandler {
-	return &DocumentHandler{documentService: documentService}
+func NewDocumentHandler(documentService *service.DocumentService, verification *service.EmailVerificationService) *DocumentHandler {
+	return &DocumentHandler{documentService: documentService, verification: verification}
 }
 
 // Upload handles document upload
@@ -29,6 +30,10 @@ func (h *DocumentHandler) Upload(c *gin.Context) {
 		return
 	}
 
+	if !requireVerifiedEmail(c, h.verification, userID, service.RestrictDocumentUpload) {
+		return
+	}
+
 	// Parse form data
 	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form data", "details": err.Error()})
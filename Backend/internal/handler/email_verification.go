@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type EmailVerificationHandler struct {
+	verificationService *service.EmailVerificationService
+}
+
+func NewEmailVerificationHandler(verificationService *service.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{verificationService: verificationService}
+}
+
+// Verify confirms an email address with the token from the verification email
+func (h *EmailVerificationHandler) Verify(c *gin.Context) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	if err := h.verificationService.Verify(c.Request.Context(), request.Token); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidVerificationToken) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email address verified"})
+}
+
+// Resend mails a new verification link to the authenticated user
+func (h *EmailVerificationHandler) Resend(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.verificationService.Resend(c.Request.Context(), userID); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrEmailAlreadyVerified):
+			status = http.StatusConflict
+		case errors.Is(err, service.ErrVerificationTooSoon):
+			status = http.StatusTooManyRequests
+		case errors.Is(err, service.ErrUserNotFound):
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "verification email sent"})
+}
+
+// requireVerifiedEmail responds with 403 and returns false when the user
+// must verify their email before performing action
+func requireVerifiedEmail(c *gin.Context, verificationService *service.EmailVerificationService, userID uuid.UUID, action string) bool {
+	err := verificationService.RequireVerified(c.Request.Context(), userID, action)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "email_not_verified"})
+		return false
+	}
+
+	log.Printf("Failed to check email verification for %s: %v", userID, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check email verification"})
+	return false
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type EmergencyAccessHandler struct {
+	emergencyService *service.EmergencyAccessService
+}
+
+func NewEmergencyAccessHandler(emergencyService *service.EmergencyAccessService) *EmergencyAccessHandler {
+	return &EmergencyAccessHandler{emergencyService: emergencyService}
+}
+
+// respondAccessPending answers an access attempt that opened or is waiting on
+// an emergency access request, so the nominee can see where it stands
+func respondAccessPending(c *gin.Context, err error) bool {
+	var pendingErr *service.AccessPendingError
+	if !errors.As(err, &pendingErr) {
+		return false
+	}
+
+	status := http.StatusAccepted
+	if pendingErr.Request.Status == service.AccessRequestDenied {
+		status = http.StatusForbidden
+	}
+
+	c.JSON(status, gin.H{"error": pendingErr.Error(), "request": pendingErr.Request})
+	return true
+}
+
+// ListForOwner returns the emergency access requests made for the user's account
+func (h *EmergencyAccessHandler) ListForOwner(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	requests, err := h.emergencyService.ListForOwner(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch access requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// ListForNominee returns the emergency access requests the user made as a nominee
+func (h *EmergencyAccessHandler) ListForNominee(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	requests, err := h.emergencyService.ListForNominee(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch access requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// Deny lets the owner deny a pending request
+func (h *EmergencyAccessHandler) Deny(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request ID"})
+		return
+	}
+
+	request, err := h.emergencyService.Deny(c.Request.Context(), userID, requestID)
+	if err != nil {
+		respondDenyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// DenyWithToken denies a request from the link in the owner's notification email
+func (h *EmergencyAccessHandler) DenyWithToken(c *gin.Context) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	accessRequest, err := h.emergencyService.DenyWithToken(c.Request.Context(), request.Token)
+	if err != nil {
+		respondDenyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "emergency access request denied", "status": accessRequest.Status})
+}
+
+func respondDenyError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrAccessRequestNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to deny access request"})
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type InactivityHandler struct {
+	inactivityService *service.InactivityService
+}
+
+func NewInactivityHandler(inactivityService *service.InactivityService) *InactivityHandler {
+	return &InactivityHandler{inactivityService: inactivityService}
+}
+
+// GetStatus returns the user's inactivity settings and when reminders and
+// release would happen
+func (h *InactivityHandler) GetStatus(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	status, err := h.inactivityService.GetStatus(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch inactivity status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// UpdateSettings changes the inactivity period or turns the switch off
+func (h *InactivityHandler) UpdateSettings(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		Enabled        *bool `json:"enabled" binding:"required"`
+		InactivityDays int   `json:"inactivity_days" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	status, err := h.inactivityService.UpdateSettings(c.Request.Context(), userID, *request.Enabled, request.InactivityDays)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidInactivityPeriod) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update inactivity settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// CheckIn restarts the user's inactivity period
+func (h *InactivityHandler) CheckIn(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	status, err := h.inactivityService.CheckIn(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
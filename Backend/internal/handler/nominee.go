@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"encoding/csv"
 	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,26 +17,29 @@ import (
 )
 
 type NomineeHandler struct {
-	nomineeService  *service.NomineeService
-	userService     *service.UserService
-	assetService    *service.AssetService
-	documentService *service.DocumentService
-	authService     *service.AuthService
+	nomineeService *service.NomineeService
+	userService    *service.UserService
+	nomineeData    *service.NomineeDataService
+	authService    *service.AuthService
+	verification   *service.EmailVerificationService
+	invitations    *service.NomineeInvitationService
 }
 
 func NewNomineeHandler(
 	nomineeService *service.NomineeService,
 	userService *service.UserService,
-	assetService *service.AssetService,
-	documentService *service.DocumentService,
+	nomineeData *service.NomineeDataService,
 	authService *service.AuthService,
+	verification *service.EmailVerificationService,
+	invitations *service.NomineeInvitationService,
 ) *NomineeHandler {
 	return &NomineeHandler{
-		nomineeService:  nomineeService,
-		userService:     userService,
-		assetService:    assetService,
-		documentService: documentService,
-		authService:     authService,
+		nomineeService: nomineeService,
+		userService:    userService,
+		nomineeData:    nomineeData,
+		authService:    authService,
+		verification:   verification,
+		invitations:    invitations,
 	}
 }
 
@@ -50,6 +57,10 @@ func (h *NomineeHandler) Create(c *gin.Context) {
 		PhoneNumber  string `json:"phone_number"`
 		Relationship string `json:"relationship"`
 		AccessLevel  string `json:"access_level" binding:"required"`
+		// AccessDurationHours is how long each nominee sign-in lasts, 24 hours if unset
+		AccessDurationHours int `json:"access_duration_hours"`
+		// PermissionProfileID narrows what the nominee sees beyond the access level
+		PermissionProfileID *uuid.UUID `json:"permission_profile_id"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -62,42 +73,52 @@ func (h *NomineeHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if !requireVerifiedEmail(c, h.verification, userID, service.RestrictNomineeInvitations) {
+		return
+	}
+
 	nominee := &model.Nominee{
-		UserID:       userID,
-		Name:         request.Name,
-		Email:        request.Email,
-		PhoneNumber:  request.PhoneNumber,
-		Relationship: request.Relationship,
-		AccessLevel:  request.AccessLevel,
-		Status:       "Pending",
+		UserID:              userID,
+		Name:                request.Name,
+		Email:               request.Email,
+		PhoneNumber:         request.PhoneNumber,
+		Relationship:        request.Relationship,
+		AccessLevel:         request.AccessLevel,
+		AccessDurationHours: request.AccessDurationHours,
+		PermissionProfileID: request.PermissionProfileID,
 	}
 
 	if err := h.nomineeService.Create(c.Request.Context(), nominee); err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, service.ErrNomineeExists) {
 			status = http.StatusConflict
+		} else if errors.Is(err, service.ErrInvalidAccessDuration) || errors.Is(err, service.ErrPermissionProfileNotFound) {
+			status = http.StatusBadRequest
 		}
 		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Generate an emergency access code for the nominee
-	accessCode, err := h.authService.GenerateNomineeInvite(c.Request.Context(), nominee.ID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate access code"})
-		return
+	// The nominee exists either way; a failed invitation can be resent
+	message := "An invitation has been emailed to your nominee."
+	invitationSent := true
+	if _, err := h.invitations.Send(c.Request.Context(), nominee); err != nil {
+		log.Printf("Failed to send invitation to nominee %s: %v", nominee.ID, err)
+		message = "The nominee was added but the invitation email could not be sent. Please resend it."
+		invitationSent = false
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"nominee": gin.H{
-			"id":           nominee.ID,
-			"name":         nominee.Name,
-			"email":        nominee.Email,
-			"access_level": nominee.AccessLevel,
-			"status":       nominee.Status,
+			"id":                    nominee.ID,
+			"name":                  nominee.Name,
+			"email":                 nominee.Email,
+			"access_level":          nominee.AccessLevel,
+			"permission_profile_id": nominee.PermissionProfileID,
+			"status":                nominee.Status,
 		},
-		"code":    accessCode,
-		"message": "Store this emergency access code securely and share it with your nominee. This code will not be shown again.",
+		"invitation_sent": invitationSent,
+		"message":         message,
 	})
 }
 
@@ -165,6 +186,10 @@ func (h *NomineeHandler) Update(c *gin.Context) {
 		PhoneNumber  string `json:"phone_number"`
 		Relationship string `json:"relationship"`
 		AccessLevel  string `json:"access_level" binding:"required"`
+		// AccessDurationHours of 0 keeps the current duration
+		AccessDurationHours int `json:"access_duration_hours"`
+		// PermissionProfileID of null falls back to the access level's profile
+		PermissionProfileID *uuid.UUID `json:"permission_profile_id"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -190,13 +215,15 @@ func (h *NomineeHandler) Update(c *gin.Context) {
 	}
 
 	nominee := &model.Nominee{
-		ID:           nomineeID,
-		UserID:       userID,
-		Name:         request.Name,
-		Email:        existingNominee.Email,
-		PhoneNumber:  request.PhoneNumber,
-		Relationship: request.Relationship,
-		AccessLevel:  request.AccessLevel,
+		ID:                  nomineeID,
+		UserID:              userID,
+		Name:                request.Name,
+		Email:               existingNominee.Email,
+		PhoneNumber:         request.PhoneNumber,
+		Relationship:        request.Relationship,
+		AccessLevel:         request.AccessLevel,
+		AccessDurationHours: request.AccessDurationHours,
+		PermissionProfileID: request.PermissionProfileID,
 	}
 
 	if err := h.nomineeService.Update(c.Request.Context(), nominee, userID); err != nil {
@@ -205,6 +232,8 @@ func (h *NomineeHandler) Update(c *gin.Context) {
 			status = http.StatusNotFound
 		} else if errors.Is(err, service.ErrUnauthorized) {
 			status = http.StatusForbidden
+		} else if errors.Is(err, service.ErrInvalidAccessDuration) || errors.Is(err, service.ErrPermissionProfileNotFound) {
+			status = http.StatusBadRequest
 		}
 		c.JSON(status, gin.H{"error": err.Error()})
 		return
@@ -241,23 +270,21 @@ func (h *NomineeHandler) Delete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "nominee deleted successfully"})
 }
 
-func (h *NomineeHandler) SendInvitation(c *gin.Context) {
+// Revoke withdraws a nominee's access and ends their sessions straight away
+func (h *NomineeHandler) Revoke(c *gin.Context) {
 	userID, ok := types.ExtractUserIDFromGin(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	idParam := c.Param("id")
-	nomineeID, err := uuid.Parse(idParam)
+	nomineeID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid nominee ID"})
 		return
 	}
 
-	// Get nominee to validate ownership
-	nominee, err := h.nomineeService.GetByID(c.Request.Context(), nomineeID, userID)
-	if err != nil {
+	if err := h.nomineeService.RevokeNominee(c.Request.Context(), nomineeID, userID); err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, service.ErrNomineeNotFound) {
 			status = http.StatusNotFound
@@ -268,29 +295,95 @@ func (h *NomineeHandler) SendInvitation(c *gin.Context) {
 		return
 	}
 
-	// Generate access code
-	accessCode, err := h.authService.GenerateNomineeInvite(c.Request.Context(), nomineeID)
+	c.JSON(http.StatusOK, gin.H{"message": "nominee access revoked"})
+}
+
+// ListSessions returns the live nominee sessions on the user's account
+func (h *NomineeHandler) ListSessions(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	sessions, err := h.nomineeService.ListSessions(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate invitation code"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch nominee sessions", "details": err.Error()})
 		return
 	}
 
-	// Update nominee status to active immediately
-	if err := h.nomineeService.ActivateNominee(c.Request.Context(), nomineeID, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to activate nominee"})
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession signs a nominee out of one session on the user's account
+func (h *NomineeHandler) RevokeSession(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		return
+	}
+
+	if err := h.nomineeService.RevokeSession(c.Request.Context(), userID, sessionID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrNomineeSessionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "nominee session revoked"})
+}
+
+func (h *NomineeHandler) SendInvitation(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	idParam := c.Param("id")
+	nomineeID, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid nominee ID"})
+		return
+	}
+
+	if !requireVerifiedEmail(c, h.verification, userID, service.RestrictNomineeInvitations) {
+		return
+	}
+
+	nominee, invitation, err := h.invitations.Resend(c.Request.Context(), nomineeID, userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrNomineeNotFound) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, service.ErrUnauthorized) {
+			status = http.StatusForbidden
+		} else if errors.Is(err, service.ErrInvitationNotAllowed) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Return the code directly to be shared with the nominee
 	c.JSON(http.StatusOK, gin.H{
-		"message":       "Access code generated successfully",
-		"code":          accessCode,
+		"message":       "Invitation sent",
 		"nominee_email": nominee.Email,
 		"nominee_name":  nominee.Name,
-		"instructions":  "Share this code with your nominee. They can use it along with their email to access your investment information in case of emergency.",
+		"expires_at":    invitation.ExpiresAt,
 	})
 }
 
+// GetAccessLogs returns a page of the nominee access log, filtered by
+// nominee_id, action, ip, from and to. With format=csv or format=json every
+// matching log is exported as a file instead.
 func (h *NomineeHandler) GetAccessLogs(c *gin.Context) {
 	userID, ok := types.ExtractUserIDFromGin(c)
 	if !ok {
@@ -298,36 +391,52 @@ func (h *NomineeHandler) GetAccessLogs(c *gin.Context) {
 		return
 	}
 
-	logs, nominees, err := h.nomineeService.GetAccessLogs(c.Request.Context(), userID)
+	filter, err := accessLogFilter(c, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch access logs", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	nomineeMap := make(map[string]string)
-	for _, nominee := range nominees {
-		nomineeMap[nominee.ID.String()] = nominee.Name
-	}
+	format := c.Query("format")
+	if format != "" {
+		if format != "csv" && format != "json" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+			return
+		}
 
-	type EnhancedLog struct {
-		model.NomineeAccessLog
-		NomineeName string `json:"nominee_name"`
-	}
+		logs, err := h.nomineeService.ExportAccessLogs(c.Request.Context(), filter)
+		if err != nil {
+			log.Printf("Failed to export access logs for user %s: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export access logs"})
+			return
+		}
 
-	enhancedLogs := make([]EnhancedLog, 0, len(logs))
-	for _, log := range logs {
-		name, ok := nomineeMap[log.NomineeID.String()]
-		if !ok {
-			name = "Unknown"
+		filename := "nominee-access-log-" + time.Now().Format("2006-01-02") + "." + format
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		if format == "json" {
+			c.JSON(http.StatusOK, logs)
+			return
 		}
 
-		enhancedLogs = append(enhancedLogs, EnhancedLog{
-			NomineeAccessLog: log,
-			NomineeName:      name,
-		})
+		writeAccessLogCSV(c, logs)
+		return
+	}
+
+	logs, nextCursor, err := h.nomineeService.ListAccessLogs(c.Request.Context(), filter, c.Query("cursor"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Failed to fetch access logs for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch access logs"})
+		return
 	}
 
-	c.JSON(http.StatusOK, enhancedLogs)
+	c.JSON(http.StatusOK, gin.H{
+		"logs":        logs,
+		"next_cursor": nextCursor,
+	})
 }
 
 func (h *NomineeHandler) GetUsersForNominee(c *gin.Context) {
@@ -337,13 +446,18 @@ func (h *NomineeHandler) GetUsersForNominee(c *gin.Context) {
 		return
 	}
 
+	// Nominees are matched by email, so only a proven address may see who named it
+	if !requireVerifiedEmail(c, h.verification, userID, service.RestrictNomineeLookup) {
+		return
+	}
+
 	user, err := h.userService.GetByID(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user details"})
 		return
 	}
 
-	users, err := h.nomineeService.GetUsersForNominee(c.Request.Context(), user.Email)
+	users, err := h.nomineeService.GetUsersForNominee(c.Request.Context(), userID, user.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch users"})
 		return
@@ -352,39 +466,41 @@ func (h *NomineeHandler) GetUsersForNominee(c *gin.Context) {
 	c.JSON(http.StatusOK, users)
 }
 
+// AccessUserData lets a signed-in user who accepted a nominee invitation with
+// their account into the owner's data
 func (h *NomineeHandler) AccessUserData(c *gin.Context) {
-	var request struct {
-		Email      string `json:"email" binding:"required,email"`
-		AccessCode string `json:"access_code" binding:"required"`
-		UserID     string `json:"user_id" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+	currentUserID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	userID, err := uuid.Parse(request.UserID)
+	userID, err := uuid.Parse(c.Param("userID"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
 		return
 	}
 
-	// Verify the nominee's access
-	isValid, nomineeInfo, err := h.nomineeService.VerifyAccessCode(
-		c.Request.Context(),
-		request.Email,
-		userID,
-		request.AccessCode,
-	)
-
-	if err != nil || !isValid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid access code"})
+	nomineeInfo, err := h.nomineeService.VerifyLinkedAccess(c.Request.Context(), currentUserID, userID, clientInfo(c))
+	if respondAccessPending(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrNomineeNotFound) || errors.Is(err, service.ErrNomineeRevoked) || errors.Is(err, service.ErrNomineeNotActive) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you are not an accepted nominee of this account"})
+		return
+	}
+	if errors.Is(err, service.ErrNomineeSuspended) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to verify linked nominee access for user %s: %v", currentUserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify nominee access"})
 		return
 	}
 
-	// Generate access token for further API usage
-	token, err := h.authService.GenerateNomineeToken(nomineeInfo.ID, userID, nomineeInfo.AccessLevel)
+	// Start a session for the duration the owner chose
+	token, session, err := h.nomineeService.StartSession(c.Request.Context(), nomineeInfo, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate access token"})
 		return
@@ -411,147 +527,269 @@ func (h *NomineeHandler) AccessUserData(c *gin.Context) {
 		return
 	}
 
-	// Collect data based on access level
-	var assets []model.Asset
-	var documents []model.Document
-
-	if nomineeInfo.AccessLevel == "Full" || nomineeInfo.AccessLevel == "Limited" {
-		assets, _ = h.assetService.GetByUserID(c.Request.Context(), userID)
+	// What the nominee sees is decided by their permission profile
+	view, err := h.nomineeData.View(c.Request.Context(), nomineeInfo.ID, nomineeInfo.AccessLevel)
+	if err != nil {
+		log.Printf("Failed to load nominee view for nominee %s: %v", nomineeInfo.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load data"})
+		return
 	}
 
-	if nomineeInfo.AccessLevel == "Full" || nomineeInfo.AccessLevel == "Limited" {
-		documents, _ = h.documentService.GetByUserID(c.Request.Context(), userID)
-	} else {
-		documents, _ = h.documentService.GetNomineeDocuments(c.Request.Context(), nomineeInfo.ID)
+	// Other nominees can see how close the owner's quorum is
+	quorum, err := h.nomineeService.QuorumStatus(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load quorum status for user %s: %v", userID, err)
 	}
 
 	// Return the data
 	c.JSON(http.StatusOK, gin.H{
 		"access_token": token,
 		"token_type":   "Bearer",
+		"expires_at":   session.ExpiresAt,
 		"user": gin.H{
 			"id":    user.ID,
 			"name":  user.Name,
 			"email": user.Email,
 		},
 		"access_level": nomineeInfo.AccessLevel,
-		"assets":       assets,
-		"documents":    documents,
+		"permissions":  view.Permissions,
+		"assets":       view.Assets,
+		"documents":    view.Documents,
+		"messages":     view.Messages,
+		"quorum":       quorum,
 	})
 }
 
-// LogNomineeAccess is a helper method to log nominee access activities
-func (h *NomineeHandler) LogNomineeAccess(c *gin.Context) {
-	nomineeID, ok := types.ExtractUserIDFromGin(c)
+func (h *NomineeHandler) GetUserData(c *gin.Context) {
+	nomineeID, ownerID, ok := types.ExtractNomineeFromGin(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires nominee access"})
 		return
 	}
 
-	var request struct {
-		Action string `json:"action" binding:"required"`
+	userIDParam := c.Param("userID")
+	userID, err := uuid.Parse(userIDParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+	// Authorize already checks this, but the handler must never serve another owner's data
+	if userID != ownerID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "nominee can only access the account that named them"})
 		return
 	}
 
+	accessLevel := c.GetString(string(types.AccessLevelKey))
+
+	// Get the user for basic info
+	user, err := h.userService.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	// Log the access attempt
 	accessLog := &model.NomineeAccessLog{
 		NomineeID:  nomineeID,
 		Date:       time.Now(),
-		Action:     request.Action,
+		Action:     "Viewed User Data",
 		IPAddress:  c.ClientIP(),
 		DeviceInfo: c.Request.UserAgent(),
 	}
 
 	if err := h.nomineeService.LogNomineeAccess(c.Request.Context(), accessLog); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log access"})
+		// Just log the error but don't fail the request
+		// Production code would have a logger here
+	}
+
+	// What the nominee sees is decided by their permission profile
+	view, err := h.nomineeData.View(c.Request.Context(), nomineeID, accessLevel)
+	if err != nil {
+		log.Printf("Failed to load nominee view for nominee %s: %v", nomineeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load data"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "access logged successfully"})
+	h.nomineeService.NotifyAccess(c.Request.Context(), nomineeID, "viewed your data", clientInfo(c))
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": gin.H{
+			"id":    user.ID,
+			"name":  user.Name,
+			"email": user.Email,
+		},
+		"access_level": accessLevel,
+		"permissions":  view.Permissions,
+		"assets":       view.Assets,
+		"documents":    view.Documents,
+		"messages":     view.Messages,
+	})
 }
 
-func (h *NomineeHandler) GetUserData(c *gin.Context) {
-	isNominee, exists := c.Get(string(types.IsNomineeKey))
-	if !exists || !isNominee.(bool) {
+// ListDocuments returns the documents the owner shared with the nominee
+func (h *NomineeHandler) ListDocuments(c *gin.Context) {
+	nomineeID, _, ok := types.ExtractNomineeFromGin(c)
+	if !ok {
 		c.JSON(http.StatusForbidden, gin.H{"error": "requires nominee access"})
 		return
 	}
 
-	userIDParam := c.Param("userID")
-	userID, err := uuid.Parse(userIDParam)
+	accessLevel := c.GetString(string(types.AccessLevelKey))
+
+	documents, profile, err := h.nomineeData.SharedDocuments(c.Request.Context(), nomineeID, accessLevel)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		log.Printf("Failed to load shared documents for nominee %s: %v", nomineeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch documents"})
 		return
 	}
 
-	nomineeID, _ := types.ExtractUserIDFromGin(c)
-	accessLevel, _ := c.Get(string(types.AccessLevelKey))
+	c.JSON(http.StatusOK, gin.H{
+		"documents":       documents,
+		"allow_downloads": profile.AllowDownloads,
+	})
+}
 
-	// Get the user for basic info
-	user, err := h.userService.GetByID(c.Request.Context(), userID)
+// DownloadDocument streams a shared document to the nominee, or with
+// ?delivery=url returns a short-lived link to it instead
+func (h *NomineeHandler) DownloadDocument(c *gin.Context) {
+	nomineeID, _, ok := types.ExtractNomineeFromGin(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires nominee access"})
+		return
+	}
+
+	docID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid document ID"})
 		return
 	}
 
-	// Log the access attempt
-	accessLog := &model.NomineeAccessLog{
-		NomineeID:  nomineeID,
-		Date:       time.Now(),
-		Action:     "Viewed User Data",
-		IPAddress:  c.ClientIP(),
-		DeviceInfo: c.Request.UserAgent(),
+	accessLevel := c.GetString(string(types.AccessLevelKey))
+
+	if c.Query("delivery") == "url" {
+		url, document, expiresAt, err := h.nomineeData.DownloadURL(c.Request.Context(), nomineeID, accessLevel, docID, clientInfo(c))
+		if err != nil {
+			respondNomineeDownloadError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"url":        url,
+			"filename":   document.Filename,
+			"expires_at": expiresAt,
+		})
+		return
 	}
 
-	if err := h.nomineeService.LogNomineeAccess(c.Request.Context(), accessLog); err != nil {
-		// Just log the error but don't fail the request
-		// Production code would have a logger here
+	fileData, document, err := h.nomineeData.Download(c.Request.Context(), nomineeID, accessLevel, docID, clientInfo(c))
+	if err != nil {
+		respondNomineeDownloadError(c, err)
+		return
 	}
 
-	// Collect data based on access level
-	var assets []model.Asset
-	var documents []model.Document
+	c.Header("Content-Disposition", "attachment; filename="+document.Filename)
+	c.Data(http.StatusOK, document.MimeType, fileData)
+}
 
-	if accessLevel.(string) == "Full" {
-		assets, _ = h.assetService.GetByUserID(c.Request.Context(), userID)
-		documents, _ = h.documentService.GetByUserID(c.Request.Context(), userID)
+func respondNomineeDownloadError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, service.ErrDocumentNotFound) {
+		status = http.StatusNotFound
+	} else if errors.Is(err, service.ErrDownloadNotAllowed) || errors.Is(err, service.ErrVaultLocked) {
+		status = http.StatusForbidden
+	} else if errors.Is(err, service.ErrEncryptedDocumentURL) {
+		status = http.StatusConflict
+	}
 
-		c.JSON(http.StatusOK, gin.H{
-			"user": gin.H{
-				"id":    user.ID,
-				"name":  user.Name,
-				"email": user.Email,
-			},
-			"access_level": accessLevel,
-			"assets":       assets,
-			"documents":    documents,
-		})
-	} else if accessLevel.(string) == "Limited" {
-		assets, _ = h.assetService.GetByUserID(c.Request.Context(), userID)
+	c.JSON(status, gin.H{"error": err.Error()})
+}
 
-		c.JSON(http.StatusOK, gin.H{
-			"user": gin.H{
-				"id":    user.ID,
-				"name":  user.Name,
-				"email": user.Email,
-			},
-			"access_level": accessLevel,
-			"assets":       assets,
-		})
-	} else {
-		documents, _ = h.documentService.GetNomineeDocuments(c.Request.Context(), nomineeID)
+// accessLogFilter reads the access log filters from the query string. Dates
+// are RFC 3339 times or plain dates, and a plain to date includes that day.
+func accessLogFilter(c *gin.Context, userID uuid.UUID) (model.NomineeAccessLogFilter, error) {
+	filter := model.NomineeAccessLogFilter{
+		UserID:    userID,
+		Action:    strings.TrimSpace(c.Query("action")),
+		IPAddress: strings.TrimSpace(c.Query("ip")),
+	}
 
-		c.JSON(http.StatusOK, gin.H{
-			"user": gin.H{
-				"id":    user.ID,
-				"name":  user.Name,
-				"email": user.Email,
-			},
-			"access_level": accessLevel,
-			"documents":    documents,
+	if value := c.Query("nominee_id"); value != "" {
+		nomineeID, err := uuid.Parse(value)
+		if err != nil {
+			return filter, errors.New("invalid nominee ID")
+		}
+		filter.NomineeID = &nomineeID
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, _, err := parseLogDate(value)
+		if err != nil {
+			return filter, errors.New("from must be a date or RFC 3339 time")
+		}
+		filter.From = &from
+	}
+
+	if value := c.Query("to"); value != "" {
+		to, dateOnly, err := parseLogDate(value)
+		if err != nil {
+			return filter, errors.New("to must be a date or RFC 3339 time")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return filter, errors.New("limit must be a positive number")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// parseLogDate accepts an RFC 3339 time or a date, reporting which it was
+func parseLogDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	return t, true, err
+}
+
+func writeAccessLogCSV(c *gin.Context, logs []model.NomineeAccessLog) {
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/csv")
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"date", "nominee_id", "nominee_name", "action", "ip_address", "device_info"})
+	for _, entry := range logs {
+		writer.Write([]string{
+			entry.Date.Format(time.RFC3339),
+			entry.NomineeID.String(),
+			csvSafe(entry.NomineeName),
+			csvSafe(entry.Action),
+			csvSafe(entry.IPAddress),
+			csvSafe(entry.DeviceInfo),
 		})
 	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		log.Printf("Failed to write access log export: %v", err)
+	}
+}
+
+// csvSafe stops spreadsheet apps treating nominee-controlled text, such as
+// the user agent, as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
 }
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type NomineeAnomalyHandler struct {
+	anomalyService *service.NomineeAnomalyService
+}
+
+func NewNomineeAnomalyHandler(anomalyService *service.NomineeAnomalyService) *NomineeAnomalyHandler {
+	return &NomineeAnomalyHandler{anomalyService: anomalyService}
+}
+
+// GetRules returns how the owner wants their nominees' access watched
+func (h *NomineeAnomalyHandler) GetRules(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rules, err := h.anomalyService.GetRules(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch access rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// UpdateRules sets the owner's allowed hours and whether suspicious access
+// suspends the nominee
+func (h *NomineeAnomalyHandler) UpdateRules(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		AllowedFrom  *int   `json:"allowed_from"`
+		AllowedUntil *int   `json:"allowed_until"`
+		Timezone     string `json:"timezone"`
+		AutoSuspend  bool   `json:"auto_suspend"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	rules := &model.NomineeAccessRules{
+		UserID:       userID,
+		AllowedFrom:  request.AllowedFrom,
+		AllowedUntil: request.AllowedUntil,
+		Timezone:     request.Timezone,
+		AutoSuspend:  request.AutoSuspend,
+	}
+
+	if err := h.anomalyService.UpdateRules(c.Request.Context(), rules); err != nil {
+		if errors.Is(err, service.ErrInvalidAccessRules) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save access rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// ListAnomalies returns the latest suspicious accesses by the owner's nominees
+func (h *NomineeAnomalyHandler) ListAnomalies(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	anomalies, err := h.anomalyService.ListAnomalies(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch suspicious access"})
+		return
+	}
+
+	c.JSON(http.StatusOK, anomalies)
+}
+
+// Reinstate lifts a nominee's suspension after the owner has reviewed it
+func (h *NomineeAnomalyHandler) Reinstate(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	nomineeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid nominee ID"})
+		return
+	}
+
+	nominee, err := h.anomalyService.Reinstate(c.Request.Context(), userID, nomineeID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrNomineeNotFound) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, service.ErrNomineeNotSuspended) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, nominee)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type NomineeInvitationHandler struct {
+	invitationService *service.NomineeInvitationService
+}
+
+func NewNomineeInvitationHandler(invitationService *service.NomineeInvitationService) *NomineeInvitationHandler {
+	return &NomineeInvitationHandler{invitationService: invitationService}
+}
+
+// Get shows who sent an invitation, so the nominee can decide how to answer
+func (h *NomineeInvitationHandler) Get(c *gin.Context) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	details, err := h.invitationService.Get(c.Request.Context(), request.Token)
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+// Accept accepts an invitation and sets the nominee's own password
+func (h *NomineeInvitationHandler) Accept(c *gin.Context) {
+	var request struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=8"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	if err := h.invitationService.Accept(c.Request.Context(), request.Token, request.Password); err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "invitation accepted, sign in for emergency access with your email and this password"})
+}
+
+// AcceptWithAccount accepts an invitation for the signed-in user
+func (h *NomineeInvitationHandler) AcceptWithAccount(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	if err := h.invitationService.AcceptWithAccount(c.Request.Context(), request.Token, userID); err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "invitation accepted, your account is now linked to this nomination"})
+}
+
+// Decline turns an invitation down
+func (h *NomineeInvitationHandler) Decline(c *gin.Context) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	if err := h.invitationService.Decline(c.Request.Context(), request.Token); err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "invitation declined"})
+}
+
+func respondInvitationError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrInvalidInvitation):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrNomineePasswordLength):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrSelfNomination):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{"error": err.Error()})
+}
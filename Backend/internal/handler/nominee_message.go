@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type NomineeMessageHandler struct {
+	messageService *service.NomineeMessageService
+}
+
+func NewNomineeMessageHandler(messageService *service.NomineeMessageService) *NomineeMessageHandler {
+	return &NomineeMessageHandler{messageService: messageService}
+}
+
+type nomineeMessageRequest struct {
+	Subject       string      `json:"subject" binding:"required"`
+	Body          string      `json:"body" binding:"required"`
+	NomineeIDs    []uuid.UUID `json:"nominee_ids" binding:"required"`
+	AttachmentIDs []uuid.UUID `json:"attachment_ids"`
+}
+
+func (r nomineeMessageRequest) toModel(userID uuid.UUID) *model.NomineeMessage {
+	recipients := make([]model.NomineeMessageRecipient, 0, len(r.NomineeIDs))
+	for _, nomineeID := range r.NomineeIDs {
+		recipients = append(recipients, model.NomineeMessageRecipient{NomineeID: nomineeID})
+	}
+
+	return &model.NomineeMessage{
+		UserID:        userID,
+		Subject:       r.Subject,
+		Body:          r.Body,
+		Recipients:    recipients,
+		AttachmentIDs: r.AttachmentIDs,
+	}
+}
+
+// List returns the owner's messages with when each nominee first opened them
+func (h *NomineeMessageHandler) List(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	messages, err := h.messageService.List(c.Request.Context(), userID)
+	if err != nil {
+		respondNomineeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+func (h *NomineeMessageHandler) GetByID(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message ID"})
+		return
+	}
+
+	message, err := h.messageService.Get(c.Request.Context(), userID, messageID)
+	if err != nil {
+		respondNomineeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+func (h *NomineeMessageHandler) Create(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request nomineeMessageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	message := request.toModel(userID)
+	if err := h.messageService.Create(c.Request.Context(), message); err != nil {
+		respondNomineeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
+
+func (h *NomineeMessageHandler) Update(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message ID"})
+		return
+	}
+
+	var request nomineeMessageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	message := request.toModel(userID)
+	message.ID = messageID
+	if err := h.messageService.Update(c.Request.Context(), message); err != nil {
+		respondNomineeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+func (h *NomineeMessageHandler) Delete(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message ID"})
+		return
+	}
+
+	if err := h.messageService.Delete(c.Request.Context(), userID, messageID); err != nil {
+		respondNomineeMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "message deleted"})
+}
+
+func respondNomineeMessageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNomineeMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNomineeMessagesDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidNomineeMessage),
+		errors.Is(err, service.ErrNomineeMessageRecipients),
+		errors.Is(err, service.ErrNomineeNotFound),
+		errors.Is(err, service.ErrDocumentNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process message"})
+	}
+}
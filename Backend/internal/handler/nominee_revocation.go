@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sampatti/internal/service"
+)
+
+type NomineeRevocationHandler struct {
+	notifier *service.NomineeAccessNotifier
+}
+
+func NewNomineeRevocationHandler(notifier *service.NomineeAccessNotifier) *NomineeRevocationHandler {
+	return &NomineeRevocationHandler{notifier: notifier}
+}
+
+// Revoke redeems the "this wasn't expected" link from a nominee access
+// notice. The link itself is the owner's proof, so no sign-in is needed.
+func (h *NomineeRevocationHandler) Revoke(c *gin.Context) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	nominee, err := h.notifier.Revoke(c.Request.Context(), request.Token)
+	if errors.Is(err, service.ErrInvalidRevocationLink) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to revoke nominee from access notice link: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke nominee"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "nominee access revoked",
+		"nominee_name": nominee.Name,
+	})
+}
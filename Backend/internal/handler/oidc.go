@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+// oidcStateCookie binds a sign-in or link request to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService}
+}
+
+// GetProvider tells the login page whether single sign-on is available
+func (h *OIDCHandler) GetProvider(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": h.oidcService.Enabled(),
+		"name":    h.oidcService.ProviderName(),
+	})
+}
+
+// Authorize returns the provider URL the browser should be sent to for sign-in
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	authURL, state, err := h.oidcService.BeginLogin(c.Request.Context())
+	if err != nil {
+		c.JSON(oidcErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.setStateCookie(c, state, int(service.OIDCStateTTL.Seconds()))
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// Callback receives the code and state the provider redirected back with
+// after a sign-in
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	request, boundState, ok := h.bindCallback(c)
+	if !ok {
+		return
+	}
+
+	login, err := h.oidcService.Callback(c.Request.Context(), request.Code, request.State, boundState, clientInfo(c))
+	if err != nil {
+		c.JSON(oidcErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if login.TwoFactorRequired() {
+		respondWithChallenge(c, login)
+		return
+	}
+
+	respondWithLogin(c, login)
+}
+
+// Link returns the provider URL that links an identity to the signed-in user
+func (h *OIDCHandler) Link(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	authURL, state, err := h.oidcService.BeginLink(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(oidcErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.setStateCookie(c, state, int(service.OIDCStateTTL.Seconds()))
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// LinkCallback receives the code and state the provider redirected back with
+// after a link request; only the user who started it can finish it
+func (h *OIDCHandler) LinkCallback(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	request, boundState, ok := h.bindCallback(c)
+	if !ok {
+		return
+	}
+
+	identity, err := h.oidcService.CompleteLink(c.Request.Context(), userID, request.Code, request.State, boundState)
+	if err != nil {
+		c.JSON(oidcErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"linked": true, "identity": identity})
+}
+
+// ListIdentities returns the identities linked to the signed-in user
+func (h *OIDCHandler) ListIdentities(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	identities, err := h.oidcService.ListIdentities(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch linked identities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// Unlink removes a linked identity
+func (h *OIDCHandler) Unlink(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	identityID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid identity ID"})
+		return
+	}
+
+	if err := h.oidcService.Unlink(c.Request.Context(), userID, identityID); err != nil {
+		c.JSON(oidcErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "identity unlinked"})
+}
+
+type oidcCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// bindCallback reads the callback body and the state cookie, which is
+// cleared either way since a state can only be used once
+func (h *OIDCHandler) bindCallback(c *gin.Context) (*oidcCallbackRequest, string, bool) {
+	boundState, _ := c.Cookie(oidcStateCookie)
+	h.setStateCookie(c, "", -1)
+
+	var request oidcCallbackRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return nil, "", false
+	}
+
+	return &request, boundState, true
+}
+
+func (h *OIDCHandler) setStateCookie(c *gin.Context, state string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, "/api/v1", "", h.oidcService.SecureCookies(), true)
+}
+
+func oidcErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrOIDCDisabled), errors.Is(err, service.ErrOIDCIdentityNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrOIDCInvalidState):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrOIDCFailed), errors.Is(err, service.ErrOIDCEmailNotVerified):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrOIDCAccountUnverified), errors.Is(err, service.ErrOIDCIdentityLinked):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type PermissionProfileHandler struct {
+	profileService *service.PermissionProfileService
+}
+
+func NewPermissionProfileHandler(profileService *service.PermissionProfileService) *PermissionProfileHandler {
+	return &PermissionProfileHandler{profileService: profileService}
+}
+
+// permissionProfileRequest leaves a type list out to allow every type
+type permissionProfileRequest struct {
+	Name                 string   `json:"name" binding:"required"`
+	AssetTypes           []string `json:"asset_types"`
+	RedactAccountNumbers bool     `json:"redact_account_numbers"`
+	RedactInstitutions   bool     `json:"redact_institutions"`
+	RedactNotes          bool     `json:"redact_notes"`
+	DocumentTypes        []string `json:"document_types"`
+	SharedDocumentsOnly  bool     `json:"shared_documents_only"`
+	AllowDownloads       bool     `json:"allow_downloads"`
+}
+
+func (r permissionProfileRequest) toModel(userID uuid.UUID) *model.PermissionProfile {
+	return &model.PermissionProfile{
+		UserID:               userID,
+		Name:                 r.Name,
+		AssetTypes:           r.AssetTypes,
+		RedactAccountNumbers: r.RedactAccountNumbers,
+		RedactInstitutions:   r.RedactInstitutions,
+		RedactNotes:          r.RedactNotes,
+		DocumentTypes:        r.DocumentTypes,
+		SharedDocumentsOnly:  r.SharedDocumentsOnly,
+		AllowDownloads:       r.AllowDownloads,
+	}
+}
+
+// List returns the built-in profiles and the user's own
+func (h *PermissionProfileHandler) List(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	profiles, err := h.profileService.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch permission profiles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profiles)
+}
+
+func (h *PermissionProfileHandler) Create(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request permissionProfileRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	profile := request.toModel(userID)
+	if err := h.profileService.Create(c.Request.Context(), profile); err != nil {
+		respondPermissionProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, profile)
+}
+
+func (h *PermissionProfileHandler) Update(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	profileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid permission profile ID"})
+		return
+	}
+
+	var request permissionProfileRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	profile := request.toModel(userID)
+	profile.ID = profileID
+	if err := h.profileService.Update(c.Request.Context(), profile); err != nil {
+		respondPermissionProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *PermissionProfileHandler) Delete(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	profileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid permission profile ID"})
+		return
+	}
+
+	if err := h.profileService.Delete(c.Request.Context(), userID, profileID); err != nil {
+		respondPermissionProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "permission profile deleted"})
+}
+
+func respondPermissionProfileError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrPermissionProfileNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrPermissionProfileName), errors.Is(err, service.ErrInvalidDocumentType):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrPermissionProfileExists), errors.Is(err, service.ErrPermissionProfileInUse):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{"error": err.Error()})
+}
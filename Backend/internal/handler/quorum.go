@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type QuorumHandler struct {
+	quorumService *service.QuorumService
+}
+
+func NewQuorumHandler(quorumService *service.QuorumService) *QuorumHandler {
+	return &QuorumHandler{quorumService: quorumService}
+}
+
+// GetPolicy returns the owner's quorum policy and the approvals collected so far
+func (h *QuorumHandler) GetPolicy(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	status, err := h.quorumService.GetStatus(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch quorum policy"})
+		return
+	}
+
+	if status == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": service.ErrQuorumPolicyNotFound.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SetPolicy creates or replaces the owner's quorum policy
+func (h *QuorumHandler) SetPolicy(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		Threshold   int `json:"threshold" binding:"required"`
+		WindowHours int `json:"window_hours" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	status, err := h.quorumService.SetPolicy(c.Request.Context(), userID, request.Threshold, request.WindowHours)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidQuorumThreshold) || errors.Is(err, service.ErrInvalidQuorumWindow) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save quorum policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// DeletePolicy removes the owner's quorum policy
+func (h *QuorumHandler) DeletePolicy(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.quorumService.DeletePolicy(c.Request.Context(), userID); err != nil {
+		if errors.Is(err, service.ErrQuorumPolicyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete quorum policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "quorum policy removed"})
+}
+
+// GetNomineeView shows a nominee which of their fellow nominees have approved
+func (h *QuorumHandler) GetNomineeView(c *gin.Context) {
+	_, ownerID, ok := types.ExtractNomineeFromGin(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires nominee access"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("userID"))
+	if err != nil || userID != ownerID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "nominee can only access the account that named them"})
+		return
+	}
+
+	status, err := h.quorumService.GetStatus(c.Request.Context(), ownerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch quorum status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quorum": status})
+}
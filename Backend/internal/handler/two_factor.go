@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactorService *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactorService: twoFactorService}
+}
+
+// GetStatus returns the authenticated user's two-factor status
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	status, err := h.twoFactorService.Status(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Enroll starts TOTP enrollment and returns the secret and provisioning URI
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	secret, uri, err := h.twoFactorService.BeginEnrollment(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": uri,
+		"message":          "Scan the provisioning URI with your authenticator app, then confirm with the first code it shows.",
+	})
+}
+
+// Confirm finishes enrollment with the first TOTP code and returns recovery codes
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	codes, err := h.twoFactorService.ConfirmEnrollment(c.Request.Context(), userID, request.Code)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "two-factor authentication enabled",
+		"recovery_codes": codes,
+		"instructions":   "Store these recovery codes somewhere safe. Each can be used once if you lose your authenticator. They will not be shown again.",
+	})
+}
+
+// Disable turns off two-factor authentication
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	if err := h.twoFactorService.Disable(c.Request.Context(), userID, request.Password, request.Code); err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(c.Request.Context(), userID, request.Code)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidCredentials), errors.Is(err, service.ErrInvalidTwoFactorCode):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrTwoFactorNotEnrolled), errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -119,6 +119,8 @@ func (h *UserHandler) UpdateSettings(c *gin.Context) {
 		status := http.StatusInternalServerError
 		if errors.Is(err, service.ErrUserNotFound) {
 			status = http.StatusNotFound
+		} else if errors.Is(err, service.ErrTwoFactorSettingsChanged) {
+			status = http.StatusBadRequest
 		}
 		c.JSON(status, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type VaultHandler struct {
+	vaultService *service.VaultService
+}
+
+func NewVaultHandler(vaultService *service.VaultService) *VaultHandler {
+	return &VaultHandler{vaultService: vaultService}
+}
+
+// GetStatus returns the owner's vault and which shares are outstanding.
+// The vault only decides when nominees can open encrypted documents; the
+// server can always open them for the owner with its own key.
+func (h *VaultHandler) GetStatus(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	status, err := h.vaultService.Status(c.Request.Context(), userID)
+	if err != nil {
+		respondVaultError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Split creates a new vault key and splits it among the owner's nominees,
+// replacing any earlier split
+func (h *VaultHandler) Split(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		Threshold int `json:"threshold" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	status, err := h.vaultService.Split(c.Request.Context(), userID, request.Threshold)
+	if err != nil {
+		respondVaultError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GetProgress tells a nominee how many vault shares have been submitted
+func (h *VaultHandler) GetProgress(c *gin.Context) {
+	nomineeID, _, ok := types.ExtractNomineeFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	progress, err := h.vaultService.Progress(c.Request.Context(), nomineeID)
+	if err != nil {
+		respondVaultError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// SubmitShare takes the nominee's vault share during emergency access
+func (h *VaultHandler) SubmitShare(c *gin.Context) {
+	nomineeID, _, ok := types.ExtractNomineeFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		Share string `json:"share" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	progress, err := h.vaultService.SubmitShare(c.Request.Context(), nomineeID, request.Share, clientInfo(c))
+	if err != nil {
+		respondVaultError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+func respondVaultError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrVaultDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrVaultNotFound), errors.Is(err, service.ErrNomineeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrVaultNoNominees), errors.Is(err, service.ErrInvalidVaultThreshold),
+		errors.Is(err, service.ErrInvalidVaultShare):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAccessNotReleased):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process vault request"})
+	}
+}
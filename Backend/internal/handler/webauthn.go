@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type WebAuthnHandler struct {
+	webauthnService *service.WebAuthnService
+	authService     *service.AuthService
+}
+
+func NewWebAuthnHandler(webauthnService *service.WebAuthnService, authService *service.AuthService) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		webauthnService: webauthnService,
+		authService:     authService,
+	}
+}
+
+// BeginRegistration returns the options for registering a new authenticator
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ceremonyID, options, err := h.webauthnService.BeginRegistration(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(webauthnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ceremony_id": ceremonyID, "public_key": options})
+}
+
+// FinishRegistration stores the authenticator created with the options from BeginRegistration
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		CeremonyID uuid.UUID                     `json:"ceremony_id" binding:"required"`
+		Name       string                        `json:"name"`
+		Credential *service.RegistrationResponse `json:"credential" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	credential, err := h.webauthnService.FinishRegistration(c.Request.Context(), userID, request.CeremonyID, request.Name, request.Credential)
+	if err != nil {
+		c.JSON(webauthnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, credential)
+}
+
+// BeginLogin returns the options for a passwordless login with a passkey
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	ceremonyID, options, err := h.webauthnService.BeginLogin(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(webauthnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ceremony_id": ceremonyID, "public_key": options})
+}
+
+// FinishLogin signs the user in with the passkey assertion
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+	var request struct {
+		CeremonyID uuid.UUID                  `json:"ceremony_id" binding:"required"`
+		Credential *service.AssertionResponse `json:"credential" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	result, err := h.authService.LoginWithPasskey(c.Request.Context(), request.CeremonyID, request.Credential, clientInfo(c))
+	if err != nil {
+		c.JSON(webauthnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	respondWithLogin(c, result)
+}
+
+// BeginSecondFactor returns assertion options for answering a login challenge with a security key
+func (h *WebAuthnHandler) BeginSecondFactor(c *gin.Context) {
+	var request struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	ceremonyID, options, err := h.authService.BeginWebAuthnVerification(c.Request.Context(), request.ChallengeToken)
+	if err != nil {
+		c.JSON(webauthnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ceremony_id": ceremonyID, "public_key": options})
+}
+
+// FinishSecondFactor completes a two-factor login with the security key assertion
+func (h *WebAuthnHandler) FinishSecondFactor(c *gin.Context) {
+	var request struct {
+		ChallengeToken string                     `json:"challenge_token" binding:"required"`
+		CeremonyID     uuid.UUID                  `json:"ceremony_id" binding:"required"`
+		Credential     *service.AssertionResponse `json:"credential" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	result, err := h.authService.VerifyLoginWithWebAuthn(c.Request.Context(), request.ChallengeToken, request.CeremonyID, request.Credential, clientInfo(c))
+	if respondTooManyAttempts(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(webauthnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	respondWithLogin(c, result)
+}
+
+// ListCredentials returns the authenticated user's registered authenticators
+func (h *WebAuthnHandler) ListCredentials(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	credentials, err := h.webauthnService.ListCredentials(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch credentials"})
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+// RemoveCredential deletes one of the user's authenticators; the password is required
+func (h *WebAuthnHandler) RemoveCredential(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	credentialID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credential ID"})
+		return
+	}
+
+	var request struct {
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	if err := h.webauthnService.RemoveCredential(c.Request.Context(), userID, credentialID, request.Password); err != nil {
+		c.JSON(webauthnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "credential removed"})
+}
+
+func webauthnErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound), errors.Is(err, service.ErrCredentialNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidCredentials),
+		errors.Is(err, service.ErrInvalidToken),
+		errors.Is(err, service.ErrExpiredToken),
+		errors.Is(err, service.ErrWebAuthnVerification):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrWebAuthnCeremony):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrNoWebAuthnCredentials), errors.Is(err, service.ErrCredentialAlreadyRegistered):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
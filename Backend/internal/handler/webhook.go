@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sampatti/internal/service"
+	"github.com/sampatti/internal/types"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// Get returns where the user's security notifications are posted
+func (h *WebhookHandler) Get(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	webhook, err := h.webhookService.Get(c.Request.Context(), userID)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// Set points the user's security notifications at a URL. The signing
+// secret is only ever shown in this response.
+func (h *WebhookHandler) Set(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var request struct {
+		URL string `json:"url" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	webhook, secret, err := h.webhookService.Set(c.Request.Context(), userID, request.URL)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidWebhookURL) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhook": webhook,
+		"secret":  secret,
+		"message": "Store this secret now, it won't be shown again. Deliveries are signed with it in the X-Sampatti-Signature header.",
+	})
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	userID, ok := types.ExtractUserIDFromGin(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.webhookService.Delete(c.Request.Context(), userID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrWebhookNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook removed"})
+}
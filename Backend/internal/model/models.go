@@ -20,6 +20,8 @@ type User struct {
 	Notifications    bool       `json:"notifications" db:"notifications"`
 	DefaultCurrency  string     `json:"default_currency" db:"default_currency"`
 	TwoFactorEnabled bool       `json:"two_factor_enabled" db:"two_factor_enabled"`
+	EmailVerified    bool       `json:"email_verified" db:"email_verified"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at" db:"email_verified_at"`
 }
 
 type Asset struct {
@@ -56,19 +58,84 @@ type AssetHistory struct {
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
+// AssetBeneficiary is a nominee's share of an asset
+type AssetBeneficiary struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	AssetID         uuid.UUID `json:"asset_id" db:"asset_id"`
+	NomineeID       uuid.UUID `json:"nominee_id" db:"nominee_id"`
+	SharePercentage float64   `json:"share_percentage" db:"share_percentage"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	// Nominee details, loaded by a join
+	NomineeName         string `json:"nominee_name" db:"nominee_name"`
+	NomineeRelationship string `json:"nominee_relationship" db:"nominee_relationship"`
+	NomineeStatus       string `json:"nominee_status" db:"nominee_status"`
+}
+
 type Nominee struct {
-	ID                  uuid.UUID  `json:"id" db:"id"`
-	UserID              uuid.UUID  `json:"user_id" db:"user_id"`
-	Name                string     `json:"name" db:"name"`
-	Email               string     `json:"email" db:"email"`
-	PhoneNumber         string     `json:"phone_number" db:"phone_number"`
-	Relationship        string     `json:"relationship" db:"relationship"`
-	AccessLevel         string     `json:"access_level" db:"access_level"`
-	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
-	Status              string     `json:"status" db:"status"`
-	EmergencyAccessCode string     `json:"-" db:"emergency_access_code"`
-	LastAccessDate      *time.Time `json:"last_access_date" db:"last_access_date"`
+	ID             uuid.UUID  `json:"id" db:"id"`
+	UserID         uuid.UUID  `json:"user_id" db:"user_id"`
+	Name           string     `json:"name" db:"name"`
+	Email          string     `json:"email" db:"email"`
+	PhoneNumber    string     `json:"phone_number" db:"phone_number"`
+	Relationship   string     `json:"relationship" db:"relationship"`
+	AccessLevel    string     `json:"access_level" db:"access_level"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	Status         string     `json:"status" db:"status"`
+	PasswordHash   string     `json:"-" db:"password_hash"`
+	LinkedUserID   *uuid.UUID `json:"linked_user_id" db:"linked_user_id"`
+	InvitedAt      *time.Time `json:"invited_at" db:"invited_at"`
+	RespondedAt    *time.Time `json:"responded_at" db:"responded_at"`
+	LastAccessDate *time.Time `json:"last_access_date" db:"last_access_date"`
+	// AccessEligibleAt is set once the owner's account has been released to nominees
+	AccessEligibleAt *time.Time `json:"access_eligible_at" db:"access_eligible_at"`
+	// AccessDurationHours is how long each of the nominee's sign-ins lasts
+	AccessDurationHours int `json:"access_duration_hours" db:"access_duration_hours"`
+	// PermissionProfileID picks an owner-defined profile; without one the
+	// built-in profile for AccessLevel applies
+	PermissionProfileID *uuid.UUID `json:"permission_profile_id" db:"permission_profile_id"`
+	// SuspendedAt is set while the nominee is suspended pending the owner's review
+	SuspendedAt *time.Time `json:"suspended_at" db:"suspended_at"`
+}
+
+// EmergencyAccessRequest is a nominee's request for emergency access. It is
+// granted at GrantAt unless the owner denies it first.
+type EmergencyAccessRequest struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	NomineeID     uuid.UUID  `json:"nominee_id" db:"nominee_id"`
+	UserID        uuid.UUID  `json:"user_id" db:"user_id"`
+	Status        string     `json:"status" db:"status"`
+	RequestedAt   time.Time  `json:"requested_at" db:"requested_at"`
+	GrantAt       time.Time  `json:"grant_at" db:"grant_at"`
+	ResolvedAt    *time.Time `json:"resolved_at" db:"resolved_at"`
+	ExpiresAt     *time.Time `json:"expires_at" db:"expires_at"`
+	DenyTokenHash string     `json:"-" db:"deny_token_hash"`
+	IPAddress     string     `json:"ip_address" db:"ip_address"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	// Names for listings, loaded by joins
+	NomineeName  string `json:"nominee_name,omitempty" db:"nominee_name"`
+	NomineeEmail string `json:"nominee_email,omitempty" db:"nominee_email"`
+	OwnerName    string `json:"owner_name,omitempty" db:"owner_name"`
+}
+
+// QuorumPolicy requires Threshold nominees to approve within WindowHours
+// before Full access is released
+type QuorumPolicy struct {
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	Threshold   int        `json:"threshold" db:"threshold"`
+	WindowHours int        `json:"window_hours" db:"window_hours"`
+	MetAt       *time.Time `json:"met_at" db:"met_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// QuorumApproval is a nominee's most recent approval inside the window
+type QuorumApproval struct {
+	NomineeID   uuid.UUID `json:"nominee_id" db:"nominee_id"`
+	NomineeName string    `json:"nominee_name" db:"nominee_name"`
+	ApprovedAt  time.Time `json:"approved_at" db:"approved_at"`
 }
 
 type NomineeAccessLog struct {
@@ -78,6 +145,23 @@ type NomineeAccessLog struct {
 	Action     string    `json:"action" db:"action"`
 	IPAddress  string    `json:"ip_address" db:"ip_address"`
 	DeviceInfo string    `json:"device_info" db:"device_info"`
+	// NomineeName is only loaded for the owner's access log
+	NomineeName string `json:"nominee_name,omitempty" db:"nominee_name"`
+}
+
+// NomineeAccessLogFilter narrows an owner's nominee access log. Logs are
+// returned newest first, starting after the (BeforeDate, BeforeID) cursor.
+type NomineeAccessLogFilter struct {
+	UserID     uuid.UUID
+	NomineeID  *uuid.UUID
+	Action     string
+	IPAddress  string
+	From       *time.Time
+	To         *time.Time
+	BeforeDate *time.Time
+	BeforeID   *uuid.UUID
+	// Limit of 0 returns every matching log
+	Limit int
 }
 
 type Document struct {
@@ -118,3 +202,348 @@ type AlertAction struct {
 	Description string    `json:"description" db:"description"`
 	Completed   bool      `json:"completed" db:"completed"`
 }
+
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// NomineeInvitation is an emailed link a nominee uses to accept or decline
+type NomineeInvitation struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	NomineeID uuid.UUID  `json:"nominee_id" db:"nominee_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// NomineeRevocationToken backs the link in an owner's nominee access notice
+// that revokes the nominee without signing in
+type NomineeRevocationToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	NomineeID uuid.UUID  `json:"nominee_id" db:"nominee_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// NomineeMessage is a sealed letter of instruction from an owner to some of
+// their nominees. Subject and Body are only ever stored encrypted.
+type NomineeMessage struct {
+	ID            uuid.UUID                 `json:"id" db:"id"`
+	UserID        uuid.UUID                 `json:"user_id" db:"user_id"`
+	Subject       string                    `json:"subject" db:"-"`
+	Body          string                    `json:"body" db:"-"`
+	SealedContent []byte                    `json:"-" db:"sealed_content"`
+	CreatedAt     time.Time                 `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time                 `json:"updated_at" db:"updated_at"`
+	Recipients    []NomineeMessageRecipient `json:"recipients" db:"-"`
+	AttachmentIDs []uuid.UUID               `json:"attachment_ids" db:"-"`
+	// Attachments is only loaded for the nominee who opens the message
+	Attachments []Document `json:"attachments,omitempty" db:"-"`
+}
+
+// NomineeMessageRecipient is a nominee a message is addressed to
+type NomineeMessageRecipient struct {
+	MessageID     uuid.UUID  `json:"-" db:"message_id"`
+	NomineeID     uuid.UUID  `json:"nominee_id" db:"nominee_id"`
+	NomineeName   string     `json:"nominee_name" db:"nominee_name"`
+	FirstOpenedAt *time.Time `json:"first_opened_at" db:"first_opened_at"`
+}
+
+// NotificationWebhook is where an owner's security notifications are posted
+type NotificationWebhook struct {
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	URL       string    `json:"url" db:"url"`
+	Secret    string    `json:"-" db:"secret"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type EmailVerificationToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	Email     string     `json:"email" db:"email"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+type UserTOTP struct {
+	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
+	Secret       string     `json:"-" db:"secret"`
+	ConfirmedAt  *time.Time `json:"confirmed_at" db:"confirmed_at"`
+	LastUsedStep int64      `json:"-" db:"last_used_step"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+type Session struct {
+	ID              uuid.UUID  `json:"-" db:"id"`
+	FamilyID        uuid.UUID  `json:"id" db:"family_id"`
+	UserID          uuid.UUID  `json:"user_id" db:"user_id"`
+	IPAddress       string     `json:"ip_address" db:"ip_address"`
+	UserAgent       string     `json:"user_agent" db:"user_agent"`
+	AuthenticatedAt time.Time  `json:"authenticated_at" db:"authenticated_at"`
+	CreatedAt       time.Time  `json:"last_refreshed_at" db:"created_at"`
+	ExpiresAt       time.Time  `json:"expires_at" db:"expires_at"`
+	RotatedAt       *time.Time `json:"-" db:"rotated_at"`
+	RevokedAt       *time.Time `json:"-" db:"revoked_at"`
+	Current         bool       `json:"current" db:"-"`
+}
+
+// NomineeSession is one nominee sign-in. UserID is the owner whose data it
+// reaches.
+type NomineeSession struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	NomineeID   uuid.UUID  `json:"nominee_id" db:"nominee_id"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	AccessLevel string     `json:"access_level" db:"access_level"`
+	IPAddress   string     `json:"ip_address" db:"ip_address"`
+	UserAgent   string     `json:"user_agent" db:"user_agent"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt   *time.Time `json:"-" db:"revoked_at"`
+	// Nominee name for listings, loaded by a join
+	NomineeName string `json:"nominee_name,omitempty" db:"nominee_name"`
+}
+
+// PermissionProfile decides what a nominee sees of the owner's data. A nil
+// type list allows every type and an empty one allows none. Built-in profiles
+// stand in for the access levels and are never stored.
+type PermissionProfile struct {
+	ID                   uuid.UUID `json:"id" db:"id"`
+	UserID               uuid.UUID `json:"user_id" db:"user_id"`
+	Name                 string    `json:"name" db:"name"`
+	AssetTypes           []string  `json:"asset_types" db:"asset_types"`
+	RedactAccountNumbers bool      `json:"redact_account_numbers" db:"redact_account_numbers"`
+	RedactInstitutions   bool      `json:"redact_institutions" db:"redact_institutions"`
+	RedactNotes          bool      `json:"redact_notes" db:"redact_notes"`
+	DocumentTypes        []string  `json:"document_types" db:"document_types"`
+	SharedDocumentsOnly  bool      `json:"shared_documents_only" db:"shared_documents_only"`
+	AllowDownloads       bool      `json:"allow_downloads" db:"allow_downloads"`
+	BuiltIn              bool      `json:"built_in" db:"-"`
+	CreatedAt            time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type AuthAttempt struct {
+	Key          string     `json:"key" db:"key"`
+	Failures     int        `json:"failures" db:"failures"`
+	LastFailedAt time.Time  `json:"last_failed_at" db:"last_failed_at"`
+	BlockedUntil *time.Time `json:"blocked_until" db:"blocked_until"`
+}
+
+type SecurityEvent struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	EventType string     `json:"event_type" db:"event_type"`
+	UserID    *uuid.UUID `json:"user_id" db:"user_id"`
+	IPAddress string     `json:"ip_address" db:"ip_address"`
+	Details   string     `json:"details" db:"details"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+type WebAuthnCredential struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
+	CredentialID []byte     `json:"-" db:"credential_id"`
+	PublicKey    []byte     `json:"-" db:"public_key"`
+	Algorithm    int64      `json:"algorithm" db:"algorithm"`
+	SignCount    int64      `json:"-" db:"sign_count"`
+	AAGUID       []byte     `json:"-" db:"aaguid"`
+	Transports   string     `json:"transports" db:"transports"`
+	Name         string     `json:"name" db:"name"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at" db:"last_used_at"`
+}
+
+type WebAuthnChallenge struct {
+	ID        uuid.UUID  `db:"id"`
+	UserID    *uuid.UUID `db:"user_id"`
+	Purpose   string     `db:"purpose"`
+	Challenge []byte     `db:"challenge"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+type PersonalAccessToken struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	Name        string     `json:"name" db:"name"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	TokenPrefix string     `json:"token_prefix" db:"token_prefix"`
+	Scopes      []string   `json:"scopes" db:"scopes"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt   *time.Time `json:"-" db:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+type OIDCIdentity struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	Issuer      string     `json:"issuer" db:"issuer"`
+	Subject     string     `json:"subject" db:"subject"`
+	Email       string     `json:"email" db:"email"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at" db:"last_login_at"`
+}
+
+type OIDCLoginState struct {
+	ID           uuid.UUID  `db:"id"`
+	StateHash    string     `db:"state_hash"`
+	Nonce        string     `db:"nonce"`
+	CodeVerifier string     `db:"code_verifier"`
+	UserID       *uuid.UUID `db:"user_id"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+type InactivitySwitch struct {
+	UserID         uuid.UUID  `json:"user_id" db:"user_id"`
+	Enabled        bool       `json:"enabled" db:"enabled"`
+	InactivityDays int        `json:"inactivity_days" db:"inactivity_days"`
+	LastCheckInAt  time.Time  `json:"last_check_in_at" db:"last_check_in_at"`
+	RemindersSent  int        `json:"reminders_sent" db:"reminders_sent"`
+	LastReminderAt *time.Time `json:"last_reminder_at" db:"last_reminder_at"`
+	ReleasedAt     *time.Time `json:"released_at" db:"released_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	// Owner details, only loaded by the background check
+	OwnerName  string     `json:"-" db:"owner_name"`
+	OwnerEmail string     `json:"-" db:"owner_email"`
+	LastLogin  *time.Time `json:"-" db:"last_login"`
+}
+
+// ClaimInstruction is an owner's guidance for claiming the assets held at
+// one institution
+type ClaimInstruction struct {
+	ID           uuid.UUID              `json:"id" db:"id"`
+	UserID       uuid.UUID              `json:"user_id" db:"user_id"`
+	Institution  string                 `json:"institution" db:"institution"`
+	Instructions string                 `json:"instructions" db:"instructions"`
+	Steps        []ClaimInstructionStep `json:"steps" db:"-"`
+	CreatedAt    time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+// ClaimInstructionStep is one step of the checklist an instruction generates
+type ClaimInstructionStep struct {
+	Title             string   `json:"title" db:"title"`
+	RequiredDocuments []string `json:"required_documents" db:"required_documents"`
+}
+
+// ClaimStep is one step of claiming an asset and how far nominees have got with it
+type ClaimStep struct {
+	ID                uuid.UUID       `json:"id" db:"id"`
+	AssetID           uuid.UUID       `json:"asset_id" db:"asset_id"`
+	Position          int             `json:"position" db:"position"`
+	Title             string          `json:"title" db:"title"`
+	RequiredDocuments []string        `json:"required_documents" db:"required_documents"`
+	Status            string          `json:"status" db:"status"`
+	Notes             string          `json:"notes" db:"notes"`
+	UpdatedBy         *uuid.UUID      `json:"updated_by" db:"updated_by"`
+	CreatedAt         time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at" db:"updated_at"`
+	Documents         []ClaimDocument `json:"documents" db:"-"`
+}
+
+// ClaimDocument is a file a nominee uploaded for a claim step
+type ClaimDocument struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	StepID     uuid.UUID `json:"step_id" db:"step_id"`
+	NomineeID  uuid.UUID `json:"nominee_id" db:"nominee_id"`
+	Filename   string    `json:"filename" db:"filename"`
+	FileSize   int64     `json:"file_size" db:"file_size"`
+	MimeType   string    `json:"mime_type" db:"mime_type"`
+	StorageKey string    `json:"-" db:"storage_key"`
+	UploadedAt time.Time `json:"uploaded_at" db:"uploaded_at"`
+}
+
+// AuditEntry is one link in an owner's tamper-evident audit chain
+type AuditEntry struct {
+	OwnerID    uuid.UUID  `json:"owner_id" db:"owner_id"`
+	Seq        int64      `json:"seq" db:"seq"`
+	OccurredAt time.Time  `json:"occurred_at" db:"occurred_at"`
+	ActorType  string     `json:"actor_type" db:"actor_type"`
+	ActorID    *uuid.UUID `json:"actor_id" db:"actor_id"`
+	Action     string     `json:"action" db:"action"`
+	EntityType string     `json:"entity_type" db:"entity_type"`
+	EntityID   *uuid.UUID `json:"entity_id" db:"entity_id"`
+	Summary    string     `json:"summary" db:"summary"`
+	IPAddress  string     `json:"ip_address" db:"ip_address"`
+	PrevHash   string     `json:"prev_hash" db:"prev_hash"`
+	Hash       string     `json:"hash" db:"hash"`
+}
+
+// NomineeAccessRules is how an owner wants their nominees' access watched.
+// AllowedFrom and AllowedUntil are hours of the day in Timezone; access
+// outside them is suspicious.
+type NomineeAccessRules struct {
+	UserID       uuid.UUID `json:"-" db:"user_id"`
+	AllowedFrom  *int      `json:"allowed_from" db:"allowed_from"`
+	AllowedUntil *int      `json:"allowed_until" db:"allowed_until"`
+	Timezone     string    `json:"timezone" db:"timezone"`
+	AutoSuspend  bool      `json:"auto_suspend" db:"auto_suspend"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// NomineeAccessAnomaly is a nominee access the rules scored as suspicious
+type NomineeAccessAnomaly struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	NomineeID   uuid.UUID `json:"nominee_id" db:"nominee_id"`
+	UserID      uuid.UUID `json:"-" db:"user_id"`
+	AccessLogID uuid.UUID `json:"access_log_id" db:"access_log_id"`
+	Score       int       `json:"score" db:"score"`
+	Reasons     []string  `json:"reasons" db:"-"`
+	Action      string    `json:"action" db:"action"`
+	IPAddress   string    `json:"ip_address" db:"ip_address"`
+	DeviceInfo  string    `json:"device_info" db:"device_info"`
+	Suspended   bool      `json:"suspended" db:"suspended"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	// NomineeName is loaded for the owner's listing
+	NomineeName string `json:"nominee_name" db:"nominee_name"`
+}
+
+// Vault holds the public half of an owner's recovery key. The private half
+// is only ever held as shares by their nominees.
+type Vault struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"-" db:"user_id"`
+	PublicKey []byte    `json:"-" db:"public_key"`
+	Threshold int       `json:"threshold" db:"threshold"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// VaultShare is one nominee's share of a vault recovery key
+type VaultShare struct {
+	VaultID        uuid.UUID  `json:"-" db:"vault_id"`
+	NomineeID      uuid.UUID  `json:"nominee_id" db:"nominee_id"`
+	ShareIndex     int        `json:"share_index" db:"share_index"`
+	ShareHash      string     `json:"-" db:"share_hash"`
+	SealedShare    []byte     `json:"-" db:"sealed_share"`
+	DeliveredAt    *time.Time `json:"delivered_at" db:"delivered_at"`
+	SubmittedShare []byte     `json:"-" db:"submitted_share"`
+	SubmittedAt    *time.Time `json:"submitted_at" db:"submitted_at"`
+	// Nominee details for the owner's listing, loaded by a join
+	NomineeName   string `json:"nominee_name" db:"nominee_name"`
+	NomineeStatus string `json:"nominee_status" db:"nominee_status"`
+}
+
+// DocumentKey is an encrypted document's key, wrapped for the owner and
+// for recovery through the owner's vault
+type DocumentKey struct {
+	DocumentID      uuid.UUID  `db:"document_id"`
+	UserID          uuid.UUID  `db:"user_id"`
+	OwnerWrapped    []byte     `db:"owner_wrapped"`
+	RecoveryWrapped []byte     `db:"recovery_wrapped"`
+	VaultID         *uuid.UUID `db:"vault_id"`
+	CreatedAt       time.Time  `db:"created_at"`
+}
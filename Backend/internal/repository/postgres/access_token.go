@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sampatti/internal/model"
+)
+
+type PersonalAccessTokenRepository struct {
+	db *sqlx.DB
+}
+
+func NewPersonalAccessTokenRepository(db *sqlx.DB) *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{db: db}
+}
+
+type PersonalAccessTokenDB struct {
+	ID          uuid.UUID      `db:"id"`
+	UserID      uuid.UUID      `db:"user_id"`
+	Name        string         `db:"name"`
+	TokenHash   string         `db:"token_hash"`
+	TokenPrefix string         `db:"token_prefix"`
+	Scopes      pq.StringArray `db:"scopes"`
+	ExpiresAt   time.Time      `db:"expires_at"`
+	LastUsedAt  *time.Time     `db:"last_used_at"`
+	RevokedAt   *time.Time     `db:"revoked_at"`
+	CreatedAt   time.Time      `db:"created_at"`
+}
+
+func toPersonalAccessTokenModel(dbToken PersonalAccessTokenDB) model.PersonalAccessToken {
+	return model.PersonalAccessToken{
+		ID:          dbToken.ID,
+		UserID:      dbToken.UserID,
+		Name:        dbToken.Name,
+		TokenHash:   dbToken.TokenHash,
+		TokenPrefix: dbToken.TokenPrefix,
+		Scopes:      []string(dbToken.Scopes),
+		ExpiresAt:   dbToken.ExpiresAt,
+		LastUsedAt:  dbToken.LastUsedAt,
+		RevokedAt:   dbToken.RevokedAt,
+		CreatedAt:   dbToken.CreatedAt,
+	}
+}
+
+const personalAccessTokenColumns = `
+	id, user_id, name, token_hash, token_prefix, scopes,
+	expires_at, last_used_at, revoked_at, created_at
+`
+
+func (r *PersonalAccessTokenRepository) Create(ctx context.Context, token *model.PersonalAccessToken) error {
+	query := `
+		INSERT INTO personal_access_tokens (
+			id, user_id, name, token_hash, token_prefix, scopes, expires_at, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+	`
+
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		token.ID,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		token.TokenPrefix,
+		pq.StringArray(token.Scopes),
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+
+	return err
+}
+
+// GetActiveByHash returns an unrevoked, unexpired token by its hash
+func (r *PersonalAccessTokenRepository) GetActiveByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	var dbToken PersonalAccessTokenDB
+	query := `
+		SELECT ` + personalAccessTokenColumns + `
+		FROM personal_access_tokens
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > $2
+	`
+
+	err := r.db.GetContext(ctx, &dbToken, query, tokenHash, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	token := toPersonalAccessTokenModel(dbToken)
+	return &token, nil
+}
+
+// ListByUserID returns the user's unrevoked tokens, including expired ones
+func (r *PersonalAccessTokenRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]model.PersonalAccessToken, error) {
+	var dbTokens []PersonalAccessTokenDB
+	query := `
+		SELECT ` + personalAccessTokenColumns + `
+		FROM personal_access_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &dbTokens, query, userID); err != nil {
+		return nil, err
+	}
+
+	tokens := make([]model.PersonalAccessToken, 0, len(dbTokens))
+	for _, dbToken := range dbTokens {
+		tokens = append(tokens, toPersonalAccessTokenModel(dbToken))
+	}
+
+	return tokens, nil
+}
+
+// TouchLastUsed records a use of the token, writing at most once per interval
+// so busy scripts don't turn every request into an update
+func (r *PersonalAccessTokenRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, interval time.Duration) error {
+	now := time.Now()
+	query := `
+		UPDATE personal_access_tokens SET last_used_at = $1
+		WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, now, id, now.Add(-interval))
+	return err
+}
+
+// Revoke revokes one of the user's tokens and reports whether it was active
+func (r *PersonalAccessTokenRepository) Revoke(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE personal_access_tokens SET revoked_at = $1
+		WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+const auditEntryColumns = `
+	owner_id, seq, occurred_at, actor_type, actor_id, action, entity_type,
+	entity_id, summary, ip_address, prev_hash, hash
+`
+
+// Append adds an entry to the end of its owner's chain. It fills in Seq and
+// PrevHash, then Hash using hash, while holding the owner's chain head.
+func (r *AuditRepository) Append(ctx context.Context, entry *model.AuditEntry, hash func(*model.AuditEntry) string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO audit_heads (owner_id, seq, hash) VALUES ($1, 0, '')
+		ON CONFLICT (owner_id) DO NOTHING
+	`, entry.OwnerID); err != nil {
+		return err
+	}
+
+	var head struct {
+		Seq  int64  `db:"seq"`
+		Hash string `db:"hash"`
+	}
+	if err := tx.GetContext(ctx, &head, `SELECT seq, hash FROM audit_heads WHERE owner_id = $1 FOR UPDATE`, entry.OwnerID); err != nil {
+		return err
+	}
+
+	entry.Seq = head.Seq + 1
+	entry.PrevHash = head.Hash
+	entry.Hash = hash(entry)
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO audit_log (`+auditEntryColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`,
+		entry.OwnerID,
+		entry.Seq,
+		entry.OccurredAt,
+		entry.ActorType,
+		entry.ActorID,
+		entry.Action,
+		entry.EntityType,
+		entry.EntityID,
+		entry.Summary,
+		entry.IPAddress,
+		entry.PrevHash,
+		entry.Hash,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE audit_heads SET seq = $1, hash = $2 WHERE owner_id = $3`, entry.Seq, entry.Hash, entry.OwnerID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListByOwner returns up to limit of the owner's entries before seq, newest
+// first. A beforeSeq of 0 starts from the latest entry.
+func (r *AuditRepository) ListByOwner(ctx context.Context, ownerID uuid.UUID, beforeSeq int64, limit int) ([]model.AuditEntry, error) {
+	entries := make([]model.AuditEntry, 0)
+	query := `
+		SELECT ` + auditEntryColumns + `
+		FROM audit_log
+		WHERE owner_id = $1 AND ($2 = 0 OR seq < $2)
+		ORDER BY seq DESC
+		LIMIT $3
+	`
+
+	if err := r.db.SelectContext(ctx, &entries, query, ownerID, beforeSeq, limit); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// ListChain returns up to limit of the owner's entries after seq, oldest first
+func (r *AuditRepository) ListChain(ctx context.Context, ownerID uuid.UUID, afterSeq int64, limit int) ([]model.AuditEntry, error) {
+	entries := make([]model.AuditEntry, 0)
+	query := `
+		SELECT ` + auditEntryColumns + `
+		FROM audit_log
+		WHERE owner_id = $1 AND seq > $2
+		ORDER BY seq
+		LIMIT $3
+	`
+
+	if err := r.db.SelectContext(ctx, &entries, query, ownerID, afterSeq, limit); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetHead returns the seq and hash the owner's chain should end with. An
+// owner with no entries has seq 0.
+func (r *AuditRepository) GetHead(ctx context.Context, ownerID uuid.UUID) (int64, string, error) {
+	var head struct {
+		Seq  int64  `db:"seq"`
+		Hash string `db:"hash"`
+	}
+
+	err := r.db.GetContext(ctx, &head, `SELECT seq, hash FROM audit_heads WHERE owner_id = $1`, ownerID)
+	return head.Seq, head.Hash, err
+}
+
+// ListOwners returns every owner with an audit chain, including owners who
+// appear only in the log after their chain head went missing
+func (r *AuditRepository) ListOwners(ctx context.Context) ([]uuid.UUID, error) {
+	owners := make([]uuid.UUID, 0)
+	query := `
+		SELECT owner_id FROM audit_heads
+		UNION
+		SELECT DISTINCT owner_id FROM audit_log
+		ORDER BY owner_id
+	`
+
+	if err := r.db.SelectContext(ctx, &owners, query); err != nil {
+		return nil, err
+	}
+
+	return owners, nil
+}
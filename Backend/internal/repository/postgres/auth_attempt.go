@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+// AuthAttemptRepository tracks failed authentication attempts in Postgres so
+// limits hold across every API replica
+type AuthAttemptRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuthAttemptRepository(db *sqlx.DB) *AuthAttemptRepository {
+	return &AuthAttemptRepository{db: db}
+}
+
+// Get returns the attempt state for key, or nil when there have been no recent failures
+func (r *AuthAttemptRepository) Get(ctx context.Context, key string) (*model.AuthAttempt, error) {
+	var attempt model.AuthAttempt
+	query := `
+		SELECT key, failures, last_failed_at, blocked_until
+		FROM auth_attempts
+		WHERE key = $1
+	`
+
+	err := r.db.GetContext(ctx, &attempt, query, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// RecordFailure atomically increments the failure count for key. The count
+// starts over when the previous failure is older than window and key isn't blocked.
+func (r *AuthAttemptRepository) RecordFailure(ctx context.Context, key string, window time.Duration) (*model.AuthAttempt, error) {
+	var attempt model.AuthAttempt
+	query := `
+		INSERT INTO auth_attempts (key, failures, last_failed_at, blocked_until)
+		VALUES ($1, 1, $2, NULL)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN auth_attempts.last_failed_at < $3
+					AND (auth_attempts.blocked_until IS NULL OR auth_attempts.blocked_until < $2)
+				THEN 1
+				ELSE auth_attempts.failures + 1
+			END,
+			last_failed_at = EXCLUDED.last_failed_at
+		RETURNING key, failures, last_failed_at, blocked_until
+	`
+
+	now := time.Now()
+	err := r.db.GetContext(ctx, &attempt, query, key, now, now.Add(-window))
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// Block prevents further attempts for key until the given time
+func (r *AuthAttemptRepository) Block(ctx context.Context, key string, until time.Time) error {
+	query := `
+		UPDATE auth_attempts SET
+			blocked_until = $1
+		WHERE key = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, until, key)
+	return err
+}
+
+// Reset clears the failures recorded for key
+func (r *AuthAttemptRepository) Reset(ctx context.Context, key string) error {
+	query := `DELETE FROM auth_attempts WHERE key = $1`
+	_, err := r.db.ExecContext(ctx, query, key)
+	return err
+}
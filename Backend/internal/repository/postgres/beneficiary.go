@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type BeneficiaryRepository struct {
+	db *sqlx.DB
+}
+
+func NewBeneficiaryRepository(db *sqlx.DB) *BeneficiaryRepository {
+	return &BeneficiaryRepository{db: db}
+}
+
+const beneficiarySelect = `
+	SELECT b.id, b.asset_id, b.nominee_id, b.share_percentage, b.created_at, b.updated_at,
+		n.name AS nominee_name, n.relationship AS nominee_relationship, n.status AS nominee_status
+	FROM asset_beneficiaries b
+	JOIN nominees n ON n.id = b.nominee_id
+`
+
+func (r *BeneficiaryRepository) GetByAssetID(ctx context.Context, assetID uuid.UUID) ([]model.AssetBeneficiary, error) {
+	beneficiaries := make([]model.AssetBeneficiary, 0)
+	query := beneficiarySelect + `
+		WHERE b.asset_id = $1
+		ORDER BY b.share_percentage DESC, n.name
+	`
+
+	err := r.db.SelectContext(ctx, &beneficiaries, query, assetID)
+	return beneficiaries, err
+}
+
+// GetByUserID returns the beneficiaries of all of an owner's assets
+func (r *BeneficiaryRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]model.AssetBeneficiary, error) {
+	beneficiaries := make([]model.AssetBeneficiary, 0)
+	query := beneficiarySelect + `
+		JOIN assets a ON a.id = b.asset_id
+		WHERE a.user_id = $1
+		ORDER BY b.asset_id, b.share_percentage DESC
+	`
+
+	err := r.db.SelectContext(ctx, &beneficiaries, query, userID)
+	return beneficiaries, err
+}
+
+// ReplaceForAsset swaps an asset's allocation for a new one in a single transaction
+func (r *BeneficiaryRepository) ReplaceForAsset(ctx context.Context, assetID uuid.UUID, beneficiaries []model.AssetBeneficiary) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM asset_beneficiaries WHERE asset_id = $1`, assetID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO asset_beneficiaries (id, asset_id, nominee_id, share_percentage, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+	`
+
+	now := time.Now()
+	for i := range beneficiaries {
+		beneficiaries[i].ID = uuid.New()
+		beneficiaries[i].AssetID = assetID
+		beneficiaries[i].CreatedAt = now
+		beneficiaries[i].UpdatedAt = now
+
+		if _, err := tx.ExecContext(ctx, query, beneficiaries[i].ID, assetID, beneficiaries[i].NomineeID, beneficiaries[i].SharePercentage, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *BeneficiaryRepository) DeleteByAssetID(ctx context.Context, assetID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM asset_beneficiaries WHERE asset_id = $1`, assetID)
+	return err
+}
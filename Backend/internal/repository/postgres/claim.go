@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sampatti/internal/model"
+)
+
+type ClaimRepository struct {
+	db *sqlx.DB
+}
+
+func NewClaimRepository(db *sqlx.DB) *ClaimRepository {
+	return &ClaimRepository{db: db}
+}
+
+type ClaimStepDB struct {
+	ID                uuid.UUID      `db:"id"`
+	AssetID           uuid.UUID      `db:"asset_id"`
+	Position          int            `db:"position"`
+	Title             string         `db:"title"`
+	RequiredDocuments pq.StringArray `db:"required_documents"`
+	Status            string         `db:"status"`
+	Notes             string         `db:"notes"`
+	UpdatedBy         *uuid.UUID     `db:"updated_by"`
+	CreatedAt         time.Time      `db:"created_at"`
+	UpdatedAt         time.Time      `db:"updated_at"`
+}
+
+func toClaimStepModel(dbStep ClaimStepDB) model.ClaimStep {
+	return model.ClaimStep{
+		ID:                dbStep.ID,
+		AssetID:           dbStep.AssetID,
+		Position:          dbStep.Position,
+		Title:             dbStep.Title,
+		RequiredDocuments: []string(dbStep.RequiredDocuments),
+		Status:            dbStep.Status,
+		Notes:             dbStep.Notes,
+		UpdatedBy:         dbStep.UpdatedBy,
+		CreatedAt:         dbStep.CreatedAt,
+		UpdatedAt:         dbStep.UpdatedAt,
+		Documents:         make([]model.ClaimDocument, 0),
+	}
+}
+
+// CreateSteps stores the generated checklists. Steps another nominee's
+// request already generated are skipped.
+func (r *ClaimRepository) CreateSteps(ctx context.Context, steps []model.ClaimStep) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO claim_steps (id, asset_id, position, title, required_documents, status, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+			ON CONFLICT (asset_id, position) DO NOTHING
+		`, uuid.New(), step.AssetID, step.Position, step.Title, pq.StringArray(step.RequiredDocuments), step.Status, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListStepsByAssetIDs returns the checklists of the assets in order, with their documents
+func (r *ClaimRepository) ListStepsByAssetIDs(ctx context.Context, assetIDs []uuid.UUID) ([]model.ClaimStep, error) {
+	var dbSteps []ClaimStepDB
+	query := `
+		SELECT id, asset_id, position, title, required_documents, status, notes,
+			updated_by, created_at, updated_at
+		FROM claim_steps
+		WHERE asset_id = ANY($1)
+		ORDER BY asset_id, position
+	`
+
+	if err := r.db.SelectContext(ctx, &dbSteps, query, pq.Array(assetIDs)); err != nil {
+		return nil, err
+	}
+
+	steps := make([]model.ClaimStep, 0, len(dbSteps))
+	index := make(map[uuid.UUID]int, len(dbSteps))
+	ids := make([]uuid.UUID, 0, len(dbSteps))
+	for _, dbStep := range dbSteps {
+		index[dbStep.ID] = len(steps)
+		ids = append(ids, dbStep.ID)
+		steps = append(steps, toClaimStepModel(dbStep))
+	}
+
+	if len(ids) == 0 {
+		return steps, nil
+	}
+
+	var documents []model.ClaimDocument
+	if err := r.db.SelectContext(ctx, &documents, `
+		SELECT id, step_id, nominee_id, filename, file_size, mime_type, storage_key, uploaded_at
+		FROM claim_documents
+		WHERE step_id = ANY($1)
+		ORDER BY uploaded_at
+	`, pq.Array(ids)); err != nil {
+		return nil, err
+	}
+
+	for _, document := range documents {
+		i := index[document.StepID]
+		steps[i].Documents = append(steps[i].Documents, document)
+	}
+
+	return steps, nil
+}
+
+func (r *ClaimRepository) GetStepByID(ctx context.Context, id uuid.UUID) (*model.ClaimStep, error) {
+	var dbStep ClaimStepDB
+	query := `
+		SELECT id, asset_id, position, title, required_documents, status, notes,
+			updated_by, created_at, updated_at
+		FROM claim_steps
+		WHERE id = $1
+	`
+
+	if err := r.db.GetContext(ctx, &dbStep, query, id); err != nil {
+		return nil, err
+	}
+
+	step := toClaimStepModel(dbStep)
+	return &step, nil
+}
+
+// UpdateStep saves a step's progress
+func (r *ClaimRepository) UpdateStep(ctx context.Context, step *model.ClaimStep) error {
+	query := `
+		UPDATE claim_steps SET status = $1, notes = $2, updated_by = $3, updated_at = $4
+		WHERE id = $5
+	`
+
+	step.UpdatedAt = time.Now()
+	_, err := r.db.ExecContext(ctx, query, step.Status, step.Notes, step.UpdatedBy, step.UpdatedAt, step.ID)
+	return err
+}
+
+func (r *ClaimRepository) CreateDocument(ctx context.Context, document *model.ClaimDocument) error {
+	query := `
+		INSERT INTO claim_documents (id, step_id, nominee_id, filename, file_size, mime_type, storage_key, uploaded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	document.ID = uuid.New()
+	document.UploadedAt = time.Now()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		document.ID,
+		document.StepID,
+		document.NomineeID,
+		document.Filename,
+		document.FileSize,
+		document.MimeType,
+		document.StorageKey,
+		document.UploadedAt,
+	)
+	return err
+}
+
+func (r *ClaimRepository) GetDocumentByID(ctx context.Context, id uuid.UUID) (*model.ClaimDocument, error) {
+	var document model.ClaimDocument
+	query := `
+		SELECT id, step_id, nominee_id, filename, file_size, mime_type, storage_key, uploaded_at
+		FROM claim_documents
+		WHERE id = $1
+	`
+
+	if err := r.db.GetContext(ctx, &document, query, id); err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// DeleteDocument removes a document the nominee uploaded and reports whether it existed
+func (r *ClaimRepository) DeleteDocument(ctx context.Context, nomineeID, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM claim_documents WHERE id = $1 AND nominee_id = $2`, id, nomineeID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sampatti/internal/model"
+)
+
+type ClaimInstructionRepository struct {
+	db *sqlx.DB
+}
+
+func NewClaimInstructionRepository(db *sqlx.DB) *ClaimInstructionRepository {
+	return &ClaimInstructionRepository{db: db}
+}
+
+type ClaimInstructionStepDB struct {
+	InstructionID     uuid.UUID      `db:"instruction_id"`
+	Position          int            `db:"position"`
+	Title             string         `db:"title"`
+	RequiredDocuments pq.StringArray `db:"required_documents"`
+}
+
+func (r *ClaimInstructionRepository) Create(ctx context.Context, instruction *model.ClaimInstruction) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	instruction.ID = uuid.New()
+	instruction.CreatedAt = time.Now()
+	instruction.UpdatedAt = instruction.CreatedAt
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO claim_instructions (id, user_id, institution, instructions, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, instruction.ID, instruction.UserID, instruction.Institution, instruction.Instructions, instruction.CreatedAt, instruction.UpdatedAt); err != nil {
+		return err
+	}
+
+	if err := r.setSteps(ctx, tx, instruction); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update saves one of the user's instructions and reports whether it existed.
+// Checklists already generated from it are left as they are.
+func (r *ClaimInstructionRepository) Update(ctx context.Context, instruction *model.ClaimInstruction) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	instruction.UpdatedAt = time.Now()
+
+	if err := tx.GetContext(ctx, &instruction.CreatedAt, `
+		UPDATE claim_instructions SET institution = $1, instructions = $2, updated_at = $3
+		WHERE id = $4 AND user_id = $5
+		RETURNING created_at
+	`, instruction.Institution, instruction.Instructions, instruction.UpdatedAt, instruction.ID, instruction.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := r.setSteps(ctx, tx, instruction); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// ListByUserID returns the user's instructions with their steps, by institution
+func (r *ClaimInstructionRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]model.ClaimInstruction, error) {
+	instructions := make([]model.ClaimInstruction, 0)
+	query := `
+		SELECT id, user_id, institution, instructions, created_at, updated_at
+		FROM claim_instructions
+		WHERE user_id = $1
+		ORDER BY LOWER(institution)
+	`
+
+	if err := r.db.SelectContext(ctx, &instructions, query, userID); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadSteps(ctx, instructions); err != nil {
+		return nil, err
+	}
+
+	return instructions, nil
+}
+
+func (r *ClaimInstructionRepository) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM claim_instructions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// Private methods
+
+func (r *ClaimInstructionRepository) setSteps(ctx context.Context, tx *sqlx.Tx, instruction *model.ClaimInstruction) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM claim_instruction_steps WHERE instruction_id = $1`, instruction.ID); err != nil {
+		return err
+	}
+
+	for i, step := range instruction.Steps {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO claim_instruction_steps (instruction_id, position, title, required_documents)
+			VALUES ($1, $2, $3, $4)
+		`, instruction.ID, i+1, step.Title, pq.StringArray(step.RequiredDocuments)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ClaimInstructionRepository) loadSteps(ctx context.Context, instructions []model.ClaimInstruction) error {
+	if len(instructions) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(instructions))
+	index := make(map[uuid.UUID]int, len(instructions))
+	for i := range instructions {
+		ids = append(ids, instructions[i].ID)
+		index[instructions[i].ID] = i
+		instructions[i].Steps = make([]model.ClaimInstructionStep, 0)
+	}
+
+	var steps []ClaimInstructionStepDB
+	if err := r.db.SelectContext(ctx, &steps, `
+		SELECT instruction_id, position, title, required_documents
+		FROM claim_instruction_steps
+		WHERE instruction_id = ANY($1)
+		ORDER BY position
+	`, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		i := index[step.InstructionID]
+		instructions[i].Steps = append(instructions[i].Steps, model.ClaimInstructionStep{
+			Title:             step.Title,
+			RequiredDocuments: []string(step.RequiredDocuments),
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type EmailVerificationRepository struct {
+	db *sqlx.DB
+}
+
+func NewEmailVerificationRepository(db *sqlx.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+func (r *EmailVerificationRepository) Create(ctx context.Context, token *model.EmailVerificationToken) error {
+	query := `
+		INSERT INTO email_verification_tokens (
+			id, user_id, email, token_hash, expires_at, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		)
+	`
+
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		token.ID,
+		token.UserID,
+		token.Email,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+
+	return err
+}
+
+// Consume marks an unused, unexpired token as used and returns it.
+// The update is a single statement so a token can only be redeemed once.
+func (r *EmailVerificationRepository) Consume(ctx context.Context, tokenHash string) (*model.EmailVerificationToken, error) {
+	var token model.EmailVerificationToken
+	query := `
+		UPDATE email_verification_tokens SET
+			used_at = $1
+		WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1
+		RETURNING id, user_id, email, token_hash, expires_at, used_at, created_at
+	`
+
+	err := r.db.GetContext(ctx, &token, query, time.Now(), tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// InvalidateForUser marks every outstanding token of a user as used
+func (r *EmailVerificationRepository) InvalidateForUser(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE email_verification_tokens SET
+			used_at = $1
+		WHERE user_id = $2 AND used_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), userID)
+	return err
+}
+
+// LastSentAt returns when the most recent token for a user was created, or nil if none was
+func (r *EmailVerificationRepository) LastSentAt(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	var sentAt *time.Time
+	query := `SELECT MAX(created_at) FROM email_verification_tokens WHERE user_id = $1`
+
+	err := r.db.GetContext(ctx, &sentAt, query, userID)
+	return sentAt, err
+}
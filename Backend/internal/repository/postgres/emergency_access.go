@@ -0,0 +1,202 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type EmergencyAccessRepository struct {
+	db *sqlx.DB
+}
+
+func NewEmergencyAccessRepository(db *sqlx.DB) *EmergencyAccessRepository {
+	return &EmergencyAccessRepository{db: db}
+}
+
+const emergencyAccessColumns = `
+	r.id, r.nominee_id, r.user_id, r.status, r.requested_at, r.grant_at, r.resolved_at,
+	r.expires_at, r.deny_token_hash, r.ip_address, r.created_at, r.updated_at
+`
+
+func (r *EmergencyAccessRepository) Create(ctx context.Context, request *model.EmergencyAccessRequest) error {
+	query := `
+		INSERT INTO emergency_access_requests (
+			id, nominee_id, user_id, status, requested_at, grant_at,
+			deny_token_hash, ip_address, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+		)
+	`
+
+	request.ID = uuid.New()
+	request.CreatedAt = time.Now()
+	request.UpdatedAt = request.CreatedAt
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		request.ID,
+		request.NomineeID,
+		request.UserID,
+		request.Status,
+		request.RequestedAt,
+		request.GrantAt,
+		request.DenyTokenHash,
+		request.IPAddress,
+		request.CreatedAt,
+		request.UpdatedAt,
+	)
+
+	return err
+}
+
+func (r *EmergencyAccessRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.EmergencyAccessRequest, error) {
+	var request model.EmergencyAccessRequest
+	query := `SELECT ` + emergencyAccessColumns + ` FROM emergency_access_requests r WHERE r.id = $1`
+
+	err := r.db.GetContext(ctx, &request, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// GetLatestByNomineeID returns the nominee's most recent request
+func (r *EmergencyAccessRepository) GetLatestByNomineeID(ctx context.Context, nomineeID uuid.UUID) (*model.EmergencyAccessRequest, error) {
+	var request model.EmergencyAccessRequest
+	query := `
+		SELECT ` + emergencyAccessColumns + `
+		FROM emergency_access_requests r
+		WHERE r.nominee_id = $1
+		ORDER BY r.requested_at DESC
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &request, query, nomineeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// ListByUserID returns the requests made for an owner's account, newest first
+func (r *EmergencyAccessRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]model.EmergencyAccessRequest, error) {
+	requests := make([]model.EmergencyAccessRequest, 0)
+	query := `
+		SELECT ` + emergencyAccessColumns + `, n.name AS nominee_name, n.email AS nominee_email
+		FROM emergency_access_requests r
+		JOIN nominees n ON n.id = r.nominee_id
+		WHERE r.user_id = $1
+		ORDER BY r.requested_at DESC
+	`
+
+	err := r.db.SelectContext(ctx, &requests, query, userID)
+	return requests, err
+}
+
+// ListByNomineeEmail returns the requests made as a nominee with this email
+// across every owner who named them, newest first
+func (r *EmergencyAccessRepository) ListByNomineeEmail(ctx context.Context, email string) ([]model.EmergencyAccessRequest, error) {
+	requests := make([]model.EmergencyAccessRequest, 0)
+	query := `
+		SELECT ` + emergencyAccessColumns + `, n.name AS nominee_name, n.email AS nominee_email, u.name AS owner_name
+		FROM emergency_access_requests r
+		JOIN nominees n ON n.id = r.nominee_id
+		JOIN users u ON u.id = r.user_id
+		WHERE n.email = $1
+		ORDER BY r.requested_at DESC
+	`
+
+	err := r.db.SelectContext(ctx, &requests, query, email)
+	return requests, err
+}
+
+// Deny marks a pending request for the owner's account as denied. It returns
+// nil when there's no such pending request.
+func (r *EmergencyAccessRepository) Deny(ctx context.Context, userID, id uuid.UUID) (*model.EmergencyAccessRequest, error) {
+	return r.resolve(ctx, `
+		UPDATE emergency_access_requests r SET status = 'Denied', resolved_at = $1, updated_at = $1
+		WHERE r.id = $2 AND r.user_id = $3 AND r.status = 'Pending'
+		RETURNING `+emergencyAccessColumns,
+		time.Now(), id, userID,
+	)
+}
+
+// DenyByToken denies the pending request a one-click deny link was issued for
+func (r *EmergencyAccessRepository) DenyByToken(ctx context.Context, tokenHash string) (*model.EmergencyAccessRequest, error) {
+	return r.resolve(ctx, `
+		UPDATE emergency_access_requests r SET status = 'Denied', resolved_at = $1, updated_at = $1
+		WHERE r.deny_token_hash = $2 AND r.status = 'Pending'
+		RETURNING `+emergencyAccessColumns,
+		time.Now(), tokenHash,
+	)
+}
+
+// Grant grants a pending request whose waiting period is over. It returns
+// nil when the request was already resolved.
+func (r *EmergencyAccessRepository) Grant(ctx context.Context, id uuid.UUID, expiresAt time.Time) (*model.EmergencyAccessRequest, error) {
+	return r.resolve(ctx, `
+		UPDATE emergency_access_requests r SET status = 'Granted', resolved_at = $1, expires_at = $2, updated_at = $1
+		WHERE r.id = $3 AND r.status = 'Pending' AND r.grant_at <= $1
+		RETURNING `+emergencyAccessColumns,
+		time.Now(), expiresAt, id,
+	)
+}
+
+// GrantDue grants every pending request whose waiting period is over and
+// returns them
+func (r *EmergencyAccessRepository) GrantDue(ctx context.Context, expiresAt time.Time) ([]model.EmergencyAccessRequest, error) {
+	requests := make([]model.EmergencyAccessRequest, 0)
+	query := `
+		UPDATE emergency_access_requests r SET status = 'Granted', resolved_at = $1, expires_at = $2, updated_at = $1
+		WHERE r.status = 'Pending' AND r.grant_at <= $1
+		RETURNING ` + emergencyAccessColumns
+
+	err := r.db.SelectContext(ctx, &requests, query, time.Now(), expiresAt)
+	return requests, err
+}
+
+// ExpireDue marks granted requests whose access has run out as expired and
+// forgets the vault shares their nominees submitted, unless the owner's
+// inactivity switch still gives those nominees access
+func (r *EmergencyAccessRepository) ExpireDue(ctx context.Context) (int64, error) {
+	query := `
+		WITH expired AS (
+			UPDATE emergency_access_requests SET status = 'Expired', updated_at = $1
+			WHERE status = 'Granted' AND expires_at <= $1
+			RETURNING nominee_id
+		), cleared AS (
+			UPDATE vault_shares s SET submitted_share = NULL, submitted_at = NULL
+			FROM nominees n
+			WHERE n.id = s.nominee_id AND n.access_eligible_at IS NULL
+				AND s.submitted_at IS NOT NULL
+				AND s.nominee_id IN (SELECT nominee_id FROM expired)
+		)
+		SELECT COUNT(*) FROM expired
+	`
+
+	var expired int64
+	err := r.db.GetContext(ctx, &expired, query, time.Now())
+	return expired, err
+}
+
+// Private methods
+
+func (r *EmergencyAccessRepository) resolve(ctx context.Context, query string, args ...interface{}) (*model.EmergencyAccessRequest, error) {
+	requests := make([]model.EmergencyAccessRequest, 0, 1)
+	if err := r.db.SelectContext(ctx, &requests, query, args...); err != nil {
+		return nil, err
+	}
+
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	return &requests[0], nil
+}
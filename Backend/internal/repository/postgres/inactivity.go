@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type InactivitySwitchRepository struct {
+	db *sqlx.DB
+}
+
+func NewInactivitySwitchRepository(db *sqlx.DB) *InactivitySwitchRepository {
+	return &InactivitySwitchRepository{db: db}
+}
+
+const inactivitySwitchColumns = `
+	s.user_id, s.enabled, s.inactivity_days, s.last_check_in_at, s.reminders_sent,
+	s.last_reminder_at, s.released_at, s.created_at, s.updated_at
+`
+
+// GetByUserID returns the user's switch, creating it with defaults on first use
+func (r *InactivitySwitchRepository) GetByUserID(ctx context.Context, userID uuid.UUID, defaultDays int) (*model.InactivitySwitch, error) {
+	if err := r.ensure(ctx, userID, defaultDays); err != nil {
+		return nil, err
+	}
+
+	var sw model.InactivitySwitch
+	query := `SELECT ` + inactivitySwitchColumns + ` FROM inactivity_switches s WHERE s.user_id = $1`
+
+	err := r.db.GetContext(ctx, &sw, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sw, nil
+}
+
+func (r *InactivitySwitchRepository) UpdateSettings(ctx context.Context, userID uuid.UUID, enabled bool, inactivityDays int) error {
+	query := `
+		UPDATE inactivity_switches SET
+			enabled = $1,
+			inactivity_days = $2,
+			updated_at = $3
+		WHERE user_id = $4
+	`
+
+	_, err := r.db.ExecContext(ctx, query, enabled, inactivityDays, time.Now(), userID)
+	return err
+}
+
+// CheckIn restarts the inactivity period and withdraws any release, along
+// with vault shares nominees submitted
+func (r *InactivitySwitchRepository) CheckIn(ctx context.Context, userID uuid.UUID) error {
+	return r.reset(ctx, userID, true)
+}
+
+// ResetRelease withdraws a release and pending reminders after the owner came
+// back, without touching their last check-in
+func (r *InactivitySwitchRepository) ResetRelease(ctx context.Context, userID uuid.UUID) error {
+	return r.reset(ctx, userID, false)
+}
+
+// ListMonitored returns the switch of every owner who has nominees, with the
+// owner's contact details and last login. Owners who added nominees since the
+// last run get a switch whose period starts now.
+func (r *InactivitySwitchRepository) ListMonitored(ctx context.Context, defaultDays int) ([]model.InactivitySwitch, error) {
+	insert := `
+		INSERT INTO inactivity_switches (user_id, inactivity_days, last_check_in_at, created_at, updated_at)
+		SELECT DISTINCT n.user_id, $1::INT, $2::TIMESTAMPTZ, $2::TIMESTAMPTZ, $2::TIMESTAMPTZ FROM nominees n
+		ON CONFLICT (user_id) DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, insert, defaultDays, time.Now()); err != nil {
+		return nil, err
+	}
+
+	switches := make([]model.InactivitySwitch, 0)
+	query := `
+		SELECT ` + inactivitySwitchColumns + `,
+			u.name AS owner_name, u.email AS owner_email, u.last_login
+		FROM inactivity_switches s
+		JOIN users u ON u.id = s.user_id
+		WHERE EXISTS (SELECT 1 FROM nominees n WHERE n.user_id = s.user_id)
+	`
+
+	err := r.db.SelectContext(ctx, &switches, query)
+	return switches, err
+}
+
+// AdvanceReminders moves the reminder count from one stage to the next. It
+// reports false when another run already did, so each reminder is sent once.
+func (r *InactivitySwitchRepository) AdvanceReminders(ctx context.Context, userID uuid.UUID, from, to int) (bool, error) {
+	query := `
+		UPDATE inactivity_switches SET
+			reminders_sent = $1,
+			last_reminder_at = $2,
+			updated_at = $2
+		WHERE user_id = $3 AND reminders_sent = $4 AND released_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, to, time.Now(), userID, from)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// Release marks the switch released and every nominee who hasn't been revoked
+// as eligible for access. It reports false if the switch was already released.
+func (r *InactivitySwitchRepository) Release(ctx context.Context, userID uuid.UUID) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE inactivity_switches SET released_at = $1, updated_at = $1
+		WHERE user_id = $2 AND released_at IS NULL
+	`, now, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE nominees SET access_eligible_at = $1, updated_at = $1
+		WHERE user_id = $2 AND status <> 'Revoked' AND access_eligible_at IS NULL
+	`, now, userID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Private methods
+
+func (r *InactivitySwitchRepository) ensure(ctx context.Context, userID uuid.UUID, defaultDays int) error {
+	query := `
+		INSERT INTO inactivity_switches (user_id, inactivity_days, last_check_in_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $3, $3)
+		ON CONFLICT (user_id) DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, defaultDays, time.Now())
+	return err
+}
+
+func (r *InactivitySwitchRepository) reset(ctx context.Context, userID uuid.UUID, checkIn bool) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `
+		UPDATE inactivity_switches SET
+			reminders_sent = 0,
+			last_reminder_at = NULL,
+			released_at = NULL,
+			updated_at = $1
+		WHERE user_id = $2
+	`
+	if checkIn {
+		query = `
+			UPDATE inactivity_switches SET
+				last_check_in_at = $1,
+				reminders_sent = 0,
+				last_reminder_at = NULL,
+				released_at = NULL,
+				updated_at = $1
+			WHERE user_id = $2
+		`
+	}
+
+	if _, err := tx.ExecContext(ctx, query, now, userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE nominees SET access_eligible_at = NULL, updated_at = $1
+		WHERE user_id = $2 AND access_eligible_at IS NOT NULL
+	`, now, userID); err != nil {
+		return err
+	}
+
+	// The owner is back, so shares collected to open their vault are forgotten
+	if _, err := tx.ExecContext(ctx, clearOwnerSubmittedShares, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
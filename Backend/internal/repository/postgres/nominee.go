@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,9 +24,9 @@ func (r *NomineeRepository) Create(ctx context.Context, nominee *model.Nominee)
 		INSERT INTO nominees (
 			id, user_id, name, email, phone_number, relationship,
 			access_level, created_at, updated_at, status,
-			emergency_access_code
+			access_duration_hours, permission_profile_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
 		)
 	`
 
@@ -46,7 +47,8 @@ func (r *NomineeRepository) Create(ctx context.Context, nominee *model.Nominee)
 		nominee.CreatedAt,
 		nominee.UpdatedAt,
 		nominee.Status,
-		nominee.EmergencyAccessCode,
+		nominee.AccessDurationHours,
+		nominee.PermissionProfileID,
 	)
 
 	return err
@@ -57,7 +59,9 @@ func (r *NomineeRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.N
 	query := `
 		SELECT id, user_id, name, email, phone_number, relationship,
 			access_level, created_at, updated_at, status,
-			emergency_access_code, last_access_date
+			COALESCE(password_hash, '') AS password_hash, linked_user_id, invited_at, responded_at,
+			access_duration_hours, last_access_date, access_eligible_at,
+			permission_profile_id, suspended_at
 		FROM nominees
 		WHERE id = $1
 	`
@@ -75,7 +79,9 @@ func (r *NomineeRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (
 	query := `
 		SELECT id, user_id, name, email, phone_number, relationship,
 			access_level, created_at, updated_at, status,
-			emergency_access_code, last_access_date
+			COALESCE(password_hash, '') AS password_hash, linked_user_id, invited_at, responded_at,
+			access_duration_hours, last_access_date, access_eligible_at,
+			permission_profile_id, suspended_at
 		FROM nominees
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -103,23 +109,106 @@ func (r *NomineeRepository) UpdateStatus(ctx context.Context, id uuid.UUID, stat
 	return err
 }
 
+// Revoke sets the nominee's status to Revoked and forgets any vault share
+// they submitted
+func (r *NomineeRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE nominees SET status = 'Revoked', updated_at = $1
+		WHERE id = $2
+	`, time.Now(), id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, clearNomineeSubmittedShares, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetSuspended suspends the nominee at the given time, forgetting any vault
+// share they submitted, or lifts the suspension when at is nil
+func (r *NomineeRepository) SetSuspended(ctx context.Context, id uuid.UUID, at *time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE nominees SET suspended_at = $1, updated_at = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, at, time.Now(), id); err != nil {
+		return err
+	}
+
+	if at != nil {
+		if _, err := tx.ExecContext(ctx, clearNomineeSubmittedShares, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (r *NomineeRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM nominees WHERE id = $1`
 	_, err := r.db.ExecContext(ctx, query, id)
 	return err
 }
 
-func (r *NomineeRepository) GetAccessLogs(ctx context.Context, nomineeID uuid.UUID) ([]model.NomineeAccessLog, error) {
-	var logs []model.NomineeAccessLog
+// ListAccessLogs returns the access logs of all the owner's nominees that
+// match the filter, newest first, with each nominee's name
+func (r *NomineeRepository) ListAccessLogs(ctx context.Context, filter model.NomineeAccessLogFilter) ([]model.NomineeAccessLog, error) {
+	conditions := []string{"n.user_id = $1"}
+	args := []interface{}{filter.UserID}
+
+	addCondition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if filter.NomineeID != nil {
+		addCondition("l.nominee_id = $%d", *filter.NomineeID)
+	}
+	if filter.Action != "" {
+		addCondition("l.action ILIKE '%%' || $%d || '%%'", escapeLike(filter.Action))
+	}
+	if filter.IPAddress != "" {
+		addCondition("l.ip_address = $%d", filter.IPAddress)
+	}
+	if filter.From != nil {
+		addCondition("l.date >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("l.date < $%d", *filter.To)
+	}
+	if filter.BeforeDate != nil && filter.BeforeID != nil {
+		args = append(args, *filter.BeforeDate, *filter.BeforeID)
+		conditions = append(conditions, fmt.Sprintf("(l.date, l.id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
 	query := `
-		SELECT id, nominee_id, date, action, ip_address, device_info
-		FROM nominee_access_logs
-		WHERE nominee_id = $1
-		ORDER BY date DESC
+		SELECT l.id, l.nominee_id, l.date, l.action,
+			COALESCE(l.ip_address, '') AS ip_address,
+			COALESCE(l.device_info, '') AS device_info,
+			n.name AS nominee_name
+		FROM nominee_access_logs l
+		JOIN nominees n ON n.id = l.nominee_id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY l.date DESC, l.id DESC
 	`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
 
-	err := r.db.SelectContext(ctx, &logs, query, nomineeID)
-	if err != nil {
+	logs := make([]model.NomineeAccessLog, 0)
+	if err := r.db.SelectContext(ctx, &logs, query, args...); err != nil {
 		return nil, err
 	}
 
@@ -132,7 +221,9 @@ func (r *NomineeRepository) GetByNomineeEmail(ctx context.Context, email string)
 	query := `
 		SELECT id, user_id, name, email, phone_number, relationship,
 			access_level, created_at, updated_at, status,
-			emergency_access_code, last_access_date
+			COALESCE(password_hash, '') AS password_hash, linked_user_id, invited_at, responded_at,
+			access_duration_hours, last_access_date, access_eligible_at,
+			permission_profile_id, suspended_at
 		FROM nominees
 		WHERE email = $1
 	`
@@ -150,7 +241,9 @@ func (r *NomineeRepository) GetByEmailAndUserID(ctx context.Context, email strin
 	query := `
 		SELECT id, user_id, name, email, phone_number, relationship,
 			access_level, created_at, updated_at, status,
-			emergency_access_code, last_access_date
+			COALESCE(password_hash, '') AS password_hash, linked_user_id, invited_at, responded_at,
+			access_duration_hours, last_access_date, access_eligible_at,
+			permission_profile_id, suspended_at
 		FROM nominees
 		WHERE email = $1 AND user_id = $2
 	`
@@ -173,8 +266,9 @@ func (r *NomineeRepository) Update(ctx context.Context, nominee *model.Nominee)
 			access_level = $4,
 			updated_at = $5,
 			status = $6,
-			emergency_access_code = $7
-		WHERE id = $8
+			access_duration_hours = $7,
+			permission_profile_id = $8
+		WHERE id = $9
 	`
 
 	nominee.UpdatedAt = time.Now()
@@ -188,39 +282,33 @@ func (r *NomineeRepository) Update(ctx context.Context, nominee *model.Nominee)
 		nominee.AccessLevel,
 		nominee.UpdatedAt,
 		nominee.Status,
-		nominee.EmergencyAccessCode,
+		nominee.AccessDurationHours,
+		nominee.PermissionProfileID,
 		nominee.ID,
 	)
 
 	return err
 }
 
-// UpdateEmergencyAccessCode - Method for updating just the emergency code
-func (r *NomineeRepository) UpdateEmergencyAccessCode(ctx context.Context, nomineeID uuid.UUID, code string) error {
+// GetByLinkedUserID returns the nominees that were accepted with a Sampatti account
+func (r *NomineeRepository) GetByLinkedUserID(ctx context.Context, linkedUserID uuid.UUID) ([]model.Nominee, error) {
+	var nominees []model.Nominee
 	query := `
-		UPDATE nominees SET
-			emergency_access_code = $1,
-			updated_at = $2,
-			status = 'Pending'
-		WHERE id = $3
+		SELECT id, user_id, name, email, phone_number, relationship,
+			access_level, created_at, updated_at, status,
+			COALESCE(password_hash, '') AS password_hash, linked_user_id, invited_at, responded_at,
+			access_duration_hours, last_access_date, access_eligible_at,
+			permission_profile_id, suspended_at
+		FROM nominees
+		WHERE linked_user_id = $1
 	`
 
-	now := time.Now()
-	result, err := r.db.ExecContext(ctx, query, code, now, nomineeID)
-	if err != nil {
-		return fmt.Errorf("failed to update emergency access code: %w", err)
-	}
-
-	rows, err := result.RowsAffected()
+	err := r.db.SelectContext(ctx, &nominees, query, linkedUserID)
 	if err != nil {
-		return fmt.Errorf("error checking rows affected: %w", err)
-	}
-
-	if rows == 0 {
-		return fmt.Errorf("no rows affected when updating access code for nominee %s", nomineeID)
+		return nil, err
 	}
 
-	return nil
+	return nominees, nil
 }
 
 func (r *NomineeRepository) GetByEmail(ctx context.Context, email string) (*model.Nominee, error) {
@@ -228,7 +316,9 @@ func (r *NomineeRepository) GetByEmail(ctx context.Context, email string) (*mode
 	query := `
 		SELECT id, user_id, name, email, phone_number, relationship,
 			access_level, created_at, updated_at, status,
-			emergency_access_code, last_access_date
+			COALESCE(password_hash, '') AS password_hash, linked_user_id, invited_at, responded_at,
+			access_duration_hours, last_access_date, access_eligible_at,
+			permission_profile_id, suspended_at
 		FROM nominees
 		WHERE email = $1
 		LIMIT 1
@@ -287,3 +377,8 @@ func (r *NomineeRepository) LogAccess(ctx context.Context, log *model.NomineeAcc
 
 	return err
 }
+
+// escapeLike makes a user's search text match literally in a LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
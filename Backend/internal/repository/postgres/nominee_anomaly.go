@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sampatti/internal/model"
+)
+
+type NomineeAnomalyRepository struct {
+	db *sqlx.DB
+}
+
+func NewNomineeAnomalyRepository(db *sqlx.DB) *NomineeAnomalyRepository {
+	return &NomineeAnomalyRepository{db: db}
+}
+
+type NomineeAccessAnomalyDB struct {
+	model.NomineeAccessAnomaly
+	Reasons pq.StringArray `db:"reasons"`
+}
+
+// NomineeAccessHistory is what a nominee has accessed from before
+type NomineeAccessHistory struct {
+	Accesses    int
+	IPAddresses []string
+	Devices     []string
+}
+
+// GetRules returns the owner's access rules
+func (r *NomineeAnomalyRepository) GetRules(ctx context.Context, userID uuid.UUID) (*model.NomineeAccessRules, error) {
+	var rules model.NomineeAccessRules
+	query := `
+		SELECT user_id, allowed_from, allowed_until, timezone, auto_suspend, updated_at
+		FROM nominee_access_rules
+		WHERE user_id = $1
+	`
+
+	if err := r.db.GetContext(ctx, &rules, query, userID); err != nil {
+		return nil, err
+	}
+
+	return &rules, nil
+}
+
+func (r *NomineeAnomalyRepository) SaveRules(ctx context.Context, rules *model.NomineeAccessRules) error {
+	query := `
+		INSERT INTO nominee_access_rules (user_id, allowed_from, allowed_until, timezone, auto_suspend, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET
+			allowed_from = EXCLUDED.allowed_from,
+			allowed_until = EXCLUDED.allowed_until,
+			timezone = EXCLUDED.timezone,
+			auto_suspend = EXCLUDED.auto_suspend,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	rules.UpdatedAt = time.Now()
+	_, err := r.db.ExecContext(ctx, query, rules.UserID, rules.AllowedFrom, rules.AllowedUntil, rules.Timezone, rules.AutoSuspend, rules.UpdatedAt)
+	return err
+}
+
+// AccessHistory returns the nominee's earlier accesses from a device, that
+// is all but the system's own entries and the log entry being scored
+func (r *NomineeAnomalyRepository) AccessHistory(ctx context.Context, nomineeID, excludeLogID uuid.UUID) (*NomineeAccessHistory, error) {
+	history := &NomineeAccessHistory{}
+
+	if err := r.db.GetContext(ctx, &history.Accesses, `
+		SELECT COUNT(*) FROM nominee_access_logs
+		WHERE nominee_id = $1 AND id <> $2 AND COALESCE(device_info, '') <> ''
+	`, nomineeID, excludeLogID); err != nil {
+		return nil, err
+	}
+
+	if history.Accesses == 0 {
+		return history, nil
+	}
+
+	if err := r.db.SelectContext(ctx, &history.IPAddresses, `
+		SELECT DISTINCT ip_address FROM nominee_access_logs
+		WHERE nominee_id = $1 AND id <> $2 AND COALESCE(device_info, '') <> '' AND COALESCE(ip_address, '') <> ''
+	`, nomineeID, excludeLogID); err != nil {
+		return nil, err
+	}
+
+	if err := r.db.SelectContext(ctx, &history.Devices, `
+		SELECT DISTINCT device_info FROM nominee_access_logs
+		WHERE nominee_id = $1 AND id <> $2 AND COALESCE(device_info, '') <> ''
+	`, nomineeID, excludeLogID); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// CountAccessSince counts the nominee's accesses from a device since the given time
+func (r *NomineeAnomalyRepository) CountAccessSince(ctx context.Context, nomineeID uuid.UUID, since time.Time) (int, error) {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM nominee_access_logs
+		WHERE nominee_id = $1 AND date >= $2 AND COALESCE(device_info, '') <> ''
+	`
+
+	err := r.db.GetContext(ctx, &count, query, nomineeID, since)
+	return count, err
+}
+
+func (r *NomineeAnomalyRepository) Create(ctx context.Context, anomaly *model.NomineeAccessAnomaly) error {
+	query := `
+		INSERT INTO nominee_access_anomalies (
+			id, nominee_id, user_id, access_log_id, score, reasons, action,
+			ip_address, device_info, suspended, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		)
+	`
+
+	anomaly.ID = uuid.New()
+	anomaly.CreatedAt = time.Now()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		anomaly.ID,
+		anomaly.NomineeID,
+		anomaly.UserID,
+		anomaly.AccessLogID,
+		anomaly.Score,
+		pq.StringArray(anomaly.Reasons),
+		anomaly.Action,
+		anomaly.IPAddress,
+		anomaly.DeviceInfo,
+		anomaly.Suspended,
+		anomaly.CreatedAt,
+	)
+	return err
+}
+
+// ListByUserID returns the latest suspicious accesses by the owner's nominees, newest first
+func (r *NomineeAnomalyRepository) ListByUserID(ctx context.Context, userID uuid.UUID, limit int) ([]model.NomineeAccessAnomaly, error) {
+	var dbAnomalies []NomineeAccessAnomalyDB
+	query := `
+		SELECT a.id, a.nominee_id, a.user_id, a.access_log_id, a.score, a.reasons,
+			a.action, a.ip_address, a.device_info, a.suspended, a.created_at,
+			n.name AS nominee_name
+		FROM nominee_access_anomalies a
+		JOIN nominees n ON n.id = a.nominee_id
+		WHERE a.user_id = $1
+		ORDER BY a.created_at DESC
+		LIMIT $2
+	`
+
+	if err := r.db.SelectContext(ctx, &dbAnomalies, query, userID, limit); err != nil {
+		return nil, err
+	}
+
+	anomalies := make([]model.NomineeAccessAnomaly, 0, len(dbAnomalies))
+	for _, dbAnomaly := range dbAnomalies {
+		anomaly := dbAnomaly.NomineeAccessAnomaly
+		anomaly.Reasons = []string(dbAnomaly.Reasons)
+		anomalies = append(anomalies, anomaly)
+	}
+
+	return anomalies, nil
+}
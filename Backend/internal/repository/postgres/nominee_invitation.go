@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type NomineeInvitationRepository struct {
+	db *sqlx.DB
+}
+
+func NewNomineeInvitationRepository(db *sqlx.DB) *NomineeInvitationRepository {
+	return &NomineeInvitationRepository{db: db}
+}
+
+// Create stores a new invitation, invalidating the nominee's earlier ones,
+// and marks the nominee Invited. It returns false without storing anything
+// when the nominee has already accepted or been revoked.
+func (r *NomineeInvitationRepository) Create(ctx context.Context, invitation *model.NomineeInvitation) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	invitation.ID = uuid.New()
+	invitation.CreatedAt = time.Now()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE nominees SET status = 'Invited', invited_at = $1, responded_at = NULL, updated_at = $1
+		WHERE id = $2 AND status IN ('Invited', 'Declined')
+	`, invitation.CreatedAt, invitation.NomineeID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE nominee_invitations SET used_at = $1
+		WHERE nominee_id = $2 AND used_at IS NULL
+	`, invitation.CreatedAt, invitation.NomineeID); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO nominee_invitations (id, nominee_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, invitation.ID, invitation.NomineeID, invitation.TokenHash, invitation.ExpiresAt, invitation.CreatedAt); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// GetByTokenHash returns an unused, unexpired invitation
+func (r *NomineeInvitationRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*model.NomineeInvitation, error) {
+	var invitation model.NomineeInvitation
+	query := `
+		SELECT id, nominee_id, token_hash, expires_at, used_at, created_at
+		FROM nominee_invitations
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+	`
+
+	err := r.db.GetContext(ctx, &invitation, query, tokenHash, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// Accept redeems an invitation and stores how the nominee will sign in:
+// a password hash, a linked account, or both. It returns the nominee's ID.
+func (r *NomineeInvitationRepository) Accept(ctx context.Context, tokenHash, passwordHash string, linkedUserID *uuid.UUID) (uuid.UUID, error) {
+	return r.respond(ctx, tokenHash, "Accepted", passwordHash, linkedUserID)
+}
+
+// Decline redeems an invitation without giving the nominee any way in
+func (r *NomineeInvitationRepository) Decline(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	return r.respond(ctx, tokenHash, "Declined", "", nil)
+}
+
+// Private methods
+
+// respond uses up the invitation and records the nominee's answer in one
+// transaction. It returns sql.ErrNoRows when the invitation isn't valid or
+// the nominee is no longer waiting for an answer.
+func (r *NomineeInvitationRepository) respond(ctx context.Context, tokenHash, status, passwordHash string, linkedUserID *uuid.UUID) (uuid.UUID, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var nomineeID uuid.UUID
+	if err := tx.GetContext(ctx, &nomineeID, `
+		UPDATE nominee_invitations SET used_at = $1
+		WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1
+		RETURNING nominee_id
+	`, now, tokenHash); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE nominees SET
+			status = $1,
+			password_hash = NULLIF($2, ''),
+			linked_user_id = $3,
+			responded_at = $4,
+			updated_at = $4
+		WHERE id = $5 AND status = 'Invited'
+	`, status, passwordHash, linkedUserID, now, nomineeID)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if rows == 0 {
+		return uuid.UUID{}, sql.ErrNoRows
+	}
+
+	return nomineeID, tx.Commit()
+}
@@ -0,0 +1,306 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sampatti/internal/model"
+)
+
+type NomineeMessageRepository struct {
+	db *sqlx.DB
+}
+
+func NewNomineeMessageRepository(db *sqlx.DB) *NomineeMessageRepository {
+	return &NomineeMessageRepository{db: db}
+}
+
+// Create stores a message with its recipients and attachments. The ID is
+// chosen by the caller, since the sealed content is bound to it.
+func (r *NomineeMessageRepository) Create(ctx context.Context, message *model.NomineeMessage) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	message.CreatedAt = time.Now()
+	message.UpdatedAt = message.CreatedAt
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO nominee_messages (id, user_id, sealed_content, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, message.ID, message.UserID, message.SealedContent, message.CreatedAt, message.UpdatedAt); err != nil {
+		return err
+	}
+
+	if err := r.setRecipients(ctx, tx, message); err != nil {
+		return err
+	}
+
+	if err := r.setAttachments(ctx, tx, message); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update saves one of the user's messages and reports whether it existed.
+// Recipients who stay on the message keep their first opened time.
+func (r *NomineeMessageRepository) Update(ctx context.Context, message *model.NomineeMessage) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	message.UpdatedAt = time.Now()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE nominee_messages SET sealed_content = $1, updated_at = $2
+		WHERE id = $3 AND user_id = $4
+	`, message.SealedContent, message.UpdatedAt, message.ID, message.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	if err := r.setRecipients(ctx, tx, message); err != nil {
+		return false, err
+	}
+
+	if err := r.setAttachments(ctx, tx, message); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *NomineeMessageRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.NomineeMessage, error) {
+	var message model.NomineeMessage
+	query := `
+		SELECT id, user_id, sealed_content, created_at, updated_at
+		FROM nominee_messages
+		WHERE id = $1
+	`
+
+	if err := r.db.GetContext(ctx, &message, query, id); err != nil {
+		return nil, err
+	}
+
+	messages := []model.NomineeMessage{message}
+	if err := r.loadDetails(ctx, messages, nil); err != nil {
+		return nil, err
+	}
+
+	return &messages[0], nil
+}
+
+// ListByUserID returns the owner's messages, newest first
+func (r *NomineeMessageRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]model.NomineeMessage, error) {
+	messages := make([]model.NomineeMessage, 0)
+	query := `
+		SELECT id, user_id, sealed_content, created_at, updated_at
+		FROM nominee_messages
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &messages, query, userID); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadDetails(ctx, messages, nil); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// ListForNominee returns the messages addressed to a nominee. Only the
+// nominee's own recipient entry is loaded.
+func (r *NomineeMessageRepository) ListForNominee(ctx context.Context, nomineeID uuid.UUID) ([]model.NomineeMessage, error) {
+	messages := make([]model.NomineeMessage, 0)
+	query := `
+		SELECT m.id, m.user_id, m.sealed_content, m.created_at, m.updated_at
+		FROM nominee_messages m
+		JOIN nominee_message_recipients mr ON mr.message_id = m.id
+		WHERE mr.nominee_id = $1
+		ORDER BY m.created_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &messages, query, nomineeID); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadDetails(ctx, messages, &nomineeID); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// ListAttachments returns the documents attached to the messages, keyed by message ID
+func (r *NomineeMessageRepository) ListAttachments(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID][]model.Document, error) {
+	var rows []struct {
+		MessageID uuid.UUID `db:"message_id"`
+		DocumentDB
+	}
+	query := `
+		SELECT ma.message_id, d.id, d.user_id, d.asset_id, d.document_type, d.title,
+			d.description, d.filename, d.file_size, d.mime_type,
+			d.storage_key, d.upload_date, d.tags, d.is_encrypted,
+			d.accessible_to_nominees
+		FROM nominee_message_attachments ma
+		JOIN documents d ON d.id = ma.document_id
+		WHERE ma.message_id = ANY($1)
+		ORDER BY d.upload_date DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &rows, query, pq.Array(messageIDs)); err != nil {
+		return nil, err
+	}
+
+	attachments := make(map[uuid.UUID][]model.Document, len(messageIDs))
+	for _, row := range rows {
+		attachments[row.MessageID] = append(attachments[row.MessageID], toDocumentModel(row.DocumentDB))
+	}
+
+	return attachments, nil
+}
+
+// MarkOpened records the first time a nominee opened each of the messages
+func (r *NomineeMessageRepository) MarkOpened(ctx context.Context, nomineeID uuid.UUID, messageIDs []uuid.UUID) error {
+	query := `
+		UPDATE nominee_message_recipients SET first_opened_at = $1
+		WHERE nominee_id = $2 AND message_id = ANY($3) AND first_opened_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), nomineeID, pq.Array(messageIDs))
+	return err
+}
+
+// Delete removes one of the user's messages and reports whether it existed
+func (r *NomineeMessageRepository) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM nominee_messages WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// Private methods
+
+// setRecipients makes the message's recipient list match message.Recipients
+func (r *NomineeMessageRepository) setRecipients(ctx context.Context, tx *sqlx.Tx, message *model.NomineeMessage) error {
+	nomineeIDs := make([]uuid.UUID, 0, len(message.Recipients))
+	for _, recipient := range message.Recipients {
+		nomineeIDs = append(nomineeIDs, recipient.NomineeID)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM nominee_message_recipients
+		WHERE message_id = $1 AND NOT (nominee_id = ANY($2))
+	`, message.ID, pq.Array(nomineeIDs)); err != nil {
+		return err
+	}
+
+	for _, nomineeID := range nomineeIDs {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO nominee_message_recipients (message_id, nominee_id)
+			VALUES ($1, $2)
+			ON CONFLICT (message_id, nominee_id) DO NOTHING
+		`, message.ID, nomineeID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setAttachments replaces the message's attachments with message.AttachmentIDs
+func (r *NomineeMessageRepository) setAttachments(ctx context.Context, tx *sqlx.Tx, message *model.NomineeMessage) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM nominee_message_attachments WHERE message_id = $1`, message.ID); err != nil {
+		return err
+	}
+
+	for _, documentID := range message.AttachmentIDs {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO nominee_message_attachments (message_id, document_id)
+			VALUES ($1, $2)
+			ON CONFLICT (message_id, document_id) DO NOTHING
+		`, message.ID, documentID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadDetails fills in recipients and attachment IDs, only the given
+// nominee's recipient entry when nomineeID is set
+func (r *NomineeMessageRepository) loadDetails(ctx context.Context, messages []model.NomineeMessage, nomineeID *uuid.UUID) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(messages))
+	index := make(map[uuid.UUID]int, len(messages))
+	for i := range messages {
+		ids = append(ids, messages[i].ID)
+		index[messages[i].ID] = i
+		messages[i].Recipients = make([]model.NomineeMessageRecipient, 0)
+		messages[i].AttachmentIDs = make([]uuid.UUID, 0)
+	}
+
+	var recipients []model.NomineeMessageRecipient
+	if err := r.db.SelectContext(ctx, &recipients, `
+		SELECT mr.message_id, mr.nominee_id, n.name AS nominee_name, mr.first_opened_at
+		FROM nominee_message_recipients mr
+		JOIN nominees n ON n.id = mr.nominee_id
+		WHERE mr.message_id = ANY($1) AND ($2::UUID IS NULL OR mr.nominee_id = $2)
+		ORDER BY n.name
+	`, pq.Array(ids), nomineeID); err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		i := index[recipient.MessageID]
+		messages[i].Recipients = append(messages[i].Recipients, recipient)
+	}
+
+	var attachments []struct {
+		MessageID  uuid.UUID `db:"message_id"`
+		DocumentID uuid.UUID `db:"document_id"`
+	}
+	if err := r.db.SelectContext(ctx, &attachments, `
+		SELECT message_id, document_id
+		FROM nominee_message_attachments
+		WHERE message_id = ANY($1)
+	`, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		i := index[attachment.MessageID]
+		messages[i].AttachmentIDs = append(messages[i].AttachmentIDs, attachment.DocumentID)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type NomineeRevocationRepository struct {
+	db *sqlx.DB
+}
+
+func NewNomineeRevocationRepository(db *sqlx.DB) *NomineeRevocationRepository {
+	return &NomineeRevocationRepository{db: db}
+}
+
+func (r *NomineeRevocationRepository) Create(ctx context.Context, token *model.NomineeRevocationToken) error {
+	query := `
+		INSERT INTO nominee_revocation_tokens (id, nominee_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+
+	_, err := r.db.ExecContext(ctx, query, token.ID, token.NomineeID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	return err
+}
+
+// Revoke redeems a revocation link, revokes its nominee, clears their
+// password so it can't be used again and forgets any vault share they
+// submitted, all in one transaction. It returns
+// the nominee's ID, or sql.ErrNoRows when the link isn't valid.
+func (r *NomineeRevocationRepository) Revoke(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var nomineeID uuid.UUID
+	if err := tx.GetContext(ctx, &nomineeID, `
+		UPDATE nominee_revocation_tokens SET used_at = $1
+		WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1
+		RETURNING nominee_id
+	`, now, tokenHash); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	// Every other link for the nominee has done its job too
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE nominee_revocation_tokens SET used_at = $1
+		WHERE nominee_id = $2 AND used_at IS NULL
+	`, now, nomineeID); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE nominees SET status = 'Revoked', password_hash = NULL, updated_at = $1
+		WHERE id = $2
+	`, now, nomineeID); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, clearNomineeSubmittedShares, nomineeID); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return nomineeID, tx.Commit()
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type NomineeSessionRepository struct {
+	db *sqlx.DB
+}
+
+func NewNomineeSessionRepository(db *sqlx.DB) *NomineeSessionRepository {
+	return &NomineeSessionRepository{db: db}
+}
+
+func (r *NomineeSessionRepository) Create(ctx context.Context, session *model.NomineeSession) error {
+	query := `
+		INSERT INTO nominee_sessions (
+			id, nominee_id, user_id, access_level, ip_address, user_agent,
+			created_at, expires_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+	`
+
+	session.ID = uuid.New()
+	session.CreatedAt = time.Now()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		session.ID,
+		session.NomineeID,
+		session.UserID,
+		session.AccessLevel,
+		session.IPAddress,
+		session.UserAgent,
+		session.CreatedAt,
+		session.ExpiresAt,
+	)
+
+	return err
+}
+
+// Touch records that a session was used and reports whether it is still
+// live: not revoked, not expired, and its nominee still accepted
+func (r *NomineeSessionRepository) Touch(ctx context.Context, id, nomineeID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE nominee_sessions s SET
+			last_used_at = $1
+		FROM nominees n
+		WHERE s.id = $2 AND s.nominee_id = $3 AND n.id = s.nominee_id
+			AND s.revoked_at IS NULL AND s.expires_at > $1 AND n.status = 'Accepted'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id, nomineeID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+// ListActiveByUserID returns the live sessions of the owner's nominees
+func (r *NomineeSessionRepository) ListActiveByUserID(ctx context.Context, userID uuid.UUID) ([]model.NomineeSession, error) {
+	var sessions []model.NomineeSession
+	query := `
+		SELECT s.id, s.nominee_id, s.user_id, s.access_level, s.ip_address, s.user_agent,
+			s.created_at, s.expires_at, s.last_used_at, s.revoked_at, n.name AS nominee_name
+		FROM nominee_sessions s
+		JOIN nominees n ON n.id = s.nominee_id
+		WHERE s.user_id = $1 AND s.revoked_at IS NULL AND s.expires_at > $2
+		ORDER BY s.created_at DESC
+	`
+
+	err := r.db.SelectContext(ctx, &sessions, query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeForUser revokes a session only if it reaches the owner's data, and reports whether it did
+func (r *NomineeSessionRepository) RevokeForUser(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE nominee_sessions SET
+			revoked_at = $1
+		WHERE user_id = $2 AND id = $3 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), userID, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+// RevokeAllForNominee revokes every session of a nominee
+func (r *NomineeSessionRepository) RevokeAllForNominee(ctx context.Context, nomineeID uuid.UUID) error {
+	query := `
+		UPDATE nominee_sessions SET
+			revoked_at = $1
+		WHERE nominee_id = $2 AND revoked_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), nomineeID)
+	return err
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type NotificationWebhookRepository struct {
+	db *sqlx.DB
+}
+
+func NewNotificationWebhookRepository(db *sqlx.DB) *NotificationWebhookRepository {
+	return &NotificationWebhookRepository{db: db}
+}
+
+// Upsert sets the user's webhook, replacing any earlier one
+func (r *NotificationWebhookRepository) Upsert(ctx context.Context, webhook *model.NotificationWebhook) error {
+	query := `
+		INSERT INTO notification_webhooks (user_id, url, secret, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			url = EXCLUDED.url,
+			secret = EXCLUDED.secret,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at, updated_at
+	`
+
+	return r.db.QueryRowxContext(ctx, query, webhook.UserID, webhook.URL, webhook.Secret, time.Now()).
+		Scan(&webhook.CreatedAt, &webhook.UpdatedAt)
+}
+
+func (r *NotificationWebhookRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*model.NotificationWebhook, error) {
+	var webhook model.NotificationWebhook
+	query := `
+		SELECT user_id, url, secret, created_at, updated_at
+		FROM notification_webhooks
+		WHERE user_id = $1
+	`
+
+	if err := r.db.GetContext(ctx, &webhook, query, userID); err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// Delete removes the user's webhook and reports whether there was one
+func (r *NotificationWebhookRepository) Delete(ctx context.Context, userID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM notification_webhooks WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
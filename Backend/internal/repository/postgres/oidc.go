@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type OIDCRepository struct {
+	db *sqlx.DB
+}
+
+func NewOIDCRepository(db *sqlx.DB) *OIDCRepository {
+	return &OIDCRepository{db: db}
+}
+
+func (r *OIDCRepository) CreateIdentity(ctx context.Context, identity *model.OIDCIdentity) error {
+	query := `
+		INSERT INTO oidc_identities (
+			id, user_id, issuer, subject, email, created_at, last_login_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		)
+	`
+
+	identity.ID = uuid.New()
+	identity.CreatedAt = time.Now()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		identity.ID,
+		identity.UserID,
+		identity.Issuer,
+		identity.Subject,
+		identity.Email,
+		identity.CreatedAt,
+		identity.LastLoginAt,
+	)
+
+	return err
+}
+
+func (r *OIDCRepository) GetIdentity(ctx context.Context, issuer, subject string) (*model.OIDCIdentity, error) {
+	var identity model.OIDCIdentity
+	query := `
+		SELECT id, user_id, issuer, subject, email, created_at, last_login_at
+		FROM oidc_identities
+		WHERE issuer = $1 AND subject = $2
+	`
+
+	err := r.db.GetContext(ctx, &identity, query, issuer, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (r *OIDCRepository) ListIdentitiesByUserID(ctx context.Context, userID uuid.UUID) ([]model.OIDCIdentity, error) {
+	identities := make([]model.OIDCIdentity, 0)
+	query := `
+		SELECT id, user_id, issuer, subject, email, created_at, last_login_at
+		FROM oidc_identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	err := r.db.SelectContext(ctx, &identities, query, userID)
+	return identities, err
+}
+
+// RecordLogin stores the time of a sign-in and the email the provider reported
+func (r *OIDCRepository) RecordLogin(ctx context.Context, id uuid.UUID, email string) error {
+	query := `UPDATE oidc_identities SET last_login_at = $1, email = $2 WHERE id = $3`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), email, id)
+	return err
+}
+
+// DeleteIdentity unlinks one of the user's identities and reports whether it existed
+func (r *OIDCRepository) DeleteIdentity(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	query := `DELETE FROM oidc_identities WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *OIDCRepository) CreateState(ctx context.Context, state *model.OIDCLoginState) error {
+	query := `
+		INSERT INTO oidc_login_states (
+			id, state_hash, nonce, code_verifier, user_id, expires_at, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		)
+	`
+
+	state.ID = uuid.New()
+	state.CreatedAt = time.Now()
+
+	// Abandoned sign-ins leave expired states behind; clear them as new ones are issued
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < $1`, state.CreatedAt); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		state.ID,
+		state.StateHash,
+		state.Nonce,
+		state.CodeVerifier,
+		state.UserID,
+		state.ExpiresAt,
+		state.CreatedAt,
+	)
+
+	return err
+}
+
+// ConsumeState deletes an unexpired state and returns it
+func (r *OIDCRepository) ConsumeState(ctx context.Context, stateHash string) (*model.OIDCLoginState, error) {
+	var state model.OIDCLoginState
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1 AND expires_at > $2
+		RETURNING id, state_hash, nonce, code_verifier, user_id, expires_at, created_at
+	`
+
+	err := r.db.GetContext(ctx, &state, query, stateHash, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type PasswordResetRepository struct {
+	db *sqlx.DB
+}
+
+func NewPasswordResetRepository(db *sqlx.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+func (r *PasswordResetRepository) Create(ctx context.Context, token *model.PasswordResetToken) error {
+	query := `
+		INSERT INTO password_reset_tokens (
+			id, user_id, token_hash, expires_at, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5
+		)
+	`
+
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		token.ID,
+		token.UserID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+
+	return err
+}
+
+// Redeem marks an unused, unexpired token as used and sets the user's new
+// password in one transaction, so a failed update leaves the token usable.
+// The token update is a single statement so a token can only be redeemed once.
+func (r *PasswordResetRepository) Redeem(ctx context.Context, tokenHash, passwordHash string) (*model.PasswordResetToken, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var token model.PasswordResetToken
+	query := `
+		UPDATE password_reset_tokens SET
+			used_at = $1
+		WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1
+		RETURNING id, user_id, token_hash, expires_at, used_at, created_at
+	`
+
+	if err := tx.GetContext(ctx, &token, query, now, tokenHash); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET
+			password_hash = $1,
+			updated_at = $2
+		WHERE id = $3
+	`, passwordHash, now, token.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// InvalidateForUser marks every outstanding token of a user as used
+func (r *PasswordResetRepository) InvalidateForUser(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE password_reset_tokens SET
+			used_at = $1
+		WHERE user_id = $2 AND used_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), userID)
+	return err
+}
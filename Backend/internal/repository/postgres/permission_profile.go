@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sampatti/internal/model"
+)
+
+type PermissionProfileRepository struct {
+	db *sqlx.DB
+}
+
+func NewPermissionProfileRepository(db *sqlx.DB) *PermissionProfileRepository {
+	return &PermissionProfileRepository{db: db}
+}
+
+type PermissionProfileDB struct {
+	ID                   uuid.UUID      `db:"id"`
+	UserID               uuid.UUID      `db:"user_id"`
+	Name                 string         `db:"name"`
+	AssetTypes           pq.StringArray `db:"asset_types"`
+	RedactAccountNumbers bool           `db:"redact_account_numbers"`
+	RedactInstitutions   bool           `db:"redact_institutions"`
+	RedactNotes          bool           `db:"redact_notes"`
+	DocumentTypes        pq.StringArray `db:"document_types"`
+	SharedDocumentsOnly  bool           `db:"shared_documents_only"`
+	AllowDownloads       bool           `db:"allow_downloads"`
+	CreatedAt            time.Time      `db:"created_at"`
+	UpdatedAt            time.Time      `db:"updated_at"`
+}
+
+// toPermissionProfileModel keeps NULL type lists nil, since nil means every type
+func toPermissionProfileModel(dbProfile PermissionProfileDB) model.PermissionProfile {
+	return model.PermissionProfile{
+		ID:                   dbProfile.ID,
+		UserID:               dbProfile.UserID,
+		Name:                 dbProfile.Name,
+		AssetTypes:           []string(dbProfile.AssetTypes),
+		RedactAccountNumbers: dbProfile.RedactAccountNumbers,
+		RedactInstitutions:   dbProfile.RedactInstitutions,
+		RedactNotes:          dbProfile.RedactNotes,
+		DocumentTypes:        []string(dbProfile.DocumentTypes),
+		SharedDocumentsOnly:  dbProfile.SharedDocumentsOnly,
+		AllowDownloads:       dbProfile.AllowDownloads,
+		CreatedAt:            dbProfile.CreatedAt,
+		UpdatedAt:            dbProfile.UpdatedAt,
+	}
+}
+
+const permissionProfileColumns = `
+	id, user_id, name, asset_types, redact_account_numbers, redact_institutions,
+	redact_notes, document_types, shared_documents_only, allow_downloads,
+	created_at, updated_at
+`
+
+func (r *PermissionProfileRepository) Create(ctx context.Context, profile *model.PermissionProfile) error {
+	query := `
+		INSERT INTO permission_profiles (
+			id, user_id, name, asset_types, redact_account_numbers, redact_institutions,
+			redact_notes, document_types, shared_documents_only, allow_downloads,
+			created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+		)
+	`
+
+	profile.ID = uuid.New()
+	profile.CreatedAt = time.Now()
+	profile.UpdatedAt = profile.CreatedAt
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		profile.ID,
+		profile.UserID,
+		profile.Name,
+		pq.StringArray(profile.AssetTypes),
+		profile.RedactAccountNumbers,
+		profile.RedactInstitutions,
+		profile.RedactNotes,
+		pq.StringArray(profile.DocumentTypes),
+		profile.SharedDocumentsOnly,
+		profile.AllowDownloads,
+		profile.CreatedAt,
+		profile.UpdatedAt,
+	)
+
+	return err
+}
+
+func (r *PermissionProfileRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.PermissionProfile, error) {
+	var dbProfile PermissionProfileDB
+	query := `
+		SELECT ` + permissionProfileColumns + `
+		FROM permission_profiles
+		WHERE id = $1
+	`
+
+	if err := r.db.GetContext(ctx, &dbProfile, query, id); err != nil {
+		return nil, err
+	}
+
+	profile := toPermissionProfileModel(dbProfile)
+	return &profile, nil
+}
+
+func (r *PermissionProfileRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]model.PermissionProfile, error) {
+	var dbProfiles []PermissionProfileDB
+	query := `
+		SELECT ` + permissionProfileColumns + `
+		FROM permission_profiles
+		WHERE user_id = $1
+		ORDER BY name
+	`
+
+	if err := r.db.SelectContext(ctx, &dbProfiles, query, userID); err != nil {
+		return nil, err
+	}
+
+	profiles := make([]model.PermissionProfile, 0, len(dbProfiles))
+	for _, dbProfile := range dbProfiles {
+		profiles = append(profiles, toPermissionProfileModel(dbProfile))
+	}
+
+	return profiles, nil
+}
+
+// Update saves one of the user's profiles and reports whether it existed
+func (r *PermissionProfileRepository) Update(ctx context.Context, profile *model.PermissionProfile) (bool, error) {
+	query := `
+		UPDATE permission_profiles SET
+			name = $1,
+			asset_types = $2,
+			redact_account_numbers = $3,
+			redact_institutions = $4,
+			redact_notes = $5,
+			document_types = $6,
+			shared_documents_only = $7,
+			allow_downloads = $8,
+			updated_at = $9
+		WHERE id = $10 AND user_id = $11
+	`
+
+	profile.UpdatedAt = time.Now()
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		profile.Name,
+		pq.StringArray(profile.AssetTypes),
+		profile.RedactAccountNumbers,
+		profile.RedactInstitutions,
+		profile.RedactNotes,
+		pq.StringArray(profile.DocumentTypes),
+		profile.SharedDocumentsOnly,
+		profile.AllowDownloads,
+		profile.UpdatedAt,
+		profile.ID,
+		profile.UserID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// Delete removes one of the user's profiles and reports whether it existed
+func (r *PermissionProfileRepository) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	query := `DELETE FROM permission_profiles WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type QuorumRepository struct {
+	db *sqlx.DB
+}
+
+func NewQuorumRepository(db *sqlx.DB) *QuorumRepository {
+	return &QuorumRepository{db: db}
+}
+
+func (r *QuorumRepository) GetPolicy(ctx context.Context, userID uuid.UUID) (*model.QuorumPolicy, error) {
+	var policy model.QuorumPolicy
+	query := `
+		SELECT user_id, threshold, window_hours, met_at, created_at, updated_at
+		FROM nominee_quorum_policies
+		WHERE user_id = $1
+	`
+
+	err := r.db.GetContext(ctx, &policy, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// SavePolicy creates or replaces the owner's policy. Approvals collected
+// under the old policy are discarded and the quorum starts over.
+func (r *QuorumRepository) SavePolicy(ctx context.Context, policy *model.QuorumPolicy) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `
+		INSERT INTO nominee_quorum_policies (user_id, threshold, window_hours, met_at, created_at, updated_at)
+		VALUES ($1, $2, $3, NULL, $4, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			threshold = EXCLUDED.threshold,
+			window_hours = EXCLUDED.window_hours,
+			met_at = NULL,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := tx.ExecContext(ctx, query, policy.UserID, policy.Threshold, policy.WindowHours, now); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM nominee_quorum_approvals WHERE user_id = $1`, policy.UserID); err != nil {
+		return err
+	}
+
+	policy.MetAt = nil
+	policy.UpdatedAt = now
+
+	return tx.Commit()
+}
+
+// DeletePolicy removes the owner's policy and its approvals
+func (r *QuorumRepository) DeletePolicy(ctx context.Context, userID uuid.UUID) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM nominee_quorum_policies WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM nominee_quorum_approvals WHERE user_id = $1`, userID); err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, tx.Commit()
+}
+
+func (r *QuorumRepository) AddApproval(ctx context.Context, userID, nomineeID uuid.UUID, ipAddress string) error {
+	query := `
+		INSERT INTO nominee_quorum_approvals (id, user_id, nominee_id, approved_at, ip_address)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, uuid.New(), userID, nomineeID, time.Now(), ipAddress)
+	return err
+}
+
+// ListApprovals returns the latest approval of each nominee, who hasn't
+// been revoked since, made after the given time
+func (r *QuorumRepository) ListApprovals(ctx context.Context, userID uuid.UUID, since time.Time) ([]model.QuorumApproval, error) {
+	approvals := make([]model.QuorumApproval, 0)
+	query := `
+		SELECT a.nominee_id, n.name AS nominee_name, MAX(a.approved_at) AS approved_at
+		FROM nominee_quorum_approvals a
+		JOIN nominees n ON n.id = a.nominee_id
+		WHERE a.user_id = $1 AND a.approved_at >= $2 AND n.status <> 'Revoked'
+		GROUP BY a.nominee_id, n.name
+		ORDER BY approved_at
+	`
+
+	err := r.db.SelectContext(ctx, &approvals, query, userID, since)
+	return approvals, err
+}
+
+// MarkMet records when the quorum was reached. It reports false if it
+// already had been.
+func (r *QuorumRepository) MarkMet(ctx context.Context, userID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE nominee_quorum_policies SET met_at = $1, updated_at = $1
+		WHERE user_id = $2 AND met_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// CountEligibleNominees counts the owner's nominees who haven't been revoked
+// or declined their invitation
+func (r *QuorumRepository) CountEligibleNominees(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM nominees WHERE user_id = $1 AND status NOT IN ('Revoked', 'Declined')`
+
+	err := r.db.GetContext(ctx, &count, query, userID)
+	return count, err
+}
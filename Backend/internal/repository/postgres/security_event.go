@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type SecurityEventRepository struct {
+	db *sqlx.DB
+}
+
+func NewSecurityEventRepository(db *sqlx.DB) *SecurityEventRepository {
+	return &SecurityEventRepository{db: db}
+}
+
+func (r *SecurityEventRepository) Create(ctx context.Context, event *model.SecurityEvent) error {
+	query := `
+		INSERT INTO security_events (
+			id, event_type, user_id, ip_address, details, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		)
+	`
+
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		event.ID,
+		event.EventType,
+		event.UserID,
+		event.IPAddress,
+		event.Details,
+		event.CreatedAt,
+	)
+
+	return err
+}
@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+// ErrSessionNotRotatable is returned when a refresh token was already rotated or revoked
+var ErrSessionNotRotatable = errors.New("session already rotated or revoked")
+
+type SessionRepository struct {
+	db *sqlx.DB
+}
+
+func NewSessionRepository(db *sqlx.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session *model.Session) error {
+	query := `
+		INSERT INTO sessions (
+			id, family_id, user_id, ip_address, user_agent,
+			authenticated_at, created_at, expires_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+	`
+
+	session.CreatedAt = time.Now()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		session.ID,
+		session.FamilyID,
+		session.UserID,
+		session.IPAddress,
+		session.UserAgent,
+		session.AuthenticatedAt,
+		session.CreatedAt,
+		session.ExpiresAt,
+	)
+
+	return err
+}
+
+func (r *SessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Session, error) {
+	var session model.Session
+	query := `
+		SELECT id, family_id, user_id, ip_address, user_agent,
+			authenticated_at, created_at, expires_at, rotated_at, revoked_at
+		FROM sessions
+		WHERE id = $1
+	`
+
+	err := r.db.GetContext(ctx, &session, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// Rotate marks the old session as rotated and inserts its successor in one
+// transaction. Two concurrent refreshes with the same token can't both win.
+func (r *SessionRepository) Rotate(ctx context.Context, oldID uuid.UUID, next *model.Session) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE sessions SET
+			rotated_at = $1
+		WHERE id = $2 AND rotated_at IS NULL AND revoked_at IS NULL
+	`, now, oldID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrSessionNotRotatable
+	}
+
+	next.CreatedAt = now
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO sessions (
+			id, family_id, user_id, ip_address, user_agent,
+			authenticated_at, created_at, expires_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+	`,
+		next.ID,
+		next.FamilyID,
+		next.UserID,
+		next.IPAddress,
+		next.UserAgent,
+		next.AuthenticatedAt,
+		next.CreatedAt,
+		next.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListActiveByUserID returns the live head of every session family, i.e. one row per signed-in device
+func (r *SessionRepository) ListActiveByUserID(ctx context.Context, userID uuid.UUID) ([]model.Session, error) {
+	var sessions []model.Session
+	query := `
+		SELECT id, family_id, user_id, ip_address, user_agent,
+			authenticated_at, created_at, expires_at, rotated_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1 AND rotated_at IS NULL AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY created_at DESC
+	`
+
+	err := r.db.SelectContext(ctx, &sessions, query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeFamily revokes every token of a session family
+func (r *SessionRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	query := `
+		UPDATE sessions SET
+			revoked_at = $1
+		WHERE family_id = $2 AND revoked_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), familyID)
+	return err
+}
+
+// RevokeFamilyForUser revokes a family only if it belongs to the user, and reports whether it did
+func (r *SessionRepository) RevokeFamilyForUser(ctx context.Context, userID, familyID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE sessions SET
+			revoked_at = $1
+		WHERE user_id = $2 AND family_id = $3 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), userID, familyID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+// RevokeAllForUser revokes all of a user's sessions, optionally keeping one family
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID, keepFamilyID *uuid.UUID) error {
+	query := `
+		UPDATE sessions SET
+			revoked_at = $1
+		WHERE user_id = $2 AND revoked_at IS NULL AND ($3::uuid IS NULL OR family_id <> $3)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), userID, keepFamilyID)
+	return err
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type TwoFactorRepository struct {
+	db *sqlx.DB
+}
+
+func NewTwoFactorRepository(db *sqlx.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// SaveTOTP stores a new, unconfirmed TOTP secret, replacing any previous enrollment
+func (r *TwoFactorRepository) SaveTOTP(ctx context.Context, userID uuid.UUID, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret, confirmed_at, last_used_step, created_at)
+		VALUES ($1, $2, NULL, 0, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			confirmed_at = NULL,
+			last_used_step = 0,
+			created_at = EXCLUDED.created_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, secret, time.Now())
+	return err
+}
+
+func (r *TwoFactorRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*model.UserTOTP, error) {
+	var totp model.UserTOTP
+	query := `
+		SELECT user_id, secret, confirmed_at, last_used_step, created_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	err := r.db.GetContext(ctx, &totp, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &totp, nil
+}
+
+func (r *TwoFactorRepository) ConfirmTOTP(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE user_totp SET
+			confirmed_at = $1
+		WHERE user_id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), userID)
+	return err
+}
+
+// MarkStepUsed records the accepted time step. It returns false when the step
+// (or a later one) was already used, which means the code is being replayed.
+func (r *TwoFactorRepository) MarkStepUsed(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query := `
+		UPDATE user_totp SET
+			last_used_step = $1
+		WHERE user_id = $2 AND last_used_step < $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *TwoFactorRepository) DeleteTOTP(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM user_totp WHERE user_id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+// ReplaceRecoveryCodes discards existing recovery codes and stores the new hashes
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	insertQuery := `
+		INSERT INTO user_recovery_codes (id, user_id, code_hash, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	now := time.Now()
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, insertQuery, uuid.New(), userID, hash, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ConsumeRecoveryCode marks a matching unused recovery code as used
+func (r *TwoFactorRepository) ConsumeRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes SET
+			used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+func (r *TwoFactorRepository) CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+
+	err := r.db.GetContext(ctx, &count, query, userID)
+	return count, err
+}
+
+func (r *TwoFactorRepository) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM user_recovery_codes WHERE user_id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
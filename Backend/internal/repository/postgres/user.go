@@ -136,3 +136,39 @@ func (r *UserRepository) UpdateLastLogin(ctx context.Context, id uuid.UUID) erro
 
 	return err
 }
+
+func (r *UserRepository) UpdateTwoFactorEnabled(ctx context.Context, id uuid.UUID, enabled bool) error {
+	query := `
+		UPDATE users SET
+			two_factor_enabled = $1,
+			updated_at = $2
+		WHERE id = $3
+	`
+
+	_, err := r.db.ExecContext(ctx, query, enabled, time.Now(), id)
+	return err
+}
+
+// MarkEmailVerified flags the user's email as verified, provided it is still
+// the address the verification was sent to. It reports whether a row changed.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID, email string) (bool, error) {
+	query := `
+		UPDATE users SET
+			email_verified = TRUE,
+			email_verified_at = $1,
+			updated_at = $1
+		WHERE id = $2 AND email = $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id, email)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
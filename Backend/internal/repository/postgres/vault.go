@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+// A submitted vault share only counts while its nominee has emergency
+// access. These forget submitted shares when that access ends, from within
+// the statements or transactions that end it.
+const (
+	clearNomineeSubmittedShares = `
+		UPDATE vault_shares SET submitted_share = NULL, submitted_at = NULL
+		WHERE nominee_id = $1 AND submitted_at IS NOT NULL
+	`
+	clearOwnerSubmittedShares = `
+		UPDATE vault_shares SET submitted_share = NULL, submitted_at = NULL
+		WHERE vault_id IN (SELECT id FROM vaults WHERE user_id = $1) AND submitted_at IS NOT NULL
+	`
+)
+
+type VaultRepository struct {
+	db *sqlx.DB
+}
+
+func NewVaultRepository(db *sqlx.DB) *VaultRepository {
+	return &VaultRepository{db: db}
+}
+
+func (r *VaultRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*model.Vault, error) {
+	var vault model.Vault
+	query := `
+		SELECT id, user_id, public_key, threshold, created_at
+		FROM vaults
+		WHERE user_id = $1
+	`
+
+	if err := r.db.GetContext(ctx, &vault, query, userID); err != nil {
+		return nil, err
+	}
+
+	return &vault, nil
+}
+
+// Replace swaps the owner's vault for a new one with its shares, and points
+// the recovery copies of their document keys at it. Shares of the old vault,
+// including any submitted, are discarded.
+func (r *VaultRepository) Replace(ctx context.Context, vault *model.Vault, shares []model.VaultShare, keys []model.DocumentKey) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM vaults WHERE user_id = $1`, vault.UserID); err != nil {
+		return err
+	}
+
+	vault.CreatedAt = time.Now()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO vaults (id, user_id, public_key, threshold, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, vault.ID, vault.UserID, vault.PublicKey, vault.Threshold, vault.CreatedAt); err != nil {
+		return err
+	}
+
+	for _, share := range shares {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO vault_shares (vault_id, nominee_id, share_index, share_hash, sealed_share)
+			VALUES ($1, $2, $3, $4, $5)
+		`, vault.ID, share.NomineeID, share.ShareIndex, share.ShareHash, share.SealedShare); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range keys {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE document_keys SET recovery_wrapped = $1, vault_id = $2
+			WHERE document_id = $3 AND user_id = $4
+		`, key.RecoveryWrapped, vault.ID, key.DocumentID, vault.UserID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListShares returns the vault's shares with their nominees, by share index
+func (r *VaultRepository) ListShares(ctx context.Context, vaultID uuid.UUID) ([]model.VaultShare, error) {
+	shares := make([]model.VaultShare, 0)
+	query := `
+		SELECT s.vault_id, s.nominee_id, s.share_index, s.share_hash, s.sealed_share,
+			s.delivered_at, s.submitted_share, s.submitted_at,
+			n.name AS nominee_name, n.status AS nominee_status
+		FROM vault_shares s
+		JOIN nominees n ON n.id = s.nominee_id
+		WHERE s.vault_id = $1
+		ORDER BY s.share_index
+	`
+
+	if err := r.db.SelectContext(ctx, &shares, query, vaultID); err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+func (r *VaultRepository) GetShare(ctx context.Context, vaultID, nomineeID uuid.UUID) (*model.VaultShare, error) {
+	var share model.VaultShare
+	query := `
+		SELECT s.vault_id, s.nominee_id, s.share_index, s.share_hash, s.sealed_share,
+			s.delivered_at, s.submitted_share, s.submitted_at,
+			n.name AS nominee_name, n.status AS nominee_status
+		FROM vault_shares s
+		JOIN nominees n ON n.id = s.nominee_id
+		WHERE s.vault_id = $1 AND s.nominee_id = $2
+	`
+
+	if err := r.db.GetContext(ctx, &share, query, vaultID, nomineeID); err != nil {
+		return nil, err
+	}
+
+	return &share, nil
+}
+
+// MarkDelivered records that the nominee has been sent their share and
+// forgets the server's copy of it
+func (r *VaultRepository) MarkDelivered(ctx context.Context, vaultID, nomineeID uuid.UUID) error {
+	query := `
+		UPDATE vault_shares SET delivered_at = $1, sealed_share = NULL
+		WHERE vault_id = $2 AND nominee_id = $3 AND delivered_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), vaultID, nomineeID)
+	return err
+}
+
+// SubmitShare keeps the share a nominee handed in during emergency access
+func (r *VaultRepository) SubmitShare(ctx context.Context, vaultID, nomineeID uuid.UUID, sealedShare []byte) error {
+	query := `
+		UPDATE vault_shares SET submitted_share = $1, submitted_at = $2
+		WHERE vault_id = $3 AND nominee_id = $4
+	`
+
+	_, err := r.db.ExecContext(ctx, query, sealedShare, time.Now(), vaultID, nomineeID)
+	return err
+}
+
+func (r *VaultRepository) CreateDocumentKey(ctx context.Context, key *model.DocumentKey) error {
+	query := `
+		INSERT INTO document_keys (document_id, user_id, owner_wrapped, recovery_wrapped, vault_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	key.CreatedAt = time.Now()
+	_, err := r.db.ExecContext(ctx, query, key.DocumentID, key.UserID, key.OwnerWrapped, key.RecoveryWrapped, key.VaultID, key.CreatedAt)
+	return err
+}
+
+func (r *VaultRepository) GetDocumentKey(ctx context.Context, documentID uuid.UUID) (*model.DocumentKey, error) {
+	var key model.DocumentKey
+	query := `
+		SELECT document_id, user_id, owner_wrapped, recovery_wrapped, vault_id, created_at
+		FROM document_keys
+		WHERE document_id = $1
+	`
+
+	if err := r.db.GetContext(ctx, &key, query, documentID); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *VaultRepository) ListDocumentKeys(ctx context.Context, userID uuid.UUID) ([]model.DocumentKey, error) {
+	keys := make([]model.DocumentKey, 0)
+	query := `
+		SELECT document_id, user_id, owner_wrapped, recovery_wrapped, vault_id, created_at
+		FROM document_keys
+		WHERE user_id = $1
+	`
+
+	if err := r.db.SelectContext(ctx, &keys, query, userID); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sampatti/internal/model"
+)
+
+type WebAuthnRepository struct {
+	db *sqlx.DB
+}
+
+func NewWebAuthnRepository(db *sqlx.DB) *WebAuthnRepository {
+	return &WebAuthnRepository{db: db}
+}
+
+const webauthnCredentialColumns = `
+	id, user_id, credential_id, public_key, algorithm, sign_count,
+	aaguid, transports, name, created_at, last_used_at
+`
+
+func (r *WebAuthnRepository) CreateCredential(ctx context.Context, credential *model.WebAuthnCredential) error {
+	query := `
+		INSERT INTO webauthn_credentials (
+			id, user_id, credential_id, public_key, algorithm, sign_count,
+			aaguid, transports, name, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+		)
+	`
+
+	credential.ID = uuid.New()
+	credential.CreatedAt = time.Now()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		credential.ID,
+		credential.UserID,
+		credential.CredentialID,
+		credential.PublicKey,
+		credential.Algorithm,
+		credential.SignCount,
+		credential.AAGUID,
+		credential.Transports,
+		credential.Name,
+		credential.CreatedAt,
+	)
+
+	return err
+}
+
+func (r *WebAuthnRepository) GetByCredentialID(ctx context.Context, credentialID []byte) (*model.WebAuthnCredential, error) {
+	var credential model.WebAuthnCredential
+	query := `SELECT ` + webauthnCredentialColumns + ` FROM webauthn_credentials WHERE credential_id = $1`
+
+	err := r.db.GetContext(ctx, &credential, query, credentialID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+func (r *WebAuthnRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]model.WebAuthnCredential, error) {
+	credentials := make([]model.WebAuthnCredential, 0)
+	query := `
+		SELECT ` + webauthnCredentialColumns + `
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	err := r.db.SelectContext(ctx, &credentials, query, userID)
+	return credentials, err
+}
+
+func (r *WebAuthnRepository) CountByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = $1`
+
+	err := r.db.GetContext(ctx, &count, query, userID)
+	return count, err
+}
+
+// RecordUse stores the new signature counter. It returns false when the
+// counter didn't move forward, which suggests a cloned authenticator.
+// Authenticators that don't implement counters always report zero; the
+// service requires user verification from those instead.
+func (r *WebAuthnRepository) RecordUse(ctx context.Context, id uuid.UUID, signCount int64) (bool, error) {
+	query := `
+		UPDATE webauthn_credentials SET
+			sign_count = $1,
+			last_used_at = $2
+		WHERE id = $3 AND (sign_count < $1 OR (sign_count = 0 AND $1 = 0))
+	`
+
+	result, err := r.db.ExecContext(ctx, query, signCount, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// DeleteCredential removes a user's credential and reports whether it existed
+func (r *WebAuthnRepository) DeleteCredential(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	query := `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *WebAuthnRepository) CreateChallenge(ctx context.Context, challenge *model.WebAuthnChallenge) error {
+	query := `
+		INSERT INTO webauthn_challenges (
+			id, user_id, purpose, challenge, expires_at, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		)
+	`
+
+	challenge.ID = uuid.New()
+	challenge.CreatedAt = time.Now()
+
+	// Expired challenges are never consumed, so clear them out as new ones are issued
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM webauthn_challenges WHERE expires_at < $1`, challenge.CreatedAt); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		challenge.ID,
+		challenge.UserID,
+		challenge.Purpose,
+		challenge.Challenge,
+		challenge.ExpiresAt,
+		challenge.CreatedAt,
+	)
+
+	return err
+}
+
+// ConsumeChallenge deletes an unexpired challenge issued for purpose and returns it
+func (r *WebAuthnRepository) ConsumeChallenge(ctx context.Context, id uuid.UUID, purpose string) (*model.WebAuthnChallenge, error) {
+	var challenge model.WebAuthnChallenge
+	query := `
+		DELETE FROM webauthn_challenges
+		WHERE id = $1 AND purpose = $2 AND expires_at > $3
+		RETURNING id, user_id, purpose, challenge, expires_at, created_at
+	`
+
+	err := r.db.GetContext(ctx, &challenge, query, id, purpose, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
@@ -20,14 +20,20 @@ var (
 
 type AssetService struct {
 	assetRepo *postgres.AssetRepository
+	audit     *AuditService
 }
 
-func NewAssetService(assetRepo *postgres.AssetRepository) *AssetService {
-	return &AssetService{assetRepo: assetRepo}
+func NewAssetService(assetRepo *postgres.AssetRepository, audit *AuditService) *AssetService {
+	return &AssetService{assetRepo: assetRepo, audit: audit}
 }
 
 func (s *AssetService) Create(ctx context.Context, asset *model.Asset) error {
-	return s.assetRepo.Create(ctx, asset)
+	if err := s.assetRepo.Create(ctx, asset); err != nil {
+		return err
+	}
+
+	s.audit.RecordUserAction(ctx, asset.UserID, "asset.created", "asset", asset.ID, asset.AssetName)
+	return nil
 }
 
 func (s *AssetService) GetByID(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*model.Asset, error) {
@@ -63,7 +69,12 @@ func (s *AssetService) Update(ctx context.Context, asset *model.Asset, userID uu
 
 	asset.UserID = existingAsset.UserID
 
-	return s.assetRepo.Update(ctx, asset)
+	if err := s.assetRepo.Update(ctx, asset); err != nil {
+		return err
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "asset.updated", "asset", asset.ID, asset.AssetName)
+	return nil
 }
 
 func (s *AssetService) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
@@ -76,7 +87,12 @@ func (s *AssetService) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUI
 		return ErrUnauthorized
 	}
 
-	return s.assetRepo.Delete(ctx, id)
+	if err := s.assetRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "asset.deleted", "asset", id, existingAsset.AssetName)
+	return nil
 }
 
 func (s *AssetService) UpdateValue(ctx context.Context, id uuid.UUID, userID uuid.UUID, value float64, notes string) error {
@@ -89,7 +105,12 @@ func (s *AssetService) UpdateValue(ctx context.Context, id uuid.UUID, userID uui
 		return ErrUnauthorized
 	}
 
-	return s.assetRepo.UpdateValue(ctx, id, value, notes)
+	if err := s.assetRepo.UpdateValue(ctx, id, value, notes); err != nil {
+		return err
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "asset.value_updated", "asset", id, fmt.Sprintf("%s: %.2f to %.2f", existingAsset.AssetName, existingAsset.CurrentValue, value))
+	return nil
 }
 
 func (s *AssetService) GetHistory(ctx context.Context, assetID uuid.UUID, userID uuid.UUID) ([]model.AssetHistory, error) {
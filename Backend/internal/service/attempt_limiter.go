@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+)
+
+var ErrTooManyAttempts = errors.New("too many failed attempts, try again later")
+
+// AttemptLimitError is returned while a key is in backoff or locked out.
+// It matches ErrTooManyAttempts with errors.Is.
+type AttemptLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AttemptLimitError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *AttemptLimitError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// AttemptStore persists failed attempt counters
+type AttemptStore interface {
+	// Get returns the attempt state for key, or nil when there is none
+	Get(ctx context.Context, key string) (*model.AuthAttempt, error)
+	// RecordFailure increments the failure count for key, starting over when
+	// the last failure is older than window and key isn't blocked
+	RecordFailure(ctx context.Context, key string, window time.Duration) (*model.AuthAttempt, error)
+	// Block prevents attempts for key until the given time
+	Block(ctx context.Context, key string, until time.Time) error
+	// Reset forgets all failures for key
+	Reset(ctx context.Context, key string) error
+}
+
+// AttemptScope identifies a credential check being rate limited
+type AttemptScope struct {
+	// Action names the endpoint, e.g. "login" or "emergency_access"
+	Action string
+	// Account is what the caller is guessing at, such as an email address
+	Account string
+	// IPAddress is the caller's address
+	IPAddress string
+	// UserID is the account owner affected, when known, for security events
+	UserID *uuid.UUID
+}
+
+func (s AttemptScope) accountKey() string {
+	return fmt.Sprintf("%s:account:%s", s.Action, strings.ToLower(strings.TrimSpace(s.Account)))
+}
+
+func (s AttemptScope) ipKey() string {
+	return fmt.Sprintf("%s:ip:%s", s.Action, s.IPAddress)
+}
+
+// AttemptResult describes an account after a failed attempt
+type AttemptResult struct {
+	Failures int
+	// Escalated is set on the failure that started backoff and on the one
+	// that caused a lockout, so callers can notify without repeating themselves
+	Escalated bool
+	LockedOut bool
+}
+
+// AttemptLimiter applies exponential backoff and temporary lockouts to
+// credential checks, tracked per account and per client IP
+type AttemptLimiter struct {
+	store     AttemptStore
+	eventRepo *postgres.SecurityEventRepository
+	cfg       *config.SecurityConfig
+}
+
+func NewAttemptLimiter(store AttemptStore, eventRepo *postgres.SecurityEventRepository, cfg *config.SecurityConfig) *AttemptLimiter {
+	return &AttemptLimiter{
+		store:     store,
+		eventRepo: eventRepo,
+		cfg:       cfg,
+	}
+}
+
+// Check returns an *AttemptLimitError if the account or IP is currently blocked
+func (l *AttemptLimiter) Check(ctx context.Context, scope AttemptScope) error {
+	now := time.Now()
+	var retryAfter time.Duration
+
+	for _, key := range []string{scope.accountKey(), scope.ipKey()} {
+		attempt, err := l.store.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to check attempts: %w", err)
+		}
+
+		if attempt != nil && attempt.BlockedUntil != nil && attempt.BlockedUntil.After(now) {
+			if wait := attempt.BlockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+
+	if retryAfter > 0 {
+		return &AttemptLimitError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed attempt against the account and the IP and
+// blocks further attempts once the free attempts are used up
+func (l *AttemptLimiter) RecordFailure(ctx context.Context, scope AttemptScope) (*AttemptResult, error) {
+	failures, locked, err := l.recordKey(ctx, scope, scope.accountKey(), l.cfg.FreeAttempts, l.cfg.LockoutThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := l.recordKey(ctx, scope, scope.ipKey(), l.cfg.IPFreeAttempts, l.cfg.IPLockoutThreshold); err != nil {
+		return nil, err
+	}
+
+	return &AttemptResult{
+		Failures:  failures,
+		Escalated: failures == l.cfg.FreeAttempts+1 || failures == l.cfg.LockoutThreshold,
+		LockedOut: locked,
+	}, nil
+}
+
+// RecordSuccess clears the account's failures. The IP counter is left alone
+// so one valid account can't be used to reset guessing at others.
+func (l *AttemptLimiter) RecordSuccess(ctx context.Context, scope AttemptScope) error {
+	return l.store.Reset(ctx, scope.accountKey())
+}
+
+// Private methods
+
+func (l *AttemptLimiter) recordKey(ctx context.Context, scope AttemptScope, key string, free, threshold int) (int, bool, error) {
+	attempt, err := l.store.RecordFailure(ctx, key, l.cfg.AttemptWindow)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to record attempt: %w", err)
+	}
+
+	delay, locked := l.penalty(attempt.Failures, free, threshold)
+	if delay > 0 {
+		if err := l.store.Block(ctx, key, time.Now().Add(delay)); err != nil {
+			return 0, false, fmt.Errorf("failed to block attempts: %w", err)
+		}
+	}
+
+	if locked && attempt.Failures == threshold {
+		l.logLockout(ctx, scope, key, attempt.Failures)
+	}
+
+	return attempt.Failures, locked, nil
+}
+
+// penalty returns how long to block after the given number of failures, and
+// whether that block is a lockout rather than backoff
+func (l *AttemptLimiter) penalty(failures, free, threshold int) (time.Duration, bool) {
+	if failures >= threshold {
+		return l.cfg.LockoutDuration, true
+	}
+
+	if failures <= free {
+		return 0, false
+	}
+
+	delay := l.cfg.BaseBackoff
+	for i := free + 1; i < failures && delay < l.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > l.cfg.MaxBackoff {
+		delay = l.cfg.MaxBackoff
+	}
+
+	return delay, false
+}
+
+func (l *AttemptLimiter) logLockout(ctx context.Context, scope AttemptScope, key string, failures int) {
+	details := fmt.Sprintf("%s locked out for %s after %d failed attempts", key, l.cfg.LockoutDuration, failures)
+	log.Printf("Security event: %s (ip %s)", details, scope.IPAddress)
+
+	event := &model.SecurityEvent{
+		EventType: "Lockout",
+		UserID:    scope.UserID,
+		IPAddress: scope.IPAddress,
+		Details:   details,
+	}
+
+	if err := l.eventRepo.Create(ctx, event); err != nil {
+		log.Printf("Failed to record security event: %v", err)
+	}
+}
+
+// MemoryAttemptStore keeps attempt counters in process memory. It is meant
+// for single-node deployments and development; counters reset on restart.
+type MemoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*model.AuthAttempt
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{attempts: make(map[string]*model.AuthAttempt)}
+}
+
+func (m *MemoryAttemptStore) Get(ctx context.Context, key string) (*model.AuthAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt, ok := m.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *attempt
+	return &copied, nil
+}
+
+func (m *MemoryAttemptStore) RecordFailure(ctx context.Context, key string, window time.Duration) (*model.AuthAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.prune(now, window)
+
+	attempt, ok := m.attempts[key]
+	if !ok || isStale(attempt, now, window) {
+		attempt = &model.AuthAttempt{Key: key}
+		m.attempts[key] = attempt
+	}
+
+	attempt.Failures++
+	attempt.LastFailedAt = now
+
+	copied := *attempt
+	return &copied, nil
+}
+
+func (m *MemoryAttemptStore) Block(ctx context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if attempt, ok := m.attempts[key]; ok {
+		attempt.BlockedUntil = &until
+	}
+	return nil
+}
+
+func (m *MemoryAttemptStore) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
+
+// prune drops counters that would start over anyway, so memory stays bounded
+func (m *MemoryAttemptStore) prune(now time.Time, window time.Duration) {
+	for key, attempt := range m.attempts {
+		if isStale(attempt, now, window) {
+			delete(m.attempts, key)
+		}
+	}
+}
+
+func isStale(attempt *model.AuthAttempt, now time.Time, window time.Duration) bool {
+	if attempt.BlockedUntil != nil && attempt.BlockedUntil.After(now) {
+		return false
+	}
+	return attempt.LastFailedAt.Before(now.Add(-window))
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+)
+
+// Who performed an audited action
+const (
+	AuditActorUser    = "user"
+	AuditActorNominee = "nominee"
+	AuditActorSystem  = "system"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+	auditVerifyBatchSize = 500
+)
+
+// AuditEvent describes an action to record in the owner's audit trail
+type AuditEvent struct {
+	OwnerID    uuid.UUID
+	ActorType  string
+	ActorID    *uuid.UUID
+	Action     string
+	EntityType string
+	EntityID   *uuid.UUID
+	Summary    string
+	IPAddress  string
+}
+
+// AuditVerification is the result of checking one owner's audit chain
+type AuditVerification struct {
+	OwnerID         uuid.UUID `json:"owner_id"`
+	Valid           bool      `json:"valid"`
+	Entries         int64     `json:"entries"`
+	FirstInvalidSeq int64     `json:"first_invalid_seq,omitempty"`
+	Problem         string    `json:"problem,omitempty"`
+}
+
+// auditHashInput fixes the fields, and their order, that an entry's hash covers
+type auditHashInput struct {
+	OwnerID    string `json:"owner_id"`
+	Seq        int64  `json:"seq"`
+	OccurredAt string `json:"occurred_at"`
+	ActorType  string `json:"actor_type"`
+	ActorID    string `json:"actor_id"`
+	Action     string `json:"action"`
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	Summary    string `json:"summary"`
+	IPAddress  string `json:"ip_address"`
+	PrevHash   string `json:"prev_hash"`
+}
+
+// AuditService keeps an append-only, hash-chained trail of changes and
+// access for each owner. Every entry includes the hash of the one before
+// it, so an edited, removed or reordered entry breaks the chain.
+type AuditService struct {
+	auditRepo *postgres.AuditRepository
+	failures  atomic.Int64
+}
+
+func NewAuditService(auditRepo *postgres.AuditRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// Record appends an event to the owner's chain. Callers usually go on after
+// a failure so auditing never undoes the action it describes; failures are
+// logged and counted in Failures so gaps in the trail are visible.
+func (s *AuditService) Record(ctx context.Context, event AuditEvent) error {
+	entry := &model.AuditEntry{
+		OwnerID:    event.OwnerID,
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+		ActorType:  event.ActorType,
+		ActorID:    event.ActorID,
+		Action:     event.Action,
+		EntityType: event.EntityType,
+		EntityID:   event.EntityID,
+		Summary:    event.Summary,
+		IPAddress:  event.IPAddress,
+	}
+
+	if err := s.auditRepo.Append(ctx, entry, hashAuditEntry); err != nil {
+		s.failures.Add(1)
+		log.Printf("Failed to record audit event %s for owner %s: %v", event.Action, event.OwnerID, err)
+		return err
+	}
+
+	return nil
+}
+
+// Failures is how many events could not be recorded since the server started
+func (s *AuditService) Failures() int64 {
+	return s.failures.Load()
+}
+
+// List returns a page of the owner's audit trail, newest first, starting
+// before the given sequence number (0 for the latest)
+func (s *AuditService) List(ctx context.Context, ownerID uuid.UUID, beforeSeq int64, limit int) ([]model.AuditEntry, error) {
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+	if limit > maxAuditPageSize {
+		limit = maxAuditPageSize
+	}
+
+	return s.auditRepo.ListByOwner(ctx, ownerID, beforeSeq, limit)
+}
+
+// Verify walks the owner's chain from the start, recomputing each hash and
+// checking it links to the previous entry and ends at the recorded head
+func (s *AuditService) Verify(ctx context.Context, ownerID uuid.UUID) (*AuditVerification, error) {
+	result := &AuditVerification{OwnerID: ownerID, Valid: true}
+
+	headSeq, headHash, err := s.auditRepo.GetHead(ctx, ownerID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	var seq int64
+	prevHash := ""
+	for {
+		entries, err := s.auditRepo.ListChain(ctx, ownerID, seq, auditVerifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+			switch {
+			case entry.Seq != seq+1:
+				return result.fail(seq+1, fmt.Sprintf("entries %d to %d are missing", seq+1, entry.Seq-1)), nil
+			case entry.PrevHash != prevHash:
+				return result.fail(entry.Seq, "entry does not link to the previous entry"), nil
+			case hashAuditEntry(entry) != entry.Hash:
+				return result.fail(entry.Seq, "entry has been modified"), nil
+			}
+
+			seq = entry.Seq
+			prevHash = entry.Hash
+			result.Entries++
+		}
+
+		if len(entries) < auditVerifyBatchSize {
+			break
+		}
+	}
+
+	switch {
+	case headSeq > seq:
+		return result.fail(seq+1, fmt.Sprintf("entries %d to %d are missing", seq+1, headSeq)), nil
+	case headSeq < seq:
+		return result.fail(headSeq+1, "chain head is behind the latest entry"), nil
+	case headHash != prevHash:
+		return result.fail(seq, "latest entry does not match the recorded chain head"), nil
+	}
+
+	return result, nil
+}
+
+// VerifyAll checks the chain of every owner with an audit trail
+func (s *AuditService) VerifyAll(ctx context.Context) ([]AuditVerification, error) {
+	owners, err := s.auditRepo.ListOwners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]AuditVerification, 0, len(owners))
+	for _, ownerID := range owners {
+		result, err := s.Verify(ctx, ownerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify audit trail of %s: %w", ownerID, err)
+		}
+		results = append(results, *result)
+	}
+
+	return results, nil
+}
+
+// RecordUserAction records a change the owner made to their own data
+func (s *AuditService) RecordUserAction(ctx context.Context, userID uuid.UUID, action, entityType string, entityID uuid.UUID, summary string) error {
+	return s.Record(ctx, AuditEvent{
+		OwnerID:    userID,
+		ActorType:  AuditActorUser,
+		ActorID:    &userID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   &entityID,
+		Summary:    summary,
+	})
+}
+
+// Private methods
+
+func (v *AuditVerification) fail(seq int64, problem string) *AuditVerification {
+	v.Valid = false
+	v.FirstInvalidSeq = seq
+	v.Problem = problem
+	return v
+}
+
+// hashAuditEntry returns the hex SHA-256 of everything in the entry but its own hash
+func hashAuditEntry(entry *model.AuditEntry) string {
+	input := auditHashInput{
+		OwnerID:    entry.OwnerID.String(),
+		Seq:        entry.Seq,
+		OccurredAt: entry.OccurredAt.UTC().Format(time.RFC3339Nano),
+		ActorType:  entry.ActorType,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		Summary:    entry.Summary,
+		IPAddress:  entry.IPAddress,
+		PrevHash:   entry.PrevHash,
+	}
+	if entry.ActorID != nil {
+		input.ActorID = entry.ActorID.String()
+	}
+	if entry.EntityID != nil {
+		input.EntityID = entry.EntityID.String()
+	}
+
+	data, _ := json.Marshal(input)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -2,11 +2,14 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"log"
+	"net/url"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/sampatti/internal/config"
 	"github.com/sampatti/internal/model"
@@ -19,26 +22,61 @@ var (
 	ErrUserAlreadyExists  = errors.New("user already exists")
 	ErrInvalidToken       = errors.New("invalid token")
 	ErrExpiredToken       = errors.New("token expired")
+	ErrInvalidResetToken  = errors.New("invalid or expired reset token")
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+	ErrSessionNotFound    = errors.New("session not found")
+)
+
+const (
+	challengeTokenTTL = 5 * time.Minute
+	refreshReuseGrace = 10 * time.Second
 )
 
 type AuthService struct {
-	userRepo     *postgres.UserRepository
-	nomineeRepo  *postgres.NomineeRepository
-	cfg          *config.JWTConfig
-	passwordUtil *util.PasswordUtil
+	userRepo         *postgres.UserRepository
+	nomineeRepo      *postgres.NomineeRepository
+	resetRepo        *postgres.PasswordResetRepository
+	sessionRepo      *postgres.SessionRepository
+	cfg              *config.JWTConfig
+	appCfg           *config.AppConfig
+	passwordUtil     *util.PasswordUtil
+	jwtUtil          *util.JWTUtil
+	mailer           Mailer
+	twoFactorService *TwoFactorService
+	webauthnService  *WebAuthnService
+	limiter          *AttemptLimiter
+	audit            *AuditService
 }
 
 func NewAuthService(
 	userRepo *postgres.UserRepository,
 	nomineeRepo *postgres.NomineeRepository,
+	resetRepo *postgres.PasswordResetRepository,
+	sessionRepo *postgres.SessionRepository,
 	cfg *config.JWTConfig,
+	appCfg *config.AppConfig,
 	passwordUtil *util.PasswordUtil,
+	jwtUtil *util.JWTUtil,
+	mailer Mailer,
+	twoFactorService *TwoFactorService,
+	webauthnService *WebAuthnService,
+	limiter *AttemptLimiter,
+	audit *AuditService,
 ) *AuthService {
 	return &AuthService{
-		userRepo:     userRepo,
-		nomineeRepo:  nomineeRepo,
-		cfg:          cfg,
-		passwordUtil: passwordUtil,
+		userRepo:         userRepo,
+		nomineeRepo:      nomineeRepo,
+		resetRepo:        resetRepo,
+		sessionRepo:      sessionRepo,
+		cfg:              cfg,
+		appCfg:           appCfg,
+		passwordUtil:     passwordUtil,
+		jwtUtil:          jwtUtil,
+		mailer:           mailer,
+		twoFactorService: twoFactorService,
+		webauthnService:  webauthnService,
+		audit:            audit,
+		limiter:          limiter,
 	}
 }
 
@@ -64,99 +102,291 @@ func (s *AuthService) RegisterUser(ctx context.Context, user *model.User, passwo
 	return s.userRepo.Create(ctx, user)
 }
 
-// Login authenticates a user and returns access and refresh tokens
-func (s *AuthService) Login(ctx context.Context, email, password string) (string, string, error) {
+// ClientInfo describes the device a request came from
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// Second factors a login challenge can be answered with
+const (
+	SecondFactorTOTP     = "totp"
+	SecondFactorWebAuthn = "webauthn"
+)
+
+// LoginResult carries either the issued tokens or, when the account has
+// two-factor authentication enabled, a challenge token for the second step
+// and the second factors the user can answer it with
+type LoginResult struct {
+	AccessToken    string
+	RefreshToken   string
+	ChallengeToken string
+	SecondFactors  []string
+	User           *model.User
+}
+
+// TwoFactorRequired reports whether the login still needs a second factor
+func (r *LoginResult) TwoFactorRequired() bool {
+	return r.ChallengeToken != ""
+}
+
+// Login authenticates a user and returns access and refresh tokens, or a
+// two-factor challenge when the account requires one
+func (s *AuthService) Login(ctx context.Context, email, password string, client ClientInfo) (*LoginResult, error) {
+	scope := AttemptScope{Action: "login", Account: email, IPAddress: client.IPAddress}
+	if err := s.limiter.Check(ctx, scope); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
-		return "", "", ErrInvalidCredentials
+		// Unknown emails count too, so probing for accounts is throttled the same way
+		return nil, s.loginFailed(ctx, scope)
 	}
+	scope.UserID = &user.ID
 
 	// Verify password
 	if !s.passwordUtil.CheckPasswordHash(password, user.PasswordHash) {
-		return "", "", ErrInvalidCredentials
+		return nil, s.loginFailed(ctx, scope)
 	}
 
-	// Update last login time
-	if err := s.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
-		return "", "", fmt.Errorf("failed to update last login: %w", err)
+	result, err := s.FirstFactorPassed(ctx, user, client)
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate tokens
-	accessToken, err := s.generateAccessToken(user.ID)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate access token: %w", err)
+	if !result.TwoFactorRequired() {
+		if err := s.limiter.RecordSuccess(ctx, scope); err != nil {
+			log.Printf("Failed to reset login attempts for %s: %v", user.ID, err)
+		}
 	}
 
-	refreshToken, err := s.generateRefreshToken(user.ID)
+	return result, nil
+}
+
+// FirstFactorPassed continues a login once the user has proven their identity
+// with a password or an external identity provider. It returns a two-factor
+// challenge when the account has a second factor, and tokens otherwise.
+func (s *AuthService) FirstFactorPassed(ctx context.Context, user *model.User, client ClientInfo) (*LoginResult, error) {
+	secondFactors, err := s.secondFactors(ctx, user)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, err
 	}
 
-	return accessToken, refreshToken, nil
+	if len(secondFactors) > 0 {
+		challengeToken, err := s.jwtUtil.GenerateChallengeToken(user.ID, challengeTokenTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate challenge token: %w", err)
+		}
+
+		return &LoginResult{ChallengeToken: challengeToken, SecondFactors: secondFactors, User: user}, nil
+	}
+
+	return s.completeLogin(ctx, user, client)
 }
 
-// RefreshToken generates a new access token from a refresh token
-func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (string, error) {
-	// Parse refresh token
-	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// VerifyLogin completes a two-factor login with the challenge token from Login
+// and a TOTP or recovery code
+func (s *AuthService) VerifyLogin(ctx context.Context, challengeToken, code string, client ClientInfo) (*LoginResult, error) {
+	userID, err := s.parseChallengeToken(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Second factor guesses share the account's login counter
+	scope := AttemptScope{Action: "login", Account: user.Email, IPAddress: client.IPAddress, UserID: &user.ID}
+	if err := s.limiter.Check(ctx, scope); err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorService.VerifyCode(ctx, user.ID, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if _, recordErr := s.limiter.RecordFailure(ctx, scope); recordErr != nil {
+				log.Printf("Failed to record login attempt: %v", recordErr)
+			}
 		}
-		return []byte(s.cfg.RefreshSecret), nil
-	})
+		return nil, err
+	}
+
+	if err := s.limiter.RecordSuccess(ctx, scope); err != nil {
+		log.Printf("Failed to reset login attempts for %s: %v", user.ID, err)
+	}
+
+	return s.completeLogin(ctx, user, client)
+}
 
+// BeginWebAuthnVerification starts a security key assertion that answers the
+// challenge token from Login
+func (s *AuthService) BeginWebAuthnVerification(ctx context.Context, challengeToken string) (uuid.UUID, *CredentialRequestOptions, error) {
+	userID, err := s.parseChallengeToken(challengeToken)
 	if err != nil {
-		return "", ErrInvalidToken
+		return uuid.Nil, nil, err
 	}
 
-	if !token.Valid {
-		return "", ErrInvalidToken
+	return s.webauthnService.BeginLogin(ctx, &userID)
+}
+
+// VerifyLoginWithWebAuthn completes a two-factor login with a security key or passkey
+func (s *AuthService) VerifyLoginWithWebAuthn(ctx context.Context, challengeToken string, ceremonyID uuid.UUID, response *AssertionResponse, client ClientInfo) (*LoginResult, error) {
+	userID, err := s.parseChallengeToken(challengeToken)
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract claims
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", ErrInvalidToken
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrInvalidToken
 	}
 
-	// Check if token is expired
-	expiration, ok := claims["exp"].(float64)
-	if !ok {
-		return "", ErrInvalidToken
+	scope := AttemptScope{Action: "login", Account: user.Email, IPAddress: client.IPAddress, UserID: &user.ID}
+	if err := s.limiter.Check(ctx, scope); err != nil {
+		return nil, err
 	}
 
-	if time.Unix(int64(expiration), 0).Before(time.Now()) {
-		return "", ErrExpiredToken
+	if _, err := s.webauthnService.FinishLogin(ctx, &userID, ceremonyID, response); err != nil {
+		if errors.Is(err, ErrWebAuthnVerification) {
+			if _, recordErr := s.limiter.RecordFailure(ctx, scope); recordErr != nil {
+				log.Printf("Failed to record login attempt: %v", recordErr)
+			}
+		}
+		return nil, err
 	}
 
-	// Extract user ID
-	userIDStr, ok := claims["sub"].(string)
-	if !ok {
-		return "", ErrInvalidToken
+	if err := s.limiter.RecordSuccess(ctx, scope); err != nil {
+		log.Printf("Failed to reset login attempts for %s: %v", user.ID, err)
 	}
 
-	userID, err := uuid.Parse(userIDStr)
+	return s.completeLogin(ctx, user, client)
+}
+
+// LoginWithPasskey signs a user in with a discoverable credential and no password.
+// The authenticator must have verified the user itself, so no second factor is asked for.
+func (s *AuthService) LoginWithPasskey(ctx context.Context, ceremonyID uuid.UUID, response *AssertionResponse, client ClientInfo) (*LoginResult, error) {
+	user, err := s.webauthnService.FinishLogin(ctx, nil, ceremonyID, response)
 	if err != nil {
-		return "", ErrInvalidToken
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, client)
+}
+
+// RefreshToken rotates a refresh token and returns a new access and refresh token.
+// Presenting a refresh token that was already rotated means it leaked, so the
+// whole session family is revoked.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (string, string, error) {
+	claims, err := s.parseRefreshToken(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, claims.sessionID)
+	if err != nil || session.UserID != claims.userID {
+		return "", "", ErrInvalidToken
+	}
+
+	if session.RevokedAt != nil {
+		return "", "", ErrInvalidToken
+	}
+
+	if session.RotatedAt != nil {
+		// Clients that refresh from two tabs at once present the same token
+		// twice within moments; only treat older reuse as a stolen token
+		if time.Since(*session.RotatedAt) < refreshReuseGrace {
+			return "", "", ErrInvalidToken
+		}
+		s.revokeFamilyOnReuse(ctx, session)
+		return "", "", ErrRefreshTokenReused
+	}
+
+	if session.ExpiresAt.Before(time.Now()) {
+		return "", "", ErrExpiredToken
 	}
 
 	// Check if user exists
-	_, err = s.userRepo.GetByID(ctx, userID)
+	if _, err := s.userRepo.GetByID(ctx, session.UserID); err != nil {
+		return "", "", ErrInvalidToken
+	}
+
+	next := s.newSession(session.UserID, session.FamilyID, session.AuthenticatedAt, client)
+	if err := s.sessionRepo.Rotate(ctx, session.ID, next); err != nil {
+		if errors.Is(err, postgres.ErrSessionNotRotatable) {
+			// A concurrent request rotated this token first
+			return "", "", ErrInvalidToken
+		}
+		return "", "", fmt.Errorf("failed to rotate session: %w", err)
+	}
+
+	accessToken, err := s.generateAccessToken(next.UserID, next.FamilyID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	newRefreshToken, err := s.generateRefreshToken(next)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	return accessToken, newRefreshToken, nil
+}
+
+// Logout revokes the session family of a refresh token
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	claims, err := s.parseRefreshToken(refreshToken)
+	if err != nil && !errors.Is(err, ErrExpiredToken) {
+		return err
+	}
+	if claims == nil {
+		return ErrInvalidToken
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, claims.sessionID)
+	if err != nil || session.UserID != claims.userID {
+		return ErrInvalidToken
+	}
+
+	return s.sessionRepo.RevokeFamily(ctx, session.FamilyID)
+}
+
+// ListSessions returns the user's signed-in devices, flagging the current one
+func (s *AuthService) ListSessions(ctx context.Context, userID uuid.UUID, currentFamilyID uuid.UUID) ([]model.Session, error) {
+	sessions, err := s.sessionRepo.ListActiveByUserID(ctx, userID)
 	if err != nil {
-		return "", ErrInvalidToken
+		return nil, err
 	}
 
-	// Generate new access token
-	accessToken, err := s.generateAccessToken(userID)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].FamilyID == currentFamilyID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession signs out one of the user's devices
+func (s *AuthService) RevokeSession(ctx context.Context, userID, familyID uuid.UUID) error {
+	revoked, err := s.sessionRepo.RevokeFamilyForUser(ctx, userID, familyID)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate access token: %w", err)
+		return err
+	}
+
+	if !revoked {
+		return ErrSessionNotFound
 	}
 
-	return accessToken, nil
+	return nil
+}
+
+// RevokeOtherSessions signs out every device except the current one
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID, currentFamilyID uuid.UUID) error {
+	return s.sessionRepo.RevokeAllForUser(ctx, userID, &currentFamilyID)
 }
 
 // ChangePassword changes a user's password
-func (s *AuthService) ChangePassword(ctx context.Context, userID uuid.UUID, oldPassword, newPassword string) error {
+func (s *AuthService) ChangePassword(ctx context.Context, userID uuid.UUID, currentFamilyID uuid.UUID, oldPassword, newPassword string) error {
 	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
@@ -173,146 +403,248 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID uuid.UUID, oldP
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	return s.userRepo.UpdatePassword(ctx, userID, passwordHash)
+	if err := s.userRepo.UpdatePassword(ctx, userID, passwordHash); err != nil {
+		return err
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "user.password_changed", "user", userID, "")
+
+	// Sign out every other device that knew the old password
+	return s.sessionRepo.RevokeAllForUser(ctx, userID, &currentFamilyID)
 }
 
-// NomineeEmergencyAccess handles the emergency access process for nominees
-func (s *AuthService) NomineeEmergencyAccess(ctx context.Context, email, emergencyAccessCode string) (string, error) {
-	// Log for debugging
-	fmt.Printf("Attempting emergency access for email: %s with code: %s\n", email, emergencyAccessCode)
+// RequestPasswordReset issues a single-use reset token and mails it to the user.
+// Unknown emails are ignored so callers can't probe which accounts exist.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
 
-	// Get the nominee by email
-	nominee, err := s.nomineeRepo.GetByEmail(ctx, email)
+	rawToken, err := util.GenerateSecureToken(32)
 	if err != nil {
-		fmt.Printf("Nominee not found for email %s: %v\n", email, err)
-		return "", ErrInvalidCredentials
+		return err
 	}
 
-	// Log for debugging
-	fmt.Printf("Found nominee: %s, stored code hash: %s\n", nominee.ID, nominee.EmergencyAccessCode)
+	// Only the most recently requested link should work
+	if err := s.resetRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to invalidate previous reset tokens: %w", err)
+	}
 
-	// Check if the nominee has an emergency access code set
-	if nominee.EmergencyAccessCode == "" {
-		fmt.Printf("Nominee %s has no emergency access code set\n", nominee.ID)
-		return "", errors.New("no emergency access code has been set for this nominee")
+	resetToken := &model.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: util.HashToken(rawToken),
+		ExpiresAt: time.Now().Add(s.appCfg.PasswordResetExpiry),
 	}
 
-	// Verify the emergency access code
-	if !s.passwordUtil.CheckPasswordHash(emergencyAccessCode, nominee.EmergencyAccessCode) {
-		fmt.Printf("Invalid access code for nominee %s\n", nominee.ID)
-		return "", ErrInvalidCredentials
+	if err := s.resetRepo.Create(ctx, resetToken); err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
 	}
 
-	fmt.Printf("Access code verification successful for nominee %s\n", nominee.ID)
+	resetURL := fmt.Sprintf("%s/reset-password?token=%s", strings.TrimRight(s.appCfg.FrontendURL, "/"), url.QueryEscape(rawToken))
+	msg := MailMessage{
+		To:      user.Email,
+		Subject: "Reset your Sampatti password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nWe received a request to reset your Sampatti password. Use the link below to choose a new one:\n\n%s\n\nThe link expires in %d minutes and can only be used once. If you didn't ask for this, you can ignore this email.\n",
+			user.Name,
+			resetURL,
+			int(s.appCfg.PasswordResetExpiry.Minutes()),
+		),
+	}
 
-	// If access is successful, update nominee status to Active if currently Pending
-	if nominee.Status == "Pending" {
-		if err := s.nomineeRepo.UpdateStatus(ctx, nominee.ID, "Active"); err != nil {
-			// Just log the error but don't fail the authentication
-			fmt.Printf("Warning: Failed to activate nominee: %v\n", err)
-		} else {
-			fmt.Printf("Updated nominee %s status to Active\n", nominee.ID)
-		}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send reset email: %w", err)
 	}
 
-	// Generate JWT token for nominee
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":          nominee.ID.String(),
-		"user_id":      nominee.UserID.String(),
-		"access_type":  "nominee",
-		"access_level": nominee.AccessLevel,
-		"exp":          time.Now().Add(time.Hour * 24).Unix(), // 24-hour access
-		"iat":          time.Now().Unix(),
-	})
+	return nil
+}
+
+// ResetPassword redeems a reset token and sets the new password
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if len(newPassword) < 8 {
+		return fmt.Errorf("password must be at least 8 characters")
+	}
 
-	// Sign the token
-	tokenString, err := token.SignedString([]byte(s.cfg.Secret))
+	passwordHash, err := s.passwordUtil.HashPassword(newPassword)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
+		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	// Log the nominee access
-	accessLog := &model.NomineeAccessLog{
-		NomineeID: nominee.ID,
-		Date:      time.Now(),
-		Action:    "Emergency Access",
+	resetToken, err := s.resetRepo.Redeem(ctx, util.HashToken(token), passwordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
 	}
 
-	if err := s.nomineeRepo.LogAccess(ctx, accessLog); err != nil {
-		// Just log the error but don't fail the authentication
-		fmt.Printf("Warning: Failed to log nominee access: %v\n", err)
+	s.audit.RecordUserAction(ctx, resetToken.UserID, "user.password_reset", "user", resetToken.UserID, "Reset from an emailed link")
+
+	// Whoever had access before the reset shouldn't keep it
+	if err := s.sessionRepo.RevokeAllForUser(ctx, resetToken.UserID, nil); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
 	}
 
-	return tokenString, nil
+	return nil
 }
 
-// GenerateNomineeInvite creates an emergency access code for a nominee
-func (s *AuthService) GenerateNomineeInvite(ctx context.Context, nomineeID uuid.UUID) (string, error) {
-	// Generate a random access code (8 characters is easy to remember but secure enough for this purpose)
-	rawCode := util.GenerateRandomString(8)
-	fmt.Printf("Generated access code for nominee %s: %s\n", nomineeID, rawCode)
+// Private methods
 
-	// Hash the code for storage
-	hashedCode, err := s.passwordUtil.HashPassword(rawCode)
-	if err != nil {
-		return "", fmt.Errorf("failed to hash access code: %w", err)
+// loginFailed records a failed login and returns the error to report
+func (s *AuthService) loginFailed(ctx context.Context, scope AttemptScope) error {
+	if _, err := s.limiter.RecordFailure(ctx, scope); err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
 	}
 
-	// Log for debugging
-	fmt.Printf("Hashed access code for nominee %s: %s\n", nomineeID, hashedCode)
+	if scope.UserID != nil {
+		s.audit.Record(ctx, AuditEvent{
+			OwnerID:    *scope.UserID,
+			ActorType:  AuditActorUser,
+			ActorID:    scope.UserID,
+			Action:     "user.login_failed",
+			EntityType: "user",
+			EntityID:   scope.UserID,
+			Summary:    "Wrong password",
+			IPAddress:  scope.IPAddress,
+		})
+	}
+	return ErrInvalidCredentials
+}
+
+// secondFactors lists the second factors the user has set up
+func (s *AuthService) secondFactors(ctx context.Context, user *model.User) ([]string, error) {
+	factors := make([]string, 0, 2)
+	if user.TwoFactorEnabled {
+		factors = append(factors, SecondFactorTOTP)
+	}
 
-	// Update the nominee directly with just the emergency access code
-	// This avoids issues with partial updates of other nominee fields
-	if err := s.nomineeRepo.UpdateEmergencyAccessCode(ctx, nomineeID, hashedCode); err != nil {
-		return "", fmt.Errorf("failed to save access code: %w", err)
+	hasCredentials, err := s.webauthnService.HasCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check security keys: %w", err)
+	}
+	if hasCredentials {
+		factors = append(factors, SecondFactorWebAuthn)
 	}
 
-	return rawCode, nil
+	return factors, nil
 }
 
-// Private methods
+func (s *AuthService) completeLogin(ctx context.Context, user *model.User, client ClientInfo) (*LoginResult, error) {
+	// Update last login time
+	if err := s.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to update last login: %w", err)
+	}
 
-func (s *AuthService) generateAccessToken(userID uuid.UUID) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": userID.String(),
-		"exp": time.Now().Add(time.Minute * time.Duration(s.cfg.ExpiryMinutes)).Unix(),
-		"iat": time.Now().Unix(),
-	})
+	// Every login starts a new session family
+	session := s.newSession(user.ID, uuid.New(), time.Now(), client)
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
 
-	return token.SignedString([]byte(s.cfg.Secret))
-}
+	// Generate tokens
+	accessToken, err := s.generateAccessToken(user.ID, session.FamilyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
 
-func (s *AuthService) generateRefreshToken(userID uuid.UUID) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": userID.String(),
-		"exp": time.Now().Add(time.Minute * time.Duration(s.cfg.RefreshExpiry)).Unix(),
-		"iat": time.Now().Unix(),
+	refreshToken, err := s.generateRefreshToken(session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEvent{
+		OwnerID:    user.ID,
+		ActorType:  AuditActorUser,
+		ActorID:    &user.ID,
+		Action:     "user.login",
+		EntityType: "user",
+		EntityID:   &user.ID,
+		IPAddress:  client.IPAddress,
 	})
 
-	return token.SignedString([]byte(s.cfg.RefreshSecret))
+	return &LoginResult{AccessToken: accessToken, RefreshToken: refreshToken, User: user}, nil
 }
 
-func (s *AuthService) GenerateNomineeToken(nomineeID, userID uuid.UUID, accessLevel string) (string, error) {
-	// Create JWT token for nominee access
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":          nomineeID.String(),
-		"user_id":      userID.String(),
-		"access_type":  "nominee",
-		"access_level": accessLevel,
-		"exp":          time.Now().Add(time.Hour * 24).Unix(), // 24-hour access
-		"iat":          time.Now().Unix(),
-	})
+func (s *AuthService) parseChallengeToken(challengeToken string) (uuid.UUID, error) {
+	claims, err := s.jwtUtil.ParseToken(challengeToken, util.TokenTypeChallenge)
+	if err != nil {
+		if errors.Is(err, util.ErrExpiredToken) {
+			return uuid.UUID{}, ErrExpiredToken
+		}
+		return uuid.UUID{}, ErrInvalidToken
+	}
+
+	userIDStr, ok := claims["sub"].(string)
+	if !ok {
+		return uuid.UUID{}, ErrInvalidToken
+	}
 
-	// Sign the token
-	tokenString, err := token.SignedString([]byte(s.cfg.Secret))
+	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
+		return uuid.UUID{}, ErrInvalidToken
+	}
+
+	return userID, nil
+}
+
+func (s *AuthService) generateAccessToken(userID, sessionFamilyID uuid.UUID) (string, error) {
+	return s.jwtUtil.GenerateAccessToken(userID, sessionFamilyID, time.Minute*time.Duration(s.cfg.ExpiryMinutes))
+}
+
+func (s *AuthService) generateRefreshToken(session *model.Session) (string, error) {
+	return s.jwtUtil.GenerateRefreshToken(session.UserID, session.ID, session.ExpiresAt)
+}
+
+func (s *AuthService) newSession(userID, familyID uuid.UUID, authenticatedAt time.Time, client ClientInfo) *model.Session {
+	return &model.Session{
+		ID:              uuid.New(),
+		FamilyID:        familyID,
+		UserID:          userID,
+		IPAddress:       client.IPAddress,
+		UserAgent:       client.UserAgent,
+		AuthenticatedAt: authenticatedAt,
+		ExpiresAt:       time.Now().Add(time.Minute * time.Duration(s.cfg.RefreshExpiry)),
 	}
+}
 
-	return tokenString, nil
+func (s *AuthService) revokeFamilyOnReuse(ctx context.Context, session *model.Session) {
+	log.Printf("Security: refresh token reuse detected for user %s, revoking session family %s", session.UserID, session.FamilyID)
+	if err := s.sessionRepo.RevokeFamily(ctx, session.FamilyID); err != nil {
+		log.Printf("Failed to revoke session family %s: %v", session.FamilyID, err)
+	}
 }
 
-// VerifyNomineeCode verifies if an access code matches the stored hash
-func (s *AuthService) VerifyNomineeCode(accessCode, storedHash string) bool {
-	return s.passwordUtil.CheckPasswordHash(accessCode, storedHash)
+type refreshClaims struct {
+	userID    uuid.UUID
+	sessionID uuid.UUID
+}
+
+// parseRefreshToken validates a refresh token. An expired but otherwise valid
+// token returns its claims together with ErrExpiredToken.
+func (s *AuthService) parseRefreshToken(refreshToken string) (*refreshClaims, error) {
+	claims, err := s.jwtUtil.ParseToken(refreshToken, util.TokenTypeRefresh)
+	expired := errors.Is(err, util.ErrExpiredToken)
+	if err != nil && !expired {
+		return nil, ErrInvalidToken
+	}
+
+	userIDStr, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	sessionIDStr, _ := claims["jti"].(string)
+	sessionID, err := uuid.Parse(sessionIDStr)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	parsed := &refreshClaims{userID: userID, sessionID: sessionID}
+	if expired {
+		return parsed, ErrExpiredToken
+	}
+
+	return parsed, nil
 }
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+)
+
+var (
+	ErrInvalidShare         = errors.New("each share must be more than 0 and at most 100 percent, with up to two decimals")
+	ErrSharesNot100         = errors.New("beneficiary shares must add up to 100 percent")
+	ErrDuplicateBeneficiary = errors.New("a nominee can only be listed once per asset")
+	ErrBeneficiaryNotOwned  = errors.New("beneficiaries must be your own nominees and not revoked")
+)
+
+// Allocation issues flagged in the estate report
+const (
+	AllocationMissing    = "no_beneficiary"
+	AllocationIncomplete = "incomplete_allocation"
+)
+
+// BeneficiaryShare is one nominee's share in an allocation request
+type BeneficiaryShare struct {
+	NomineeID       uuid.UUID `json:"nominee_id" binding:"required"`
+	SharePercentage float64   `json:"share_percentage" binding:"required"`
+}
+
+// AssetAllocation is an asset's beneficiaries with the total they add up to
+type AssetAllocation struct {
+	AssetID             uuid.UUID                `json:"asset_id"`
+	Beneficiaries       []model.AssetBeneficiary `json:"beneficiaries"`
+	AllocatedPercentage float64                  `json:"allocated_percentage"`
+}
+
+// InheritedAsset is what a nominee receives from one asset
+type InheritedAsset struct {
+	AssetID         uuid.UUID `json:"asset_id"`
+	AssetName       string    `json:"asset_name"`
+	AssetType       string    `json:"asset_type"`
+	SharePercentage float64   `json:"share_percentage"`
+	ExpectedValue   float64   `json:"expected_value"`
+}
+
+// BeneficiaryInheritance is a nominee's expected inheritance across assets
+type BeneficiaryInheritance struct {
+	NomineeID     uuid.UUID        `json:"nominee_id"`
+	NomineeName   string           `json:"nominee_name"`
+	Relationship  string           `json:"relationship"`
+	Status        string           `json:"status"`
+	ExpectedValue float64          `json:"expected_value"`
+	Assets        []InheritedAsset `json:"assets"`
+}
+
+// FlaggedAsset is an asset whose value isn't fully allocated
+type FlaggedAsset struct {
+	AssetID             uuid.UUID `json:"asset_id"`
+	AssetName           string    `json:"asset_name"`
+	AssetType           string    `json:"asset_type"`
+	CurrentValue        float64   `json:"current_value"`
+	AllocatedPercentage float64   `json:"allocated_percentage"`
+	UnallocatedValue    float64   `json:"unallocated_value"`
+	Issue               string    `json:"issue"`
+}
+
+// EstateReport shows how the owner's estate would be distributed at current values
+type EstateReport struct {
+	TotalValue       float64                  `json:"total_value"`
+	AllocatedValue   float64                  `json:"allocated_value"`
+	UnallocatedValue float64                  `json:"unallocated_value"`
+	Beneficiaries    []BeneficiaryInheritance `json:"beneficiaries"`
+	FlaggedAssets    []FlaggedAsset           `json:"flagged_assets"`
+	GeneratedAt      time.Time                `json:"generated_at"`
+}
+
+type BeneficiaryService struct {
+	beneficiaryRepo *postgres.BeneficiaryRepository
+	assetRepo       *postgres.AssetRepository
+	nomineeRepo     *postgres.NomineeRepository
+}
+
+func NewBeneficiaryService(
+	beneficiaryRepo *postgres.BeneficiaryRepository,
+	assetRepo *postgres.AssetRepository,
+	nomineeRepo *postgres.NomineeRepository,
+) *BeneficiaryService {
+	return &BeneficiaryService{
+		beneficiaryRepo: beneficiaryRepo,
+		assetRepo:       assetRepo,
+		nomineeRepo:     nomineeRepo,
+	}
+}
+
+// GetAllocation returns the beneficiaries of one of the owner's assets
+func (s *BeneficiaryService) GetAllocation(ctx context.Context, assetID, userID uuid.UUID) (*AssetAllocation, error) {
+	if _, err := s.ownedAsset(ctx, assetID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.allocation(ctx, assetID)
+}
+
+// SetAllocation replaces an asset's beneficiaries. The shares must add up to
+// exactly 100 percent.
+func (s *BeneficiaryService) SetAllocation(ctx context.Context, assetID, userID uuid.UUID, shares []BeneficiaryShare) (*AssetAllocation, error) {
+	if _, err := s.ownedAsset(ctx, assetID, userID); err != nil {
+		return nil, err
+	}
+
+	nominees, err := s.nomineeRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load nominees: %w", err)
+	}
+
+	eligible := make(map[uuid.UUID]bool, len(nominees))
+	for _, nominee := range nominees {
+		if nominee.Status != NomineeRevoked {
+			eligible[nominee.ID] = true
+		}
+	}
+
+	seen := make(map[uuid.UUID]bool, len(shares))
+	beneficiaries := make([]model.AssetBeneficiary, 0, len(shares))
+	// Shares are summed in hundredths so 33.33 + 33.33 + 33.34 is exactly 100
+	totalHundredths := int64(0)
+
+	for _, share := range shares {
+		hundredths := math.Round(share.SharePercentage * 100)
+		if share.SharePercentage <= 0 || share.SharePercentage > 100 || math.Abs(hundredths-share.SharePercentage*100) > 1e-6 {
+			return nil, ErrInvalidShare
+		}
+
+		if seen[share.NomineeID] {
+			return nil, ErrDuplicateBeneficiary
+		}
+		seen[share.NomineeID] = true
+
+		if !eligible[share.NomineeID] {
+			return nil, ErrBeneficiaryNotOwned
+		}
+
+		totalHundredths += int64(hundredths)
+		beneficiaries = append(beneficiaries, model.AssetBeneficiary{
+			NomineeID:       share.NomineeID,
+			SharePercentage: hundredths / 100,
+		})
+	}
+
+	if totalHundredths != 100*100 {
+		return nil, ErrSharesNot100
+	}
+
+	if err := s.beneficiaryRepo.ReplaceForAsset(ctx, assetID, beneficiaries); err != nil {
+		return nil, fmt.Errorf("failed to save beneficiaries: %w", err)
+	}
+
+	return s.allocation(ctx, assetID)
+}
+
+// ClearAllocation removes every beneficiary from an asset
+func (s *BeneficiaryService) ClearAllocation(ctx context.Context, assetID, userID uuid.UUID) error {
+	if _, err := s.ownedAsset(ctx, assetID, userID); err != nil {
+		return err
+	}
+
+	return s.beneficiaryRepo.DeleteByAssetID(ctx, assetID)
+}
+
+// EstateReport computes each nominee's expected inheritance from the assets'
+// current values and flags assets that aren't fully allocated
+func (s *BeneficiaryService) EstateReport(ctx context.Context, userID uuid.UUID) (*EstateReport, error) {
+	assets, err := s.assetRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assets: %w", err)
+	}
+
+	allocations, err := s.beneficiaryRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load beneficiaries: %w", err)
+	}
+
+	byAsset := make(map[uuid.UUID][]model.AssetBeneficiary)
+	for _, allocation := range allocations {
+		byAsset[allocation.AssetID] = append(byAsset[allocation.AssetID], allocation)
+	}
+
+	report := &EstateReport{
+		Beneficiaries: make([]BeneficiaryInheritance, 0),
+		FlaggedAssets: make([]FlaggedAsset, 0),
+		GeneratedAt:   time.Now(),
+	}
+	inheritances := make(map[uuid.UUID]*BeneficiaryInheritance)
+
+	for _, asset := range assets {
+		report.TotalValue += asset.CurrentValue
+
+		allocated := 0.0
+		for _, beneficiary := range byAsset[asset.ID] {
+			value := roundCents(asset.CurrentValue * beneficiary.SharePercentage / 100)
+			allocated += beneficiary.SharePercentage
+
+			inheritance, ok := inheritances[beneficiary.NomineeID]
+			if !ok {
+				inheritance = &BeneficiaryInheritance{
+					NomineeID:    beneficiary.NomineeID,
+					NomineeName:  beneficiary.NomineeName,
+					Relationship: beneficiary.NomineeRelationship,
+					Status:       beneficiary.NomineeStatus,
+					Assets:       make([]InheritedAsset, 0),
+				}
+				inheritances[beneficiary.NomineeID] = inheritance
+			}
+
+			inheritance.ExpectedValue += value
+			inheritance.Assets = append(inheritance.Assets, InheritedAsset{
+				AssetID:         asset.ID,
+				AssetName:       asset.AssetName,
+				AssetType:       asset.AssetType,
+				SharePercentage: beneficiary.SharePercentage,
+				ExpectedValue:   value,
+			})
+			report.AllocatedValue += value
+		}
+
+		// Deleting a nominee drops their shares, which can leave an asset short of 100
+		if allocated < 100-0.005 {
+			issue := AllocationIncomplete
+			if len(byAsset[asset.ID]) == 0 {
+				issue = AllocationMissing
+			}
+
+			report.FlaggedAssets = append(report.FlaggedAssets, FlaggedAsset{
+				AssetID:             asset.ID,
+				AssetName:           asset.AssetName,
+				AssetType:           asset.AssetType,
+				CurrentValue:        asset.CurrentValue,
+				AllocatedPercentage: roundCents(allocated),
+				UnallocatedValue:    roundCents(asset.CurrentValue * (100 - allocated) / 100),
+				Issue:               issue,
+			})
+		}
+	}
+
+	for _, inheritance := range inheritances {
+		inheritance.ExpectedValue = roundCents(inheritance.ExpectedValue)
+		report.Beneficiaries = append(report.Beneficiaries, *inheritance)
+	}
+
+	sort.Slice(report.Beneficiaries, func(i, j int) bool {
+		return report.Beneficiaries[i].ExpectedValue > report.Beneficiaries[j].ExpectedValue
+	})
+
+	report.TotalValue = roundCents(report.TotalValue)
+	report.AllocatedValue = roundCents(report.AllocatedValue)
+	report.UnallocatedValue = roundCents(report.TotalValue - report.AllocatedValue)
+
+	return report, nil
+}
+
+// Private methods
+
+func (s *BeneficiaryService) ownedAsset(ctx context.Context, assetID, userID uuid.UUID) (*model.Asset, error) {
+	asset, err := s.assetRepo.GetByID(ctx, assetID)
+	if err != nil {
+		return nil, ErrAssetNotFound
+	}
+
+	if asset.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	return asset, nil
+}
+
+func (s *BeneficiaryService) allocation(ctx context.Context, assetID uuid.UUID) (*AssetAllocation, error) {
+	beneficiaries, err := s.beneficiaryRepo.GetByAssetID(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	allocated := 0.0
+	for _, beneficiary := range beneficiaries {
+		allocated += beneficiary.SharePercentage
+	}
+
+	return &AssetAllocation{
+		AssetID:             assetID,
+		Beneficiaries:       beneficiaries,
+		AllocatedPercentage: roundCents(allocated),
+	}, nil
+}
+
+// roundCents rounds amounts and percentages to two decimal places
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,549 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+)
+
+var (
+	ErrClaimStepNotFound        = errors.New("claim step not found")
+	ErrClaimDocumentNotFound    = errors.New("claim document not found")
+	ErrInvalidClaimStatus       = errors.New("status must be NotStarted, Submitted or Settled")
+	ErrClaimNotesTooLong        = errors.New("claim step notes must be at most 2000 characters")
+	ErrInvalidClaimInstruction  = errors.New("claim instructions need an institution and at most 20 steps, each with a title")
+	ErrClaimInstructionNotFound = errors.New("claim instruction not found")
+	ErrClaimInstructionExists   = errors.New("there are already claim instructions for this institution")
+)
+
+// Claim step statuses. ClaimInProgress only describes a whole asset.
+const (
+	ClaimNotStarted = "NotStarted"
+	ClaimSubmitted  = "Submitted"
+	ClaimSettled    = "Settled"
+	ClaimInProgress = "InProgress"
+)
+
+const (
+	maxClaimInstructionSteps = 20
+	maxClaimNotes            = 2000
+)
+
+// defaultClaimSteps is the checklist for assets at institutions the owner
+// left no instructions for
+var defaultClaimSteps = []model.ClaimInstructionStep{
+	{Title: "Notify the institution", RequiredDocuments: []string{"Death certificate"}},
+	{Title: "Submit the claim form", RequiredDocuments: []string{"Claim form", "Death certificate", "Nominee identity proof", "Nominee address proof"}},
+	{Title: "Receive the settlement", RequiredDocuments: []string{}},
+}
+
+// AssetClaim is the checklist for claiming one asset
+type AssetClaim struct {
+	AssetID      uuid.UUID         `json:"asset_id"`
+	AssetName    string            `json:"asset_name"`
+	AssetType    string            `json:"asset_type"`
+	Institution  string            `json:"institution"`
+	CurrentValue float64           `json:"current_value"`
+	Instructions string            `json:"instructions"`
+	Status       string            `json:"status"`
+	Steps        []model.ClaimStep `json:"steps"`
+}
+
+// SettlementSummary totals claim progress across the estate
+type SettlementSummary struct {
+	TotalAssets    int     `json:"total_assets"`
+	SettledAssets  int     `json:"settled_assets"`
+	TotalSteps     int     `json:"total_steps"`
+	SubmittedSteps int     `json:"submitted_steps"`
+	SettledSteps   int     `json:"settled_steps"`
+	PercentSettled float64 `json:"percent_settled"`
+	TotalValue     float64 `json:"total_value"`
+	SettledValue   float64 `json:"settled_value"`
+}
+
+// EstateSettlement is the consolidated claim progress a nominee sees
+type EstateSettlement struct {
+	Summary SettlementSummary `json:"summary"`
+	Assets  []AssetClaim      `json:"assets"`
+}
+
+// ClaimService guides nominees through claiming each asset from its
+// institution once they have been granted access
+type ClaimService struct {
+	claimRepo       *postgres.ClaimRepository
+	instructionRepo *postgres.ClaimInstructionRepository
+	assetRepo       *postgres.AssetRepository
+	nomineeRepo     *postgres.NomineeRepository
+	nomineeData     *NomineeDataService
+	emergency       *EmergencyAccessService
+	storageService  *StorageService
+	accessLog       *NomineeAccessLogger
+}
+
+func NewClaimService(
+	claimRepo *postgres.ClaimRepository,
+	instructionRepo *postgres.ClaimInstructionRepository,
+	assetRepo *postgres.AssetRepository,
+	nomineeRepo *postgres.NomineeRepository,
+	nomineeData *NomineeDataService,
+	emergency *EmergencyAccessService,
+	storageService *StorageService,
+	accessLog *NomineeAccessLogger,
+) *ClaimService {
+	return &ClaimService{
+		claimRepo:       claimRepo,
+		instructionRepo: instructionRepo,
+		assetRepo:       assetRepo,
+		nomineeRepo:     nomineeRepo,
+		nomineeData:     nomineeData,
+		emergency:       emergency,
+		storageService:  storageService,
+		accessLog:       accessLog,
+	}
+}
+
+// ListInstructions returns the owner's claim instructions
+func (s *ClaimService) ListInstructions(ctx context.Context, userID uuid.UUID) ([]model.ClaimInstruction, error) {
+	return s.instructionRepo.ListByUserID(ctx, userID)
+}
+
+// CreateInstruction pre-fills the checklist for assets at an institution.
+// Without steps, the default checklist is used with the owner's notes.
+func (s *ClaimService) CreateInstruction(ctx context.Context, instruction *model.ClaimInstruction) error {
+	if err := s.validateInstruction(ctx, instruction); err != nil {
+		return err
+	}
+
+	return s.instructionRepo.Create(ctx, instruction)
+}
+
+// UpdateInstruction changes an owner's instruction. Checklists nominees
+// have already started are not changed.
+func (s *ClaimService) UpdateInstruction(ctx context.Context, instruction *model.ClaimInstruction) error {
+	if err := s.validateInstruction(ctx, instruction); err != nil {
+		return err
+	}
+
+	updated, err := s.instructionRepo.Update(ctx, instruction)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return ErrClaimInstructionNotFound
+	}
+
+	return nil
+}
+
+func (s *ClaimService) DeleteInstruction(ctx context.Context, userID, id uuid.UUID) error {
+	deleted, err := s.instructionRepo.Delete(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrClaimInstructionNotFound
+	}
+
+	return nil
+}
+
+// Settlement returns the claim checklist of every asset the nominee can
+// see, generating any that don't exist yet, with overall progress
+func (s *ClaimService) Settlement(ctx context.Context, nomineeID uuid.UUID, grantedLevel string) (*EstateSettlement, error) {
+	nominee, assets, err := s.claimableAssets(ctx, nomineeID, grantedLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	instructions, err := s.generateMissing(ctx, nominee, assets)
+	if err != nil {
+		return nil, err
+	}
+
+	assetIDs := make([]uuid.UUID, 0, len(assets))
+	for _, asset := range assets {
+		assetIDs = append(assetIDs, asset.ID)
+	}
+
+	steps, err := s.claimRepo.ListStepsByAssetIDs(ctx, assetIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load claim steps: %w", err)
+	}
+
+	stepsByAsset := make(map[uuid.UUID][]model.ClaimStep, len(assets))
+	for _, step := range steps {
+		stepsByAsset[step.AssetID] = append(stepsByAsset[step.AssetID], step)
+	}
+
+	settlement := &EstateSettlement{Assets: make([]AssetClaim, 0, len(assets))}
+	summary := &settlement.Summary
+	for _, asset := range assets {
+		claim := AssetClaim{
+			AssetID:      asset.ID,
+			AssetName:    asset.AssetName,
+			AssetType:    asset.AssetType,
+			Institution:  asset.Institution,
+			CurrentValue: asset.CurrentValue,
+			Instructions: instructions[asset.ID],
+			Steps:        stepsByAsset[asset.ID],
+		}
+		if claim.Steps == nil {
+			claim.Steps = []model.ClaimStep{}
+		}
+		claim.Status = claimStatus(claim.Steps)
+
+		summary.TotalAssets++
+		summary.TotalValue += asset.CurrentValue
+		if claim.Status == ClaimSettled {
+			summary.SettledAssets++
+			summary.SettledValue += asset.CurrentValue
+		}
+
+		for _, step := range claim.Steps {
+			summary.TotalSteps++
+			switch step.Status {
+			case ClaimSubmitted:
+				summary.SubmittedSteps++
+			case ClaimSettled:
+				summary.SettledSteps++
+			}
+		}
+
+		settlement.Assets = append(settlement.Assets, claim)
+	}
+
+	if summary.TotalSteps > 0 {
+		summary.PercentSettled = math.Round(float64(summary.SettledSteps)*1000/float64(summary.TotalSteps)) / 10
+	}
+
+	return settlement, nil
+}
+
+// UpdateStep records a nominee's progress on a claim step
+func (s *ClaimService) UpdateStep(ctx context.Context, nomineeID uuid.UUID, grantedLevel string, stepID uuid.UUID, status, notes string, client ClientInfo) (*model.ClaimStep, error) {
+	if status != ClaimNotStarted && status != ClaimSubmitted && status != ClaimSettled {
+		return nil, ErrInvalidClaimStatus
+	}
+
+	notes = strings.TrimSpace(notes)
+	if len([]rune(notes)) > maxClaimNotes {
+		return nil, ErrClaimNotesTooLong
+	}
+
+	step, err := s.claimableStep(ctx, nomineeID, grantedLevel, stepID)
+	if err != nil {
+		return nil, err
+	}
+
+	step.Status = status
+	step.Notes = notes
+	step.UpdatedBy = &nomineeID
+
+	if err := s.claimRepo.UpdateStep(ctx, step); err != nil {
+		return nil, fmt.Errorf("failed to update claim step: %w", err)
+	}
+
+	s.accessLog.Record(ctx, nomineeID, fmt.Sprintf("Marked claim step %q as %s", step.Title, status), client)
+	return step, nil
+}
+
+// UploadDocument attaches a file the nominee uploaded to a claim step
+func (s *ClaimService) UploadDocument(ctx context.Context, nomineeID uuid.UUID, grantedLevel string, stepID uuid.UUID, fileData []byte, fileName string, fileSize int64, mimeType string) (*model.ClaimDocument, error) {
+	if fileSize > MaxFileSize {
+		return nil, ErrDocumentTooLarge
+	}
+
+	step, err := s.claimableStep(ctx, nomineeID, grantedLevel, stepID)
+	if err != nil {
+		return nil, err
+	}
+
+	storageKey, err := s.storageService.Upload(ctx, fileData, fileName, mimeType, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	document := &model.ClaimDocument{
+		StepID:     step.ID,
+		NomineeID:  nomineeID,
+		Filename:   fileName,
+		FileSize:   fileSize,
+		MimeType:   mimeType,
+		StorageKey: storageKey,
+	}
+
+	if err := s.claimRepo.CreateDocument(ctx, document); err != nil {
+		_ = s.storageService.Delete(ctx, storageKey)
+		return nil, fmt.Errorf("failed to create claim document record: %w", err)
+	}
+
+	return document, nil
+}
+
+// DownloadDocument returns a claim document uploaded by any of the owner's
+// nominees for an asset this nominee can see
+func (s *ClaimService) DownloadDocument(ctx context.Context, nomineeID uuid.UUID, grantedLevel string, documentID uuid.UUID) ([]byte, *model.ClaimDocument, error) {
+	document, err := s.claimableDocument(ctx, nomineeID, grantedLevel, documentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileData, err := s.storageService.Download(ctx, document.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	return fileData, document, nil
+}
+
+// DeleteDocument removes a claim document the nominee uploaded themselves
+func (s *ClaimService) DeleteDocument(ctx context.Context, nomineeID uuid.UUID, grantedLevel string, documentID uuid.UUID) error {
+	document, err := s.claimableDocument(ctx, nomineeID, grantedLevel, documentID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.claimRepo.DeleteDocument(ctx, nomineeID, document.ID)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrClaimDocumentNotFound
+	}
+
+	if err := s.storageService.Delete(ctx, document.StorageKey); err != nil {
+		log.Printf("Failed to delete claim document file %s: %v", document.StorageKey, err)
+	}
+
+	return nil
+}
+
+// Private methods
+
+// claimableAssets returns the assets the nominee can see, once they have
+// been granted access
+func (s *ClaimService) claimableAssets(ctx context.Context, nomineeID uuid.UUID, grantedLevel string) (*model.Nominee, []model.Asset, error) {
+	nominee, assets, err := s.nomineeData.Assets(ctx, nomineeID, grantedLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	granted, err := s.emergency.IsGranted(ctx, nominee)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check emergency access: %w", err)
+	}
+
+	if !granted {
+		return nil, nil, ErrAccessNotReleased
+	}
+
+	return nominee, assets, nil
+}
+
+// claimableStep finds a step of a checklist for an asset the nominee can see
+func (s *ClaimService) claimableStep(ctx context.Context, nomineeID uuid.UUID, grantedLevel string, stepID uuid.UUID) (*model.ClaimStep, error) {
+	_, assets, err := s.claimableAssets(ctx, nomineeID, grantedLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	step, err := s.claimRepo.GetStepByID(ctx, stepID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClaimStepNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, asset := range assets {
+		if asset.ID == step.AssetID {
+			return step, nil
+		}
+	}
+
+	return nil, ErrClaimStepNotFound
+}
+
+func (s *ClaimService) claimableDocument(ctx context.Context, nomineeID uuid.UUID, grantedLevel string, documentID uuid.UUID) (*model.ClaimDocument, error) {
+	document, err := s.claimRepo.GetDocumentByID(ctx, documentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClaimDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.claimableStep(ctx, nomineeID, grantedLevel, document.StepID); err != nil {
+		if errors.Is(err, ErrClaimStepNotFound) {
+			return nil, ErrClaimDocumentNotFound
+		}
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// generateMissing creates the checklists of assets that have none from the
+// owner's instructions for their institution. It returns each asset's
+// instruction notes.
+func (s *ClaimService) generateMissing(ctx context.Context, nominee *model.Nominee, assets []model.Asset) (map[uuid.UUID]string, error) {
+	notes := make(map[uuid.UUID]string, len(assets))
+	if len(assets) == 0 {
+		return notes, nil
+	}
+
+	instructions, err := s.instructionRepo.ListByUserID(ctx, nominee.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load claim instructions: %w", err)
+	}
+
+	// Visible assets may have their institution redacted, so match on the stored one
+	owned, err := s.assetRepo.GetByUserID(ctx, nominee.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assets: %w", err)
+	}
+
+	institutions := make(map[uuid.UUID]string, len(owned))
+	for _, asset := range owned {
+		institutions[asset.ID] = asset.Institution
+	}
+
+	assetIDs := make([]uuid.UUID, 0, len(assets))
+	for _, asset := range assets {
+		assetIDs = append(assetIDs, asset.ID)
+	}
+
+	existing, err := s.claimRepo.ListStepsByAssetIDs(ctx, assetIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load claim steps: %w", err)
+	}
+
+	generated := make(map[uuid.UUID]bool, len(existing))
+	for _, step := range existing {
+		generated[step.AssetID] = true
+	}
+
+	var missing []model.ClaimStep
+	for _, asset := range assets {
+		checklist := defaultClaimSteps
+		if instruction := matchInstruction(instructions, institutions[asset.ID]); instruction != nil {
+			notes[asset.ID] = instruction.Instructions
+			if len(instruction.Steps) > 0 {
+				checklist = instruction.Steps
+			}
+		}
+
+		if generated[asset.ID] {
+			continue
+		}
+
+		for i, step := range checklist {
+			missing = append(missing, model.ClaimStep{
+				AssetID:           asset.ID,
+				Position:          i + 1,
+				Title:             step.Title,
+				RequiredDocuments: step.RequiredDocuments,
+				Status:            ClaimNotStarted,
+			})
+		}
+	}
+
+	if len(missing) > 0 {
+		if err := s.claimRepo.CreateSteps(ctx, missing); err != nil {
+			return nil, fmt.Errorf("failed to create claim checklists: %w", err)
+		}
+	}
+
+	return notes, nil
+}
+
+func (s *ClaimService) validateInstruction(ctx context.Context, instruction *model.ClaimInstruction) error {
+	instruction.Institution = strings.TrimSpace(instruction.Institution)
+	instruction.Instructions = strings.TrimSpace(instruction.Instructions)
+	if instruction.Institution == "" || len(instruction.Institution) > 255 || len(instruction.Steps) > maxClaimInstructionSteps {
+		return ErrInvalidClaimInstruction
+	}
+
+	steps := make([]model.ClaimInstructionStep, 0, len(instruction.Steps))
+	for _, step := range instruction.Steps {
+		title := strings.TrimSpace(step.Title)
+		if title == "" || len(title) > 255 {
+			return ErrInvalidClaimInstruction
+		}
+
+		documents := make([]string, 0, len(step.RequiredDocuments))
+		for _, document := range step.RequiredDocuments {
+			if document = strings.TrimSpace(document); document != "" {
+				documents = append(documents, document)
+			}
+		}
+
+		steps = append(steps, model.ClaimInstructionStep{Title: title, RequiredDocuments: documents})
+	}
+	instruction.Steps = steps
+
+	existing, err := s.instructionRepo.ListByUserID(ctx, instruction.UserID)
+	if err != nil {
+		return err
+	}
+
+	if other := matchInstruction(existing, instruction.Institution); other != nil && other.ID != instruction.ID {
+		return ErrClaimInstructionExists
+	}
+
+	return nil
+}
+
+// matchInstruction finds the instruction for an institution, ignoring case
+func matchInstruction(instructions []model.ClaimInstruction, institution string) *model.ClaimInstruction {
+	institution = strings.TrimSpace(institution)
+	if institution == "" {
+		return nil
+	}
+
+	for i := range instructions {
+		if strings.EqualFold(instructions[i].Institution, institution) {
+			return &instructions[i]
+		}
+	}
+
+	return nil
+}
+
+// claimStatus sums up an asset's steps: settled once every step is
+func claimStatus(steps []model.ClaimStep) string {
+	if len(steps) == 0 {
+		return ClaimNotStarted
+	}
+
+	settled, started := 0, 0
+	for _, step := range steps {
+		switch step.Status {
+		case ClaimSettled:
+			settled++
+			started++
+		case ClaimSubmitted:
+			started++
+		}
+	}
+
+	switch {
+	case settled == len(steps):
+		return ClaimSettled
+	case started > 0:
+		return ClaimInProgress
+	default:
+		return ClaimNotStarted
+	}
+}
@@ -37,12 +37,16 @@ var ValidDocumentTypes = map[string]bool{
 type DocumentService struct {
 	documentRepo   *postgres.DocumentRepository
 	storageService *StorageService
+	audit          *AuditService
+	vault          *VaultService
 }
 
-func NewDocumentService(documentRepo *postgres.DocumentRepository, storageService *StorageService) *DocumentService {
+func NewDocumentService(documentRepo *postgres.DocumentRepository, storageService *StorageService, audit *AuditService, vault *VaultService) *DocumentService {
 	return &DocumentService{
 		documentRepo:   documentRepo,
 		storageService: storageService,
+		audit:          audit,
+		vault:          vault,
 	}
 }
 
@@ -72,8 +76,19 @@ func (s *DocumentService) Upload(
 		return nil, ErrInvalidDocumentType
 	}
 
+	// Encrypted documents get a key of their own, stored once the document exists
+	var documentKey []byte
+	content := fileData
+	if isEncrypted && s.vault.Enabled() {
+		var err error
+		content, documentKey, err = s.vault.SealDocument(fileData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt file: %w", err)
+		}
+	}
+
 	// Upload file to R2
-	storageKey, err := s.storageService.Upload(ctx, fileData, fileName, mimeType, isEncrypted)
+	storageKey, err := s.storageService.Upload(ctx, content, fileName, mimeType, isEncrypted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
@@ -101,6 +116,15 @@ func (s *DocumentService) Upload(
 		return nil, fmt.Errorf("failed to create document record: %w", err)
 	}
 
+	if documentKey != nil {
+		if err := s.vault.SaveDocumentKey(ctx, doc, documentKey); err != nil {
+			_ = s.documentRepo.Delete(ctx, doc.ID)
+			_ = s.storageService.Delete(ctx, storageKey)
+			return nil, fmt.Errorf("failed to store document key: %w", err)
+		}
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "document.uploaded", "document", doc.ID, doc.Title)
 	return doc, nil
 }
 
@@ -160,6 +184,11 @@ func (s *DocumentService) Download(ctx context.Context, id uuid.UUID, userID uui
 		return nil, "", "", fmt.Errorf("failed to download file: %w", err)
 	}
 
+	fileData, err = s.vault.OpenForOwner(ctx, doc, fileData)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to decrypt file: %w", err)
+	}
+
 	return fileData, doc.Filename, doc.MimeType, nil
 }
 
@@ -175,6 +204,15 @@ func (s *DocumentService) GetDownloadURL(ctx context.Context, id uuid.UUID, user
 		return "", "", ErrUnauthorized
 	}
 
+	// Storage only holds the ciphertext of encrypted documents
+	sealed, err := s.vault.IsSealed(ctx, doc.ID)
+	if err != nil {
+		return "", "", err
+	}
+	if sealed {
+		return "", "", ErrEncryptedDocumentURL
+	}
+
 	// Generate pre-signed URL (valid for 15 minutes)
 	url, err := s.storageService.GetSignedURL(ctx, doc.StorageKey, 15*time.Minute)
 	if err != nil {
@@ -219,7 +257,12 @@ func (s *DocumentService) Update(
 	doc.IsEncrypted = isEncrypted
 	doc.AccessibleToNominees = accessibleToNominees
 
-	return s.documentRepo.Update(ctx, doc)
+	if err := s.documentRepo.Update(ctx, doc); err != nil {
+		return err
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "document.updated", "document", id, doc.Title)
+	return nil
 }
 
 // Delete removes a document and its file
@@ -241,7 +284,12 @@ func (s *DocumentService) Delete(ctx context.Context, id uuid.UUID, userID uuid.
 	}
 
 	// Delete document record
-	return s.documentRepo.Delete(ctx, id)
+	if err := s.documentRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "document.deleted", "document", id, doc.Title)
+	return nil
 }
 
 // UpdateNomineeAccess updates which nominees can access a document
@@ -257,7 +305,12 @@ func (s *DocumentService) UpdateNomineeAccess(ctx context.Context, docID uuid.UU
 	}
 
 	// Update nominee access
-	return s.documentRepo.UpdateNomineeAccess(ctx, docID, nomineeIDs)
+	if err := s.documentRepo.UpdateNomineeAccess(ctx, docID, nomineeIDs); err != nil {
+		return err
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "document.nominee_access_updated", "document", docID, fmt.Sprintf("%s: shared with %d nominee(s)", doc.Title, len(nomineeIDs)))
+	return nil
 }
 
 // GetNomineeDocuments retrieves documents a nominee has access to
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var (
+	ErrEmailNotVerified         = errors.New("verify your email address to use this feature")
+	ErrEmailAlreadyVerified     = errors.New("email address is already verified")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrVerificationTooSoon      = errors.New("a verification email was sent recently, please wait before requesting another")
+)
+
+// Actions that can be restricted for accounts whose email isn't verified
+const (
+	RestrictNomineeInvitations = "nominee_invitations"
+	RestrictNomineeLookup      = "nominee_lookup"
+	RestrictDocumentUpload     = "document_upload"
+)
+
+const verificationResendCooldown = time.Minute
+
+type EmailVerificationService struct {
+	verificationRepo *postgres.EmailVerificationRepository
+	userRepo         *postgres.UserRepository
+	appCfg           *config.AppConfig
+	mailer           Mailer
+	restricted       map[string]bool
+}
+
+func NewEmailVerificationService(
+	verificationRepo *postgres.EmailVerificationRepository,
+	userRepo *postgres.UserRepository,
+	appCfg *config.AppConfig,
+	mailer Mailer,
+) *EmailVerificationService {
+	restricted := make(map[string]bool, len(appCfg.UnverifiedRestrictions))
+	for _, action := range appCfg.UnverifiedRestrictions {
+		restricted[action] = true
+	}
+
+	return &EmailVerificationService{
+		verificationRepo: verificationRepo,
+		userRepo:         userRepo,
+		appCfg:           appCfg,
+		mailer:           mailer,
+		restricted:       restricted,
+	}
+}
+
+// SendVerification issues a new verification link for the user's current
+// email address, invalidating any earlier link
+func (s *EmailVerificationService) SendVerification(ctx context.Context, user *model.User) error {
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	rawToken, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	if err := s.verificationRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to invalidate previous verification tokens: %w", err)
+	}
+
+	token := &model.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: util.HashToken(rawToken),
+		ExpiresAt: time.Now().Add(s.appCfg.EmailVerificationExpiry),
+	}
+
+	if err := s.verificationRepo.Create(ctx, token); err != nil {
+		return fmt.Errorf("failed to store verification token: %w", err)
+	}
+
+	verifyURL := fmt.Sprintf("%s/verify-email?token=%s", strings.TrimRight(s.appCfg.FrontendURL, "/"), url.QueryEscape(rawToken))
+	msg := MailMessage{
+		To:      user.Email,
+		Subject: "Verify your Sampatti email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm that this is your email address by opening the link below:\n\n%s\n\nThe link expires in %d hours. If you didn't create a Sampatti account, you can ignore this email.\n",
+			user.Name,
+			verifyURL,
+			int(s.appCfg.EmailVerificationExpiry.Hours()),
+		),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	return nil
+}
+
+// Resend sends a fresh verification link, at most once per cooldown period
+func (s *EmailVerificationService) Resend(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	lastSent, err := s.verificationRepo.LastSentAt(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to check previous verification email: %w", err)
+	}
+
+	if lastSent != nil && time.Since(*lastSent) < verificationResendCooldown {
+		return ErrVerificationTooSoon
+	}
+
+	return s.SendVerification(ctx, user)
+}
+
+// Verify redeems a verification token. The token only counts if the account
+// still has the address it was sent to.
+func (s *EmailVerificationService) Verify(ctx context.Context, rawToken string) error {
+	token, err := s.verificationRepo.Consume(ctx, util.HashToken(rawToken))
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	verified, err := s.userRepo.MarkEmailVerified(ctx, token.UserID, token.Email)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+
+	if !verified {
+		return ErrInvalidVerificationToken
+	}
+
+	return nil
+}
+
+// RequireVerified returns ErrEmailNotVerified when action is restricted for
+// unverified accounts and the user hasn't verified their email
+func (s *EmailVerificationService) RequireVerified(ctx context.Context, userID uuid.UUID, action string) error {
+	if !s.restricted[action] {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
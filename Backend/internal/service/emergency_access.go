@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var (
+	ErrAccessRequestNotFound = errors.New("emergency access request not found or no longer pending")
+	ErrNomineeRevoked        = errors.New("nominee access has been revoked")
+	ErrNomineeSuspended      = errors.New("nominee access is suspended until the owner reviews unusual activity")
+)
+
+// Emergency access request statuses
+const (
+	AccessRequestPending = "Pending"
+	AccessRequestDenied  = "Denied"
+	AccessRequestGranted = "Granted"
+	AccessRequestExpired = "Expired"
+)
+
+// AccessPendingError is returned instead of access while the nominee's
+// emergency access request is waiting out its period or has been denied
+type AccessPendingError struct {
+	Request *model.EmergencyAccessRequest
+}
+
+func (e *AccessPendingError) Error() string {
+	if e.Request.Status == AccessRequestDenied {
+		return "the owner has denied this emergency access request"
+	}
+	return fmt.Sprintf("emergency access has been requested and will be granted on %s unless the owner denies it", e.Request.GrantAt.Format("Jan 2, 2006 15:04 MST"))
+}
+
+func (e *AccessPendingError) Unwrap() error {
+	return ErrAccessNotReleased
+}
+
+type EmergencyAccessService struct {
+	requestRepo  *postgres.EmergencyAccessRepository
+	nomineeRepo  *postgres.NomineeRepository
+	userRepo     *postgres.UserRepository
+	alertService *AlertService
+	mailer       Mailer
+	cfg          *config.EmergencyAccessConfig
+	appCfg       *config.AppConfig
+	audit        *AuditService
+	accessLog    *NomineeAccessLogger
+}
+
+func NewEmergencyAccessService(
+	requestRepo *postgres.EmergencyAccessRepository,
+	nomineeRepo *postgres.NomineeRepository,
+	userRepo *postgres.UserRepository,
+	alertService *AlertService,
+	mailer Mailer,
+	cfg *config.EmergencyAccessConfig,
+	appCfg *config.AppConfig,
+	audit *AuditService,
+	accessLog *NomineeAccessLogger,
+) *EmergencyAccessService {
+	return &EmergencyAccessService{
+		requestRepo:  requestRepo,
+		nomineeRepo:  nomineeRepo,
+		userRepo:     userRepo,
+		alertService: alertService,
+		mailer:       mailer,
+		cfg:          cfg,
+		appCfg:       appCfg,
+		audit:        audit,
+		accessLog:    accessLog,
+	}
+}
+
+// Open decides whether a nominee who has signed in gets access.
+// It returns nil when the owner's inactivity switch has released access or a
+// request has been granted. Otherwise it opens a request, or reports the one
+// already open, as an *AccessPendingError.
+func (s *EmergencyAccessService) Open(ctx context.Context, nominee *model.Nominee, client ClientInfo) error {
+	// Revoked nominees can't bother the owner with requests
+	if nominee.Status == NomineeRevoked {
+		return ErrNomineeRevoked
+	}
+
+	if nominee.SuspendedAt != nil {
+		return ErrNomineeSuspended
+	}
+
+	if nominee.AccessEligibleAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	latest, err := s.requestRepo.GetLatestByNomineeID(ctx, nominee.ID)
+	if err == nil {
+		switch latest.Status {
+		case AccessRequestGranted:
+			if latest.ExpiresAt != nil && now.Before(*latest.ExpiresAt) {
+				return nil
+			}
+		case AccessRequestPending:
+			if now.Before(latest.GrantAt) {
+				return &AccessPendingError{Request: latest}
+			}
+			if granted := s.grant(ctx, latest); granted {
+				return nil
+			}
+			return &AccessPendingError{Request: latest}
+		case AccessRequestDenied:
+			// A denial holds for one waiting period so the owner isn't asked again straight away
+			if latest.ResolvedAt != nil && now.Before(latest.ResolvedAt.Add(s.cfg.WaitingPeriod)) {
+				return &AccessPendingError{Request: latest}
+			}
+		}
+	}
+
+	request, err := s.create(ctx, nominee, client)
+	if err != nil {
+		return err
+	}
+
+	return &AccessPendingError{Request: request}
+}
+
+// IsGranted reports whether the nominee currently has emergency access,
+// without opening or granting a request the way Open does
+func (s *EmergencyAccessService) IsGranted(ctx context.Context, nominee *model.Nominee) (bool, error) {
+	if nominee.Status == NomineeRevoked || nominee.SuspendedAt != nil {
+		return false, nil
+	}
+
+	if nominee.AccessEligibleAt != nil {
+		return true, nil
+	}
+
+	latest, err := s.requestRepo.GetLatestByNomineeID(ctx, nominee.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	granted := latest.Status == AccessRequestGranted && latest.ExpiresAt != nil && time.Now().Before(*latest.ExpiresAt)
+	return granted, nil
+}
+
+// ListForOwner returns the requests made for the owner's account
+func (s *EmergencyAccessService) ListForOwner(ctx context.Context, userID uuid.UUID) ([]model.EmergencyAccessRequest, error) {
+	return s.requestRepo.ListByUserID(ctx, userID)
+}
+
+// ListForNominee returns the requests the signed-in user made as a nominee
+func (s *EmergencyAccessService) ListForNominee(ctx context.Context, userID uuid.UUID) ([]model.EmergencyAccessRequest, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	return s.requestRepo.ListByNomineeEmail(ctx, user.Email)
+}
+
+// Deny lets the signed-in owner deny a pending request
+func (s *EmergencyAccessService) Deny(ctx context.Context, userID, requestID uuid.UUID) (*model.EmergencyAccessRequest, error) {
+	request, err := s.requestRepo.Deny(ctx, userID, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deny request: %w", err)
+	}
+
+	return s.denied(ctx, request)
+}
+
+// DenyWithToken denies a pending request from the link in the owner's
+// notification email, without signing in
+func (s *EmergencyAccessService) DenyWithToken(ctx context.Context, rawToken string) (*model.EmergencyAccessRequest, error) {
+	request, err := s.requestRepo.DenyByToken(ctx, util.HashToken(rawToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to deny request: %w", err)
+	}
+
+	return s.denied(ctx, request)
+}
+
+// RunChecks grants requests whose waiting period is over and expires
+// granted access that has run out
+func (s *EmergencyAccessService) RunChecks(ctx context.Context) error {
+	granted, err := s.requestRepo.GrantDue(ctx, time.Now().Add(s.cfg.GrantDuration))
+	if err != nil {
+		return fmt.Errorf("failed to grant due requests: %w", err)
+	}
+
+	for i := range granted {
+		s.notifyGranted(ctx, &granted[i])
+	}
+
+	if _, err := s.requestRepo.ExpireDue(ctx); err != nil {
+		return fmt.Errorf("failed to expire granted requests: %w", err)
+	}
+
+	return nil
+}
+
+// Private methods
+
+func (s *EmergencyAccessService) create(ctx context.Context, nominee *model.Nominee, client ClientInfo) (*model.EmergencyAccessRequest, error) {
+	denyToken, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	request := &model.EmergencyAccessRequest{
+		NomineeID:     nominee.ID,
+		UserID:        nominee.UserID,
+		Status:        AccessRequestPending,
+		RequestedAt:   now,
+		GrantAt:       now.Add(s.cfg.WaitingPeriod),
+		DenyTokenHash: util.HashToken(denyToken),
+		IPAddress:     client.IPAddress,
+	}
+
+	if err := s.requestRepo.Create(ctx, request); err != nil {
+		// A concurrent attempt opened the request first
+		if latest, latestErr := s.requestRepo.GetLatestByNomineeID(ctx, nominee.ID); latestErr == nil && latest.Status == AccessRequestPending {
+			return latest, nil
+		}
+		return nil, fmt.Errorf("failed to create emergency access request: %w", err)
+	}
+
+	s.accessLog.Record(ctx, nominee.ID, "Emergency access requested", client)
+	s.recordAudit(ctx, request, AuditActorNominee, &nominee.ID, "emergency_access.requested", fmt.Sprintf("%s requested emergency access", nominee.Name), client.IPAddress)
+	s.notifyOwner(ctx, nominee, request, denyToken)
+
+	return request, nil
+}
+
+// grant grants a pending request on the nominee's own attempt, in case the
+// background job hasn't got to it yet
+func (s *EmergencyAccessService) grant(ctx context.Context, request *model.EmergencyAccessRequest) bool {
+	granted, err := s.requestRepo.Grant(ctx, request.ID, time.Now().Add(s.cfg.GrantDuration))
+	if err != nil {
+		log.Printf("Failed to grant emergency access request %s: %v", request.ID, err)
+		return false
+	}
+
+	if granted != nil {
+		s.notifyGranted(ctx, granted)
+		return true
+	}
+
+	// Someone else resolved it first, so go by what they decided
+	current, err := s.requestRepo.GetByID(ctx, request.ID)
+	if err != nil {
+		return false
+	}
+	*request = *current
+
+	return current.Status == AccessRequestGranted
+}
+
+func (s *EmergencyAccessService) denied(ctx context.Context, request *model.EmergencyAccessRequest) (*model.EmergencyAccessRequest, error) {
+	if request == nil {
+		return nil, ErrAccessRequestNotFound
+	}
+
+	s.accessLog.Record(ctx, request.NomineeID, "Emergency access request denied by owner", ClientInfo{})
+	s.recordAudit(ctx, request, AuditActorUser, &request.UserID, "emergency_access.denied", "Emergency access request denied", "")
+
+	nominee, err := s.nomineeRepo.GetByID(ctx, request.NomineeID)
+	if err != nil {
+		return request, nil
+	}
+
+	msg := MailMessage{
+		To:      nominee.Email,
+		Subject: "Your emergency access request was denied",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nThe account owner has denied your emergency access request of %s. You can ask again after %s.\n",
+			nominee.Name,
+			request.RequestedAt.Format("Jan 2, 2006"),
+			request.ResolvedAt.Add(s.cfg.WaitingPeriod).Format("Jan 2, 2006 15:04 MST"),
+		),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("Failed to send denial notice for request %s: %v", request.ID, err)
+	}
+
+	return request, nil
+}
+
+func (s *EmergencyAccessService) notifyOwner(ctx context.Context, nominee *model.Nominee, request *model.EmergencyAccessRequest, denyToken string) {
+	grantAt := request.GrantAt.Format("Jan 2, 2006 15:04 MST")
+
+	alert := &model.Alert{
+		UserID:         nominee.UserID,
+		AlertType:      "Security",
+		Severity:       "Critical",
+		Message:        fmt.Sprintf("Your nominee %s has requested emergency access to your account. It will be granted on %s unless you deny it.", nominee.Name, grantAt),
+		CreatedAt:      time.Now(),
+		ExpiresAt:      &request.GrantAt,
+		ActionRequired: true,
+	}
+	if err := s.alertService.Create(ctx, alert); err != nil {
+		log.Printf("Failed to create emergency access alert for user %s: %v", nominee.UserID, err)
+	}
+
+	owner, err := s.userRepo.GetByID(ctx, nominee.UserID)
+	if err != nil {
+		log.Printf("Failed to load owner %s for emergency access notice: %v", nominee.UserID, err)
+		return
+	}
+
+	denyURL := fmt.Sprintf("%s/emergency-access/deny?token=%s", strings.TrimRight(s.appCfg.FrontendURL, "/"), url.QueryEscape(denyToken))
+	msg := MailMessage{
+		To:      owner.Email,
+		Subject: "Your nominee has requested emergency access",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour nominee %s (%s) has requested emergency access to your Sampatti account. Unless you deny it, access will be granted on %s.\n\nIf you didn't expect this, deny the request here:\n\n%s\n\nYou can also deny it from the nominees page after signing in.\n",
+			owner.Name,
+			nominee.Name,
+			nominee.Email,
+			grantAt,
+			denyURL,
+		),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("Failed to send emergency access notice to user %s: %v", owner.ID, err)
+	}
+}
+
+func (s *EmergencyAccessService) notifyGranted(ctx context.Context, request *model.EmergencyAccessRequest) {
+	s.accessLog.Record(ctx, request.NomineeID, "Emergency access granted after waiting period", ClientInfo{})
+	s.recordAudit(ctx, request, AuditActorSystem, nil, "emergency_access.granted", "Emergency access granted after waiting period", "")
+
+	alert := &model.Alert{
+		UserID:         request.UserID,
+		AlertType:      "Security",
+		Severity:       "Critical",
+		Message:        "An emergency access request you didn't deny has been granted. Revoke the nominee if this wasn't expected.",
+		CreatedAt:      time.Now(),
+		ActionRequired: true,
+	}
+	if err := s.alertService.Create(ctx, alert); err != nil {
+		log.Printf("Failed to create emergency access alert for user %s: %v", request.UserID, err)
+	}
+
+	nominee, err := s.nomineeRepo.GetByID(ctx, request.NomineeID)
+	if err != nil {
+		return
+	}
+
+	msg := MailMessage{
+		To:      nominee.Email,
+		Subject: "Your emergency access request was granted",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour emergency access request has been granted. Sign in with your nominee password or linked Sampatti account before %s:\n\n%s/emergency-access\n",
+			nominee.Name,
+			request.ExpiresAt.Format("Jan 2, 2006"),
+			strings.TrimRight(s.appCfg.FrontendURL, "/"),
+		),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("Failed to send grant notice for request %s: %v", request.ID, err)
+	}
+}
+
+func (s *EmergencyAccessService) recordAudit(ctx context.Context, request *model.EmergencyAccessRequest, actorType string, actorID *uuid.UUID, action, summary, ipAddress string) {
+	s.audit.Record(ctx, AuditEvent{
+		OwnerID:    request.UserID,
+		ActorType:  actorType,
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: "emergency_access_request",
+		EntityID:   &request.ID,
+		Summary:    summary,
+		IPAddress:  ipAddress,
+	})
+}
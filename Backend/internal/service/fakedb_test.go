@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// fakeDB stands in for Postgres in service tests. Queries are answered by
+// the first stub whose fragment they contain; other queries return no rows
+// and other statements report one affected row. Every statement is kept so
+// tests can check what was written.
+type fakeDB struct {
+	mu         sync.Mutex
+	stubs      []fakeStub
+	statements []fakeStatement
+}
+
+type fakeStub struct {
+	fragment string
+	columns  []string
+	rows     [][]driver.Value
+}
+
+type fakeStatement struct {
+	Query string
+	Args  []driver.Value
+}
+
+var (
+	registerFakeDriver sync.Once
+	fakeDBs            sync.Map
+	fakeDBCount        atomic.Int64
+)
+
+func newFakeDB(t *testing.T) (*sqlx.DB, *fakeDB) {
+	t.Helper()
+	registerFakeDriver.Do(func() { sql.Register("fakedb", fakeDriver{}) })
+
+	fake := &fakeDB{}
+	name := fmt.Sprintf("fake-%d", fakeDBCount.Add(1))
+	fakeDBs.Store(name, fake)
+
+	db, err := sqlx.Open("fakedb", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		fakeDBs.Delete(name)
+	})
+
+	return sqlx.NewDb(db.DB, "postgres"), fake
+}
+
+// on answers queries containing fragment with the given rows
+func (f *fakeDB) on(fragment string, columns []string, rows ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stubs = append(f.stubs, fakeStub{fragment: fragment, columns: columns, rows: rows})
+}
+
+// executed returns the statements that contain fragment
+func (f *fakeDB) executed(fragment string) []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	matches := make([]fakeStatement, 0)
+	for _, statement := range f.statements {
+		if strings.Contains(statement.Query, fragment) {
+			matches = append(matches, statement)
+		}
+	}
+	return matches
+}
+
+func (f *fakeDB) record(query string, args []driver.NamedValue) *fakeStub {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query = strings.Join(strings.Fields(query), " ")
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	f.statements = append(f.statements, fakeStatement{Query: query, Args: values})
+
+	for i := range f.stubs {
+		if strings.Contains(query, f.stubs[i].fragment) {
+			return &f.stubs[i]
+		}
+	}
+	return nil
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fake, ok := fakeDBs.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("no fake database %q", name)
+	}
+	return &fakeConn{db: fake.(*fakeDB)}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakedb does not prepare statements")
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+// CheckNamedValue passes every argument through as it is
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	stub := c.db.record(query, args)
+	if stub == nil {
+		return &fakeRows{}, nil
+	}
+	return &fakeRows{columns: stub.columns, rows: stub.rows}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
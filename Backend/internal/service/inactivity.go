@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+)
+
+var (
+	ErrInvalidInactivityPeriod = fmt.Errorf("inactivity period must be between %d and %d days", MinInactivityDays, MaxInactivityDays)
+	ErrAccessNotReleased       = errors.New("the owner's account has not been released to nominees")
+)
+
+const (
+	MinInactivityDays = 30
+	MaxInactivityDays = 730
+)
+
+// Inactivity states reported to the owner
+const (
+	InactivityStateDisabled  = "disabled"
+	InactivityStateActive    = "active"
+	InactivityStateReminding = "reminding"
+	InactivityStateReleased  = "released"
+)
+
+// reminderStages are the points in the grace period, as fractions of it, at
+// which reminders are sent. Each one is more urgent than the last.
+var reminderStages = []struct {
+	at       float64
+	severity string
+}{
+	{0, "Medium"},
+	{0.5, "High"},
+	{0.85, "Critical"},
+}
+
+// InactivityStatus describes where an owner is in the inactivity timeline
+type InactivityStatus struct {
+	*model.InactivitySwitch
+	State           string    `json:"state"`
+	LastActivityAt  time.Time `json:"last_activity_at"`
+	RemindersFrom   time.Time `json:"reminders_from"`
+	ReleaseAt       time.Time `json:"release_at"`
+	GracePeriodDays int       `json:"grace_period_days"`
+}
+
+type InactivityService struct {
+	switchRepo   *postgres.InactivitySwitchRepository
+	userRepo     *postgres.UserRepository
+	nomineeRepo  *postgres.NomineeRepository
+	alertService *AlertService
+	mailer       Mailer
+	cfg          *config.InactivityConfig
+	appCfg       *config.AppConfig
+}
+
+func NewInactivityService(
+	switchRepo *postgres.InactivitySwitchRepository,
+	userRepo *postgres.UserRepository,
+	nomineeRepo *postgres.NomineeRepository,
+	alertService *AlertService,
+	mailer Mailer,
+	cfg *config.InactivityConfig,
+	appCfg *config.AppConfig,
+) *InactivityService {
+	return &InactivityService{
+		switchRepo:   switchRepo,
+		userRepo:     userRepo,
+		nomineeRepo:  nomineeRepo,
+		alertService: alertService,
+		mailer:       mailer,
+		cfg:          cfg,
+		appCfg:       appCfg,
+	}
+}
+
+// GetStatus returns the user's inactivity settings and timeline
+func (s *InactivityService) GetStatus(ctx context.Context, userID uuid.UUID) (*InactivityStatus, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	sw, err := s.switchRepo.GetByUserID(ctx, userID, s.cfg.DefaultDays)
+	if err != nil {
+		return nil, err
+	}
+	sw.LastLogin = user.LastLogin
+
+	status := &InactivityStatus{
+		InactivitySwitch: sw,
+		LastActivityAt:   lastActivity(sw),
+		GracePeriodDays:  int(s.cfg.GracePeriod.Hours() / 24),
+	}
+	status.RemindersFrom = status.LastActivityAt.AddDate(0, 0, sw.InactivityDays)
+	status.ReleaseAt = s.releaseAt(sw, status.RemindersFrom)
+
+	switch {
+	case sw.ReleasedAt != nil:
+		status.State = InactivityStateReleased
+	case !sw.Enabled:
+		status.State = InactivityStateDisabled
+	case sw.RemindersSent > 0:
+		status.State = InactivityStateReminding
+	default:
+		status.State = InactivityStateActive
+	}
+
+	return status, nil
+}
+
+// UpdateSettings changes the inactivity period or turns the switch off.
+// Changing settings counts as a check-in.
+func (s *InactivityService) UpdateSettings(ctx context.Context, userID uuid.UUID, enabled bool, inactivityDays int) (*InactivityStatus, error) {
+	if inactivityDays < MinInactivityDays || inactivityDays > MaxInactivityDays {
+		return nil, ErrInvalidInactivityPeriod
+	}
+
+	if _, err := s.switchRepo.GetByUserID(ctx, userID, s.cfg.DefaultDays); err != nil {
+		return nil, err
+	}
+
+	if err := s.switchRepo.UpdateSettings(ctx, userID, enabled, inactivityDays); err != nil {
+		return nil, fmt.Errorf("failed to update inactivity settings: %w", err)
+	}
+
+	return s.CheckIn(ctx, userID)
+}
+
+// CheckIn tells Sampatti the owner is still around. It restarts the
+// inactivity period and withdraws nominee access if it had been released.
+func (s *InactivityService) CheckIn(ctx context.Context, userID uuid.UUID) (*InactivityStatus, error) {
+	if _, err := s.switchRepo.GetByUserID(ctx, userID, s.cfg.DefaultDays); err != nil {
+		return nil, err
+	}
+
+	if err := s.switchRepo.CheckIn(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to check in: %w", err)
+	}
+
+	return s.GetStatus(ctx, userID)
+}
+
+// RunChecks sends due reminders and releases nominee access for owners whose
+// grace period has run out. It is safe to run from several replicas at once.
+func (s *InactivityService) RunChecks(ctx context.Context) error {
+	switches, err := s.switchRepo.ListMonitored(ctx, s.cfg.DefaultDays)
+	if err != nil {
+		return fmt.Errorf("failed to list inactivity switches: %w", err)
+	}
+
+	for i := range switches {
+		if err := s.check(ctx, &switches[i]); err != nil {
+			log.Printf("Inactivity check failed for user %s: %v", switches[i].UserID, err)
+		}
+	}
+
+	return nil
+}
+
+// Private methods
+
+func (s *InactivityService) check(ctx context.Context, sw *model.InactivitySwitch) error {
+	now := time.Now()
+	lastActive := lastActivity(sw)
+
+	// The owner signed in after access was released, so they're not gone
+	if sw.ReleasedAt != nil {
+		if lastActive.After(*sw.ReleasedAt) {
+			if err := s.switchRepo.ResetRelease(ctx, sw.UserID); err != nil {
+				return err
+			}
+			s.alert(ctx, sw.UserID, "High", "You signed in after your nominees were given access because of inactivity. Their access has been withdrawn and your inactivity period has restarted.")
+		}
+		return nil
+	}
+
+	if !sw.Enabled {
+		return nil
+	}
+
+	remindersFrom := lastActive.AddDate(0, 0, sw.InactivityDays)
+	if now.Before(remindersFrom) {
+		if sw.RemindersSent > 0 {
+			return s.switchRepo.ResetRelease(ctx, sw.UserID)
+		}
+		return nil
+	}
+
+	if sw.RemindersSent == len(reminderStages) && !now.Before(s.releaseAt(sw, remindersFrom)) {
+		return s.release(ctx, sw)
+	}
+
+	// Past the grace period this reaches the final stage, so the last
+	// reminder goes out before release even if earlier runs were missed
+	stage := 0
+	elapsed := now.Sub(remindersFrom)
+	for stage < len(reminderStages) && elapsed >= time.Duration(reminderStages[stage].at*float64(s.cfg.GracePeriod)) {
+		stage++
+	}
+
+	if stage <= sw.RemindersSent {
+		return nil
+	}
+
+	advanced, err := s.switchRepo.AdvanceReminders(ctx, sw.UserID, sw.RemindersSent, stage)
+	if err != nil || !advanced {
+		return err
+	}
+
+	sw.RemindersSent, sw.LastReminderAt = stage, &now
+	s.remind(ctx, sw, stage, s.releaseAt(sw, remindersFrom))
+	return nil
+}
+
+// releaseAt is the end of the grace period, pushed back when the final
+// reminder went out late so the owner always has time to respond to it
+func (s *InactivityService) releaseAt(sw *model.InactivitySwitch, remindersFrom time.Time) time.Time {
+	releaseAt := remindersFrom.Add(s.cfg.GracePeriod)
+
+	if sw.RemindersSent == len(reminderStages) && sw.LastReminderAt != nil {
+		finalNotice := time.Duration((1 - reminderStages[len(reminderStages)-1].at) * float64(s.cfg.GracePeriod))
+		if earliest := sw.LastReminderAt.Add(finalNotice); earliest.After(releaseAt) {
+			releaseAt = earliest
+		}
+	}
+
+	return releaseAt
+}
+
+func (s *InactivityService) remind(ctx context.Context, sw *model.InactivitySwitch, stage int, releaseAt time.Time) {
+	severity := reminderStages[stage-1].severity
+	deadline := releaseAt.Format("Jan 2, 2006")
+
+	s.alert(ctx, sw.UserID, severity, fmt.Sprintf("You haven't been active on Sampatti for %d days. Check in before %s or your nominees will be given access to your account.", sw.InactivityDays, deadline))
+
+	subject := "Please check in to Sampatti"
+	if stage == len(reminderStages) {
+		subject = "Final reminder: your nominees will soon be given access"
+	}
+
+	msg := MailMessage{
+		To:      sw.OwnerEmail,
+		Subject: subject,
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYou haven't signed in or checked in to Sampatti for %d days. If you don't by %s, the nominees you named will be given access to your account.\n\nSign in or check in here to stop this:\n\n%s\n\nIf you'd rather change how long Sampatti waits, you can do so from the same page.\n",
+			sw.OwnerName,
+			sw.InactivityDays,
+			deadline,
+			s.checkInURL(),
+		),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("Failed to send inactivity reminder to user %s: %v", sw.UserID, err)
+	}
+}
+
+func (s *InactivityService) release(ctx context.Context, sw *model.InactivitySwitch) error {
+	released, err := s.switchRepo.Release(ctx, sw.UserID)
+	if err != nil || !released {
+		return err
+	}
+
+	log.Printf("Released nominee access for user %s after %d days of inactivity", sw.UserID, sw.InactivityDays)
+
+	s.alert(ctx, sw.UserID, "Critical", "Your nominees have been given access to your account because you didn't check in. Sign in to withdraw their access.")
+
+	msg := MailMessage{
+		To:      sw.OwnerEmail,
+		Subject: "Your nominees have been given access to your account",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYou didn't check in to Sampatti within the grace period, so your nominees can now sign in to access your account.\n\nIf you're seeing this, sign in to withdraw their access:\n\n%s\n",
+			sw.OwnerName,
+			s.checkInURL(),
+		),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("Failed to send release notice to user %s: %v", sw.UserID, err)
+	}
+
+	nominees, err := s.nomineeRepo.GetByUserID(ctx, sw.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load nominees: %w", err)
+	}
+
+	for _, nominee := range nominees {
+		// Only nominees who accepted their invitation have a way to sign in
+		if nominee.Status != NomineeAccepted {
+			continue
+		}
+
+		msg := MailMessage{
+			To:      nominee.Email,
+			Subject: fmt.Sprintf("You can now access %s's Sampatti account", sw.OwnerName),
+			Body: fmt.Sprintf(
+				"Hi %s,\n\n%s named you as a nominee on Sampatti and hasn't been active for some time. You can now sign in as their nominee to view the information they shared with you:\n\n%s/emergency-access\n",
+				nominee.Name,
+				sw.OwnerName,
+				strings.TrimRight(s.appCfg.FrontendURL, "/"),
+			),
+		}
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send release notice to nominee %s: %v", nominee.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *InactivityService) alert(ctx context.Context, userID uuid.UUID, severity, message string) {
+	alert := &model.Alert{
+		UserID:         userID,
+		AlertType:      "Inactivity",
+		Severity:       severity,
+		Message:        message,
+		CreatedAt:      time.Now(),
+		ActionRequired: true,
+	}
+
+	if err := s.alertService.Create(ctx, alert); err != nil {
+		log.Printf("Failed to create inactivity alert for user %s: %v", userID, err)
+	}
+}
+
+func (s *InactivityService) checkInURL() string {
+	return strings.TrimRight(s.appCfg.FrontendURL, "/") + "/settings/inactivity"
+}
+
+// lastActivity is the later of the owner's last login and last check-in
+func lastActivity(sw *model.InactivitySwitch) time.Time {
+	if sw.LastLogin != nil && sw.LastLogin.After(sw.LastCheckInAt) {
+		return *sw.LastLogin
+	}
+	return sw.LastCheckInAt
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/config"
+)
+
+// MailMessage is a plain text email
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing email
+type Mailer interface {
+	Send(ctx context.Context, msg MailMessage) error
+}
+
+// NewMailer returns the mailer selected by cfg.Driver
+func NewMailer(cfg *config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail driver")
+		}
+		return &SMTPMailer{cfg: cfg}, nil
+	case "file", "":
+		if err := os.MkdirAll(cfg.OutputDir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create mail output directory: %w", err)
+		}
+		return &FileMailer{from: cfg.From, dir: cfg.OutputDir}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// SMTPMailer sends email through an SMTP relay
+type SMTPMailer struct {
+	cfg *config.MailConfig
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg MailMessage) error {
+	addr := net.JoinHostPort(m.cfg.SMTPHost, m.cfg.SMTPPort)
+
+	var auth smtp.Auth
+	if m.cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", m.cfg.SMTPUsername, m.cfg.SMTPPassword, m.cfg.SMTPHost)
+	}
+
+	if err := smtp.SendMail(addr, auth, envelopeAddress(m.cfg.From), []string{msg.To}, formatMessage(m.cfg.From, msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// FileMailer writes each message to a file and logs it, for local development
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg MailMessage) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405"), uuid.New().String())
+	path := filepath.Join(m.dir, name)
+
+	if err := os.WriteFile(path, formatMessage(m.from, msg), 0o600); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+
+	log.Printf("Mail to %s (%q) written to %s", msg.To, msg.Subject, path)
+	return nil
+}
+
+func formatMessage(from string, msg MailMessage) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// envelopeAddress extracts the bare address from "Name <address>"
+func envelopeAddress(from string) string {
+	if start := strings.LastIndex(from, "<"); start >= 0 {
+		if end := strings.LastIndex(from, ">"); end > start {
+			return from[start+1 : end]
+		}
+	}
+	return from
+}
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,6 +19,24 @@ import (
 var (
 	ErrNomineeNotFound = errors.New("nominee not found")
 	ErrNomineeExists   = errors.New("nominee already exists with this email")
+	// ErrOwnerRequired means the same email and password are a nominee for
+	// several owners, so the caller has to say which account they want
+	ErrOwnerRequired         = errors.New("you are a nominee for more than one account, choose which one to access")
+	ErrInvalidAccessDuration = fmt.Errorf("access duration must be between 1 and %d hours", MaxNomineeAccessHours)
+	ErrInvalidCursor         = errors.New("invalid page cursor")
+	ErrNomineeNotActive      = errors.New("nominee access is not active")
+)
+
+// How long a nominee sign-in lasts, unless the owner picks otherwise
+const (
+	DefaultNomineeAccessHours = 24
+	MaxNomineeAccessHours     = 720
+)
+
+// Page sizes of the owner's nominee access log
+const (
+	DefaultAccessLogPageSize = 50
+	MaxAccessLogPageSize     = 200
 )
 
 type NomineeService struct {
@@ -22,18 +44,45 @@ type NomineeService struct {
 	userRepo     *postgres.UserRepository
 	passwordUtil *util.PasswordUtil
 	authService  *AuthService
+	alertService *AlertService
+	limiter      *AttemptLimiter
+	emergency    *EmergencyAccessService
+	quorum       *QuorumService
+	sessions     *NomineeSessionService
+	profiles     *PermissionProfileService
+	notifier     *NomineeAccessNotifier
+	audit        *AuditService
+	accessLog    *NomineeAccessLogger
 }
 
 func NewNomineeService(
 	nomineeRepo *postgres.NomineeRepository,
 	userRepo *postgres.UserRepository,
 	authService *AuthService,
+	alertService *AlertService,
+	limiter *AttemptLimiter,
+	emergency *EmergencyAccessService,
+	quorum *QuorumService,
+	sessions *NomineeSessionService,
+	profiles *PermissionProfileService,
+	notifier *NomineeAccessNotifier,
+	audit *AuditService,
+	accessLog *NomineeAccessLogger,
 ) *NomineeService {
 	return &NomineeService{
 		nomineeRepo:  nomineeRepo,
 		userRepo:     userRepo,
 		passwordUtil: util.NewPasswordUtil(10),
 		authService:  authService,
+		alertService: alertService,
+		limiter:      limiter,
+		emergency:    emergency,
+		quorum:       quorum,
+		sessions:     sessions,
+		profiles:     profiles,
+		notifier:     notifier,
+		audit:        audit,
+		accessLog:    accessLog,
 	}
 }
 
@@ -43,20 +92,26 @@ func (s *NomineeService) Create(ctx context.Context, nominee *model.Nominee) err
 		return ErrNomineeExists
 	}
 
-	accessCode := util.GenerateRandomString(8)
-	hashedCode, err := s.passwordUtil.HashPassword(accessCode)
-	if err != nil {
-		return fmt.Errorf("failed to hash access code: %w", err)
+	if nominee.AccessDurationHours == 0 {
+		nominee.AccessDurationHours = DefaultNomineeAccessHours
+	}
+	if err := validateAccessDuration(nominee.AccessDurationHours); err != nil {
+		return err
+	}
+
+	if nominee.PermissionProfileID != nil {
+		if err := s.profiles.CheckOwner(ctx, nominee.UserID, *nominee.PermissionProfileID); err != nil {
+			return err
+		}
 	}
 
-	nominee.EmergencyAccessCode = hashedCode
-	nominee.Status = "Pending"
+	nominee.Status = NomineeInvited
 
 	if err := s.nomineeRepo.Create(ctx, nominee); err != nil {
 		return err
 	}
 
-	nominee.EmergencyAccessCode = accessCode
+	s.audit.RecordUserAction(ctx, nominee.UserID, "nominee.created", "nominee", nominee.ID, nominee.Name)
 	return nil
 }
 
@@ -87,13 +142,30 @@ func (s *NomineeService) Update(ctx context.Context, nominee *model.Nominee, use
 		return ErrUnauthorized
 	}
 
+	if nominee.AccessDurationHours == 0 {
+		nominee.AccessDurationHours = existingNominee.AccessDurationHours
+	}
+	if err := validateAccessDuration(nominee.AccessDurationHours); err != nil {
+		return err
+	}
+
+	if nominee.PermissionProfileID != nil {
+		if err := s.profiles.CheckOwner(ctx, userID, *nominee.PermissionProfileID); err != nil {
+			return err
+		}
+	}
+
 	nominee.UserID = existingNominee.UserID
 	nominee.Email = existingNominee.Email
 	nominee.Status = existingNominee.Status
-	nominee.EmergencyAccessCode = existingNominee.EmergencyAccessCode
 	nominee.LastAccessDate = existingNominee.LastAccessDate
 
-	return s.nomineeRepo.Update(ctx, nominee)
+	if err := s.nomineeRepo.Update(ctx, nominee); err != nil {
+		return err
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "nominee.updated", "nominee", nominee.ID, nominee.Name)
+	return nil
 }
 
 func (s *NomineeService) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
@@ -106,178 +178,354 @@ func (s *NomineeService) Delete(ctx context.Context, id uuid.UUID, userID uuid.U
 		return ErrUnauthorized
 	}
 
-	return s.nomineeRepo.Delete(ctx, id)
+	if err := s.nomineeRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "nominee.deleted", "nominee", id, nominee.Name)
+	return nil
 }
 
-func (s *NomineeService) GenerateNewAccessCode(ctx context.Context, nomineeID uuid.UUID, userID uuid.UUID) (string, error) {
+func (s *NomineeService) RevokeNominee(ctx context.Context, nomineeID uuid.UUID, userID uuid.UUID) error {
 	nominee, err := s.nomineeRepo.GetByID(ctx, nomineeID)
 	if err != nil {
-		return "", ErrNomineeNotFound
+		return ErrNomineeNotFound
 	}
 
 	if nominee.UserID != userID {
-		return "", ErrUnauthorized
+		return ErrUnauthorized
 	}
 
-	accessCode := util.GenerateRandomString(8)
-	hashedCode, err := s.passwordUtil.HashPassword(accessCode)
-	if err != nil {
-		return "", fmt.Errorf("failed to hash access code: %w", err)
+	if err := s.nomineeRepo.Revoke(ctx, nomineeID); err != nil {
+		return err
 	}
 
-	if err := s.nomineeRepo.UpdateEmergencyAccessCode(ctx, nomineeID, hashedCode); err != nil {
-		return "", fmt.Errorf("failed to update access code: %w", err)
+	s.audit.RecordUserAction(ctx, userID, "nominee.revoked", "nominee", nomineeID, nominee.Name)
+
+	// Tokens already handed out must stop working now, not when they expire
+	return s.sessions.RevokeAllForNominee(ctx, nomineeID)
+}
+
+// ListAccessLogs returns a page of the owner's nominee access log matching
+// the filter, and the cursor for the next page, which is empty on the last
+func (s *NomineeService) ListAccessLogs(ctx context.Context, filter model.NomineeAccessLogFilter, cursor string) ([]model.NomineeAccessLog, string, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAccessLogPageSize
+	}
+	if filter.Limit > MaxAccessLogPageSize {
+		filter.Limit = MaxAccessLogPageSize
 	}
 
-	if err := s.ActivateNominee(ctx, nomineeID, userID); err != nil {
-		fmt.Printf("Warning: Failed to activate nominee: %v\n", err)
+	if cursor != "" {
+		date, id, err := decodeAccessLogCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.BeforeDate, filter.BeforeID = &date, &id
 	}
 
-	return accessCode, nil
-}
+	// One extra row tells whether there is another page
+	pageSize := filter.Limit
+	filter.Limit++
 
-func (s *NomineeService) ActivateNominee(ctx context.Context, nomineeID uuid.UUID, userID uuid.UUID) error {
-	nominee, err := s.nomineeRepo.GetByID(ctx, nomineeID)
+	logs, err := s.nomineeRepo.ListAccessLogs(ctx, filter)
 	if err != nil {
-		return ErrNomineeNotFound
+		return nil, "", err
 	}
 
-	if nominee.UserID != userID {
-		return ErrUnauthorized
+	if len(logs) <= pageSize {
+		return logs, "", nil
 	}
 
-	return s.nomineeRepo.UpdateStatus(ctx, nomineeID, "Active")
+	logs = logs[:pageSize]
+	last := logs[pageSize-1]
+	return logs, encodeAccessLogCursor(last.Date, last.ID), nil
 }
 
-func (s *NomineeService) RevokeNominee(ctx context.Context, nomineeID uuid.UUID, userID uuid.UUID) error {
-	nominee, err := s.nomineeRepo.GetByID(ctx, nomineeID)
+// ExportAccessLogs returns every log in the owner's nominee access log that
+// matches the filter
+func (s *NomineeService) ExportAccessLogs(ctx context.Context, filter model.NomineeAccessLogFilter) ([]model.NomineeAccessLog, error) {
+	filter.Limit = 0
+	filter.BeforeDate, filter.BeforeID = nil, nil
+
+	return s.nomineeRepo.ListAccessLogs(ctx, filter)
+}
+
+// VerifyLinkedAccess admits the signed-in user as the nominee they accepted
+// an invitation as. Their session already proves who they are, so there is
+// no secret to check here.
+func (s *NomineeService) VerifyLinkedAccess(ctx context.Context, linkedUserID, ownerID uuid.UUID, client ClientInfo) (*model.Nominee, error) {
+	nominees, err := s.nomineeRepo.GetByLinkedUserID(ctx, linkedUserID)
 	if err != nil {
-		return ErrNomineeNotFound
+		return nil, fmt.Errorf("failed to load nominees: %w", err)
 	}
 
-	if nominee.UserID != userID {
-		return ErrUnauthorized
+	var nominee *model.Nominee
+	for i := range nominees {
+		if nominees[i].UserID == ownerID {
+			nominee = &nominees[i]
+			break
+		}
+	}
+
+	if nominee == nil {
+		return nil, ErrNomineeNotFound
 	}
 
-	return s.nomineeRepo.UpdateStatus(ctx, nomineeID, "Revoked")
+	if err := s.admit(ctx, nominee, client); err != nil {
+		return nil, err
+	}
+
+	s.accessLog.Record(ctx, nominee.ID, "Signed in with linked account", client)
+	return nominee, nil
 }
 
-func (s *NomineeService) GetAccessLogs(ctx context.Context, userID uuid.UUID) ([]model.NomineeAccessLog, []model.Nominee, error) {
-	nominees, err := s.nomineeRepo.GetByUserID(ctx, userID)
+func (s *NomineeService) GetUsersForNominee(ctx context.Context, userID uuid.UUID, nomineeEmail string) ([]model.User, error) {
+	nominees, err := s.nomineeRepo.GetByNomineeEmail(ctx, nomineeEmail)
 	if err != nil {
-		return nil, nil, err
+		return nil, fmt.Errorf("failed to get nominees: %w", err)
 	}
 
-	allLogs := make([]model.NomineeAccessLog, 0)
+	// Nominees who accepted with another account are found through the link
+	linked, err := s.nomineeRepo.GetByLinkedUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nominees: %w", err)
+	}
+	nominees = append(nominees, linked...)
+
+	users := make([]model.User, 0, len(nominees))
+	seen := make(map[uuid.UUID]bool, len(nominees))
 	for _, nominee := range nominees {
-		logs, err := s.nomineeRepo.GetAccessLogs(ctx, nominee.ID)
+		if seen[nominee.UserID] {
+			continue
+		}
+		seen[nominee.UserID] = true
+
+		user, err := s.userRepo.GetByID(ctx, nominee.UserID)
 		if err != nil {
 			continue
 		}
-		allLogs = append(allLogs, logs...)
+		user.PasswordHash = ""
+		users = append(users, *user)
 	}
 
-	return allLogs, nominees, nil
+	return users, nil
 }
 
-func (s *NomineeService) VerifyAccessCode(ctx context.Context, email string, userID uuid.UUID, accessCode string) (bool, *model.Nominee, error) {
-	nominee, err := s.nomineeRepo.GetByEmailAndUserID(ctx, email, userID)
+func (s *NomineeService) LogNomineeAccess(ctx context.Context, log *model.NomineeAccessLog) error {
+	return s.accessLog.Log(ctx, log)
+}
+
+// VerifyNomineeAccess signs a nominee in with the password they set when
+// accepting their invitation. ownerID picks the account when the nominee
+// was named by more than one owner and may otherwise be nil.
+func (s *NomineeService) VerifyNomineeAccess(ctx context.Context, email, password string, ownerID *uuid.UUID, client ClientInfo) (*model.Nominee, *model.User, error) {
+	scope := AttemptScope{Action: "emergency_access", Account: email, IPAddress: client.IPAddress}
+	if err := s.limiter.Check(ctx, scope); err != nil {
+		return nil, nil, err
+	}
+
+	candidates, err := s.nomineeRepo.GetByNomineeEmail(ctx, email)
 	if err != nil {
-		return false, nil, ErrNomineeNotFound
+		return nil, nil, fmt.Errorf("failed to load nominees: %w", err)
 	}
 
-	if nominee.EmergencyAccessCode == "" {
-		return false, nil, errors.New("no emergency access code has been set")
+	matches := make([]model.Nominee, 0, 1)
+	for _, candidate := range candidates {
+		if candidate.PasswordHash == "" || (ownerID != nil && candidate.UserID != *ownerID) {
+			continue
+		}
+		if s.passwordUtil.CheckPasswordHash(password, candidate.PasswordHash) {
+			matches = append(matches, candidate)
+		}
 	}
 
-	if !s.passwordUtil.CheckPasswordHash(accessCode, nominee.EmergencyAccessCode) {
-		return false, nil, errors.New("invalid access code")
+	if len(matches) == 0 {
+		var nominee *model.Nominee
+		if len(candidates) == 1 {
+			nominee = &candidates[0]
+			scope.UserID = &nominee.UserID
+		}
+		s.recordLoginFailure(ctx, scope, nominee)
+		return nil, nil, ErrInvalidCredentials
 	}
 
-	log := &model.NomineeAccessLog{
-		NomineeID: nominee.ID,
-		Date:      time.Now(),
-		Action:    "Verified emergency access code",
+	if len(matches) > 1 {
+		return nil, nil, ErrOwnerRequired
 	}
 
-	if err := s.nomineeRepo.LogAccess(ctx, log); err != nil {
-		fmt.Printf("Warning: Failed to log nominee access: %v\n", err)
+	nominee := &matches[0]
+	scope.UserID = &nominee.UserID
+
+	if err := s.limiter.RecordSuccess(ctx, scope); err != nil {
+		log.Printf("Failed to reset access attempts: %v", err)
 	}
 
-	if nominee.Status == "Pending" {
-		if err := s.nomineeRepo.UpdateStatus(ctx, nominee.ID, "Active"); err != nil {
-			fmt.Printf("Warning: Failed to activate nominee: %v\n", err)
-		} else {
-			nominee.Status = "Active"
-		}
+	if err := s.admit(ctx, nominee, client); err != nil {
+		return nil, nil, err
 	}
 
-	return true, nominee, nil
+	user, err := s.userRepo.GetByID(ctx, nominee.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.accessLog.Record(ctx, nominee.ID, "Signed in with nominee password", client)
+	return nominee, user, nil
 }
 
-func (s *NomineeService) GetUsersForNominee(ctx context.Context, nomineeEmail string) ([]model.User, error) {
-	nominees, err := s.nomineeRepo.GetByNomineeEmail(ctx, nomineeEmail)
+// StartSession signs an admitted nominee in and returns their access token.
+// The owner is told straight away.
+func (s *NomineeService) StartSession(ctx context.Context, nominee *model.Nominee, client ClientInfo) (string, *model.NomineeSession, error) {
+	token, session, err := s.sessions.Start(ctx, nominee, client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get nominees: %w", err)
-	}
+		return "", nil, err
+	}
+
+	s.notifier.Notify(ctx, nominee.ID, "signed in", client)
+	s.audit.Record(ctx, AuditEvent{
+		OwnerID:    nominee.UserID,
+		ActorType:  AuditActorNominee,
+		ActorID:    &nominee.ID,
+		Action:     "nominee.login",
+		EntityType: "nominee",
+		EntityID:   &nominee.ID,
+		Summary:    fmt.Sprintf("%s signed in with %s access", nominee.Name, nominee.AccessLevel),
+		IPAddress:  client.IPAddress,
+	})
+	return token, session, nil
+}
 
-	users := make([]model.User, 0, len(nominees))
-	for _, nominee := range nominees {
-		user, err := s.userRepo.GetByID(ctx, nominee.UserID)
-		if err != nil {
-			continue
-		}
-		user.PasswordHash = ""
-		users = append(users, *user)
-	}
+// NotifyAccess tells the owner that their nominee just did action
+func (s *NomineeService) NotifyAccess(ctx context.Context, nomineeID uuid.UUID, action string, client ClientInfo) {
+	s.notifier.Notify(ctx, nomineeID, action, client)
+}
 
-	return users, nil
+// ListSessions returns the live sessions of the owner's nominees
+func (s *NomineeService) ListSessions(ctx context.Context, ownerID uuid.UUID) ([]model.NomineeSession, error) {
+	return s.sessions.ListForOwner(ctx, ownerID)
 }
 
-func (s *NomineeService) LogNomineeAccess(ctx context.Context, log *model.NomineeAccessLog) error {
-	if log.Date.IsZero() {
-		log.Date = time.Now()
+// RevokeSession ends one nominee session on the owner's account
+func (s *NomineeService) RevokeSession(ctx context.Context, ownerID, sessionID uuid.UUID) error {
+	return s.sessions.Revoke(ctx, ownerID, sessionID)
+}
+
+// QuorumStatus returns the owner's quorum progress, or nil without a policy
+func (s *NomineeService) QuorumStatus(ctx context.Context, ownerID uuid.UUID) (*QuorumStatus, error) {
+	return s.quorum.GetStatus(ctx, ownerID)
+}
+
+// admit decides whether a signed-in nominee gets in. Only accepted nominees
+// who aren't suspended go further: their
+// attempt counts towards the owner's quorum, access waits for the inactivity
+// switch or an emergency access request, and Full access is held back until
+// the quorum is met. nominee.AccessLevel is set to the level granted.
+func (s *NomineeService) admit(ctx context.Context, nominee *model.Nominee, client ClientInfo) error {
+	// None of these may count towards the quorum
+	if nominee.Status == NomineeRevoked {
+		return ErrNomineeRevoked
+	}
+	if nominee.Status != NomineeAccepted {
+		return ErrNomineeNotActive
+	}
+	if nominee.SuspendedAt != nil {
+		return ErrNomineeSuspended
+	}
+
+	quorum, err := s.quorum.Approve(ctx, nominee, client)
+	if err != nil {
+		return err
+	}
+
+	if err := s.emergency.Open(ctx, nominee, client); err != nil {
+		return err
 	}
 
-	return s.nomineeRepo.LogAccess(ctx, log)
+	nominee.AccessLevel = s.quorum.EffectiveAccessLevel(ctx, nominee, quorum)
+	return nil
 }
 
-func (s *NomineeService) VerifyNomineeAccess(ctx context.Context, email, accessCode string) (*model.Nominee, *model.User, error) {
-	nominee, err := s.nomineeRepo.GetByEmail(ctx, email)
+// recordLoginFailure counts a wrong nominee password and, when guessing
+// escalates to backoff or lockout, alerts the owner who named the nominee
+func (s *NomineeService) recordLoginFailure(ctx context.Context, scope AttemptScope, nominee *model.Nominee) {
+	result, err := s.limiter.RecordFailure(ctx, scope)
 	if err != nil {
-		return nil, nil, ErrNomineeNotFound
+		log.Printf("Failed to record access attempt: %v", err)
+		return
 	}
 
-	if nominee.EmergencyAccessCode == "" {
-		return nil, nil, errors.New("no emergency access code has been set")
+	if nominee == nil {
+		return
 	}
 
-	if !s.passwordUtil.CheckPasswordHash(accessCode, nominee.EmergencyAccessCode) {
-		return nil, nil, errors.New("invalid access code")
+	s.audit.Record(ctx, AuditEvent{
+		OwnerID:    nominee.UserID,
+		ActorType:  AuditActorNominee,
+		ActorID:    &nominee.ID,
+		Action:     "nominee.login_failed",
+		EntityType: "nominee",
+		EntityID:   &nominee.ID,
+		Summary:    fmt.Sprintf("Wrong password for %s", nominee.Name),
+		IPAddress:  scope.IPAddress,
+	})
+
+	if !result.Escalated {
+		return
 	}
 
-	user, err := s.userRepo.GetByID(ctx, nominee.UserID)
+	message := fmt.Sprintf("Someone has entered a wrong password for your nominee %s %d times. Further attempts are being slowed down.", nominee.Name, result.Failures)
+	if result.LockedOut {
+		message = fmt.Sprintf("Emergency access for your nominee %s has been temporarily locked after %d wrong passwords. If this wasn't your nominee, consider revoking their access.", nominee.Name, result.Failures)
+	}
+
+	alert := &model.Alert{
+		UserID:         nominee.UserID,
+		AlertType:      "Security",
+		Severity:       "High",
+		Message:        message,
+		CreatedAt:      time.Now(),
+		ActionRequired: true,
+	}
+
+	if err := s.alertService.Create(ctx, alert); err != nil {
+		log.Printf("Failed to alert owner about password guessing: %v", err)
+	}
+}
+
+func validateAccessDuration(hours int) error {
+	if hours < 1 || hours > MaxNomineeAccessHours {
+		return ErrInvalidAccessDuration
+	}
+	return nil
+}
+
+// encodeAccessLogCursor points after the given log, in newest first order
+func encodeAccessLogCursor(date time.Time, id uuid.UUID) string {
+	raw := fmt.Sprintf("%d:%s", date.UnixNano(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeAccessLogCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		return nil, nil, err
+		return time.Time{}, uuid.UUID{}, ErrInvalidCursor
 	}
 
-	log := &model.NomineeAccessLog{
-		NomineeID: nominee.ID,
-		Date:      time.Now(),
-		Action:    "Emergency Access Verification",
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return time.Time{}, uuid.UUID{}, ErrInvalidCursor
 	}
 
-	if err := s.nomineeRepo.LogAccess(ctx, log); err != nil {
-		fmt.Printf("Warning: Failed to log nominee access: %v\n", err)
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, ErrInvalidCursor
 	}
 
-	if nominee.Status == "Pending" {
-		if err := s.nomineeRepo.UpdateStatus(ctx, nominee.ID, "Active"); err != nil {
-			fmt.Printf("Warning: Failed to activate nominee: %v\n", err)
-		} else {
-			nominee.Status = "Active"
-		}
+	logID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.UUID{}, ErrInvalidCursor
 	}
 
-	return nominee, user, nil
+	return time.Unix(0, unixNano), logID, nil
 }
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+)
+
+// NomineeAccessLogger writes the nominee access log. Every entry goes through
+// it so accesses made from a device are scored for anomalies as they happen.
+type NomineeAccessLogger struct {
+	nomineeRepo *postgres.NomineeRepository
+	anomalies   *NomineeAnomalyService
+}
+
+func NewNomineeAccessLogger(nomineeRepo *postgres.NomineeRepository, anomalies *NomineeAnomalyService) *NomineeAccessLogger {
+	return &NomineeAccessLogger{
+		nomineeRepo: nomineeRepo,
+		anomalies:   anomalies,
+	}
+}
+
+// Log appends accessLog and scores it
+func (l *NomineeAccessLogger) Log(ctx context.Context, accessLog *model.NomineeAccessLog) error {
+	if accessLog.Date.IsZero() {
+		accessLog.Date = time.Now()
+	}
+
+	if err := l.nomineeRepo.LogAccess(ctx, accessLog); err != nil {
+		return err
+	}
+
+	l.anomalies.Inspect(ctx, accessLog)
+	return nil
+}
+
+// Record logs that the nominee did action from client. Entries the system
+// writes itself pass an empty ClientInfo. Failures are logged rather than
+// returned so they never block the nominee.
+func (l *NomineeAccessLogger) Record(ctx context.Context, nomineeID uuid.UUID, action string, client ClientInfo) {
+	accessLog := &model.NomineeAccessLog{
+		NomineeID:  nomineeID,
+		Action:     action,
+		IPAddress:  client.IPAddress,
+		DeviceInfo: client.UserAgent,
+	}
+
+	if err := l.Log(ctx, accessLog); err != nil {
+		log.Printf("Failed to log access by nominee %s: %v", nomineeID, err)
+	}
+}
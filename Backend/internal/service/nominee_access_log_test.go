@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/repository/postgres"
+)
+
+var nomineeColumns = []string{
+	"id", "user_id", "name", "email", "phone_number", "relationship",
+	"access_level", "created_at", "updated_at", "status",
+	"password_hash", "linked_user_id", "invited_at", "responded_at",
+	"access_duration_hours", "last_access_date", "access_eligible_at",
+	"permission_profile_id", "suspended_at",
+}
+
+const testUserAgent = "Mozilla/5.0 (X11; Linux x86_64)"
+
+// newTestNomineeService returns a service whose nominee has the given status,
+// has been released access and signed in before from knownIP with testUserAgent
+func newTestNomineeService(t *testing.T, status, knownIP string) (*NomineeService, *fakeDB, uuid.UUID, uuid.UUID) {
+	t.Helper()
+	db, fake := newFakeDB(t)
+
+	nomineeID, ownerID, linkedUserID := uuid.New(), uuid.New(), uuid.New()
+	now := time.Now()
+	fake.on("FROM nominees", nomineeColumns, []driver.Value{
+		nomineeID.String(), ownerID.String(), "Asha", "asha@example.com", "", "Sister",
+		"Limited", now, now, status,
+		"", linkedUserID.String(), now, now,
+		DefaultNomineeAccessHours, now, now,
+		nil, nil,
+	})
+	fake.on("SELECT DISTINCT ip_address", []string{"ip_address"}, []driver.Value{knownIP})
+	fake.on("SELECT DISTINCT device_info", []string{"device_info"}, []driver.Value{testUserAgent})
+	fake.on("AND id <> $2", []string{"count"}, []driver.Value{int64(3)})
+	fake.on("AND date >= $2", []string{"count"}, []driver.Value{int64(1)})
+
+	nomineeRepo := postgres.NewNomineeRepository(db)
+	alerts := NewAlertService(postgres.NewAlertRepository(db))
+	cfg := &config.AnomalyConfig{AlertScore: 25, BurstWindow: time.Hour}
+
+	anomalies := NewNomineeAnomalyService(postgres.NewNomineeAnomalyRepository(db), nomineeRepo, alerts, nil, nil, cfg)
+	accessLog := NewNomineeAccessLogger(nomineeRepo, anomalies)
+	quorum := NewQuorumService(postgres.NewQuorumRepository(db), nomineeRepo, alerts, accessLog)
+	emergency := NewEmergencyAccessService(postgres.NewEmergencyAccessRepository(db), nomineeRepo, nil, alerts, nil, nil, nil, nil, accessLog)
+	service := NewNomineeService(nomineeRepo, nil, nil, alerts, nil, emergency, quorum, nil, nil, nil, nil, accessLog)
+
+	return service, fake, linkedUserID, ownerID
+}
+
+func TestSignInFromNewIPRangeAlertsOwner(t *testing.T) {
+	service, fake, linkedUserID, ownerID := newTestNomineeService(t, NomineeAccepted, "203.0.113.7")
+
+	client := ClientInfo{IPAddress: "198.51.100.20", UserAgent: testUserAgent}
+	if _, err := service.VerifyLinkedAccess(context.Background(), linkedUserID, ownerID, client); err != nil {
+		t.Fatalf("VerifyLinkedAccess: %v", err)
+	}
+
+	logs := fake.executed("INSERT INTO nominee_access_logs")
+	if len(logs) != 1 || logs[0].Args[4] != client.IPAddress || logs[0].Args[5] != client.UserAgent {
+		t.Fatalf("access log entries = %v", logs)
+	}
+
+	alerts := fake.executed("INSERT INTO alerts")
+	if len(alerts) != 1 {
+		t.Fatalf("%d alerts raised, want 1", len(alerts))
+	}
+	// Arguments follow the alerts columns; the message is the sixth
+	if message, _ := alerts[0].Args[5].(string); !strings.Contains(message, anomalyDescriptions[AnomalyNewIPRange]) {
+		t.Fatalf("alert message %q doesn't mention the new network", message)
+	}
+}
+
+func TestSignInFromKnownIPRangeDoesNotAlert(t *testing.T) {
+	service, fake, linkedUserID, ownerID := newTestNomineeService(t, NomineeAccepted, "203.0.113.7")
+
+	client := ClientInfo{IPAddress: "203.0.113.50", UserAgent: testUserAgent}
+	if _, err := service.VerifyLinkedAccess(context.Background(), linkedUserID, ownerID, client); err != nil {
+		t.Fatalf("VerifyLinkedAccess: %v", err)
+	}
+
+	if alerts := fake.executed("INSERT INTO alerts"); len(alerts) != 0 {
+		t.Fatalf("%d alerts raised for a known network", len(alerts))
+	}
+}
+
+func TestSignInRejectsNomineesWhoHaventAccepted(t *testing.T) {
+	for _, status := range []string{NomineeInvited, NomineeDeclined} {
+		service, fake, linkedUserID, ownerID := newTestNomineeService(t, status, "203.0.113.7")
+
+		client := ClientInfo{IPAddress: "198.51.100.20", UserAgent: testUserAgent}
+		if _, err := service.VerifyLinkedAccess(context.Background(), linkedUserID, ownerID, client); !errors.Is(err, ErrNomineeNotActive) {
+			t.Fatalf("%s nominee: VerifyLinkedAccess error = %v, want ErrNomineeNotActive", status, err)
+		}
+
+		// Turned away before counting towards the quorum or opening a request
+		if statements := fake.executed("nominee_quorum"); len(statements) != 0 {
+			t.Fatalf("%s nominee reached the quorum: %v", status, statements)
+		}
+		if statements := fake.executed("INSERT INTO"); len(statements) != 0 {
+			t.Fatalf("%s nominee caused writes: %v", status, statements)
+		}
+	}
+}
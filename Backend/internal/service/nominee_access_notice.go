@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var ErrInvalidRevocationLink = errors.New("revocation link is invalid or has expired")
+
+// NomineeAccessNotifier tells owners as soon as a nominee uses their access
+// and lets them revoke the nominee from the notice without signing in
+type NomineeAccessNotifier struct {
+	revocationRepo *postgres.NomineeRevocationRepository
+	nomineeRepo    *postgres.NomineeRepository
+	userRepo       *postgres.UserRepository
+	alertService   *AlertService
+	mailer         Mailer
+	webhooks       *WebhookService
+	sessions       *NomineeSessionService
+	appCfg         *config.AppConfig
+	audit          *AuditService
+}
+
+func NewNomineeAccessNotifier(
+	revocationRepo *postgres.NomineeRevocationRepository,
+	nomineeRepo *postgres.NomineeRepository,
+	userRepo *postgres.UserRepository,
+	alertService *AlertService,
+	mailer Mailer,
+	webhooks *WebhookService,
+	sessions *NomineeSessionService,
+	appCfg *config.AppConfig,
+	audit *AuditService,
+) *NomineeAccessNotifier {
+	return &NomineeAccessNotifier{
+		revocationRepo: revocationRepo,
+		nomineeRepo:    nomineeRepo,
+		userRepo:       userRepo,
+		alertService:   alertService,
+		mailer:         mailer,
+		webhooks:       webhooks,
+		sessions:       sessions,
+		appCfg:         appCfg,
+		audit:          audit,
+	}
+}
+
+// Notify alerts the owner in the app, by email and on their webhook that a
+// nominee just did action, e.g. "signed in". Failures are logged rather
+// than returned so they never block the nominee.
+func (n *NomineeAccessNotifier) Notify(ctx context.Context, nomineeID uuid.UUID, action string, client ClientInfo) {
+	nominee, err := n.nomineeRepo.GetByID(ctx, nomineeID)
+	if err != nil {
+		log.Printf("Failed to load nominee %s for access notice: %v", nomineeID, err)
+		return
+	}
+
+	alert := &model.Alert{
+		UserID:    nominee.UserID,
+		AlertType: "Nominee",
+		Severity:  "Medium",
+		Message:   fmt.Sprintf("Your nominee %s %s from %s. If you didn't expect this, revoke them from your nominees.", nominee.Name, action, client.IPAddress),
+		CreatedAt: time.Now(),
+	}
+	if err := n.alertService.Create(ctx, alert); err != nil {
+		log.Printf("Failed to create access alert for user %s: %v", nominee.UserID, err)
+	}
+
+	revokeURL, err := n.revocationLink(ctx, nominee.ID)
+	if err != nil {
+		log.Printf("Failed to create revocation link for nominee %s: %v", nominee.ID, err)
+		return
+	}
+
+	owner, err := n.userRepo.GetByID(ctx, nominee.UserID)
+	if err != nil {
+		log.Printf("Failed to load owner %s for access notice: %v", nominee.UserID, err)
+		return
+	}
+
+	msg := MailMessage{
+		To:      owner.Email,
+		Subject: fmt.Sprintf("%s just accessed your Sampatti account", nominee.Name),
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour nominee %s %s at %s.\n\nIP address: %s\nDevice: %s\n\nIf you didn't expect this, revoke their access straight away with the link below. It works without signing in, ends all of their sessions and clears their password:\n\n%s\n\nThe link expires in %d days.\n",
+			owner.Name,
+			nominee.Name,
+			action,
+			time.Now().Format(time.RFC1123),
+			client.IPAddress,
+			client.UserAgent,
+			revokeURL,
+			int(n.appCfg.NomineeRevocationExpiry.Hours()/24),
+		),
+	}
+	if err := n.mailer.Send(ctx, msg); err != nil {
+		log.Printf("Failed to send access notice to user %s: %v", owner.ID, err)
+	}
+
+	data := map[string]interface{}{
+		"nominee_id":   nominee.ID,
+		"nominee_name": nominee.Name,
+		"action":       action,
+		"ip_address":   client.IPAddress,
+		"user_agent":   client.UserAgent,
+		"revoke_url":   revokeURL,
+	}
+	if err := n.webhooks.Deliver(ctx, owner.ID, "nominee.access", data); err != nil {
+		log.Printf("Failed to deliver access webhook for user %s: %v", owner.ID, err)
+	}
+}
+
+// Revoke redeems a revocation link: the nominee is revoked, their password
+// cleared and every session they hold ended. Nominees have had no access
+// code since they moved to invitations and passwords, so clearing
+// password_hash and setting the status to Revoked stands in for rotating
+// the access code; the nominee can't sign in again unless the owner
+// removes and adds them again.
+func (n *NomineeAccessNotifier) Revoke(ctx context.Context, rawToken string) (*model.Nominee, error) {
+	nomineeID, err := n.revocationRepo.Revoke(ctx, util.HashToken(rawToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidRevocationLink
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke nominee: %w", err)
+	}
+
+	if err := n.sessions.RevokeAllForNominee(ctx, nomineeID); err != nil {
+		return nil, fmt.Errorf("failed to end nominee sessions: %w", err)
+	}
+
+	nominee, err := n.nomineeRepo.GetByID(ctx, nomineeID)
+	if err != nil {
+		return nil, ErrNomineeNotFound
+	}
+
+	alert := &model.Alert{
+		UserID:    nominee.UserID,
+		AlertType: "Nominee",
+		Severity:  "High",
+		Message:   fmt.Sprintf("%s's nominee access was revoked from an access notice link. Remove and add them again if this was a mistake.", nominee.Name),
+		CreatedAt: time.Now(),
+	}
+	if err := n.alertService.Create(ctx, alert); err != nil {
+		log.Printf("Failed to create revocation alert for user %s: %v", nominee.UserID, err)
+	}
+
+	// Whoever holds the link acts for the owner, who needn't be signed in
+	n.audit.Record(ctx, AuditEvent{
+		OwnerID:    nominee.UserID,
+		ActorType:  AuditActorUser,
+		ActorID:    &nominee.UserID,
+		Action:     "nominee.revoked",
+		EntityType: "nominee",
+		EntityID:   &nominee.ID,
+		Summary:    nominee.Name + ": revoked from an access notice link",
+	})
+
+	return nominee, nil
+}
+
+// Private methods
+
+func (n *NomineeAccessNotifier) revocationLink(ctx context.Context, nomineeID uuid.UUID) (string, error) {
+	rawToken, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	token := &model.NomineeRevocationToken{
+		NomineeID: nomineeID,
+		TokenHash: util.HashToken(rawToken),
+		ExpiresAt: time.Now().Add(n.appCfg.NomineeRevocationExpiry),
+	}
+	if err := n.revocationRepo.Create(ctx, token); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/nominee-revoke?token=%s", strings.TrimRight(n.appCfg.FrontendURL, "/"), url.QueryEscape(rawToken)), nil
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+)
+
+var (
+	ErrInvalidAccessRules  = errors.New("allowed hours must both be set, between 0 and 23 and different, with a valid timezone")
+	ErrNomineeNotSuspended = errors.New("nominee is not suspended")
+)
+
+// Reasons an access is suspicious, with the score each adds
+const (
+	AnomalyFirstAccess  = "first_access"
+	AnomalyNewDevice    = "new_device"
+	AnomalyNewIPRange   = "new_ip_range"
+	AnomalyBurst        = "burst"
+	AnomalyOutsideHours = "outside_hours"
+)
+
+var anomalyScores = map[string]int{
+	AnomalyFirstAccess:  25,
+	AnomalyNewDevice:    25,
+	AnomalyNewIPRange:   25,
+	AnomalyBurst:        50,
+	AnomalyOutsideHours: 50,
+}
+
+var anomalyDescriptions = map[string]string{
+	AnomalyFirstAccess:  "first ever access",
+	AnomalyNewDevice:    "new device",
+	AnomalyNewIPRange:   "new network",
+	AnomalyBurst:        "unusually many views in a short time",
+	AnomalyOutsideHours: "outside your allowed hours",
+}
+
+const maxAnomalyListSize = 100
+
+// NomineeAnomalyService scores each nominee access against the nominee's
+// history and the owner's rules. Suspicious access alerts the owner and,
+// if they chose to, suspends the nominee until they review it.
+type NomineeAnomalyService struct {
+	anomalyRepo  *postgres.NomineeAnomalyRepository
+	nomineeRepo  *postgres.NomineeRepository
+	alertService *AlertService
+	sessions     *NomineeSessionService
+	audit        *AuditService
+	cfg          *config.AnomalyConfig
+}
+
+func NewNomineeAnomalyService(
+	anomalyRepo *postgres.NomineeAnomalyRepository,
+	nomineeRepo *postgres.NomineeRepository,
+	alertService *AlertService,
+	sessions *NomineeSessionService,
+	audit *AuditService,
+	cfg *config.AnomalyConfig,
+) *NomineeAnomalyService {
+	return &NomineeAnomalyService{
+		anomalyRepo:  anomalyRepo,
+		nomineeRepo:  nomineeRepo,
+		alertService: alertService,
+		sessions:     sessions,
+		audit:        audit,
+		cfg:          cfg,
+	}
+}
+
+// Inspect scores an access the nominee just made, once it is in the access
+// log. Entries the system writes itself carry no device and are skipped.
+// Failures are logged rather than returned so they never block the nominee.
+func (s *NomineeAnomalyService) Inspect(ctx context.Context, accessLog *model.NomineeAccessLog) {
+	if accessLog.DeviceInfo == "" {
+		return
+	}
+
+	if err := s.inspect(ctx, accessLog); err != nil {
+		log.Printf("Failed to score access %s by nominee %s: %v", accessLog.ID, accessLog.NomineeID, err)
+	}
+}
+
+// GetRules returns the owner's rules, or the defaults if they never set any
+func (s *NomineeAnomalyService) GetRules(ctx context.Context, userID uuid.UUID) (*model.NomineeAccessRules, error) {
+	rules, err := s.anomalyRepo.GetRules(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &model.NomineeAccessRules{UserID: userID, Timezone: "UTC"}, nil
+	}
+	return rules, err
+}
+
+func (s *NomineeAnomalyService) UpdateRules(ctx context.Context, rules *model.NomineeAccessRules) error {
+	if rules.Timezone == "" {
+		rules.Timezone = "UTC"
+	}
+
+	if _, err := time.LoadLocation(rules.Timezone); err != nil {
+		return ErrInvalidAccessRules
+	}
+
+	if (rules.AllowedFrom == nil) != (rules.AllowedUntil == nil) {
+		return ErrInvalidAccessRules
+	}
+
+	if rules.AllowedFrom != nil {
+		from, until := *rules.AllowedFrom, *rules.AllowedUntil
+		if from < 0 || from > 23 || until < 0 || until > 23 || from == until {
+			return ErrInvalidAccessRules
+		}
+	}
+
+	return s.anomalyRepo.SaveRules(ctx, rules)
+}
+
+// ListAnomalies returns the latest suspicious accesses by the owner's nominees
+func (s *NomineeAnomalyService) ListAnomalies(ctx context.Context, userID uuid.UUID) ([]model.NomineeAccessAnomaly, error) {
+	return s.anomalyRepo.ListByUserID(ctx, userID, maxAnomalyListSize)
+}
+
+// Reinstate lifts a suspension once the owner has reviewed it
+func (s *NomineeAnomalyService) Reinstate(ctx context.Context, userID, nomineeID uuid.UUID) (*model.Nominee, error) {
+	nominee, err := s.nomineeRepo.GetByID(ctx, nomineeID)
+	if err != nil || nominee.UserID != userID {
+		return nil, ErrNomineeNotFound
+	}
+
+	if nominee.SuspendedAt == nil {
+		return nil, ErrNomineeNotSuspended
+	}
+
+	if err := s.nomineeRepo.SetSuspended(ctx, nomineeID, nil); err != nil {
+		return nil, err
+	}
+	nominee.SuspendedAt = nil
+
+	s.audit.RecordUserAction(ctx, userID, "nominee.reinstated", "nominee", nomineeID, nominee.Name)
+	return nominee, nil
+}
+
+// Private methods
+
+func (s *NomineeAnomalyService) inspect(ctx context.Context, accessLog *model.NomineeAccessLog) error {
+	nominee, err := s.nomineeRepo.GetByID(ctx, accessLog.NomineeID)
+	if err != nil {
+		return err
+	}
+
+	rules, err := s.GetRules(ctx, nominee.UserID)
+	if err != nil {
+		return err
+	}
+
+	history, err := s.anomalyRepo.AccessHistory(ctx, nominee.ID, accessLog.ID)
+	if err != nil {
+		return err
+	}
+
+	reasons := make([]string, 0)
+	if history.Accesses == 0 {
+		reasons = append(reasons, AnomalyFirstAccess)
+	} else {
+		if !containsString(history.Devices, accessLog.DeviceInfo) {
+			reasons = append(reasons, AnomalyNewDevice)
+		}
+		if accessLog.IPAddress != "" && !inKnownIPRange(history.IPAddresses, accessLog.IPAddress) {
+			reasons = append(reasons, AnomalyNewIPRange)
+		}
+	}
+
+	// Only the access that reaches the limit counts, so a burst alerts once
+	recent, err := s.anomalyRepo.CountAccessSince(ctx, nominee.ID, accessLog.Date.Add(-s.cfg.BurstWindow))
+	if err != nil {
+		return err
+	}
+	if s.cfg.BurstLimit > 0 && recent == s.cfg.BurstLimit {
+		reasons = append(reasons, AnomalyBurst)
+	}
+
+	if outsideAllowedHours(rules, accessLog.Date) {
+		reasons = append(reasons, AnomalyOutsideHours)
+	}
+
+	score := 0
+	for _, reason := range reasons {
+		score += anomalyScores[reason]
+	}
+
+	if score < s.cfg.AlertScore {
+		return nil
+	}
+
+	anomaly := &model.NomineeAccessAnomaly{
+		NomineeID:   nominee.ID,
+		UserID:      nominee.UserID,
+		AccessLogID: accessLog.ID,
+		Score:       score,
+		Reasons:     reasons,
+		Action:      accessLog.Action,
+		IPAddress:   accessLog.IPAddress,
+		DeviceInfo:  accessLog.DeviceInfo,
+		Suspended:   rules.AutoSuspend && nominee.SuspendedAt == nil,
+	}
+
+	if anomaly.Suspended {
+		if err := s.suspend(ctx, nominee); err != nil {
+			log.Printf("Failed to suspend nominee %s: %v", nominee.ID, err)
+			anomaly.Suspended = nominee.SuspendedAt != nil
+		}
+	}
+
+	if err := s.anomalyRepo.Create(ctx, anomaly); err != nil {
+		log.Printf("Failed to record suspicious access by nominee %s: %v", nominee.ID, err)
+	}
+
+	s.alert(ctx, nominee, anomaly)
+	return nil
+}
+
+func (s *NomineeAnomalyService) suspend(ctx context.Context, nominee *model.Nominee) error {
+	now := time.Now()
+	if err := s.nomineeRepo.SetSuspended(ctx, nominee.ID, &now); err != nil {
+		return err
+	}
+	nominee.SuspendedAt = &now
+
+	if err := s.sessions.RevokeAllForNominee(ctx, nominee.ID); err != nil {
+		return fmt.Errorf("failed to end nominee sessions: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEvent{
+		OwnerID:    nominee.UserID,
+		ActorType:  AuditActorSystem,
+		Action:     "nominee.suspended",
+		EntityType: "nominee",
+		EntityID:   &nominee.ID,
+		Summary:    fmt.Sprintf("%s suspended after suspicious access", nominee.Name),
+	})
+	return nil
+}
+
+func (s *NomineeAnomalyService) alert(ctx context.Context, nominee *model.Nominee, anomaly *model.NomineeAccessAnomaly) {
+	descriptions := make([]string, 0, len(anomaly.Reasons))
+	for _, reason := range anomaly.Reasons {
+		descriptions = append(descriptions, anomalyDescriptions[reason])
+	}
+
+	message := fmt.Sprintf("Unusual access by your nominee %s from %s: %s.", nominee.Name, anomaly.IPAddress, strings.Join(descriptions, ", "))
+	if anomaly.Suspended {
+		message += " Their access has been suspended until you review it."
+	} else {
+		message += " If you didn't expect this, revoke them from your nominees."
+	}
+
+	alert := &model.Alert{
+		UserID:         nominee.UserID,
+		AlertType:      "Security",
+		Severity:       "High",
+		Message:        message,
+		CreatedAt:      time.Now(),
+		ActionRequired: true,
+	}
+	if err := s.alertService.Create(ctx, alert); err != nil {
+		log.Printf("Failed to alert user %s about suspicious nominee access: %v", nominee.UserID, err)
+	}
+}
+
+// outsideAllowedHours reports whether at falls outside the owner's allowed
+// hours, which may wrap past midnight
+func outsideAllowedHours(rules *model.NomineeAccessRules, at time.Time) bool {
+	if rules.AllowedFrom == nil || rules.AllowedUntil == nil {
+		return false
+	}
+
+	location, err := time.LoadLocation(rules.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	hour := at.In(location).Hour()
+	from, until := *rules.AllowedFrom, *rules.AllowedUntil
+	if from < until {
+		return hour < from || hour >= until
+	}
+	return hour < from && hour >= until
+}
+
+// inKnownIPRange reports whether ip shares a /24 (IPv4) or /48 (IPv6)
+// network with any address the nominee used before
+func inKnownIPRange(known []string, ip string) bool {
+	network := ipNetwork(ip)
+	for _, address := range known {
+		if ipNetwork(address) == network {
+			return true
+		}
+	}
+	return false
+}
+
+func ipNetwork(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return address
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+)
+
+var ErrDownloadNotAllowed = errors.New("your permissions do not allow downloading documents")
+
+const (
+	// redactedValue replaces fields a nominee's profile hides
+	redactedValue = "********"
+	// nomineeDownloadURLExpiry keeps links handed to nominees short-lived
+	nomineeDownloadURLExpiry = 5 * time.Minute
+)
+
+// NomineeView is the part of an owner's data a nominee may see
+type NomineeView struct {
+	Permissions *model.PermissionProfile `json:"permissions"`
+	Assets      []model.Asset            `json:"assets"`
+	Documents   []model.Document         `json:"documents"`
+	// Messages stays empty until the nominee is granted emergency access
+	Messages []model.NomineeMessage `json:"messages"`
+}
+
+// NomineeDataService is the only way nominee-facing endpoints read an
+// owner's data, so every one of them applies the same permission profile
+type NomineeDataService struct {
+	nomineeRepo    *postgres.NomineeRepository
+	assetRepo      *postgres.AssetRepository
+	documentRepo   *postgres.DocumentRepository
+	storageService *StorageService
+	profiles       *PermissionProfileService
+	messages       *NomineeMessageService
+	notifier       *NomineeAccessNotifier
+	accessLog      *NomineeAccessLogger
+	vault          *VaultService
+}
+
+func NewNomineeDataService(
+	nomineeRepo *postgres.NomineeRepository,
+	assetRepo *postgres.AssetRepository,
+	documentRepo *postgres.DocumentRepository,
+	storageService *StorageService,
+	profiles *PermissionProfileService,
+	messages *NomineeMessageService,
+	notifier *NomineeAccessNotifier,
+	accessLog *NomineeAccessLogger,
+	vault *VaultService,
+) *NomineeDataService {
+	return &NomineeDataService{
+		nomineeRepo:    nomineeRepo,
+		assetRepo:      assetRepo,
+		documentRepo:   documentRepo,
+		storageService: storageService,
+		profiles:       profiles,
+		messages:       messages,
+		notifier:       notifier,
+		accessLog:      accessLog,
+		vault:          vault,
+	}
+}
+
+// View returns the owner's assets and documents filtered and redacted by
+// the nominee's profile, along with any messages released to the nominee.
+// grantedLevel is the access level of the session.
+func (s *NomineeDataService) View(ctx context.Context, nomineeID uuid.UUID, grantedLevel string) (*NomineeView, error) {
+	nominee, profile, err := s.effectiveProfile(ctx, nomineeID, grantedLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &NomineeView{
+		Permissions: profile,
+		Documents:   []model.Document{},
+	}
+
+	view.Assets, err = s.visibleAssets(ctx, nominee, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.DocumentTypes == nil || len(profile.DocumentTypes) > 0 {
+		var documents []model.Document
+		if profile.SharedDocumentsOnly {
+			documents, err = s.documentRepo.GetNomineeDocuments(ctx, nominee.ID)
+		} else {
+			documents, err = s.documentRepo.GetByUserID(ctx, nominee.UserID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load documents: %w", err)
+		}
+
+		view.Documents = allowedDocuments(documents, profile)
+	}
+
+	messages, err := s.messages.Released(ctx, nominee)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range messages {
+		messages[i].Attachments = allowedDocuments(messages[i].Attachments, profile)
+	}
+	view.Messages = messages
+
+	return view, nil
+}
+
+// Assets returns the owner's assets the nominee's profile lets them see,
+// redacted, along with the nominee
+func (s *NomineeDataService) Assets(ctx context.Context, nomineeID uuid.UUID, grantedLevel string) (*model.Nominee, []model.Asset, error) {
+	nominee, profile, err := s.effectiveProfile(ctx, nomineeID, grantedLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	assets, err := s.visibleAssets(ctx, nominee, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nominee, assets, nil
+}
+
+// SharedDocuments returns the documents the owner shared with the nominee
+// that their profile lets them see. Only these can be downloaded.
+func (s *NomineeDataService) SharedDocuments(ctx context.Context, nomineeID uuid.UUID, grantedLevel string) ([]model.Document, *model.PermissionProfile, error) {
+	nominee, profile, err := s.effectiveProfile(ctx, nomineeID, grantedLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shared, err := s.documentRepo.GetNomineeDocuments(ctx, nominee.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load documents: %w", err)
+	}
+
+	return allowedDocuments(shared, profile), profile, nil
+}
+
+// Download streams a shared document to the nominee, logging the download
+// and telling the owner. Encrypted documents are decrypted with the owner's
+// vault, once enough nominees have submitted their shares.
+func (s *NomineeDataService) Download(ctx context.Context, nomineeID uuid.UUID, grantedLevel string, documentID uuid.UUID, client ClientInfo) ([]byte, *model.Document, error) {
+	nominee, document, err := s.downloadable(ctx, nomineeID, grantedLevel, documentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileData, err := s.storageService.Download(ctx, document.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	fileData, err = s.vault.OpenForNominee(ctx, nominee, document, fileData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.logDownload(ctx, nomineeID, document, client)
+	return fileData, document, nil
+}
+
+// DownloadURL returns a short-lived link to a shared document and logs the download
+func (s *NomineeDataService) DownloadURL(ctx context.Context, nomineeID uuid.UUID, grantedLevel string, documentID uuid.UUID, client ClientInfo) (string, *model.Document, time.Time, error) {
+	_, document, err := s.downloadable(ctx, nomineeID, grantedLevel, documentID)
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+
+	// Storage only holds the ciphertext of encrypted documents
+	sealed, err := s.vault.IsSealed(ctx, document.ID)
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+	if sealed {
+		return "", nil, time.Time{}, ErrEncryptedDocumentURL
+	}
+
+	expiresAt := time.Now().Add(nomineeDownloadURLExpiry)
+	url, err := s.storageService.GetSignedURL(ctx, document.StorageKey, nomineeDownloadURLExpiry)
+	if err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("failed to generate download URL: %w", err)
+	}
+
+	s.logDownload(ctx, nomineeID, document, client)
+	return url, document, expiresAt, nil
+}
+
+// Private methods
+
+func (s *NomineeDataService) effectiveProfile(ctx context.Context, nomineeID uuid.UUID, grantedLevel string) (*model.Nominee, *model.PermissionProfile, error) {
+	nominee, err := s.nomineeRepo.GetByID(ctx, nomineeID)
+	if err != nil {
+		return nil, nil, ErrNomineeNotFound
+	}
+
+	profile, err := s.profiles.Effective(ctx, nominee, grantedLevel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load permission profile: %w", err)
+	}
+
+	return nominee, profile, nil
+}
+
+func (s *NomineeDataService) visibleAssets(ctx context.Context, nominee *model.Nominee, profile *model.PermissionProfile) ([]model.Asset, error) {
+	visible := []model.Asset{}
+	if profile.AssetTypes != nil && len(profile.AssetTypes) == 0 {
+		return visible, nil
+	}
+
+	assets, err := s.assetRepo.GetByUserID(ctx, nominee.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assets: %w", err)
+	}
+
+	for _, asset := range assets {
+		if allowsType(profile.AssetTypes, asset.AssetType) {
+			visible = append(visible, redactAsset(asset, profile))
+		}
+	}
+
+	return visible, nil
+}
+
+// downloadable finds a document the owner shared with the nominee or
+// attached to a message released to them, provided their profile allows
+// downloads
+func (s *NomineeDataService) downloadable(ctx context.Context, nomineeID uuid.UUID, grantedLevel string, documentID uuid.UUID) (*model.Nominee, *model.Document, error) {
+	nominee, profile, err := s.effectiveProfile(ctx, nomineeID, grantedLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shared, err := s.documentRepo.GetNomineeDocuments(ctx, nominee.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load documents: %w", err)
+	}
+
+	var document *model.Document
+	for i := range shared {
+		if shared[i].ID == documentID {
+			document = &shared[i]
+			break
+		}
+	}
+
+	if document == nil {
+		document, err = s.messages.ReleasedAttachment(ctx, nominee, documentID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if !allowsType(profile.DocumentTypes, document.DocumentType) {
+		return nil, nil, ErrDocumentNotFound
+	}
+
+	if !profile.AllowDownloads {
+		return nil, nil, ErrDownloadNotAllowed
+	}
+
+	return nominee, document, nil
+}
+
+func (s *NomineeDataService) logDownload(ctx context.Context, nomineeID uuid.UUID, document *model.Document, client ClientInfo) {
+	s.accessLog.Record(ctx, nomineeID, "Downloaded document: "+document.Title, client)
+	s.notifier.Notify(ctx, nomineeID, fmt.Sprintf("downloaded the document %q", document.Title), client)
+}
+
+// allowedDocuments keeps the documents whose type the profile lets the nominee see
+func allowedDocuments(documents []model.Document, profile *model.PermissionProfile) []model.Document {
+	allowed := make([]model.Document, 0, len(documents))
+	for _, document := range documents {
+		if allowsType(profile.DocumentTypes, document.DocumentType) {
+			allowed = append(allowed, document)
+		}
+	}
+
+	return allowed
+}
+
+func redactAsset(asset model.Asset, profile *model.PermissionProfile) model.Asset {
+	if profile.RedactAccountNumbers && asset.AccountNumber != "" {
+		asset.AccountNumber = redactedValue
+	}
+	if profile.RedactInstitutions && asset.Institution != "" {
+		asset.Institution = redactedValue
+	}
+	if profile.RedactNotes && asset.Notes != "" {
+		asset.Notes = redactedValue
+	}
+
+	return asset
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var (
+	ErrInvalidInvitation     = errors.New("invalid or expired invitation")
+	ErrInvitationNotAllowed  = errors.New("this nominee has already accepted or has been revoked")
+	ErrSelfNomination        = errors.New("you can't accept an invitation to be your own nominee")
+	ErrNomineePasswordLength = errors.New("password must be at least 8 characters")
+)
+
+// Nominee statuses
+const (
+	NomineeInvited  = "Invited"
+	NomineeAccepted = "Accepted"
+	NomineeDeclined = "Declined"
+	NomineeRevoked  = "Revoked"
+)
+
+// InvitationDetails is what an invited nominee sees before answering
+type InvitationDetails struct {
+	NomineeName  string    `json:"nominee_name"`
+	NomineeEmail string    `json:"nominee_email"`
+	Relationship string    `json:"relationship"`
+	AccessLevel  string    `json:"access_level"`
+	OwnerName    string    `json:"owner_name"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type NomineeInvitationService struct {
+	invitationRepo *postgres.NomineeInvitationRepository
+	nomineeRepo    *postgres.NomineeRepository
+	userRepo       *postgres.UserRepository
+	passwordUtil   *util.PasswordUtil
+	alertService   *AlertService
+	mailer         Mailer
+	appCfg         *config.AppConfig
+	vault          *VaultService
+}
+
+func NewNomineeInvitationService(
+	invitationRepo *postgres.NomineeInvitationRepository,
+	nomineeRepo *postgres.NomineeRepository,
+	userRepo *postgres.UserRepository,
+	passwordUtil *util.PasswordUtil,
+	alertService *AlertService,
+	mailer Mailer,
+	appCfg *config.AppConfig,
+	vault *VaultService,
+) *NomineeInvitationService {
+	return &NomineeInvitationService{
+		invitationRepo: invitationRepo,
+		nomineeRepo:    nomineeRepo,
+		userRepo:       userRepo,
+		passwordUtil:   passwordUtil,
+		alertService:   alertService,
+		mailer:         mailer,
+		appCfg:         appCfg,
+		vault:          vault,
+	}
+}
+
+// Send emails the nominee a new invitation link, invalidating any earlier
+// one, along with their vault share if it hasn't been delivered yet
+func (s *NomineeInvitationService) Send(ctx context.Context, nominee *model.Nominee) (*model.NomineeInvitation, error) {
+	owner, err := s.userRepo.GetByID(ctx, nominee.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	rawToken, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &model.NomineeInvitation{
+		NomineeID: nominee.ID,
+		TokenHash: util.HashToken(rawToken),
+		ExpiresAt: time.Now().Add(s.appCfg.NomineeInvitationExpiry),
+	}
+
+	created, err := s.invitationRepo.Create(ctx, invitation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store invitation: %w", err)
+	}
+	if !created {
+		return nil, ErrInvitationNotAllowed
+	}
+	nominee.Status = NomineeInvited
+
+	inviteURL := fmt.Sprintf("%s/nominee-invitation?token=%s", strings.TrimRight(s.appCfg.FrontendURL, "/"), url.QueryEscape(rawToken))
+	msg := MailMessage{
+		To:      nominee.Email,
+		Subject: fmt.Sprintf("%s has named you as a nominee on Sampatti", owner.Name),
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n%s has named you as a nominee on Sampatti, so you can be given access to the information they choose to share if something happens to them.\n\nOpen the link below to accept. You can set a password for nominee access or use an existing Sampatti account:\n\n%s\n\nIf you'd rather not be their nominee, you can decline from the same page. The link expires in %d days.\n",
+			nominee.Name,
+			owner.Name,
+			inviteURL,
+			int(s.appCfg.NomineeInvitationExpiry.Hours()/24),
+		),
+	}
+
+	share, threshold, hasShare := s.vault.PendingShare(ctx, nominee)
+	if hasShare {
+		msg.Body += fmt.Sprintf("\n%s has also given you a share of the recovery key for their encrypted documents:\n\n%s\n\n%sKeep it somewhere safe and private. We don't keep a copy.\n", owner.Name, share, vaultShareInstructions(threshold))
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return nil, fmt.Errorf("failed to send invitation email: %w", err)
+	}
+
+	if hasShare {
+		s.vault.ShareDelivered(ctx, nominee)
+	}
+
+	return invitation, nil
+}
+
+// Resend sends a fresh invitation to one of the owner's nominees
+func (s *NomineeInvitationService) Resend(ctx context.Context, nomineeID, ownerID uuid.UUID) (*model.Nominee, *model.NomineeInvitation, error) {
+	nominee, err := s.nomineeRepo.GetByID(ctx, nomineeID)
+	if err != nil {
+		return nil, nil, ErrNomineeNotFound
+	}
+
+	if nominee.UserID != ownerID {
+		return nil, nil, ErrUnauthorized
+	}
+
+	invitation, err := s.Send(ctx, nominee)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nominee, invitation, nil
+}
+
+// Get describes a valid invitation without using it up
+func (s *NomineeInvitationService) Get(ctx context.Context, rawToken string) (*InvitationDetails, error) {
+	invitation, nominee, err := s.lookup(ctx, rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.userRepo.GetByID(ctx, nominee.UserID)
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	return &InvitationDetails{
+		NomineeName:  nominee.Name,
+		NomineeEmail: nominee.Email,
+		Relationship: nominee.Relationship,
+		AccessLevel:  nominee.AccessLevel,
+		OwnerName:    owner.Name,
+		ExpiresAt:    invitation.ExpiresAt,
+	}, nil
+}
+
+// Accept accepts an invitation with a password the nominee will use for
+// emergency access
+func (s *NomineeInvitationService) Accept(ctx context.Context, rawToken, password string) error {
+	if len(password) < 8 {
+		return ErrNomineePasswordLength
+	}
+
+	passwordHash, err := s.passwordUtil.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	nomineeID, err := s.invitationRepo.Accept(ctx, util.HashToken(rawToken), passwordHash, nil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidInvitation
+	}
+	if err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	s.responded(ctx, nomineeID, NomineeAccepted)
+	return nil
+}
+
+// AcceptWithAccount accepts an invitation for the signed-in user. The nominee
+// then reaches emergency access through that account and whatever sign-in
+// methods it has, passkeys included.
+func (s *NomineeInvitationService) AcceptWithAccount(ctx context.Context, rawToken string, userID uuid.UUID) error {
+	_, nominee, err := s.lookup(ctx, rawToken)
+	if err != nil {
+		return err
+	}
+
+	if nominee.UserID == userID {
+		return ErrSelfNomination
+	}
+
+	nomineeID, err := s.invitationRepo.Accept(ctx, util.HashToken(rawToken), "", &userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidInvitation
+	}
+	if err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	s.responded(ctx, nomineeID, NomineeAccepted)
+	return nil
+}
+
+// Decline turns an invitation down. The owner can invite the nominee again.
+func (s *NomineeInvitationService) Decline(ctx context.Context, rawToken string) error {
+	nomineeID, err := s.invitationRepo.Decline(ctx, util.HashToken(rawToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidInvitation
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decline invitation: %w", err)
+	}
+
+	s.responded(ctx, nomineeID, NomineeDeclined)
+	return nil
+}
+
+// Private methods
+
+func (s *NomineeInvitationService) lookup(ctx context.Context, rawToken string) (*model.NomineeInvitation, *model.Nominee, error) {
+	invitation, err := s.invitationRepo.GetByTokenHash(ctx, util.HashToken(rawToken))
+	if err != nil {
+		return nil, nil, ErrInvalidInvitation
+	}
+
+	nominee, err := s.nomineeRepo.GetByID(ctx, invitation.NomineeID)
+	if err != nil || nominee.Status != NomineeInvited {
+		return nil, nil, ErrInvalidInvitation
+	}
+
+	return invitation, nominee, nil
+}
+
+// responded tells the owner how their nominee answered
+func (s *NomineeInvitationService) responded(ctx context.Context, nomineeID uuid.UUID, status string) {
+	nominee, err := s.nomineeRepo.GetByID(ctx, nomineeID)
+	if err != nil {
+		log.Printf("Failed to load nominee %s after invitation response: %v", nomineeID, err)
+		return
+	}
+
+	message := fmt.Sprintf("%s accepted your invitation to be a nominee.", nominee.Name)
+	severity := "Low"
+	if status == NomineeDeclined {
+		message = fmt.Sprintf("%s declined your invitation to be a nominee. You can invite them again or name someone else.", nominee.Name)
+		severity = "Medium"
+	}
+
+	alert := &model.Alert{
+		UserID:         nominee.UserID,
+		AlertType:      "Nominee",
+		Severity:       severity,
+		Message:        message,
+		CreatedAt:      time.Now(),
+		ActionRequired: status == NomineeDeclined,
+	}
+	if err := s.alertService.Create(ctx, alert); err != nil {
+		log.Printf("Failed to create invitation alert for user %s: %v", nominee.UserID, err)
+	}
+}
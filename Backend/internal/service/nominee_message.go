@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var (
+	ErrNomineeMessagesDisabled  = errors.New("messages to nominees need an encryption key to be configured")
+	ErrNomineeMessageNotFound   = errors.New("message not found")
+	ErrInvalidNomineeMessage    = errors.New("message needs a subject of up to 200 characters and a body of up to 20000 characters")
+	ErrNomineeMessageRecipients = errors.New("message must be addressed to at least one of your nominees")
+)
+
+const (
+	maxNomineeMessageSubject = 200
+	maxNomineeMessageBody    = 20000
+)
+
+// sealedMessageContent is what gets encrypted for each message
+type sealedMessageContent struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// NomineeMessageService keeps owners' letters of instruction encrypted at
+// rest and only opens them to nominees once emergency access is granted
+type NomineeMessageService struct {
+	messageRepo  *postgres.NomineeMessageRepository
+	nomineeRepo  *postgres.NomineeRepository
+	documentRepo *postgres.DocumentRepository
+	emergency    *EmergencyAccessService
+	sealer       *util.Sealer
+}
+
+func NewNomineeMessageService(
+	messageRepo *postgres.NomineeMessageRepository,
+	nomineeRepo *postgres.NomineeRepository,
+	documentRepo *postgres.DocumentRepository,
+	emergency *EmergencyAccessService,
+	sealer *util.Sealer,
+) *NomineeMessageService {
+	return &NomineeMessageService{
+		messageRepo:  messageRepo,
+		nomineeRepo:  nomineeRepo,
+		documentRepo: documentRepo,
+		emergency:    emergency,
+		sealer:       sealer,
+	}
+}
+
+// Enabled reports whether an encryption key is configured. Without one no
+// message can be written or opened.
+func (s *NomineeMessageService) Enabled() bool {
+	return s.sealer != nil
+}
+
+// List returns the owner's messages with when each recipient first opened them
+func (s *NomineeMessageService) List(ctx context.Context, userID uuid.UUID) ([]model.NomineeMessage, error) {
+	if !s.Enabled() {
+		return nil, ErrNomineeMessagesDisabled
+	}
+
+	messages, err := s.messageRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range messages {
+		if err := s.open(&messages[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return messages, nil
+}
+
+func (s *NomineeMessageService) Get(ctx context.Context, userID, id uuid.UUID) (*model.NomineeMessage, error) {
+	if !s.Enabled() {
+		return nil, ErrNomineeMessagesDisabled
+	}
+
+	message, err := s.messageRepo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNomineeMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if message.UserID != userID {
+		return nil, ErrNomineeMessageNotFound
+	}
+
+	if err := s.open(message); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// Create seals and stores a new message addressed to message.Recipients
+func (s *NomineeMessageService) Create(ctx context.Context, message *model.NomineeMessage) error {
+	if !s.Enabled() {
+		return ErrNomineeMessagesDisabled
+	}
+
+	if err := s.validate(ctx, message); err != nil {
+		return err
+	}
+
+	message.ID = uuid.New()
+	if err := s.seal(message); err != nil {
+		return err
+	}
+
+	if err := s.messageRepo.Create(ctx, message); err != nil {
+		return fmt.Errorf("failed to save message: %w", err)
+	}
+
+	return s.reload(ctx, message)
+}
+
+// Update reseals an existing message. Nominees who were already recipients
+// keep their first opened time.
+func (s *NomineeMessageService) Update(ctx context.Context, message *model.NomineeMessage) error {
+	if !s.Enabled() {
+		return ErrNomineeMessagesDisabled
+	}
+
+	if err := s.validate(ctx, message); err != nil {
+		return err
+	}
+
+	if err := s.seal(message); err != nil {
+		return err
+	}
+
+	updated, err := s.messageRepo.Update(ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to save message: %w", err)
+	}
+
+	if !updated {
+		return ErrNomineeMessageNotFound
+	}
+
+	return s.reload(ctx, message)
+}
+
+func (s *NomineeMessageService) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	deleted, err := s.messageRepo.Delete(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrNomineeMessageNotFound
+	}
+
+	return nil
+}
+
+// Released opens the messages addressed to the nominee, with their
+// attachments, and records them as opened. Nothing is released until the
+// nominee has been granted emergency access.
+func (s *NomineeMessageService) Released(ctx context.Context, nominee *model.Nominee) ([]model.NomineeMessage, error) {
+	messages, err := s.released(ctx, nominee)
+	if err != nil || len(messages) == 0 {
+		return messages, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(messages))
+	for i := range messages {
+		if err := s.open(&messages[i]); err != nil {
+			return nil, err
+		}
+		ids = append(ids, messages[i].ID)
+	}
+
+	attachments, err := s.messageRepo.ListAttachments(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attachments: %w", err)
+	}
+
+	for i := range messages {
+		messages[i].Attachments = attachments[messages[i].ID]
+		if messages[i].Attachments == nil {
+			messages[i].Attachments = []model.Document{}
+		}
+	}
+
+	if err := s.messageRepo.MarkOpened(ctx, nominee.ID, ids); err != nil {
+		log.Printf("Failed to record messages opened by nominee %s: %v", nominee.ID, err)
+	}
+
+	return messages, nil
+}
+
+// ReleasedAttachment finds a document attached to one of the nominee's
+// released messages
+func (s *NomineeMessageService) ReleasedAttachment(ctx context.Context, nominee *model.Nominee, documentID uuid.UUID) (*model.Document, error) {
+	messages, err := s.released(ctx, nominee)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+
+	attachments, err := s.messageRepo.ListAttachments(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attachments: %w", err)
+	}
+
+	for _, documents := range attachments {
+		for i := range documents {
+			if documents[i].ID == documentID {
+				return &documents[i], nil
+			}
+		}
+	}
+
+	return nil, ErrDocumentNotFound
+}
+
+// Private methods
+
+// released returns the still sealed messages addressed to the nominee, or
+// none before access is granted
+func (s *NomineeMessageService) released(ctx context.Context, nominee *model.Nominee) ([]model.NomineeMessage, error) {
+	if !s.Enabled() {
+		return []model.NomineeMessage{}, nil
+	}
+
+	granted, err := s.emergency.IsGranted(ctx, nominee)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check emergency access: %w", err)
+	}
+
+	if !granted {
+		return []model.NomineeMessage{}, nil
+	}
+
+	messages, err := s.messageRepo.ListForNominee(ctx, nominee.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (s *NomineeMessageService) validate(ctx context.Context, message *model.NomineeMessage) error {
+	message.Subject = strings.TrimSpace(message.Subject)
+	message.Body = strings.TrimSpace(message.Body)
+	if message.Subject == "" || message.Body == "" ||
+		utf8.RuneCountInString(message.Subject) > maxNomineeMessageSubject ||
+		utf8.RuneCountInString(message.Body) > maxNomineeMessageBody {
+		return ErrInvalidNomineeMessage
+	}
+
+	nominees, err := s.nomineeRepo.GetByUserID(ctx, message.UserID)
+	if err != nil {
+		return err
+	}
+
+	owned := make(map[uuid.UUID]bool, len(nominees))
+	for _, nominee := range nominees {
+		owned[nominee.ID] = true
+	}
+
+	recipients := make([]model.NomineeMessageRecipient, 0, len(message.Recipients))
+	seen := make(map[uuid.UUID]bool, len(message.Recipients))
+	for _, recipient := range message.Recipients {
+		if !owned[recipient.NomineeID] {
+			return ErrNomineeNotFound
+		}
+		if !seen[recipient.NomineeID] {
+			seen[recipient.NomineeID] = true
+			recipients = append(recipients, model.NomineeMessageRecipient{NomineeID: recipient.NomineeID})
+		}
+	}
+
+	if len(recipients) == 0 {
+		return ErrNomineeMessageRecipients
+	}
+	message.Recipients = recipients
+
+	attachmentIDs := make([]uuid.UUID, 0, len(message.AttachmentIDs))
+	attached := make(map[uuid.UUID]bool, len(message.AttachmentIDs))
+	for _, documentID := range message.AttachmentIDs {
+		if attached[documentID] {
+			continue
+		}
+
+		document, err := s.documentRepo.GetByID(ctx, documentID)
+		if err != nil || document.UserID != message.UserID {
+			return ErrDocumentNotFound
+		}
+
+		attached[documentID] = true
+		attachmentIDs = append(attachmentIDs, documentID)
+	}
+	message.AttachmentIDs = attachmentIDs
+
+	return nil
+}
+
+// seal encrypts the subject and body, bound to the message's ID
+func (s *NomineeMessageService) seal(message *model.NomineeMessage) error {
+	plaintext, err := json.Marshal(sealedMessageContent{Subject: message.Subject, Body: message.Body})
+	if err != nil {
+		return err
+	}
+
+	sealed, err := s.sealer.Seal(plaintext, message.ID[:])
+	if err != nil {
+		return fmt.Errorf("failed to seal message: %w", err)
+	}
+
+	message.SealedContent = sealed
+	return nil
+}
+
+func (s *NomineeMessageService) open(message *model.NomineeMessage) error {
+	plaintext, err := s.sealer.Open(message.SealedContent, message.ID[:])
+	if err != nil {
+		return fmt.Errorf("failed to open message %s: %w", message.ID, err)
+	}
+
+	var content sealedMessageContent
+	if err := json.Unmarshal(plaintext, &content); err != nil {
+		return fmt.Errorf("failed to read message %s: %w", message.ID, err)
+	}
+
+	message.Subject = content.Subject
+	message.Body = content.Body
+	return nil
+}
+
+// reload refreshes recipients with their names and first opened times
+func (s *NomineeMessageService) reload(ctx context.Context, message *model.NomineeMessage) error {
+	stored, err := s.messageRepo.GetByID(ctx, message.ID)
+	if err != nil {
+		return err
+	}
+
+	message.Recipients = stored.Recipients
+	message.AttachmentIDs = stored.AttachmentIDs
+	message.CreatedAt = stored.CreatedAt
+	return nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var (
+	ErrNomineeSessionNotFound = errors.New("nominee session not found")
+	ErrNomineeSessionEnded    = errors.New("nominee session has been revoked or has expired")
+)
+
+type NomineeSessionService struct {
+	sessionRepo *postgres.NomineeSessionRepository
+	jwtUtil     *util.JWTUtil
+}
+
+func NewNomineeSessionService(sessionRepo *postgres.NomineeSessionRepository, jwtUtil *util.JWTUtil) *NomineeSessionService {
+	return &NomineeSessionService{
+		sessionRepo: sessionRepo,
+		jwtUtil:     jwtUtil,
+	}
+}
+
+// Start signs an admitted nominee in for the duration the owner chose and
+// returns the access token for the new session
+func (s *NomineeSessionService) Start(ctx context.Context, nominee *model.Nominee, client ClientInfo) (string, *model.NomineeSession, error) {
+	duration := time.Duration(nominee.AccessDurationHours) * time.Hour
+	if duration <= 0 {
+		duration = DefaultNomineeAccessHours * time.Hour
+	}
+
+	session := &model.NomineeSession{
+		NomineeID:   nominee.ID,
+		UserID:      nominee.UserID,
+		AccessLevel: nominee.AccessLevel,
+		IPAddress:   client.IPAddress,
+		UserAgent:   client.UserAgent,
+		ExpiresAt:   time.Now().Add(duration),
+	}
+
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return "", nil, fmt.Errorf("failed to create nominee session: %w", err)
+	}
+
+	token, err := s.jwtUtil.GenerateNomineeToken(nominee.ID, nominee.UserID, session.ID, nominee.AccessLevel, duration)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return token, session, nil
+}
+
+// Validate checks on every request that a nominee token's session is still live
+func (s *NomineeSessionService) Validate(ctx context.Context, sessionID, nomineeID uuid.UUID) error {
+	if sessionID == uuid.Nil {
+		return ErrNomineeSessionEnded
+	}
+
+	live, err := s.sessionRepo.Touch(ctx, sessionID, nomineeID)
+	if err != nil {
+		return err
+	}
+
+	if !live {
+		return ErrNomineeSessionEnded
+	}
+
+	return nil
+}
+
+// ListForOwner returns the live sessions of the owner's nominees
+func (s *NomineeSessionService) ListForOwner(ctx context.Context, userID uuid.UUID) ([]model.NomineeSession, error) {
+	return s.sessionRepo.ListActiveByUserID(ctx, userID)
+}
+
+// Revoke ends one of the sessions reaching the owner's data
+func (s *NomineeSessionService) Revoke(ctx context.Context, userID, sessionID uuid.UUID) error {
+	revoked, err := s.sessionRepo.RevokeForUser(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return ErrNomineeSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeAllForNominee ends every session of a nominee
+func (s *NomineeSessionService) RevokeAllForNominee(ctx context.Context, nomineeID uuid.UUID) error {
+	return s.sessionRepo.RevokeAllForNominee(ctx, nomineeID)
+}
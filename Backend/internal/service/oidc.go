@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var (
+	ErrOIDCDisabled         = errors.New("single sign-on is not configured")
+	ErrOIDCInvalidState     = errors.New("invalid or expired sign-in request")
+	ErrOIDCFailed           = errors.New("sign-in with the identity provider failed")
+	ErrOIDCEmailNotVerified = errors.New("the identity provider has not verified this email address")
+	// ErrOIDCAccountUnverified protects accounts someone registered with an
+	// address they never proved they own from being taken over by linking
+	ErrOIDCAccountUnverified = errors.New("an account with this email exists but its email isn't verified; sign in with your password and link the provider from your profile")
+	ErrOIDCIdentityLinked    = errors.New("this identity is already linked to another account")
+	ErrOIDCIdentityNotFound  = errors.New("linked identity not found")
+)
+
+// OIDCStateTTL is how long a sign-in or link request may take at the provider
+const OIDCStateTTL = 10 * time.Minute
+
+type OIDCService struct {
+	client       *util.OIDCClient
+	oidcRepo     *postgres.OIDCRepository
+	userRepo     *postgres.UserRepository
+	authService  *AuthService
+	passwordUtil *util.PasswordUtil
+	cfg          *config.OIDCConfig
+}
+
+func NewOIDCService(
+	oidcRepo *postgres.OIDCRepository,
+	userRepo *postgres.UserRepository,
+	authService *AuthService,
+	passwordUtil *util.PasswordUtil,
+	cfg *config.OIDCConfig,
+) *OIDCService {
+	var client *util.OIDCClient
+	if cfg.Issuer != "" {
+		client = util.NewOIDCClient(cfg.Issuer, cfg.ClientID, cfg.ClientSecret, cfg.RedirectURL, cfg.Scopes)
+	}
+
+	return &OIDCService{
+		client:       client,
+		oidcRepo:     oidcRepo,
+		userRepo:     userRepo,
+		authService:  authService,
+		passwordUtil: passwordUtil,
+		cfg:          cfg,
+	}
+}
+
+// Enabled reports whether a provider is configured
+func (s *OIDCService) Enabled() bool {
+	return s.client != nil
+}
+
+// ProviderName is the provider's display name
+func (s *OIDCService) ProviderName() string {
+	return s.cfg.ProviderName
+}
+
+// SecureCookies reports whether the state cookie should be HTTPS only
+func (s *OIDCService) SecureCookies() bool {
+	return strings.HasPrefix(s.cfg.RedirectURL, "https://")
+}
+
+// BeginLogin returns the provider URL that starts a sign-in and the state
+// the browser must present again in Callback
+func (s *OIDCService) BeginLogin(ctx context.Context) (string, string, error) {
+	return s.begin(ctx, nil)
+}
+
+// BeginLink returns the provider URL that links an identity to the user
+// and the state the browser must present again in CompleteLink
+func (s *OIDCService) BeginLink(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	return s.begin(ctx, &userID)
+}
+
+// Callback finishes a sign-in the provider redirected back from. boundState
+// is the state this browser was given by BeginLogin, so a code and state
+// someone else started can't be replayed into this browser's session.
+func (s *OIDCService) Callback(ctx context.Context, code, state, boundState string, client ClientInfo) (*LoginResult, error) {
+	loginState, claims, err := s.finish(ctx, code, state, boundState)
+	if err != nil {
+		return nil, err
+	}
+
+	// Link requests may only be finished by the user who started them
+	if loginState.UserID != nil {
+		return nil, ErrOIDCInvalidState
+	}
+
+	user, err := s.resolveUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// Second factors still apply; the provider only replaces the password
+	return s.authService.FirstFactorPassed(ctx, user, client)
+}
+
+// CompleteLink finishes a link request for the signed-in user who started it
+func (s *OIDCService) CompleteLink(ctx context.Context, userID uuid.UUID, code, state, boundState string) (*model.OIDCIdentity, error) {
+	loginState, claims, err := s.finish(ctx, code, state, boundState)
+	if err != nil {
+		return nil, err
+	}
+
+	if loginState.UserID == nil || *loginState.UserID != userID {
+		return nil, ErrOIDCInvalidState
+	}
+
+	return s.link(ctx, userID, claims)
+}
+
+// ListIdentities returns the identities linked to the user
+func (s *OIDCService) ListIdentities(ctx context.Context, userID uuid.UUID) ([]model.OIDCIdentity, error) {
+	return s.oidcRepo.ListIdentitiesByUserID(ctx, userID)
+}
+
+// Unlink removes a linked identity. Accounts created through the provider
+// can still sign in after a password reset.
+func (s *OIDCService) Unlink(ctx context.Context, userID, identityID uuid.UUID) error {
+	deleted, err := s.oidcRepo.DeleteIdentity(ctx, userID, identityID)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrOIDCIdentityNotFound
+	}
+
+	return nil
+}
+
+// Private methods
+
+func (s *OIDCService) begin(ctx context.Context, userID *uuid.UUID) (string, string, error) {
+	if !s.Enabled() {
+		return "", "", ErrOIDCDisabled
+	}
+
+	state, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := util.NewPKCEVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := s.client.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("OIDC discovery failed: %v", err)
+		return "", "", ErrOIDCFailed
+	}
+
+	loginState := &model.OIDCLoginState{
+		StateHash:    util.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		UserID:       userID,
+		ExpiresAt:    time.Now().Add(OIDCStateTTL),
+	}
+
+	if err := s.oidcRepo.CreateState(ctx, loginState); err != nil {
+		return "", "", fmt.Errorf("failed to store sign-in state: %w", err)
+	}
+
+	return authURL, state, nil
+}
+
+// finish checks the state is the one bound to this browser, consumes it and
+// exchanges the code for verified ID token claims
+func (s *OIDCService) finish(ctx context.Context, code, state, boundState string) (*model.OIDCLoginState, *util.IDTokenClaims, error) {
+	if !s.Enabled() {
+		return nil, nil, ErrOIDCDisabled
+	}
+
+	if boundState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(boundState)) != 1 {
+		return nil, nil, ErrOIDCInvalidState
+	}
+
+	loginState, err := s.oidcRepo.ConsumeState(ctx, util.HashToken(state))
+	if err != nil {
+		return nil, nil, ErrOIDCInvalidState
+	}
+
+	rawIDToken, err := s.client.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		log.Printf("OIDC code exchange failed: %v", err)
+		return nil, nil, ErrOIDCFailed
+	}
+
+	claims, err := s.client.VerifyIDToken(ctx, rawIDToken, loginState.Nonce)
+	if err != nil {
+		log.Printf("OIDC ID token rejected: %v", err)
+		return nil, nil, ErrOIDCFailed
+	}
+
+	return loginState, claims, nil
+}
+
+// resolveUser finds the user for a sign-in: the linked identity first, then
+// an existing account with the same verified email, then a new account
+func (s *OIDCService) resolveUser(ctx context.Context, claims *util.IDTokenClaims) (*model.User, error) {
+	identity, err := s.oidcRepo.GetIdentity(ctx, claims.Issuer, claims.Subject)
+	if err == nil {
+		if err := s.oidcRepo.RecordLogin(ctx, identity.ID, claims.Email); err != nil {
+			log.Printf("Failed to record OIDC login for identity %s: %v", identity.ID, err)
+		}
+
+		user, err := s.userRepo.GetByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		return user, nil
+	}
+
+	// Matching on email is only safe when the provider vouches for it
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, claims.Email)
+	if err == nil {
+		if !user.EmailVerified {
+			return nil, ErrOIDCAccountUnverified
+		}
+	} else {
+		user, err = s.createUser(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	newIdentity := &model.OIDCIdentity{
+		UserID:      user.ID,
+		Issuer:      claims.Issuer,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: &now,
+	}
+
+	if err := s.oidcRepo.CreateIdentity(ctx, newIdentity); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return user, nil
+}
+
+// createUser registers an account for a first-time sign-in. The password is
+// random and never shown, so the user signs in through the provider until
+// they set one with a password reset.
+func (s *OIDCService) createUser(ctx context.Context, claims *util.IDTokenClaims) (*model.User, error) {
+	randomPassword, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := s.passwordUtil.HashPassword(randomPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+
+	user := &model.User{
+		Name:         name,
+		Email:        claims.Email,
+		PasswordHash: passwordHash,
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if _, err := s.userRepo.MarkEmailVerified(ctx, user.ID, user.Email); err != nil {
+		return nil, fmt.Errorf("failed to mark email verified: %w", err)
+	}
+
+	return s.userRepo.GetByID(ctx, user.ID)
+}
+
+// link attaches the provider identity to a signed-in user
+func (s *OIDCService) link(ctx context.Context, userID uuid.UUID, claims *util.IDTokenClaims) (*model.OIDCIdentity, error) {
+	if existing, err := s.oidcRepo.GetIdentity(ctx, claims.Issuer, claims.Subject); err == nil {
+		if existing.UserID != userID {
+			return nil, ErrOIDCIdentityLinked
+		}
+		return existing, nil
+	}
+
+	identity := &model.OIDCIdentity{
+		UserID:  userID,
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	}
+
+	if err := s.oidcRepo.CreateIdentity(ctx, identity); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return identity, nil
+}
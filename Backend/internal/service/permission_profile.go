@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+)
+
+var (
+	ErrPermissionProfileNotFound = errors.New("permission profile not found")
+	ErrPermissionProfileExists   = errors.New("a permission profile with this name already exists")
+	ErrPermissionProfileInUse    = errors.New("permission profile is assigned to nominees, assign them another profile first")
+	ErrPermissionProfileName     = errors.New("permission profile name is required")
+)
+
+type PermissionProfileService struct {
+	profileRepo *postgres.PermissionProfileRepository
+	nomineeRepo *postgres.NomineeRepository
+}
+
+func NewPermissionProfileService(profileRepo *postgres.PermissionProfileRepository, nomineeRepo *postgres.NomineeRepository) *PermissionProfileService {
+	return &PermissionProfileService{
+		profileRepo: profileRepo,
+		nomineeRepo: nomineeRepo,
+	}
+}
+
+// BuiltInProfile is what an access level grants when the nominee has no
+// profile of their own. Unknown levels get the narrowest profile.
+func BuiltInProfile(accessLevel string) *model.PermissionProfile {
+	switch accessLevel {
+	case "Full":
+		return &model.PermissionProfile{
+			Name:           "Full",
+			AllowDownloads: true,
+			BuiltIn:        true,
+		}
+	case "Limited":
+		return &model.PermissionProfile{
+			Name:                 "Limited",
+			RedactAccountNumbers: true,
+			BuiltIn:              true,
+		}
+	default:
+		return &model.PermissionProfile{
+			Name:                "DocumentsOnly",
+			AssetTypes:          []string{},
+			SharedDocumentsOnly: true,
+			AllowDownloads:      true,
+			BuiltIn:             true,
+		}
+	}
+}
+
+// List returns the built-in profiles followed by the user's own
+func (s *PermissionProfileService) List(ctx context.Context, userID uuid.UUID) ([]model.PermissionProfile, error) {
+	custom, err := s.profileRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]model.PermissionProfile, 0, len(custom)+3)
+	for _, level := range []string{"Full", "Limited", "DocumentsOnly"} {
+		profiles = append(profiles, *BuiltInProfile(level))
+	}
+
+	return append(profiles, custom...), nil
+}
+
+func (s *PermissionProfileService) Create(ctx context.Context, profile *model.PermissionProfile) error {
+	if err := s.validate(ctx, profile); err != nil {
+		return err
+	}
+
+	return s.profileRepo.Create(ctx, profile)
+}
+
+func (s *PermissionProfileService) Update(ctx context.Context, profile *model.PermissionProfile) error {
+	if err := s.validate(ctx, profile); err != nil {
+		return err
+	}
+
+	updated, err := s.profileRepo.Update(ctx, profile)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return ErrPermissionProfileNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a profile no nominee is using. Letting nominees fall back
+// to their access level could quietly widen what they see.
+func (s *PermissionProfileService) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	nominees, err := s.nomineeRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, nominee := range nominees {
+		if nominee.PermissionProfileID != nil && *nominee.PermissionProfileID == id {
+			return ErrPermissionProfileInUse
+		}
+	}
+
+	deleted, err := s.profileRepo.Delete(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrPermissionProfileNotFound
+	}
+
+	return nil
+}
+
+// CheckOwner makes sure a profile being assigned to a nominee is the owner's
+func (s *PermissionProfileService) CheckOwner(ctx context.Context, userID, id uuid.UUID) error {
+	profile, err := s.profileRepo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPermissionProfileNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if profile.UserID != userID {
+		return ErrPermissionProfileNotFound
+	}
+
+	return nil
+}
+
+// Effective is the profile a nominee's session is held to. grantedLevel is
+// the level the session was granted; when the quorum held a nominee back
+// below their own level, their profile is narrowed to the granted one.
+func (s *PermissionProfileService) Effective(ctx context.Context, nominee *model.Nominee, grantedLevel string) (*model.PermissionProfile, error) {
+	profile := BuiltInProfile(nominee.AccessLevel)
+
+	if nominee.PermissionProfileID != nil {
+		custom, err := s.profileRepo.GetByID(ctx, *nominee.PermissionProfileID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err == nil {
+			profile = custom
+		}
+	}
+
+	if grantedLevel != nominee.AccessLevel {
+		profile = restrictProfile(profile, BuiltInProfile(grantedLevel))
+	}
+
+	return profile, nil
+}
+
+// Private methods
+
+func (s *PermissionProfileService) validate(ctx context.Context, profile *model.PermissionProfile) error {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if profile.Name == "" {
+		return ErrPermissionProfileName
+	}
+
+	for _, documentType := range profile.DocumentTypes {
+		if !ValidDocumentTypes[documentType] {
+			return ErrInvalidDocumentType
+		}
+	}
+
+	if profile.AssetTypes != nil {
+		assetTypes := make([]string, 0, len(profile.AssetTypes))
+		for _, assetType := range profile.AssetTypes {
+			if assetType = strings.TrimSpace(assetType); assetType != "" {
+				assetTypes = append(assetTypes, assetType)
+			}
+		}
+		profile.AssetTypes = assetTypes
+	}
+
+	// Names pick profiles in the UI, so they can't repeat the built-in ones either
+	existing, err := s.List(ctx, profile.UserID)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range existing {
+		if strings.EqualFold(other.Name, profile.Name) && (other.BuiltIn || other.ID != profile.ID) {
+			return ErrPermissionProfileExists
+		}
+	}
+
+	return nil
+}
+
+// restrictProfile keeps only what both profiles allow
+func restrictProfile(a, b *model.PermissionProfile) *model.PermissionProfile {
+	restricted := *a
+	restricted.AssetTypes = intersectTypes(a.AssetTypes, b.AssetTypes)
+	restricted.DocumentTypes = intersectTypes(a.DocumentTypes, b.DocumentTypes)
+	restricted.RedactAccountNumbers = a.RedactAccountNumbers || b.RedactAccountNumbers
+	restricted.RedactInstitutions = a.RedactInstitutions || b.RedactInstitutions
+	restricted.RedactNotes = a.RedactNotes || b.RedactNotes
+	restricted.SharedDocumentsOnly = a.SharedDocumentsOnly || b.SharedDocumentsOnly
+	restricted.AllowDownloads = a.AllowDownloads && b.AllowDownloads
+	return &restricted
+}
+
+// intersectTypes intersects two type lists where nil allows every type
+func intersectTypes(a, b []string) []string {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	types := make([]string, 0, len(a))
+	for _, t := range a {
+		if allowsType(b, t) {
+			types = append(types, t)
+		}
+	}
+
+	return types
+}
+
+func allowsType(types []string, t string) bool {
+	if types == nil {
+		return true
+	}
+
+	for _, allowed := range types {
+		if allowed == t {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+)
+
+var (
+	ErrInvalidQuorumThreshold = errors.New("quorum threshold must be at least 2 and no more than your number of nominees")
+	ErrInvalidQuorumWindow    = fmt.Errorf("quorum window must be between 1 and %d hours", MaxQuorumWindowHours)
+	ErrQuorumPolicyNotFound   = errors.New("no quorum policy is set")
+)
+
+const MaxQuorumWindowHours = 720
+
+// QuorumStatus is a policy with the approvals collected inside its window
+type QuorumStatus struct {
+	Threshold        int                    `json:"threshold"`
+	WindowHours      int                    `json:"window_hours"`
+	Met              bool                   `json:"met"`
+	MetAt            *time.Time             `json:"met_at"`
+	Approvals        []model.QuorumApproval `json:"approvals"`
+	EligibleNominees int                    `json:"eligible_nominees"`
+}
+
+type QuorumService struct {
+	quorumRepo   *postgres.QuorumRepository
+	nomineeRepo  *postgres.NomineeRepository
+	alertService *AlertService
+	accessLog    *NomineeAccessLogger
+}
+
+func NewQuorumService(quorumRepo *postgres.QuorumRepository, nomineeRepo *postgres.NomineeRepository, alertService *AlertService, accessLog *NomineeAccessLogger) *QuorumService {
+	return &QuorumService{
+		quorumRepo:   quorumRepo,
+		nomineeRepo:  nomineeRepo,
+		alertService: alertService,
+		accessLog:    accessLog,
+	}
+}
+
+// GetStatus returns the owner's policy and its progress, or nil without a policy
+func (s *QuorumService) GetStatus(ctx context.Context, userID uuid.UUID) (*QuorumStatus, error) {
+	policy, err := s.quorumRepo.GetPolicy(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.status(ctx, policy)
+}
+
+// SetPolicy creates or replaces the owner's policy, which restarts the quorum
+func (s *QuorumService) SetPolicy(ctx context.Context, userID uuid.UUID, threshold, windowHours int) (*QuorumStatus, error) {
+	if windowHours < 1 || windowHours > MaxQuorumWindowHours {
+		return nil, ErrInvalidQuorumWindow
+	}
+
+	nominees, err := s.quorumRepo.CountEligibleNominees(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if threshold < 2 || threshold > nominees {
+		return nil, ErrInvalidQuorumThreshold
+	}
+
+	policy := &model.QuorumPolicy{
+		UserID:      userID,
+		Threshold:   threshold,
+		WindowHours: windowHours,
+	}
+
+	if err := s.quorumRepo.SavePolicy(ctx, policy); err != nil {
+		return nil, fmt.Errorf("failed to save quorum policy: %w", err)
+	}
+
+	return s.GetStatus(ctx, userID)
+}
+
+// DeletePolicy lets any single nominee with Full access in again
+func (s *QuorumService) DeletePolicy(ctx context.Context, userID uuid.UUID) error {
+	deleted, err := s.quorumRepo.DeletePolicy(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrQuorumPolicyNotFound
+	}
+
+	return nil
+}
+
+// Approve counts a nominee's emergency access attempt towards the owner's
+// quorum. It returns nil when the owner has no policy.
+func (s *QuorumService) Approve(ctx context.Context, nominee *model.Nominee, client ClientInfo) (*QuorumStatus, error) {
+	policy, err := s.quorumRepo.GetPolicy(ctx, nominee.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.quorumRepo.AddApproval(ctx, nominee.UserID, nominee.ID, client.IPAddress); err != nil {
+		return nil, fmt.Errorf("failed to record approval: %w", err)
+	}
+
+	status, err := s.status(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	s.accessLog.Record(ctx, nominee.ID, fmt.Sprintf("Quorum approval recorded (%d of %d)", len(status.Approvals), status.Threshold), client)
+
+	if status.Met || len(status.Approvals) < status.Threshold {
+		return status, nil
+	}
+
+	met, err := s.quorumRepo.MarkMet(ctx, nominee.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record quorum: %w", err)
+	}
+
+	if met {
+		now := time.Now()
+		status.Met, status.MetAt = true, &now
+
+		s.accessLog.Record(ctx, nominee.ID, "Quorum reached: Full access released", client)
+
+		alert := &model.Alert{
+			UserID:         nominee.UserID,
+			AlertType:      "Security",
+			Severity:       "Critical",
+			Message:        fmt.Sprintf("%d of your nominees have approved emergency access, so nominees with Full access can now see all of your data.", len(status.Approvals)),
+			CreatedAt:      now,
+			ActionRequired: true,
+		}
+		if err := s.alertService.Create(ctx, alert); err != nil {
+			log.Printf("Failed to create quorum alert for user %s: %v", nominee.UserID, err)
+		}
+	}
+
+	return status, nil
+}
+
+// EffectiveAccessLevel is the level a nominee gets right now: Full access is
+// held back to Limited until the quorum has been met
+func (s *QuorumService) EffectiveAccessLevel(ctx context.Context, nominee *model.Nominee, status *QuorumStatus) string {
+	if nominee.AccessLevel != "Full" || status == nil || status.Met {
+		return nominee.AccessLevel
+	}
+
+	s.accessLog.Record(ctx, nominee.ID, fmt.Sprintf("Full access withheld until quorum is reached (%d of %d)", len(status.Approvals), status.Threshold), ClientInfo{})
+	return "Limited"
+}
+
+// Private methods
+
+func (s *QuorumService) status(ctx context.Context, policy *model.QuorumPolicy) (*QuorumStatus, error) {
+	since := time.Now().Add(-time.Duration(policy.WindowHours) * time.Hour)
+	approvals, err := s.quorumRepo.ListApprovals(ctx, policy.UserID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	eligible, err := s.quorumRepo.CountEligibleNominees(ctx, policy.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QuorumStatus{
+		Threshold:        policy.Threshold,
+		WindowHours:      policy.WindowHours,
+		Met:              policy.MetAt != nil,
+		MetAt:            policy.MetAt,
+		Approvals:        approvals,
+		EligibleNominees: eligible,
+	}, nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var (
+	ErrTwoFactorNotEnrolled     = errors.New("two-factor authentication is not enrolled")
+	ErrTwoFactorAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrInvalidTwoFactorCode     = errors.New("invalid two-factor code")
+	ErrTwoFactorSettingsChanged = errors.New("two-factor authentication must be changed through the 2fa endpoints")
+)
+
+const (
+	totpIssuer        = "Sampatti"
+	recoveryCodeCount = 10
+)
+
+type TwoFactorService struct {
+	twoFactorRepo *postgres.TwoFactorRepository
+	userRepo      *postgres.UserRepository
+	passwordUtil  *util.PasswordUtil
+	audit         *AuditService
+}
+
+func NewTwoFactorService(
+	twoFactorRepo *postgres.TwoFactorRepository,
+	userRepo *postgres.UserRepository,
+	passwordUtil *util.PasswordUtil,
+	audit *AuditService,
+) *TwoFactorService {
+	return &TwoFactorService{
+		twoFactorRepo: twoFactorRepo,
+		userRepo:      userRepo,
+		passwordUtil:  passwordUtil,
+		audit:         audit,
+	}
+}
+
+// Status reports whether 2FA is enabled and how many recovery codes are left
+func (s *TwoFactorService) Status(ctx context.Context, userID uuid.UUID) (map[string]interface{}, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	remaining, err := s.twoFactorRepo.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	totpConfirmed := false
+	if totp, err := s.twoFactorRepo.GetTOTP(ctx, userID); err == nil {
+		totpConfirmed = totp.ConfirmedAt != nil
+	}
+
+	return map[string]interface{}{
+		"enabled":                  user.TwoFactorEnabled,
+		"totp_confirmed":           totpConfirmed,
+		"recovery_codes_remaining": remaining,
+	}, nil
+}
+
+// BeginEnrollment generates a fresh TOTP secret and returns it with its otpauth URI
+func (s *TwoFactorService) BeginEnrollment(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", "", ErrUserNotFound
+	}
+
+	if user.TwoFactorEnabled {
+		return "", "", ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.twoFactorRepo.SaveTOTP(ctx, userID, secret); err != nil {
+		return "", "", fmt.Errorf("failed to save TOTP secret: %w", err)
+	}
+
+	return secret, util.TOTPProvisioningURI(totpIssuer, user.Email, secret), nil
+}
+
+// ConfirmEnrollment checks the first code from the authenticator, enables 2FA
+// and returns a new set of recovery codes. The codes are only shown once.
+func (s *TwoFactorService) ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	totp, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	if totp.ConfirmedAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	if err := s.verifyTOTP(ctx, totp, code); err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.ConfirmTOTP(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to confirm TOTP: %w", err)
+	}
+
+	codes, err := s.generateRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdateTwoFactorEnabled(ctx, userID, true); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "user.2fa_enabled", "user", userID, "Authenticator app")
+
+	return codes, nil
+}
+
+// Disable turns off 2FA after re-checking the password and a current code
+func (s *TwoFactorService) Disable(ctx context.Context, userID uuid.UUID, password, code string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if !s.passwordUtil.CheckPasswordHash(password, user.PasswordHash) {
+		return ErrInvalidCredentials
+	}
+
+	if err := s.VerifyCode(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if err := s.twoFactorRepo.DeleteTOTP(ctx, userID); err != nil {
+		return fmt.Errorf("failed to remove TOTP secret: %w", err)
+	}
+
+	if err := s.twoFactorRepo.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return fmt.Errorf("failed to remove recovery codes: %w", err)
+	}
+
+	if err := s.userRepo.UpdateTwoFactorEnabled(ctx, userID, false); err != nil {
+		return err
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "user.2fa_disabled", "user", userID, "Authenticator app")
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current TOTP code
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	totp, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil || totp.ConfirmedAt == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	if err := s.verifyTOTP(ctx, totp, code); err != nil {
+		return nil, err
+	}
+
+	return s.generateRecoveryCodes(ctx, userID)
+}
+
+// VerifyCode accepts either a current TOTP code or an unused recovery code
+func (s *TwoFactorService) VerifyCode(ctx context.Context, userID uuid.UUID, code string) error {
+	code = strings.TrimSpace(code)
+
+	totp, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil || totp.ConfirmedAt == nil {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	if len(code) == 6 {
+		return s.verifyTOTP(ctx, totp, code)
+	}
+
+	used, err := s.twoFactorRepo.ConsumeRecoveryCode(ctx, userID, util.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return fmt.Errorf("failed to check recovery code: %w", err)
+	}
+
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// Private methods
+
+// verifyTOTP accepts a code for a step after the last one used. MarkStepUsed
+// repeats the check atomically in case two requests race with the same code.
+func (s *TwoFactorService) verifyTOTP(ctx context.Context, totp *model.UserTOTP, code string) error {
+	step, ok := util.ValidateTOTP(totp.Secret, code, time.Now(), totp.LastUsedStep)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	fresh, err := s.twoFactorRepo.MarkStepUsed(ctx, totp.UserID, step)
+	if err != nil {
+		return fmt.Errorf("failed to record TOTP use: %w", err)
+	}
+
+	if !fresh {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (s *TwoFactorService) generateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		raw, err := util.GenerateCode(10)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = util.HashToken(normalizeRecoveryCode(codes[i]))
+	}
+
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"database/sql/driver"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var totpColumns = []string{"user_id", "secret", "confirmed_at", "last_used_step", "created_at"}
+
+// currentTOTP computes the RFC 6238 code for secret at now
+func currentTOTP(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(now.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func newTestTwoFactorService(t *testing.T) (*TwoFactorService, *AuditService, *fakeDB) {
+	t.Helper()
+	db, fake := newFakeDB(t)
+	fake.on("FROM audit_heads", []string{"seq", "hash"}, []driver.Value{int64(0), ""})
+
+	audit := NewAuditService(postgres.NewAuditRepository(db))
+	service := NewTwoFactorService(postgres.NewTwoFactorRepository(db), postgres.NewUserRepository(db), util.NewPasswordUtil(4), audit)
+	return service, audit, fake
+}
+
+// assertAudited checks exactly one audit entry with the action was written
+func assertAudited(t *testing.T, fake *fakeDB, audit *AuditService, action string) {
+	t.Helper()
+
+	entries := fake.executed("INSERT INTO audit_log")
+	if len(entries) != 1 {
+		t.Fatalf("%d audit entries written, want 1", len(entries))
+	}
+	// Arguments follow auditEntryColumns; the action is the sixth
+	if got := entries[0].Args[5]; got != action {
+		t.Fatalf("audit action = %v, want %s", got, action)
+	}
+	if audit.Failures() != 0 {
+		t.Fatalf("%d audit failures", audit.Failures())
+	}
+}
+
+func TestConfirmEnrollmentEnablesAndAudits(t *testing.T) {
+	service, audit, fake := newTestTwoFactorService(t)
+
+	userID := uuid.New()
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.on("FROM user_totp", totpColumns, []driver.Value{userID.String(), secret, nil, int64(0), time.Now()})
+
+	codes, err := service.ConfirmEnrollment(context.Background(), userID, currentTOTP(t, secret, time.Now()))
+	if err != nil {
+		t.Fatalf("ConfirmEnrollment: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("%d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	enabled := fake.executed("SET two_factor_enabled")
+	if len(enabled) != 1 || enabled[0].Args[0] != true {
+		t.Fatalf("two_factor_enabled updates = %v", enabled)
+	}
+
+	assertAudited(t, fake, audit, "user.2fa_enabled")
+}
+
+func TestDisableTurnsOffAndAudits(t *testing.T) {
+	service, audit, fake := newTestTwoFactorService(t)
+
+	userID := uuid.New()
+	passwordHash, err := util.NewPasswordUtil(4).HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmedAt := time.Now().Add(-time.Hour)
+	fake.on("FROM users", []string{"id", "password_hash", "two_factor_enabled"}, []driver.Value{userID.String(), passwordHash, true})
+	fake.on("FROM user_totp", totpColumns, []driver.Value{userID.String(), "GEZDGNBVGY3TQOJQ", confirmedAt, int64(0), confirmedAt})
+
+	// A recovery code is accepted while it is unused, which the fake reports
+	if err := service.Disable(context.Background(), userID, "correct horse", "ABCDE-FGHIJ"); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+
+	disabled := fake.executed("SET two_factor_enabled")
+	if len(disabled) != 1 || disabled[0].Args[0] != false {
+		t.Fatalf("two_factor_enabled updates = %v", disabled)
+	}
+
+	assertAudited(t, fake, audit, "user.2fa_disabled")
+}
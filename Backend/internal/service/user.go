@@ -55,10 +55,15 @@ func (s *UserService) UpdateSettings(ctx context.Context, id uuid.UUID, notifica
 		return ErrUserNotFound
 	}
 
+	// Two-factor authentication is enabled by confirming an enrollment and
+	// disabled with a current code, never by flipping the flag directly
+	if twoFactorEnabled != user.TwoFactorEnabled {
+		return ErrTwoFactorSettingsChanged
+	}
+
 	// Update settings
 	user.Notifications = notifications
 	user.DefaultCurrency = defaultCurrency
-	user.TwoFactorEnabled = twoFactorEnabled
 
 	return s.userRepo.Update(ctx, user)
 }
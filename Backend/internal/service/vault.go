@@ -0,0 +1,688 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var (
+	ErrVaultDisabled         = errors.New("the vault needs an encryption key to be configured")
+	ErrVaultNotFound         = errors.New("you haven't split a vault recovery key yet")
+	ErrVaultNoNominees       = errors.New("you need at least one invited or accepted nominee to hold a share")
+	ErrInvalidVaultThreshold = errors.New("threshold must be between 1 and the number of nominees holding shares")
+	ErrInvalidVaultShare     = errors.New("this share is not valid for you or belongs to a vault that has since been replaced")
+	ErrVaultLocked           = errors.New("this document is encrypted and needs more nominees to submit their vault shares before it can be opened")
+	ErrEncryptedDocumentURL  = errors.New("encrypted documents can't be downloaded through a link; download the file directly instead")
+)
+
+// vaultSharePrefix marks and versions the share text given to nominees
+const vaultSharePrefix = "SPS1-"
+
+// VaultStatus is what the owner sees of their vault
+type VaultStatus struct {
+	Vault  *model.Vault       `json:"vault"`
+	Shares []model.VaultShare `json:"shares"`
+	// Outstanding counts shares not yet delivered to their nominee
+	Outstanding int `json:"outstanding"`
+	Submitted   int `json:"submitted"`
+	// NeedsResplit is set when the nominees who should hold shares no
+	// longer match those who do
+	NeedsResplit bool `json:"needs_resplit"`
+}
+
+// VaultProgress is what a nominee sees while shares are being collected
+type VaultProgress struct {
+	HasShare       bool `json:"has_share"`
+	ShareSubmitted bool `json:"share_submitted"`
+	Threshold      int  `json:"threshold"`
+	Submitted      int  `json:"submitted"`
+	Unlocked       bool `json:"unlocked"`
+}
+
+// VaultService encrypts documents marked as encrypted with a key of their
+// own. Each key is wrapped for the owner with the server key, and for
+// recovery to an X25519 vault key whose private half is split among the
+// owner's nominees with Shamir's scheme. During emergency access the
+// nominees submit their shares and, once enough are in, the server combines
+// them to unwrap the keys.
+//
+// The shares only gate the nominee path. The owner's copy of every document
+// key is wrapped with the server's ENCRYPTION_KEY so owners can open their
+// documents without holding a key of their own, which means whoever holds
+// that key and the database can decrypt every document without any share.
+// Encryption protects documents from leaked storage or a leaked database on
+// its own, not from the server.
+type VaultService struct {
+	vaultRepo   *postgres.VaultRepository
+	nomineeRepo *postgres.NomineeRepository
+	userRepo    *postgres.UserRepository
+	emergency   *EmergencyAccessService
+	mailer      Mailer
+	audit       *AuditService
+	sealer      *util.Sealer
+}
+
+func NewVaultService(
+	vaultRepo *postgres.VaultRepository,
+	nomineeRepo *postgres.NomineeRepository,
+	userRepo *postgres.UserRepository,
+	emergency *EmergencyAccessService,
+	mailer Mailer,
+	audit *AuditService,
+	sealer *util.Sealer,
+) *VaultService {
+	return &VaultService{
+		vaultRepo:   vaultRepo,
+		nomineeRepo: nomineeRepo,
+		userRepo:    userRepo,
+		emergency:   emergency,
+		mailer:      mailer,
+		audit:       audit,
+		sealer:      sealer,
+	}
+}
+
+// Enabled reports whether an encryption key is configured. Without one
+// documents are stored as uploaded and there is no vault.
+func (s *VaultService) Enabled() bool {
+	return s.sealer != nil
+}
+
+// Status returns the owner's vault and who holds, has received and has
+// submitted each share
+func (s *VaultService) Status(ctx context.Context, userID uuid.UUID) (*VaultStatus, error) {
+	if !s.Enabled() {
+		return nil, ErrVaultDisabled
+	}
+
+	vault, err := s.vaultRepo.GetByUserID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVaultNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	shares, err := s.vaultRepo.ListShares(ctx, vault.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	nominees, err := s.shareholders(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &VaultStatus{Vault: vault, Shares: shares}
+	holders := make(map[uuid.UUID]bool, len(shares))
+	for _, share := range shares {
+		holders[share.NomineeID] = true
+		if share.DeliveredAt == nil {
+			status.Outstanding++
+		}
+		if share.SubmittedAt != nil {
+			status.Submitted++
+		}
+		if share.NomineeStatus != NomineeInvited && share.NomineeStatus != NomineeAccepted {
+			status.NeedsResplit = true
+		}
+	}
+
+	for _, nominee := range nominees {
+		if !holders[nominee.ID] {
+			status.NeedsResplit = true
+		}
+	}
+
+	return status, nil
+}
+
+// Split creates a new vault key and splits it among the owner's invited and
+// accepted nominees, any threshold of whom can recover it. The keys of
+// encrypted documents are rewrapped to it and the previous vault, with its
+// shares, is discarded. Accepted nominees are emailed their share now;
+// invited ones receive it with their next invitation.
+func (s *VaultService) Split(ctx context.Context, userID uuid.UUID, threshold int) (*VaultStatus, error) {
+	if !s.Enabled() {
+		return nil, ErrVaultDisabled
+	}
+
+	nominees, err := s.shareholders(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nominees) == 0 {
+		return nil, ErrVaultNoNominees
+	}
+
+	if threshold < 1 || threshold > len(nominees) {
+		return nil, ErrInvalidVaultThreshold
+	}
+
+	privateKey, err := util.GenerateVaultKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate vault key: %w", err)
+	}
+
+	parts, err := util.SplitSecret(privateKey.Bytes(), len(nominees), threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	vault := &model.Vault{
+		ID:        uuid.New(),
+		UserID:    userID,
+		PublicKey: privateKey.PublicKey().Bytes(),
+		Threshold: threshold,
+	}
+
+	shares := make([]model.VaultShare, len(nominees))
+	for i, nominee := range nominees {
+		sealed, err := s.sealer.Seal(parts[i], shareAssociatedData(vault.ID, nominee.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to seal vault share: %w", err)
+		}
+
+		shares[i] = model.VaultShare{
+			NomineeID:   nominee.ID,
+			ShareIndex:  int(parts[i][0]),
+			ShareHash:   hashVaultShare(parts[i]),
+			SealedShare: sealed,
+		}
+	}
+
+	keys, err := s.vaultRepo.ListDocumentKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load document keys: %w", err)
+	}
+
+	for i := range keys {
+		documentKey, err := s.sealer.Open(keys[i].OwnerWrapped, keys[i].DocumentID[:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to open key of document %s: %w", keys[i].DocumentID, err)
+		}
+
+		keys[i].RecoveryWrapped, err = util.WrapKey(vault.PublicKey, documentKey, keys[i].DocumentID[:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to wrap key of document %s: %w", keys[i].DocumentID, err)
+		}
+	}
+
+	if err := s.vaultRepo.Replace(ctx, vault, shares, keys); err != nil {
+		return nil, fmt.Errorf("failed to store vault: %w", err)
+	}
+
+	s.audit.RecordUserAction(ctx, userID, "vault.split", "vault", vault.ID, fmt.Sprintf("Recovery key split among %d nominee(s), %d needed", len(nominees), threshold))
+
+	owner, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to load user %s to deliver vault shares: %v", userID, err)
+	} else {
+		for i, nominee := range nominees {
+			if nominee.Status == NomineeAccepted {
+				s.deliver(ctx, owner, &nominees[i], vault, parts[i])
+			}
+		}
+	}
+
+	return s.Status(ctx, userID)
+}
+
+// PendingShare returns the share text of a nominee whose share hasn't been
+// delivered yet, and how many shares open the vault, for inclusion in their
+// invitation. ok is false if there is nothing to deliver.
+func (s *VaultService) PendingShare(ctx context.Context, nominee *model.Nominee) (string, int, bool) {
+	if !s.Enabled() {
+		return "", 0, false
+	}
+
+	vault, share, err := s.nomineeShare(ctx, nominee)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Failed to load vault share of nominee %s: %v", nominee.ID, err)
+		}
+		return "", 0, false
+	}
+
+	if share.DeliveredAt != nil || share.SealedShare == nil {
+		return "", 0, false
+	}
+
+	part, err := s.sealer.Open(share.SealedShare, shareAssociatedData(vault.ID, nominee.ID))
+	if err != nil {
+		log.Printf("Failed to open vault share of nominee %s: %v", nominee.ID, err)
+		return "", 0, false
+	}
+
+	return encodeVaultShare(vault.ID, part), vault.Threshold, true
+}
+
+// ShareDelivered records that the nominee has been sent their share, after
+// which the server no longer keeps a copy
+func (s *VaultService) ShareDelivered(ctx context.Context, nominee *model.Nominee) {
+	vault, err := s.vaultRepo.GetByUserID(ctx, nominee.UserID)
+	if err != nil {
+		log.Printf("Failed to load vault of user %s: %v", nominee.UserID, err)
+		return
+	}
+
+	if err := s.vaultRepo.MarkDelivered(ctx, vault.ID, nominee.ID); err != nil {
+		log.Printf("Failed to mark vault share of nominee %s as delivered: %v", nominee.ID, err)
+	}
+}
+
+// Progress tells a nominee with emergency access how many shares are in
+func (s *VaultService) Progress(ctx context.Context, nomineeID uuid.UUID) (*VaultProgress, error) {
+	nominee, err := s.grantedNominee(ctx, nomineeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.progress(ctx, nominee)
+}
+
+// SubmitShare takes a nominee's share during emergency access. It is checked
+// against the vault before being kept, sealed, until enough are in.
+func (s *VaultService) SubmitShare(ctx context.Context, nomineeID uuid.UUID, shareText string, client ClientInfo) (*VaultProgress, error) {
+	nominee, err := s.grantedNominee(ctx, nomineeID)
+	if err != nil {
+		return nil, err
+	}
+
+	vault, share, err := s.nomineeShare(ctx, nominee)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidVaultShare
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vaultID, part, ok := decodeVaultShare(shareText)
+	if !ok || vaultID != vault.ID || int(part[0]) != share.ShareIndex || hashVaultShare(part) != share.ShareHash {
+		return nil, ErrInvalidVaultShare
+	}
+
+	sealed, err := s.sealer.Seal(part, shareAssociatedData(vault.ID, nominee.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal vault share: %w", err)
+	}
+
+	if err := s.vaultRepo.SubmitShare(ctx, vault.ID, nominee.ID, sealed); err != nil {
+		return nil, fmt.Errorf("failed to store vault share: %w", err)
+	}
+
+	s.audit.Record(ctx, AuditEvent{
+		OwnerID:    nominee.UserID,
+		ActorType:  AuditActorNominee,
+		ActorID:    &nominee.ID,
+		Action:     "vault.share_submitted",
+		EntityType: "vault",
+		EntityID:   &vault.ID,
+		Summary:    fmt.Sprintf("%s submitted their vault share", nominee.Name),
+		IPAddress:  client.IPAddress,
+	})
+
+	return s.progress(ctx, nominee)
+}
+
+// SealDocument encrypts a new document's content under a fresh key, which
+// is returned for SaveDocumentKey once the document exists
+func (s *VaultService) SealDocument(content []byte) ([]byte, []byte, error) {
+	documentKey := make([]byte, 32)
+	if _, err := rand.Read(documentKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate document key: %w", err)
+	}
+
+	sealer, err := util.NewSealerFromKey(documentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sealed, err := sealer.Seal(content, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sealed, documentKey, nil
+}
+
+// SaveDocumentKey stores a document's key wrapped for the owner and, if
+// they have one, to their vault
+func (s *VaultService) SaveDocumentKey(ctx context.Context, document *model.Document, documentKey []byte) error {
+	ownerWrapped, err := s.sealer.Seal(documentKey, document.ID[:])
+	if err != nil {
+		return err
+	}
+
+	key := &model.DocumentKey{
+		DocumentID:   document.ID,
+		UserID:       document.UserID,
+		OwnerWrapped: ownerWrapped,
+	}
+
+	vault, err := s.vaultRepo.GetByUserID(ctx, document.UserID)
+	switch {
+	case err == nil:
+		key.RecoveryWrapped, err = util.WrapKey(vault.PublicKey, documentKey, document.ID[:])
+		if err != nil {
+			return err
+		}
+		key.VaultID = &vault.ID
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	return s.vaultRepo.CreateDocumentKey(ctx, key)
+}
+
+// IsSealed reports whether a document's stored content is encrypted
+func (s *VaultService) IsSealed(ctx context.Context, documentID uuid.UUID) (bool, error) {
+	_, err := s.vaultRepo.GetDocumentKey(ctx, documentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// OpenForOwner decrypts a document's content with the owner's copy of its
+// key. Content stored without a key is returned as it is.
+func (s *VaultService) OpenForOwner(ctx context.Context, document *model.Document, content []byte) ([]byte, error) {
+	key, err := s.vaultRepo.GetDocumentKey(ctx, document.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return content, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.Enabled() {
+		return nil, ErrVaultDisabled
+	}
+
+	documentKey, err := s.sealer.Open(key.OwnerWrapped, document.ID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return openDocument(documentKey, content)
+}
+
+// OpenForNominee decrypts a document's content with the vault key that the
+// submitted shares recover. Content stored without a key is returned as it is.
+func (s *VaultService) OpenForNominee(ctx context.Context, nominee *model.Nominee, document *model.Document, content []byte) ([]byte, error) {
+	key, err := s.vaultRepo.GetDocumentKey(ctx, document.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return content, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.Enabled() {
+		return nil, ErrVaultDisabled
+	}
+
+	vault, err := s.vaultRepo.GetByUserID(ctx, document.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVaultLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if key.VaultID == nil || *key.VaultID != vault.ID || key.RecoveryWrapped == nil {
+		return nil, ErrVaultLocked
+	}
+
+	privateKey, err := s.recoverKey(ctx, vault)
+	if err != nil {
+		return nil, err
+	}
+
+	documentKey, err := util.UnwrapKey(privateKey, key.RecoveryWrapped, document.ID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return openDocument(documentKey, content)
+}
+
+// Private methods
+
+// shareholders returns the owner's nominees who should hold a share
+func (s *VaultService) shareholders(ctx context.Context, userID uuid.UUID) ([]model.Nominee, error) {
+	nominees, err := s.nomineeRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load nominees: %w", err)
+	}
+
+	holders := make([]model.Nominee, 0, len(nominees))
+	for _, nominee := range nominees {
+		if nominee.Status == NomineeInvited || nominee.Status == NomineeAccepted {
+			holders = append(holders, nominee)
+		}
+	}
+
+	return holders, nil
+}
+
+func (s *VaultService) nomineeShare(ctx context.Context, nominee *model.Nominee) (*model.Vault, *model.VaultShare, error) {
+	vault, err := s.vaultRepo.GetByUserID(ctx, nominee.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	share, err := s.vaultRepo.GetShare(ctx, vault.ID, nominee.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return vault, share, nil
+}
+
+func (s *VaultService) grantedNominee(ctx context.Context, nomineeID uuid.UUID) (*model.Nominee, error) {
+	if !s.Enabled() {
+		return nil, ErrVaultDisabled
+	}
+
+	nominee, err := s.nomineeRepo.GetByID(ctx, nomineeID)
+	if err != nil {
+		return nil, ErrNomineeNotFound
+	}
+
+	granted, err := s.emergency.IsGranted(ctx, nominee)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check emergency access: %w", err)
+	}
+
+	if !granted {
+		return nil, ErrAccessNotReleased
+	}
+
+	return nominee, nil
+}
+
+func (s *VaultService) progress(ctx context.Context, nominee *model.Nominee) (*VaultProgress, error) {
+	vault, err := s.vaultRepo.GetByUserID(ctx, nominee.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVaultNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	shares, err := s.vaultRepo.ListShares(ctx, vault.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &VaultProgress{Threshold: vault.Threshold}
+	for _, share := range shares {
+		counts, err := s.submissionCounts(ctx, &share)
+		if err != nil {
+			return nil, err
+		}
+		if counts {
+			progress.Submitted++
+		}
+		if share.NomineeID == nominee.ID {
+			progress.HasShare = true
+			progress.ShareSubmitted = share.SubmittedAt != nil
+		}
+	}
+	progress.Unlocked = progress.Submitted >= vault.Threshold
+
+	return progress, nil
+}
+
+// recoverKey combines the submitted shares into the vault's private key,
+// checking it matches the vault's public key. Only shares of nominees who
+// still have emergency access count; the rest are normally cleared when
+// access ends, but a grant can run out before the next check expires it.
+func (s *VaultService) recoverKey(ctx context.Context, vault *model.Vault) (*ecdh.PrivateKey, error) {
+	shares, err := s.vaultRepo.ListShares(ctx, vault.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([][]byte, 0, vault.Threshold)
+	for _, share := range shares {
+		counts, err := s.submissionCounts(ctx, &share)
+		if err != nil {
+			return nil, err
+		}
+		if !counts {
+			continue
+		}
+
+		part, err := s.sealer.Open(share.SubmittedShare, shareAssociatedData(vault.ID, share.NomineeID))
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, part)
+		if len(parts) == vault.Threshold {
+			break
+		}
+	}
+
+	if len(parts) < vault.Threshold {
+		return nil, ErrVaultLocked
+	}
+
+	secret, err := util.CombineShares(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := util.ParseVaultKey(secret)
+	if err != nil || !bytes.Equal(privateKey.PublicKey().Bytes(), vault.PublicKey) {
+		return nil, errors.New("submitted vault shares do not recover the vault key")
+	}
+
+	return privateKey, nil
+}
+
+// submissionCounts reports whether a share has been submitted by a nominee
+// who still has emergency access
+func (s *VaultService) submissionCounts(ctx context.Context, share *model.VaultShare) (bool, error) {
+	if share.SubmittedShare == nil {
+		return false, nil
+	}
+
+	nominee, err := s.nomineeRepo.GetByID(ctx, share.NomineeID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load nominee %s: %w", share.NomineeID, err)
+	}
+
+	granted, err := s.emergency.IsGranted(ctx, nominee)
+	if err != nil {
+		return false, fmt.Errorf("failed to check emergency access: %w", err)
+	}
+
+	return granted, nil
+}
+
+func (s *VaultService) deliver(ctx context.Context, owner *model.User, nominee *model.Nominee, vault *model.Vault, part []byte) {
+	msg := MailMessage{
+		To:      nominee.Email,
+		Subject: fmt.Sprintf("Your recovery share for %s's Sampatti vault", owner.Name),
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n%s has split the recovery key for their encrypted documents among their nominees. Your share is below:\n\n%s\n\n%s\nKeep it somewhere safe and private. It replaces any share you were sent before, and we don't keep a copy.\n",
+			nominee.Name,
+			owner.Name,
+			encodeVaultShare(vault.ID, part),
+			vaultShareInstructions(vault.Threshold),
+		),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("Failed to email vault share to nominee %s: %v", nominee.ID, err)
+		return
+	}
+
+	if err := s.vaultRepo.MarkDelivered(ctx, vault.ID, nominee.ID); err != nil {
+		log.Printf("Failed to mark vault share of nominee %s as delivered: %v", nominee.ID, err)
+	}
+}
+
+// vaultShareInstructions explains what a nominee does with their share
+func vaultShareInstructions(threshold int) string {
+	return fmt.Sprintf("If you are ever given emergency access, submit this share from the nominee portal. Encrypted documents open once %d nominee(s) have submitted theirs.\n", threshold)
+}
+
+func openDocument(documentKey, content []byte) ([]byte, error) {
+	sealer, err := util.NewSealerFromKey(documentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return sealer.Open(content, nil)
+}
+
+// shareAssociatedData binds a sealed share to its vault and nominee
+func shareAssociatedData(vaultID, nomineeID uuid.UUID) []byte {
+	return append(vaultID[:], nomineeID[:]...)
+}
+
+func hashVaultShare(part []byte) string {
+	sum := sha256.Sum256(part)
+	return hex.EncodeToString(sum[:])
+}
+
+// encodeVaultShare turns a share into text a nominee can keep and paste
+// back: the vault ID followed by the share, base64 encoded
+func encodeVaultShare(vaultID uuid.UUID, part []byte) string {
+	return vaultSharePrefix + base64.RawURLEncoding.EncodeToString(append(vaultID[:], part...))
+}
+
+func decodeVaultShare(text string) (uuid.UUID, []byte, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, vaultSharePrefix) {
+		return uuid.Nil, nil, false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(text, vaultSharePrefix))
+	if err != nil || len(data) < 18 {
+		return uuid.Nil, nil, false
+	}
+
+	vaultID, _ := uuid.FromBytes(data[:16])
+	return vaultID, data[16:], true
+}
@@ -0,0 +1,467 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/config"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var (
+	ErrWebAuthnCeremony            = errors.New("invalid or expired webauthn ceremony")
+	ErrWebAuthnVerification        = errors.New("webauthn verification failed")
+	ErrNoWebAuthnCredentials       = errors.New("no security keys or passkeys are registered")
+	ErrCredentialNotFound          = errors.New("credential not found")
+	ErrCredentialAlreadyRegistered = errors.New("this authenticator is already registered")
+)
+
+// Ceremony purposes stored with each challenge, so a challenge issued for one
+// flow can't be answered in another
+const (
+	webauthnPurposeRegister     = "register"
+	webauthnPurposeLogin        = "login"
+	webauthnPurposeSecondFactor = "2fa"
+)
+
+const (
+	webauthnChallengeTTL   = 5 * time.Minute
+	webauthnChallengeBytes = 32
+	webauthnMaxNameLength  = 100
+)
+
+// CredentialDescriptor names a registered credential in ceremony options
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// CredentialCreationOptions is passed to navigator.credentials.create() by the
+// frontend after decoding the base64url fields
+type CredentialCreationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams []struct {
+		Type string `json:"type"`
+		Alg  int64  `json:"alg"`
+	} `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	Attestation string `json:"attestation"`
+}
+
+// CredentialRequestOptions is passed to navigator.credentials.get()
+type CredentialRequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is the credential returned by navigator.credentials.create(),
+// with binary fields base64url encoded
+type RegistrationResponse struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+		AttestationObject string   `json:"attestationObject" binding:"required"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the credential returned by navigator.credentials.get(),
+// with binary fields base64url encoded
+type AssertionResponse struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AuthenticatorData string `json:"authenticatorData" binding:"required"`
+		Signature         string `json:"signature" binding:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+type WebAuthnService struct {
+	webauthnRepo *postgres.WebAuthnRepository
+	userRepo     *postgres.UserRepository
+	passwordUtil *util.PasswordUtil
+	cfg          *config.WebAuthnConfig
+}
+
+func NewWebAuthnService(
+	webauthnRepo *postgres.WebAuthnRepository,
+	userRepo *postgres.UserRepository,
+	passwordUtil *util.PasswordUtil,
+	cfg *config.WebAuthnConfig,
+) *WebAuthnService {
+	return &WebAuthnService{
+		webauthnRepo: webauthnRepo,
+		userRepo:     userRepo,
+		passwordUtil: passwordUtil,
+		cfg:          cfg,
+	}
+}
+
+// BeginRegistration starts registering a new authenticator for the user
+func (s *WebAuthnService) BeginRegistration(ctx context.Context, userID uuid.UUID) (uuid.UUID, *CredentialCreationOptions, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return uuid.Nil, nil, ErrUserNotFound
+	}
+
+	existing, err := s.webauthnRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	challenge, err := s.newChallenge(ctx, &userID, webauthnPurposeRegister)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	options := &CredentialCreationOptions{
+		Challenge:          encodeBase64URL(challenge.Challenge),
+		Timeout:            webauthnChallengeTTL.Milliseconds(),
+		ExcludeCredentials: credentialDescriptors(existing),
+		Attestation:        "none",
+	}
+	options.RP.ID = s.cfg.RPID
+	options.RP.Name = s.cfg.RPName
+	options.User.ID = encodeBase64URL(user.ID[:])
+	options.User.Name = user.Email
+	options.User.DisplayName = user.Name
+	for _, alg := range []int64{util.COSEAlgEdDSA, util.COSEAlgES256, util.COSEAlgRS256} {
+		options.PubKeyCredParams = append(options.PubKeyCredParams, struct {
+			Type string `json:"type"`
+			Alg  int64  `json:"alg"`
+		}{Type: "public-key", Alg: alg})
+	}
+	// Discoverable credentials can sign in without an email; others still work as a second factor
+	options.AuthenticatorSelection.ResidentKey = "preferred"
+	options.AuthenticatorSelection.UserVerification = "preferred"
+
+	return challenge.ID, options, nil
+}
+
+// FinishRegistration verifies the authenticator's response and stores the new credential
+func (s *WebAuthnService) FinishRegistration(ctx context.Context, userID, ceremonyID uuid.UUID, name string, response *RegistrationResponse) (*model.WebAuthnCredential, error) {
+	challenge, err := s.webauthnRepo.ConsumeChallenge(ctx, ceremonyID, webauthnPurposeRegister)
+	if err != nil || challenge.UserID == nil || *challenge.UserID != userID {
+		return nil, ErrWebAuthnCeremony
+	}
+
+	clientData, err := decodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, ErrWebAuthnVerification
+	}
+	attestationObject, err := decodeBase64URL(response.Response.AttestationObject)
+	if err != nil {
+		return nil, ErrWebAuthnVerification
+	}
+
+	clientDataHash, err := util.VerifyClientData(clientData, util.WebAuthnCreate, challenge.Challenge, s.cfg.Origins)
+	if err != nil {
+		return nil, s.verificationFailed(userID, err)
+	}
+
+	authData, err := util.ParseAttestation(attestationObject, clientDataHash)
+	if err != nil {
+		return nil, s.verificationFailed(userID, err)
+	}
+
+	if err := authData.VerifyRPID(s.cfg.RPID); err != nil {
+		return nil, s.verificationFailed(userID, err)
+	}
+
+	if err := authData.VerifyFlags(false); err != nil {
+		return nil, s.verificationFailed(userID, err)
+	}
+
+	if rawID, err := decodeBase64URL(response.ID); err != nil || !bytes.Equal(rawID, authData.CredentialID) {
+		return nil, s.verificationFailed(userID, errors.New("credential ID does not match attested data"))
+	}
+
+	key, err := util.ParseCOSEKey(authData.PublicKey)
+	if err != nil {
+		return nil, s.verificationFailed(userID, err)
+	}
+
+	if _, err := s.webauthnRepo.GetByCredentialID(ctx, authData.CredentialID); err == nil {
+		return nil, ErrCredentialAlreadyRegistered
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+	if len(name) > webauthnMaxNameLength {
+		name = name[:webauthnMaxNameLength]
+	}
+
+	credential := &model.WebAuthnCredential{
+		UserID:       userID,
+		CredentialID: authData.CredentialID,
+		PublicKey:    authData.PublicKey,
+		Algorithm:    key.Algorithm,
+		SignCount:    int64(authData.SignCount),
+		AAGUID:       authData.AAGUID,
+		Transports:   strings.Join(response.Response.Transports, ","),
+		Name:         name,
+	}
+
+	if err := s.webauthnRepo.CreateCredential(ctx, credential); err != nil {
+		return nil, fmt.Errorf("failed to store credential: %w", err)
+	}
+
+	return credential, nil
+}
+
+// BeginLogin starts an assertion ceremony. Without a user it is a passwordless
+// login with a discoverable credential; with one it is that user's second factor.
+func (s *WebAuthnService) BeginLogin(ctx context.Context, userID *uuid.UUID) (uuid.UUID, *CredentialRequestOptions, error) {
+	options := &CredentialRequestOptions{
+		Timeout:          webauthnChallengeTTL.Milliseconds(),
+		RPID:             s.cfg.RPID,
+		AllowCredentials: []CredentialDescriptor{},
+		UserVerification: "required",
+	}
+
+	purpose := webauthnPurposeLogin
+	if userID != nil {
+		purpose = webauthnPurposeSecondFactor
+		options.UserVerification = "preferred"
+
+		credentials, err := s.webauthnRepo.ListByUserID(ctx, *userID)
+		if err != nil {
+			return uuid.Nil, nil, err
+		}
+		if len(credentials) == 0 {
+			return uuid.Nil, nil, ErrNoWebAuthnCredentials
+		}
+		options.AllowCredentials = credentialDescriptors(credentials)
+	}
+
+	challenge, err := s.newChallenge(ctx, userID, purpose)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	options.Challenge = encodeBase64URL(challenge.Challenge)
+
+	return challenge.ID, options, nil
+}
+
+// FinishLogin verifies an assertion from a ceremony started with BeginLogin and
+// returns the user it authenticates. userID must match the one passed to BeginLogin.
+func (s *WebAuthnService) FinishLogin(ctx context.Context, userID *uuid.UUID, ceremonyID uuid.UUID, response *AssertionResponse) (*model.User, error) {
+	purpose := webauthnPurposeLogin
+	if userID != nil {
+		purpose = webauthnPurposeSecondFactor
+	}
+
+	challenge, err := s.webauthnRepo.ConsumeChallenge(ctx, ceremonyID, purpose)
+	if err != nil {
+		return nil, ErrWebAuthnCeremony
+	}
+	if userID != nil && (challenge.UserID == nil || *challenge.UserID != *userID) {
+		return nil, ErrWebAuthnCeremony
+	}
+
+	rawID, err := decodeBase64URL(response.ID)
+	if err != nil {
+		return nil, ErrWebAuthnVerification
+	}
+
+	credential, err := s.webauthnRepo.GetByCredentialID(ctx, rawID)
+	if err != nil {
+		return nil, ErrWebAuthnVerification
+	}
+
+	if userID != nil && credential.UserID != *userID {
+		return nil, s.verificationFailed(credential.UserID, errors.New("credential belongs to another user"))
+	}
+
+	// Discoverable credentials report the user handle they were registered with
+	userHandle, err := decodeBase64URL(response.Response.UserHandle)
+	if err != nil || (userID == nil && len(userHandle) == 0) ||
+		(len(userHandle) > 0 && !bytes.Equal(userHandle, credential.UserID[:])) {
+		return nil, s.verificationFailed(credential.UserID, errors.New("user handle does not match credential"))
+	}
+
+	clientData, err := decodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, ErrWebAuthnVerification
+	}
+	rawAuthData, err := decodeBase64URL(response.Response.AuthenticatorData)
+	if err != nil {
+		return nil, ErrWebAuthnVerification
+	}
+	signature, err := decodeBase64URL(response.Response.Signature)
+	if err != nil {
+		return nil, ErrWebAuthnVerification
+	}
+
+	clientDataHash, err := util.VerifyClientData(clientData, util.WebAuthnGet, challenge.Challenge, s.cfg.Origins)
+	if err != nil {
+		return nil, s.verificationFailed(credential.UserID, err)
+	}
+
+	authData, err := util.ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, s.verificationFailed(credential.UserID, err)
+	}
+
+	if err := authData.VerifyRPID(s.cfg.RPID); err != nil {
+		return nil, s.verificationFailed(credential.UserID, err)
+	}
+
+	// Without a password the authenticator's own PIN or biometric is the second
+	// factor. Authenticators without a counter can't be checked for clones, so
+	// they have to verify the user every time as well.
+	counterless := credential.SignCount == 0 && authData.SignCount == 0
+	if err := authData.VerifyFlags(userID == nil || counterless); err != nil {
+		return nil, s.verificationFailed(credential.UserID, err)
+	}
+
+	key, err := util.ParseCOSEKey(credential.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored credential key: %w", err)
+	}
+
+	if err := key.VerifyAssertion(rawAuthData, clientDataHash, signature); err != nil {
+		return nil, s.verificationFailed(credential.UserID, err)
+	}
+
+	// RecordUse repeats the counter check atomically for concurrent logins
+	advanced := util.SignCountAdvanced(uint32(credential.SignCount), authData.SignCount)
+	if advanced {
+		advanced, err = s.webauthnRepo.RecordUse(ctx, credential.ID, int64(authData.SignCount))
+		if err != nil {
+			return nil, fmt.Errorf("failed to update credential: %w", err)
+		}
+	}
+	if !advanced {
+		log.Printf("WebAuthn credential %s of user %s presented sign count %d after %d, possible cloned authenticator",
+			credential.ID, credential.UserID, authData.SignCount, credential.SignCount)
+		return nil, ErrWebAuthnVerification
+	}
+
+	user, err := s.userRepo.GetByID(ctx, credential.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// HasCredentials reports whether the user has registered any authenticator
+func (s *WebAuthnService) HasCredentials(ctx context.Context, userID uuid.UUID) (bool, error) {
+	count, err := s.webauthnRepo.CountByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ListCredentials returns the user's registered authenticators
+func (s *WebAuthnService) ListCredentials(ctx context.Context, userID uuid.UUID) ([]model.WebAuthnCredential, error) {
+	return s.webauthnRepo.ListByUserID(ctx, userID)
+}
+
+// RemoveCredential deletes one of the user's authenticators after checking their password
+func (s *WebAuthnService) RemoveCredential(ctx context.Context, userID, credentialID uuid.UUID, password string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if !s.passwordUtil.CheckPasswordHash(password, user.PasswordHash) {
+		return ErrInvalidCredentials
+	}
+
+	deleted, err := s.webauthnRepo.DeleteCredential(ctx, userID, credentialID)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrCredentialNotFound
+	}
+
+	return nil
+}
+
+// Private methods
+
+func (s *WebAuthnService) newChallenge(ctx context.Context, userID *uuid.UUID, purpose string) (*model.WebAuthnChallenge, error) {
+	value := make([]byte, webauthnChallengeBytes)
+	if _, err := rand.Read(value); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+
+	challenge := &model.WebAuthnChallenge{
+		UserID:    userID,
+		Purpose:   purpose,
+		Challenge: value,
+		ExpiresAt: time.Now().Add(webauthnChallengeTTL),
+	}
+
+	if err := s.webauthnRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, fmt.Errorf("failed to store challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// verificationFailed logs why a ceremony was rejected and returns the generic error
+func (s *WebAuthnService) verificationFailed(userID uuid.UUID, reason error) error {
+	log.Printf("WebAuthn verification failed for user %s: %v", userID, reason)
+	return ErrWebAuthnVerification
+}
+
+func credentialDescriptors(credentials []model.WebAuthnCredential) []CredentialDescriptor {
+	descriptors := make([]CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptor := CredentialDescriptor{Type: "public-key", ID: encodeBase64URL(credential.CredentialID)}
+		if credential.Transports != "" {
+			descriptor.Transports = strings.Split(credential.Transports, ",")
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+	return descriptors
+}
+
+func encodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeBase64URL accepts base64url with or without padding, as browsers and
+// client libraries differ
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sampatti/internal/model"
+	"github.com/sampatti/internal/repository/postgres"
+	"github.com/sampatti/internal/util"
+)
+
+var (
+	ErrWebhookNotFound   = errors.New("no notification webhook is set")
+	ErrInvalidWebhookURL = errors.New("webhook URL must be an absolute https URL")
+)
+
+// webhookTimeout bounds how long a slow receiver can hold up the request
+// that triggered the notification
+const webhookTimeout = 5 * time.Second
+
+// WebhookEvent is the JSON body posted to an owner's webhook
+type WebhookEvent struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+type WebhookService struct {
+	webhookRepo *postgres.NotificationWebhookRepository
+	httpClient  *http.Client
+}
+
+func NewWebhookService(webhookRepo *postgres.NotificationWebhookRepository) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		httpClient:  &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Get returns the user's webhook
+func (s *WebhookService) Get(ctx context.Context, userID uuid.UUID) (*model.NotificationWebhook, error) {
+	webhook, err := s.webhookRepo.GetByUserID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+
+	return webhook, err
+}
+
+// Set points the user's notifications at rawURL with a fresh signing
+// secret. The secret is only ever returned here.
+func (s *WebhookService) Set(ctx context.Context, userID uuid.UUID, rawURL string) (*model.NotificationWebhook, string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, "", ErrInvalidWebhookURL
+	}
+
+	secret, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	webhook := &model.NotificationWebhook{
+		UserID: userID,
+		URL:    parsed.String(),
+		Secret: secret,
+	}
+
+	if err := s.webhookRepo.Upsert(ctx, webhook); err != nil {
+		return nil, "", fmt.Errorf("failed to save webhook: %w", err)
+	}
+
+	return webhook, secret, nil
+}
+
+func (s *WebhookService) Delete(ctx context.Context, userID uuid.UUID) error {
+	deleted, err := s.webhookRepo.Delete(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// Deliver posts an event to the user's webhook, if they set one. The body
+// is signed with HMAC-SHA256 in the X-Sampatti-Signature header.
+func (s *WebhookService) Deliver(ctx context.Context, userID uuid.UUID, event string, data interface{}) error {
+	webhook, err := s.webhookRepo.GetByUserID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(WebhookEvent{Event: event, OccurredAt: time.Now(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write(body)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sampatti-Event", event)
+	req.Header.Set("X-Sampatti-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
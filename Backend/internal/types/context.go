@@ -18,6 +18,20 @@ const (
 	AccessTypeKey ContextKey = "accessType"
 	// AccessLevelKey is the key for access level in context
 	AccessLevelKey ContextKey = "accessLevel"
+	// SessionIDKey is the key for the session family ID in context
+	SessionIDKey ContextKey = "sessionID"
+	// NomineeIDKey is the key for the nominee ID of a nominee token in context
+	NomineeIDKey ContextKey = "nomineeID"
+	// OwnerIDKey is the key for the account owner a nominee token acts for
+	OwnerIDKey ContextKey = "ownerID"
+	// TokenScopesKey holds the scopes of a personal access token; it is unset for session tokens
+	TokenScopesKey ContextKey = "tokenScopes"
+)
+
+// Principal types carried in AccessTypeKey
+const (
+	PrincipalUser    = "user"
+	PrincipalNominee = "nominee"
 )
 
 // ExtractUserID extracts user ID from context values map
@@ -41,3 +55,35 @@ func ExtractUserIDFromGin(c *gin.Context) (uuid.UUID, bool) {
 	id, ok := userID.(uuid.UUID)
 	return id, ok
 }
+
+// ExtractSessionIDFromGin extracts the session family ID from gin context
+func ExtractSessionIDFromGin(c *gin.Context) (uuid.UUID, bool) {
+	sessionID, exists := c.Get(string(SessionIDKey))
+	if !exists {
+		return uuid.UUID{}, false
+	}
+
+	id, ok := sessionID.(uuid.UUID)
+	return id, ok
+}
+
+// ExtractNomineeFromGin extracts the nominee ID and the owner's user ID of a nominee token
+func ExtractNomineeFromGin(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	nomineeID, exists := c.Get(string(NomineeIDKey))
+	if !exists {
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	ownerID, exists := c.Get(string(OwnerIDKey))
+	if !exists {
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	nid, ok := nomineeID.(uuid.UUID)
+	if !ok {
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	oid, ok := ownerID.(uuid.UUID)
+	return nid, oid, ok
+}
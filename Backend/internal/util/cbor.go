@@ -0,0 +1,134 @@
+package util
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidCBOR = errors.New("invalid CBOR data")
+
+// maxCBORDepth bounds nesting so hostile input can't exhaust the stack
+const maxCBORDepth = 16
+
+// DecodeCBOR decodes the first CBOR data item in b and returns it with the
+// bytes that follow it. Only definite-length items are supported, which is
+// all WebAuthn attestation objects and COSE keys use. Integers decode to
+// int64, byte strings to []byte, text to string, arrays to []any and maps
+// to map[any]any.
+func DecodeCBOR(b []byte) (any, []byte, error) {
+	return decodeCBORItem(b, 0)
+}
+
+func decodeCBORItem(b []byte, depth int) (any, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, fmt.Errorf("%w: nested too deeply", ErrInvalidCBOR)
+	}
+
+	if len(b) == 0 {
+		return nil, nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidCBOR)
+	}
+
+	major := b[0] >> 5
+	info := b[0] & 0x1f
+
+	// Simple values: false, true, null and undefined
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, b[1:], nil
+		case 21:
+			return true, b[1:], nil
+		case 22, 23:
+			return nil, b[1:], nil
+		default:
+			return nil, nil, fmt.Errorf("%w: unsupported simple value %d", ErrInvalidCBOR, info)
+		}
+	}
+
+	arg, rest, err := readCBORArgument(info, b[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > 1<<63-1 {
+			return nil, nil, fmt.Errorf("%w: integer overflow", ErrInvalidCBOR)
+		}
+		return int64(arg), rest, nil
+	case 1:
+		if arg > 1<<63-1 {
+			return nil, nil, fmt.Errorf("%w: integer overflow", ErrInvalidCBOR)
+		}
+		return -1 - int64(arg), rest, nil
+	case 2, 3:
+		if arg > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%w: string longer than data", ErrInvalidCBOR)
+		}
+		data := rest[:arg]
+		if major == 3 {
+			return string(data), rest[arg:], nil
+		}
+		return append(make([]byte, 0, arg), data...), rest[arg:], nil
+	case 4:
+		// Every item takes at least one byte, which bounds the allocation
+		if arg > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%w: array longer than data", ErrInvalidCBOR)
+		}
+		items := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item any
+			item, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, rest, nil
+	case 5:
+		if arg > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%w: map longer than data", ErrInvalidCBOR)
+		}
+		m := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, value any
+			key, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("%w: unsupported map key type", ErrInvalidCBOR)
+			}
+			value, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, rest, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported major type %d", ErrInvalidCBOR, major)
+	}
+}
+
+func readCBORArgument(info byte, b []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), b, nil
+	case info == 24 && len(b) >= 1:
+		return uint64(b[0]), b[1:], nil
+	case info == 25 && len(b) >= 2:
+		return uint64(binary.BigEndian.Uint16(b)), b[2:], nil
+	case info == 26 && len(b) >= 4:
+		return uint64(binary.BigEndian.Uint32(b)), b[4:], nil
+	case info == 27 && len(b) >= 8:
+		return binary.BigEndian.Uint64(b), b[8:], nil
+	case info == 31:
+		return 0, nil, fmt.Errorf("%w: indefinite lengths are not supported", ErrInvalidCBOR)
+	default:
+		return 0, nil, fmt.Errorf("%w: truncated argument", ErrInvalidCBOR)
+	}
+}
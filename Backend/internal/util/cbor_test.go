@@ -0,0 +1,159 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// cborPair and cborMap let tests encode maps with a fixed key order
+type cborPair struct {
+	Key   any
+	Value any
+}
+
+type cborMap []cborPair
+
+// encodeCBOR is a minimal encoder for building test vectors
+func encodeCBOR(v any) []byte {
+	head := func(major byte, arg uint64) []byte {
+		switch {
+		case arg < 24:
+			return []byte{major<<5 | byte(arg)}
+		case arg <= 0xff:
+			return []byte{major<<5 | 24, byte(arg)}
+		case arg <= 0xffff:
+			b := []byte{major<<5 | 25, 0, 0}
+			binary.BigEndian.PutUint16(b[1:], uint16(arg))
+			return b
+		case arg <= 0xffffffff:
+			b := []byte{major<<5 | 26, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(b[1:], uint32(arg))
+			return b
+		default:
+			b := []byte{major<<5 | 27, 0, 0, 0, 0, 0, 0, 0, 0}
+			binary.BigEndian.PutUint64(b[1:], arg)
+			return b
+		}
+	}
+
+	switch value := v.(type) {
+	case int:
+		return encodeCBOR(int64(value))
+	case int64:
+		if value < 0 {
+			return head(1, uint64(-1-value))
+		}
+		return head(0, uint64(value))
+	case []byte:
+		return append(head(2, uint64(len(value))), value...)
+	case string:
+		return append(head(3, uint64(len(value))), value...)
+	case []any:
+		out := head(4, uint64(len(value)))
+		for _, item := range value {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case cborMap:
+		out := head(5, uint64(len(value)))
+		for _, pair := range value {
+			out = append(out, encodeCBOR(pair.Key)...)
+			out = append(out, encodeCBOR(pair.Value)...)
+		}
+		return out
+	case bool:
+		if value {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	case nil:
+		return []byte{0xf6}
+	}
+	panic("unsupported test value")
+}
+
+func TestDecodeCBORMatchesRFC8949Examples(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want any
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1a000f4240", int64(1000000)},
+		{"1b000000e8d4a51000", int64(1000000000000)},
+		{"20", int64(-1)},
+		{"3903e7", int64(-1000)},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"40", []byte{}},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"60", ""},
+		{"6449455446", "IETF"},
+		{"80", []any{}},
+		{"8301820203820405", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"a201020304", map[any]any{int64(1): int64(2), int64(3): int64(4)}},
+		{"a26161016162820203", map[any]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.hex)
+		got, rest, err := DecodeCBOR(data)
+		if err != nil {
+			t.Errorf("DecodeCBOR(%s) error: %v", tt.hex, err)
+			continue
+		}
+		if len(rest) != 0 {
+			t.Errorf("DecodeCBOR(%s) left %d bytes", tt.hex, len(rest))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DecodeCBOR(%s) = %#v, want %#v", tt.hex, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeCBORReturnsTheRest(t *testing.T) {
+	_, rest, err := DecodeCBOR([]byte{0x01, 0x02, 0x03})
+	if err != nil || !bytes.Equal(rest, []byte{0x02, 0x03}) {
+		t.Fatalf("DecodeCBOR rest = %x, %v", rest, err)
+	}
+}
+
+func TestDecodeCBORRejectsMalformedInput(t *testing.T) {
+	nested := bytes.Repeat([]byte{0x81}, maxCBORDepth+2)
+	nested = append(nested, 0x00)
+
+	tests := map[string]string{
+		"empty":                   "",
+		"truncated argument":      "19 03",
+		"string longer than data": "44 0102",
+		"array longer than data":  "83 01 02",
+		"map longer than data":    "a2 01 02",
+		"missing map value":       "a1 01",
+		"indefinite length":       "5f 41 01 ff",
+		"byte string map key":     "a1 41 01 02",
+		"tag":                     "c1 1a 514b67b0",
+		"float":                   "f9 3c00",
+		"integer overflow":        "1b ffffffffffffffff",
+		"negative overflow":       "3b ffffffffffffffff",
+		"huge string length":      "5b ffffffffffffffff",
+		"huge array length":       "9b ffffffffffffffff",
+		"nested too deeply":       hex.EncodeToString(nested),
+	}
+
+	for name, input := range tests {
+		data, err := hex.DecodeString(string(bytes.ReplaceAll([]byte(input), []byte(" "), nil)))
+		if err != nil {
+			t.Fatalf("%s: bad test hex: %v", name, err)
+		}
+		if _, _, err := DecodeCBOR(data); !errors.Is(err, ErrInvalidCBOR) {
+			t.Errorf("%s: DecodeCBOR error = %v, want ErrInvalidCBOR", name, err)
+		}
+	}
+}
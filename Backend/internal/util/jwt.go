@@ -17,20 +17,32 @@ var (
 	ErrExpiredToken      = errors.New("token has expired")
 )
 
+// Token types carried in the "typ" claim. A token is only accepted where its type is expected,
+// so a refresh or challenge token can never be used as an access token.
+const (
+	TokenTypeAccess    = "access"
+	TokenTypeRefresh   = "refresh"
+	TokenTypeChallenge = "2fa_challenge"
+)
+
+// JWTUtil issues and verifies every token the API hands out
 type JWTUtil struct {
-	secret string
+	keys   *KeyManager
+	issuer string
 }
 
 type TokenClaims struct {
 	UserID      uuid.UUID
 	IsNominee   bool
 	AccessType  string
-	AccessLevel string // Used for nominees
+	AccessLevel string    // Used for nominees
+	OwnerID     uuid.UUID // Used for nominees: the user who named them
+	SessionID   uuid.UUID
 	ExpiresAt   time.Time
 }
 
-func NewJWTUtil(secret string) *JWTUtil {
-	return &JWTUtil{secret: secret}
+func NewJWTUtil(keys *KeyManager, issuer string) *JWTUtil {
+	return &JWTUtil{keys: keys, issuer: issuer}
 }
 
 func (u *JWTUtil) ExtractTokenFromHeader(authHeader string) (string, error) {
@@ -46,66 +58,82 @@ func (u *JWTUtil) ExtractTokenFromHeader(authHeader string) (string, error) {
 	return parts[1], nil
 }
 
-func (u *JWTUtil) GenerateToken(userID uuid.UUID, isNominee bool, accessType, accessLevel string) (string, error) {
-	// Set the expiration time to 24 hours from now
-	expiresAt := time.Now().Add(24 * time.Hour)
-
-	claims := jwt.MapClaims{
-		"sub": userID.String(),
-		"exp": expiresAt.Unix(),
-		"iat": time.Now().Unix(),
-	}
-
-	if isNominee {
-		claims["access_type"] = "nominee"
-		claims["access_level"] = accessLevel
-	} else {
-		claims["access_type"] = "user"
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// JWKS returns the public keys that verify tokens issued by u
+func (u *JWTUtil) JWKS() JWKSet {
+	return u.keys.JWKS()
+}
 
-	tokenString, err := token.SignedString([]byte(u.secret))
-	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
-	}
+// GenerateAccessToken issues an access token for a user session
+func (u *JWTUtil) GenerateAccessToken(userID, sessionID uuid.UUID, ttl time.Duration) (string, error) {
+	return u.sign(TokenTypeAccess, ttl, jwt.MapClaims{
+		"sub":         userID.String(),
+		"sid":         sessionID.String(),
+		"access_type": "user",
+	})
+}
 
-	return tokenString, nil
+// GenerateNomineeToken issues an access token for a nominee session on an owner's account
+func (u *JWTUtil) GenerateNomineeToken(nomineeID, ownerID, sessionID uuid.UUID, accessLevel string, ttl time.Duration) (string, error) {
+	return u.sign(TokenTypeAccess, ttl, jwt.MapClaims{
+		"sub":          nomineeID.String(),
+		"sid":          sessionID.String(),
+		"user_id":      ownerID.String(),
+		"access_type":  "nominee",
+		"access_level": accessLevel,
+	})
 }
 
-func (u *JWTUtil) ValidateToken(tokenString string) (*TokenClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(u.secret), nil
+// GenerateRefreshToken issues a refresh token for one session row
+func (u *JWTUtil) GenerateRefreshToken(userID, sessionID uuid.UUID, expiresAt time.Time) (string, error) {
+	return u.sign(TokenTypeRefresh, time.Until(expiresAt), jwt.MapClaims{
+		"sub": userID.String(),
+		"jti": sessionID.String(),
 	})
+}
 
-	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, ErrExpiredToken
-		}
-		return nil, fmt.Errorf("failed to parse token: %w", err)
-	}
+// GenerateChallengeToken issues a token proving only the password step of a two-factor login
+func (u *JWTUtil) GenerateChallengeToken(userID uuid.UUID, ttl time.Duration) (string, error) {
+	return u.sign(TokenTypeChallenge, ttl, jwt.MapClaims{
+		"sub": userID.String(),
+	})
+}
 
-	if !token.Valid {
+// ParseToken verifies a token of the given type and returns its claims. An
+// expired but otherwise valid token returns its claims with ErrExpiredToken.
+func (u *JWTUtil) ParseToken(tokenString, tokenType string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, u.keyFunc,
+		jwt.WithValidMethods([]string{AlgEdDSA, AlgRS256}),
+		jwt.WithIssuer(u.issuer),
+		jwt.WithExpirationRequired(),
+	)
+
+	expired := errors.Is(err, jwt.ErrTokenExpired)
+	if token == nil || (err != nil && !expired) {
 		return nil, ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
+	if !ok || claims["typ"] != tokenType {
 		return nil, ErrInvalidToken
 	}
 
-	// Check expiration time
-	exp, ok := claims["exp"].(float64)
-	if !ok {
-		return nil, ErrInvalidToken
+	if expired {
+		return claims, ErrExpiredToken
 	}
 
-	expirationTime := time.Unix(int64(exp), 0)
-	if time.Now().After(expirationTime) {
-		return nil, ErrExpiredToken
+	return claims, nil
+}
+
+// ValidateToken verifies an access token and extracts who it was issued to
+func (u *JWTUtil) ValidateToken(tokenString string) (*TokenClaims, error) {
+	claims, err := u.ParseToken(tokenString, TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return nil, ErrInvalidToken
 	}
 
 	// Extract user ID
@@ -124,7 +152,14 @@ func (u *JWTUtil) ValidateToken(tokenString string) (*TokenClaims, error) {
 		UserID:     userID,
 		IsNominee:  false,
 		AccessType: "user",
-		ExpiresAt:  expirationTime,
+		ExpiresAt:  expiresAt.Time,
+	}
+
+	// Session family, or nominee session, the token was issued under
+	if sessionIDStr, ok := claims["sid"].(string); ok {
+		if sessionID, err := uuid.Parse(sessionIDStr); err == nil {
+			tokenClaims.SessionID = sessionID
+		}
 	}
 
 	// If it's a nominee token, set appropriate fields
@@ -135,19 +170,61 @@ func (u *JWTUtil) ValidateToken(tokenString string) (*TokenClaims, error) {
 		if accessLevel, ok := claims["access_level"].(string); ok {
 			tokenClaims.AccessLevel = accessLevel
 		}
+
+		// A nominee token is useless without the owner it was issued for
+		ownerIDStr, ok := claims["user_id"].(string)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+
+		ownerID, err := uuid.Parse(ownerIDStr)
+		if err != nil {
+			return nil, ErrInvalidToken
+		}
+		tokenClaims.OwnerID = ownerID
 	}
 
 	return tokenClaims, nil
 }
 
-// RefreshToken generates a new token with a reset 24-hour validity period
-// Only valid tokens can be refreshed
-func (u *JWTUtil) RefreshToken(tokenString string) (string, error) {
-	claims, err := u.ValidateToken(tokenString)
+// Private methods
+
+func (u *JWTUtil) sign(tokenType string, ttl time.Duration, claims jwt.MapClaims) (string, error) {
+	key := u.keys.SigningKey()
+	now := time.Now()
+
+	claims["typ"] = tokenType
+	claims["iss"] = u.issuer
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+
+	tokenString, err := token.SignedString(key.Private)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return tokenString, nil
+}
+
+// keyFunc picks the verification key named by the token's kid header and
+// refuses tokens whose algorithm doesn't match that key
+func (u *JWTUtil) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	key, err := u.keys.VerificationKey(kid)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 	}
 
-	// Generate new token with the same claims but extended validity
-	return u.GenerateToken(claims.UserID, claims.IsNominee, claims.AccessType, claims.AccessLevel)
+	return key.Public, nil
 }
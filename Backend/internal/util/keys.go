@@ -0,0 +1,300 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// SigningKey is one key pair used for JWTs. Retired keys keep only their
+// public half so tokens they signed stay verifiable until they expire.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+}
+
+// KeyManager holds the active signing key and every key still accepted for verification
+type KeyManager struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeyManager loads every *.pem file in dir. The file name without its
+// extension is the key ID. Private keys (PKCS#8) can sign and verify; public
+// keys (PKIX) only verify. activeKID selects the signing key and may be empty
+// when dir holds exactly one private key.
+//
+// When dir is empty an ephemeral Ed25519 key is generated. Tokens signed with
+// it stop working on restart, so this is only suitable for development.
+func NewKeyManager(dir, activeKID string) (*KeyManager, error) {
+	if dir == "" {
+		return newEphemeralKeyManager()
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	sort.Strings(paths)
+
+	m := &KeyManager{keys: make(map[string]*SigningKey)}
+	var signers []string
+
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+
+		key, err := loadKey(path, kid)
+		if err != nil {
+			return nil, err
+		}
+
+		m.keys[kid] = key
+		if key.Private != nil {
+			signers = append(signers, kid)
+		}
+	}
+
+	if activeKID == "" {
+		if len(signers) != 1 {
+			return nil, fmt.Errorf("JWT_ACTIVE_KID must be set when %s holds %d private keys", dir, len(signers))
+		}
+		activeKID = signers[0]
+	}
+
+	active, ok := m.keys[activeKID]
+	if !ok || active.Private == nil {
+		return nil, fmt.Errorf("no private key found for active key ID %q in %s", activeKID, dir)
+	}
+	m.active = active
+
+	return m, nil
+}
+
+// SigningKey returns the key new tokens are signed with
+func (m *KeyManager) SigningKey() *SigningKey {
+	return m.active
+}
+
+// VerificationKey returns the key with the given ID
+func (m *KeyManager) VerificationKey(kid string) (*SigningKey, error) {
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// JWKS returns the public half of every verification key
+func (m *KeyManager) JWKS() JWKSet {
+	kids := make([]string, 0, len(m.keys))
+	for kid := range m.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := JWKSet{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		key := m.keys[kid]
+		jwk := JWK{KeyID: kid, Use: "sig", Algorithm: key.Algorithm}
+
+		switch pub := key.Public.(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+// PublicKey decodes the key material of an RSA, EC (P-256/P-384) or Ed25519 JWK
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := decode(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		if len(n) < 256 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var validate func([]byte) (*ecdh.PublicKey, error)
+		switch k.Curve {
+		case "P-256":
+			curve, validate = elliptic.P256(), ecdh.P256().NewPublicKey
+		case "P-384":
+			curve, validate = elliptic.P384(), ecdh.P384().NewPublicKey
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, errX := decode(k.X)
+		y, errY := decode(k.Y)
+		size := (curve.Params().BitSize + 7) / 8
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC point")
+		}
+		// Rejects points that aren't on the curve
+		if _, err := validate(append(append([]byte{0x04}, x...), y...)); err != nil {
+			return nil, errors.New("invalid EC point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		x, err := decode(k.X)
+		if k.Curve != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+// GenerateKeyPEM creates a new private key for alg and returns it PEM encoded (PKCS#8)
+func GenerateKeyPEM(alg string) ([]byte, error) {
+	var private any
+	var err error
+
+	switch alg {
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 3072)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// Private functions
+
+func newEphemeralKeyManager() (*KeyManager, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	suffix, err := GenerateCode(8)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: "ephemeral-" + suffix, Algorithm: AlgEdDSA, Private: private, Public: public}
+	log.Printf("JWT_KEYS_DIR is not set, signing tokens with ephemeral key %s; tokens will not survive a restart", key.ID)
+
+	return &KeyManager{active: key, keys: map[string]*SigningKey{key.ID: key}}, nil
+}
+
+func loadKey(path, kid string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s is not PEM encoded", path)
+	}
+
+	key := &SigningKey{ID: kid}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key %s: %w", path, err)
+		}
+
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("key %s can't sign", path)
+		}
+		key.Private = signer
+		key.Public = signer.Public()
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+		}
+		key.Public = parsed
+	default:
+		return nil, fmt.Errorf("key %s has unsupported PEM type %q", path, block.Type)
+	}
+
+	switch key.Public.(type) {
+	case ed25519.PublicKey:
+		key.Algorithm = AlgEdDSA
+	case *rsa.PublicKey:
+		key.Algorithm = AlgRS256
+	default:
+		return nil, fmt.Errorf("key %s must be Ed25519 or RSA", path)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,84 @@
+package util
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+)
+
+// GenerateVaultKey returns a new X25519 key pair for wrapping keys to
+func GenerateVaultKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// ParseVaultKey rebuilds an X25519 private key from its 32 bytes
+func ParseVaultKey(privateKey []byte) (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().NewPrivateKey(privateKey)
+}
+
+// WrapKey encrypts key so only the holder of the private half of publicKey
+// can recover it. The result is an ephemeral public key followed by key
+// sealed under a secret agreed with it.
+func WrapKey(publicKey []byte, key, associatedData []byte) ([]byte, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault public key: %w", err)
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	sealer, err := wrappingSealer(secret, ephemeral.PublicKey().Bytes(), publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := sealer.Seal(key, associatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ephemeral.PublicKey().Bytes(), sealed...), nil
+}
+
+// UnwrapKey recovers a key wrapped by WrapKey with the same associatedData
+func UnwrapKey(privateKey *ecdh.PrivateKey, wrapped, associatedData []byte) ([]byte, error) {
+	if len(wrapped) < 32 {
+		return nil, ErrSealedDataInvalid
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(wrapped[:32])
+	if err != nil {
+		return nil, ErrSealedDataInvalid
+	}
+
+	secret, err := privateKey.ECDH(ephemeral)
+	if err != nil {
+		return nil, ErrSealedDataInvalid
+	}
+
+	sealer, err := wrappingSealer(secret, wrapped[:32], privateKey.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return sealer.Open(wrapped[32:], associatedData)
+}
+
+// wrappingSealer derives the key-encryption key from the X25519 secret,
+// bound to both public keys
+func wrappingSealer(secret, ephemeralPublic, recipientPublic []byte) (*Sealer, error) {
+	hash := sha256.New()
+	hash.Write(secret)
+	hash.Write(ephemeralPublic)
+	hash.Write(recipientPublic)
+	return NewSealerFromKey(hash.Sum(nil))
+}
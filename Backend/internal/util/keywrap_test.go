@@ -0,0 +1,83 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWrapKeyRoundTrip(t *testing.T) {
+	vaultKey, err := GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := bytes.Repeat([]byte{0x5a}, 32)
+	associatedData := []byte("document-1")
+
+	wrapped, err := WrapKey(vaultKey.PublicKey().Bytes(), key, associatedData)
+	if err != nil {
+		t.Fatalf("WrapKey: %v", err)
+	}
+
+	again, err := WrapKey(vaultKey.PublicKey().Bytes(), key, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(wrapped, again) {
+		t.Fatal("wrapping the same key twice gave the same result")
+	}
+
+	// The vault key survives being split and rebuilt from its bytes
+	parsed, err := ParseVaultKey(vaultKey.Bytes())
+	if err != nil {
+		t.Fatalf("ParseVaultKey: %v", err)
+	}
+
+	unwrapped, err := UnwrapKey(parsed, wrapped, associatedData)
+	if err != nil {
+		t.Fatalf("UnwrapKey: %v", err)
+	}
+	if !bytes.Equal(unwrapped, key) {
+		t.Fatal("unwrapped key differs from the wrapped one")
+	}
+}
+
+func TestUnwrapKeyRejectsWrongInputs(t *testing.T) {
+	vaultKey, err := GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := GenerateVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	associatedData := []byte("document-1")
+	wrapped, err := WrapKey(vaultKey.PublicKey().Bytes(), bytes.Repeat([]byte{0x5a}, 32), associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := append([]byte(nil), wrapped...)
+	tampered[len(tampered)-1] ^= 0x01
+
+	if _, err := UnwrapKey(otherKey, wrapped, associatedData); !errors.Is(err, ErrSealedDataInvalid) {
+		t.Errorf("another vault key unwrapped the key: %v", err)
+	}
+	if _, err := UnwrapKey(vaultKey, wrapped, []byte("document-2")); !errors.Is(err, ErrSealedDataInvalid) {
+		t.Errorf("key unwrapped for another document: %v", err)
+	}
+	if _, err := UnwrapKey(vaultKey, tampered, associatedData); !errors.Is(err, ErrSealedDataInvalid) {
+		t.Errorf("tampered key unwrapped: %v", err)
+	}
+	if _, err := UnwrapKey(vaultKey, wrapped[:31], associatedData); !errors.Is(err, ErrSealedDataInvalid) {
+		t.Errorf("truncated key unwrapped: %v", err)
+	}
+}
+
+func TestWrapKeyRejectsInvalidPublicKey(t *testing.T) {
+	if _, err := WrapKey([]byte{1, 2, 3}, []byte("key"), nil); err == nil {
+		t.Fatal("WrapKey accepted a short public key")
+	}
+}
@@ -0,0 +1,315 @@
+package util
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrOIDCProvider   = errors.New("identity provider request failed")
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+const (
+	oidcHTTPTimeout     = 10 * time.Second
+	oidcMaxResponseSize = 1 << 20
+	// oidcKeyRefetchDelay limits JWKS refetches triggered by unknown key IDs
+	oidcKeyRefetchDelay = time.Minute
+	oidcClockSkew       = time.Minute
+)
+
+// OIDCClient is an OpenID Connect relying party for one provider using the
+// authorization code flow with PKCE
+type OIDCClient struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	httpClient   *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]JWK
+	keysFetchedAt time.Time
+}
+
+// IDTokenClaims are the ID token claims the application uses
+type IDTokenClaims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+func NewOIDCClient(issuer, clientID, clientSecret, redirectURL string, scopes []string) *OIDCClient {
+	return &OIDCClient{
+		issuer:       issuer,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		scopes:       scopes,
+		httpClient:   &http.Client{Timeout: oidcHTTPTimeout},
+	}
+}
+
+// Issuer returns the provider's issuer identifier
+func (o *OIDCClient) Issuer() string {
+	return o.issuer
+}
+
+// AuthCodeURL builds the provider URL the browser is sent to
+func (o *OIDCClient) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	discovery, err := o.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.clientID},
+		"redirect_uri":          {o.redirectURL},
+		"scope":                 {strings.Join(o.scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {PKCEChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the raw ID token
+func (o *OIDCClient) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	discovery, err := o.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.redirectURL},
+		"client_id":     {o.clientID},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.clientID), url.QueryEscape(o.clientSecret))
+	}
+
+	var response struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := o.do(req, &response); err != nil {
+		if response.Error != "" {
+			return "", fmt.Errorf("%w: token endpoint returned %s", ErrOIDCProvider, response.Error)
+		}
+		return "", err
+	}
+
+	if response.IDToken == "" {
+		return "", fmt.Errorf("%w: no ID token in token response", ErrOIDCProvider)
+	}
+
+	return response.IDToken, nil
+}
+
+// VerifyIDToken checks an ID token's signature, issuer, audience, expiry and
+// nonce, and returns its claims
+func (o *OIDCClient) VerifyIDToken(ctx context.Context, rawToken, nonce string) (*IDTokenClaims, error) {
+	token, err := jwt.Parse(rawToken,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return o.verificationKey(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "ES384", AlgEdDSA}),
+		jwt.WithIssuer(o.issuer),
+		jwt.WithAudience(o.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(oidcClockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidIDToken
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	// With several audiences the token must name us as the authorized party
+	if audiences, _ := claims.GetAudience(); len(audiences) > 1 {
+		if azp, _ := claims["azp"].(string); azp != o.clientID {
+			return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidIDToken)
+		}
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+
+	result := &IDTokenClaims{Issuer: o.issuer, Subject: subject}
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+
+	// Some providers send email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+
+	return result, nil
+}
+
+// NewPKCEVerifier returns a random PKCE code verifier
+func NewPKCEVerifier() (string, error) {
+	return GenerateSecureToken(32)
+}
+
+// PKCEChallenge derives the S256 code challenge for a verifier
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Private methods
+
+// discover fetches and caches the provider's configuration document
+func (o *OIDCClient) discover(ctx context.Context) (*oidcDiscovery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.discovery != nil {
+		return o.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(o.issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovery oidcDiscovery
+	if err := o.do(req, &discovery); err != nil {
+		return nil, err
+	}
+
+	if discovery.Issuer != o.issuer {
+		return nil, fmt.Errorf("%w: discovery issuer %q does not match %q", ErrOIDCProvider, discovery.Issuer, o.issuer)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete discovery document", ErrOIDCProvider)
+	}
+
+	o.discovery = &discovery
+	return o.discovery, nil
+}
+
+// verificationKey returns the provider key with the given ID, refetching the
+// JWKS when the provider has rotated to a key we haven't seen
+func (o *OIDCClient) verificationKey(ctx context.Context, kid string) (interface{}, error) {
+	discovery, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	key, ok := o.keys[kid]
+	if !ok && time.Since(o.keysFetchedAt) > oidcKeyRefetchDelay {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.JWKSURI, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var set JWKSet
+		if err := o.do(req, &set); err != nil {
+			return nil, err
+		}
+
+		o.keys = make(map[string]JWK, len(set.Keys))
+		for _, jwk := range set.Keys {
+			if jwk.Use == "" || jwk.Use == "sig" {
+				o.keys[jwk.KeyID] = jwk
+			}
+		}
+		o.keysFetchedAt = time.Now()
+
+		key, ok = o.keys[kid]
+	}
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return key.PublicKey()
+}
+
+// do sends a request and decodes a JSON response into out. Error responses
+// are decoded too, so callers can read an OAuth error code.
+func (o *OIDCClient) do(req *http.Request, out interface{}) error {
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOIDCProvider, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponseSize))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOIDCProvider, err)
+	}
+
+	decodeErr := json.Unmarshal(body, out)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned status %d", ErrOIDCProvider, req.URL.Path, resp.StatusCode)
+	}
+
+	if decodeErr != nil {
+		return fmt.Errorf("%w: invalid JSON from %s", ErrOIDCProvider, req.URL.Path)
+	}
+
+	return nil
+}
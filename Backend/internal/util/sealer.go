@@ -0,0 +1,76 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrSealedDataInvalid means sealed data was tampered with, belongs to
+// another record or was sealed with a different key
+var ErrSealedDataInvalid = errors.New("sealed data could not be opened")
+
+// Sealer encrypts data at rest with AES-256-GCM. Sealed data is the random
+// nonce followed by the ciphertext.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer builds a Sealer from a base64 encoded 32-byte key
+func NewSealer(encodedKey string) (*Sealer, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key is not valid base64: %w", err)
+	}
+
+	return NewSealerFromKey(key)
+}
+
+// NewSealerFromKey builds a Sealer from a raw 32-byte key
+func NewSealerFromKey(key []byte) (*Sealer, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts plaintext. associatedData, typically the record's ID, isn't
+// stored but must be given again to open the result, so sealed data can't
+// be moved between records.
+func (s *Sealer) Seal(plaintext, associatedData []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return s.aead.Seal(nonce, nonce, plaintext, associatedData), nil
+}
+
+// Open decrypts data produced by Seal with the same associatedData
+func (s *Sealer) Open(sealed, associatedData []byte) ([]byte, error) {
+	nonceSize := s.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, ErrSealedDataInvalid
+	}
+
+	plaintext, err := s.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], associatedData)
+	if err != nil {
+		return nil, ErrSealedDataInvalid
+	}
+
+	return plaintext, nil
+}
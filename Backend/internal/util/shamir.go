@@ -0,0 +1,113 @@
+package util
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidShares means shares are malformed, repeated or of different lengths
+var ErrInvalidShares = errors.New("shares are invalid")
+
+// SplitSecret splits secret with Shamir's scheme over GF(256) into n shares,
+// any threshold of which recover it. Each share is its x coordinate (1 to n)
+// followed by one byte per byte of secret.
+func SplitSecret(secret []byte, n, threshold int) ([][]byte, error) {
+	if threshold < 1 || n < threshold || n > 255 {
+		return nil, fmt.Errorf("cannot split into %d shares with threshold %d", n, threshold)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("secret is empty")
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][0] = byte(i + 1)
+	}
+
+	// One random polynomial per byte, with the secret byte as its constant term
+	coefficients := make([]byte, threshold)
+	for b, value := range secret {
+		coefficients[0] = value
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, fmt.Errorf("failed to generate coefficients: %w", err)
+		}
+
+		for _, share := range shares {
+			share[b+1] = evaluatePolynomial(coefficients, share[0])
+		}
+	}
+
+	return shares, nil
+}
+
+// CombineShares recovers a secret from at least threshold of its shares.
+// Too few shares give a wrong secret rather than an error, so callers
+// should check the result.
+func CombineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrInvalidShares
+	}
+
+	length := len(shares[0])
+	seen := make(map[byte]bool, len(shares))
+	for _, share := range shares {
+		if len(share) != length || length < 2 || share[0] == 0 || seen[share[0]] {
+			return nil, ErrInvalidShares
+		}
+		seen[share[0]] = true
+	}
+
+	// Lagrange interpolation at x = 0
+	secret := make([]byte, length-1)
+	for i, share := range shares {
+		basis := byte(1)
+		for j, other := range shares {
+			if i != j {
+				basis = gfMul(basis, gfDiv(other[0], other[0]^share[0]))
+			}
+		}
+
+		for b := range secret {
+			secret[b] ^= gfMul(basis, share[b+1])
+		}
+	}
+
+	return secret, nil
+}
+
+func evaluatePolynomial(coefficients []byte, x byte) byte {
+	result := byte(0)
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = gfMul(result, x) ^ coefficients[i]
+	}
+	return result
+}
+
+// gfMul multiplies in GF(256) with the AES polynomial
+func gfMul(a, b byte) byte {
+	product := byte(0)
+	for b > 0 {
+		if b&1 == 1 {
+			product ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return product
+}
+
+// gfDiv divides in GF(256); b must not be zero
+func gfDiv(a, b byte) byte {
+	// b^254 is the inverse of b
+	inverse := byte(1)
+	for i := 0; i < 254; i++ {
+		inverse = gfMul(inverse, b)
+	}
+	return gfMul(a, inverse)
+}
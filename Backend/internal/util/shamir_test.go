@@ -0,0 +1,150 @@
+package util
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func testSecret(t *testing.T) []byte {
+	t.Helper()
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatal(err)
+	}
+	return secret
+}
+
+// subsets returns every subset of shares with at least min members
+func subsets(shares [][]byte, min int) [][][]byte {
+	var result [][][]byte
+	for mask := 1; mask < 1<<len(shares); mask++ {
+		subset := make([][]byte, 0, len(shares))
+		for i := range shares {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, shares[i])
+			}
+		}
+		if len(subset) >= min {
+			result = append(result, subset)
+		}
+	}
+	return result
+}
+
+func TestGF256MatchesAESVectors(t *testing.T) {
+	// From FIPS 197, section 4.2
+	if got := gfMul(0x57, 0x83); got != 0xc1 {
+		t.Errorf("0x57 * 0x83 = %#x, want 0xc1", got)
+	}
+	if got := gfMul(0x57, 0x13); got != 0xfe {
+		t.Errorf("0x57 * 0x13 = %#x, want 0xfe", got)
+	}
+
+	for b := 1; b < 256; b++ {
+		if got := gfMul(byte(b), gfDiv(1, byte(b))); got != 1 {
+			t.Fatalf("%#x * 1/%#x = %#x, want 1", b, b, got)
+		}
+	}
+}
+
+func TestAnyThresholdOfSharesRecoversTheSecret(t *testing.T) {
+	tests := []struct{ n, threshold int }{
+		{1, 1},
+		{3, 1},
+		{3, 2},
+		{5, 3},
+		{5, 5},
+	}
+
+	for _, tt := range tests {
+		secret := testSecret(t)
+		shares, err := SplitSecret(secret, tt.n, tt.threshold)
+		if err != nil {
+			t.Fatalf("SplitSecret(%d, %d): %v", tt.n, tt.threshold, err)
+		}
+
+		for i, share := range shares {
+			if share[0] != byte(i+1) || len(share) != len(secret)+1 {
+				t.Fatalf("share %d has index %d and length %d", i, share[0], len(share))
+			}
+		}
+
+		for _, subset := range subsets(shares, tt.threshold) {
+			recovered, err := CombineShares(subset)
+			if err != nil {
+				t.Fatalf("%d of %d shares, threshold %d: %v", len(subset), tt.n, tt.threshold, err)
+			}
+			if !bytes.Equal(recovered, secret) {
+				t.Fatalf("%d of %d shares, threshold %d: recovered the wrong secret", len(subset), tt.n, tt.threshold)
+			}
+		}
+	}
+}
+
+func TestFewerThanThresholdSharesDoNotRecoverTheSecret(t *testing.T) {
+	secret := testSecret(t)
+	shares, err := SplitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, subset := range subsets(shares, 1) {
+		if len(subset) >= 3 {
+			continue
+		}
+
+		recovered, err := CombineShares(subset)
+		if err != nil {
+			t.Fatalf("%d shares: %v", len(subset), err)
+		}
+		if bytes.Equal(recovered, secret) {
+			t.Fatalf("%d shares below the threshold recovered the secret", len(subset))
+		}
+	}
+}
+
+func TestCombineSharesRejectsInvalidShares(t *testing.T) {
+	shares, err := SplitSecret(testSecret(t), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zeroIndex := append([]byte{0}, shares[1][1:]...)
+	sameIndex := append([]byte{shares[0][0]}, shares[1][1:]...)
+
+	tests := map[string][][]byte{
+		"no shares":         nil,
+		"duplicate share":   {shares[0], shares[0]},
+		"duplicate index":   {shares[0], sameIndex},
+		"zero index":        {shares[0], zeroIndex},
+		"different lengths": {shares[0], shares[1][:len(shares[1])-1]},
+		"index only":        {{1}, {2}},
+	}
+
+	for name, input := range tests {
+		if _, err := CombineShares(input); !errors.Is(err, ErrInvalidShares) {
+			t.Errorf("%s: CombineShares error = %v, want ErrInvalidShares", name, err)
+		}
+	}
+}
+
+func TestSplitSecretRejectsBadParameters(t *testing.T) {
+	tests := []struct {
+		name         string
+		secret       []byte
+		n, threshold int
+	}{
+		{"zero threshold", []byte{1}, 3, 0},
+		{"threshold above shares", []byte{1}, 2, 3},
+		{"too many shares", []byte{1}, 256, 2},
+		{"empty secret", nil, 3, 2},
+	}
+
+	for _, tt := range tests {
+		if _, err := SplitSecret(tt.secret, tt.n, tt.threshold); err == nil {
+			t.Errorf("%s: SplitSecret succeeded", tt.name)
+		}
+	}
+}
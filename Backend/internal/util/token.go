@@ -0,0 +1,43 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+// codeAlphabet omits characters that are easy to confuse when read aloud or typed
+const codeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateSecureToken returns a URL-safe random token built from n random bytes
+func GenerateSecureToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex encoded SHA-256 digest of a token.
+// Tokens are high-entropy random values, so a fast hash is enough for storage.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateCode returns a random human-friendly code of the given length
+func GenerateCode(length int) (string, error) {
+	b := make([]byte, length)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random code: %w", err)
+		}
+		b[i] = codeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
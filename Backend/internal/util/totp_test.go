@@ -0,0 +1,105 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 key from RFC 6238 appendix B, "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeMatchesRFC6238Vectors(t *testing.T) {
+	// The RFC gives 8-digit codes; ours are their last 6 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.code {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+
+		step, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0), 0)
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("ValidateTOTP at %d = (%d, %v), want (%d, true)", tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPAcceptsOneStepEitherSide(t *testing.T) {
+	// 1111111111 is step 37037037; its code is valid one step before and after
+	code := "050471"
+	step := int64(1111111111 / totpPeriod)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		ok   bool
+	}{
+		{"one step early", time.Unix((step-1)*totpPeriod, 0), true},
+		{"current step", time.Unix(step*totpPeriod, 0), true},
+		{"one step late", time.Unix((step+1)*totpPeriod+totpPeriod-1, 0), true},
+		{"two steps early", time.Unix((step-2)*totpPeriod+totpPeriod-1, 0), false},
+		{"two steps late", time.Unix((step+2)*totpPeriod, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValidateTOTP(rfc6238Secret, code, tt.at, 0)
+			if ok != tt.ok {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && got != step {
+				t.Fatalf("ValidateTOTP step = %d, want %d", got, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejectsReplay(t *testing.T) {
+	code := "050471"
+	now := time.Unix(1111111111, 0)
+
+	step, ok := ValidateTOTP(rfc6238Secret, code, now, 0)
+	if !ok {
+		t.Fatal("first use rejected")
+	}
+
+	if _, ok := ValidateTOTP(rfc6238Secret, code, now, step); ok {
+		t.Fatal("code accepted again for the step it was already used in")
+	}
+
+	if _, ok := ValidateTOTP(rfc6238Secret, code, now.Add(totpPeriod*time.Second), step); ok {
+		t.Fatal("code replayed within the window of the next step")
+	}
+
+	if _, ok := ValidateTOTP(rfc6238Secret, code, now, step+1); ok {
+		t.Fatal("code accepted after a later step was used")
+	}
+}
+
+func TestValidateTOTPRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	for _, code := range []string{"", "05047", "0504711", "abcdef"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, now, 0); ok {
+			t.Errorf("ValidateTOTP accepted %q", code)
+		}
+	}
+
+	if _, ok := ValidateTOTP("not base32!", "050471", now, 0); ok {
+		t.Error("ValidateTOTP accepted a malformed secret")
+	}
+}
@@ -0,0 +1,357 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers accepted for WebAuthn credentials
+const (
+	COSEAlgEdDSA int64 = -8
+	COSEAlgES256 int64 = -7
+	COSEAlgRS256 int64 = -257
+)
+
+// WebAuthn ceremony types found in client data
+const (
+	WebAuthnCreate = "webauthn.create"
+	WebAuthnGet    = "webauthn.get"
+)
+
+var ErrWebAuthnVerification = errors.New("webauthn verification failed")
+
+// Authenticator data flags
+const (
+	authFlagUserPresent  = 0x01
+	authFlagUserVerified = 0x04
+	authFlagAttestedData = 0x40
+	authFlagExtensions   = 0x80
+)
+
+// AuthenticatorData is the parsed authenticator data of a registration or assertion
+type AuthenticatorData struct {
+	Raw          []byte
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	// PublicKey is the credential public key in COSE format, only present during registration
+	PublicKey []byte
+}
+
+func (d *AuthenticatorData) UserPresent() bool {
+	return d.Flags&authFlagUserPresent != 0
+}
+
+func (d *AuthenticatorData) UserVerified() bool {
+	return d.Flags&authFlagUserVerified != 0
+}
+
+// ParseAuthenticatorData parses the binary authenticator data structure
+func ParseAuthenticatorData(b []byte) (*AuthenticatorData, error) {
+	if len(b) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data too short", ErrWebAuthnVerification)
+	}
+
+	data := &AuthenticatorData{
+		Raw:       b,
+		RPIDHash:  b[:32],
+		Flags:     b[32],
+		SignCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+	rest := b[37:]
+
+	if data.Flags&authFlagAttestedData != 0 {
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: attested credential data too short", ErrWebAuthnVerification)
+		}
+		data.AAGUID = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+
+		if idLen > 1023 || len(rest) < idLen {
+			return nil, fmt.Errorf("%w: invalid credential ID length", ErrWebAuthnVerification)
+		}
+		data.CredentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		// The COSE key is a CBOR map; decoding it tells us where it ends
+		_, after, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid credential public key", ErrWebAuthnVerification)
+		}
+		data.PublicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if data.Flags&authFlagExtensions != 0 {
+		_, after, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid extensions", ErrWebAuthnVerification)
+		}
+		rest = after
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing authenticator data", ErrWebAuthnVerification)
+	}
+
+	return data, nil
+}
+
+// VerifyFlags checks the user was present and, when required, verified by
+// the authenticator's own PIN or biometric
+func (d *AuthenticatorData) VerifyFlags(requireUserVerification bool) error {
+	if !d.UserPresent() {
+		return fmt.Errorf("%w: user presence flag not set", ErrWebAuthnVerification)
+	}
+	if requireUserVerification && !d.UserVerified() {
+		return fmt.Errorf("%w: user verification flag not set", ErrWebAuthnVerification)
+	}
+	return nil
+}
+
+// SignCountAdvanced reports whether a presented signature counter is
+// acceptable after the stored one. It must move forward; a counter that
+// stalls or goes back means another copy of the authenticator has been
+// used. Authenticators without counters, including synced passkeys, always
+// report zero, and for those clone detection isn't possible: 0 after 0 is
+// accepted, but 0 after any other value is not. Callers should require user
+// verification from counterless authenticators instead.
+func SignCountAdvanced(stored, presented uint32) bool {
+	if stored == 0 && presented == 0 {
+		return true
+	}
+	return presented > stored
+}
+
+// VerifyRPID checks the authenticator data was produced for our relying party ID
+func (d *AuthenticatorData) VerifyRPID(rpID string) error {
+	expected := sha256.Sum256([]byte(rpID))
+	if subtle.ConstantTimeCompare(d.RPIDHash, expected[:]) != 1 {
+		return fmt.Errorf("%w: relying party ID mismatch", ErrWebAuthnVerification)
+	}
+	return nil
+}
+
+type collectedClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// VerifyClientData checks clientDataJSON matches the ceremony, the challenge we
+// issued, and one of our origins. It returns the SHA-256 hash of the raw JSON.
+func VerifyClientData(raw []byte, ceremony string, challenge []byte, origins []string) ([]byte, error) {
+	var data collectedClientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("%w: invalid client data", ErrWebAuthnVerification)
+	}
+
+	if data.Type != ceremony {
+		return nil, fmt.Errorf("%w: unexpected ceremony type %q", ErrWebAuthnVerification, data.Type)
+	}
+
+	received, err := base64.RawURLEncoding.DecodeString(data.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return nil, fmt.Errorf("%w: challenge mismatch", ErrWebAuthnVerification)
+	}
+
+	originAllowed := false
+	for _, origin := range origins {
+		if data.Origin == origin {
+			originAllowed = true
+			break
+		}
+	}
+	if !originAllowed {
+		return nil, fmt.Errorf("%w: origin %q is not allowed", ErrWebAuthnVerification, data.Origin)
+	}
+
+	hash := sha256.Sum256(raw)
+	return hash[:], nil
+}
+
+// ParseAttestation decodes an attestation object and verifies its statement.
+// Only the "none" and "packed" formats are accepted; attestation is used to
+// bind the new key to this ceremony, not to judge the authenticator's make.
+func ParseAttestation(attestationObject, clientDataHash []byte) (*AuthenticatorData, error) {
+	decoded, _, err := DecodeCBOR(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid attestation object", ErrWebAuthnVerification)
+	}
+
+	obj, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid attestation object", ErrWebAuthnVerification)
+	}
+
+	format, _ := obj["fmt"].(string)
+	rawAuthData, _ := obj["authData"].([]byte)
+	attStmt, _ := obj["attStmt"].(map[any]any)
+
+	authData, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	if authData.PublicKey == nil {
+		return nil, fmt.Errorf("%w: no credential in attestation", ErrWebAuthnVerification)
+	}
+
+	key, err := ParseCOSEKey(authData.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "none":
+		if len(attStmt) != 0 {
+			return nil, fmt.Errorf("%w: none attestation with a statement", ErrWebAuthnVerification)
+		}
+	case "packed":
+		if err := verifyPackedAttestation(attStmt, key, rawAuthData, clientDataHash); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported attestation format %q", ErrWebAuthnVerification, format)
+	}
+
+	return authData, nil
+}
+
+// COSEKey is a credential public key
+type COSEKey struct {
+	Algorithm int64
+	Key       crypto.PublicKey
+}
+
+// ParseCOSEKey decodes an EdDSA, ES256 or RS256 COSE key
+func ParseCOSEKey(b []byte) (*COSEKey, error) {
+	decoded, _, err := DecodeCBOR(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid COSE key", ErrWebAuthnVerification)
+	}
+
+	m, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid COSE key", ErrWebAuthnVerification)
+	}
+
+	kty, _ := m[int64(1)].(int64)
+	alg, _ := m[int64(3)].(int64)
+
+	switch {
+	case kty == 1 && alg == COSEAlgEdDSA:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 key", ErrWebAuthnVerification)
+		}
+		return &COSEKey{Algorithm: alg, Key: ed25519.PublicKey(x)}, nil
+	case kty == 2 && alg == COSEAlgES256:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: invalid P-256 key", ErrWebAuthnVerification)
+		}
+
+		// Rejects points that aren't on the curve
+		point := append(append([]byte{0x04}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("%w: invalid P-256 point", ErrWebAuthnVerification)
+		}
+
+		return &COSEKey{Algorithm: alg, Key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+	case kty == 3 && alg == COSEAlgRS256:
+		n, _ := m[int64(-1)].([]byte)
+		e, _ := m[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: invalid RSA key", ErrWebAuthnVerification)
+		}
+		return &COSEKey{Algorithm: alg, Key: &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %d / algorithm %d", ErrWebAuthnVerification, kty, alg)
+	}
+}
+
+// VerifyAssertion checks an assertion signature over authenticatorData || clientDataHash
+func (k *COSEKey) VerifyAssertion(authData, clientDataHash, signature []byte) error {
+	signed := append(append([]byte(nil), authData...), clientDataHash...)
+	return verifyWebAuthnSignature(k.Algorithm, k.Key, signed, signature)
+}
+
+// Private functions
+
+func verifyPackedAttestation(attStmt map[any]any, credentialKey *COSEKey, authData, clientDataHash []byte) error {
+	alg, _ := attStmt["alg"].(int64)
+	sig, _ := attStmt["sig"].([]byte)
+	if len(sig) == 0 {
+		return fmt.Errorf("%w: packed attestation without signature", ErrWebAuthnVerification)
+	}
+
+	signed := append(append([]byte(nil), authData...), clientDataHash...)
+
+	// Full attestation: signed by the certificate's key
+	if x5c, ok := attStmt["x5c"].([]any); ok && len(x5c) > 0 {
+		der, _ := x5c[0].([]byte)
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("%w: invalid attestation certificate", ErrWebAuthnVerification)
+		}
+		return verifyWebAuthnSignature(alg, cert.PublicKey, signed, sig)
+	}
+
+	// Self attestation: signed by the new credential itself
+	if alg != credentialKey.Algorithm {
+		return fmt.Errorf("%w: attestation algorithm mismatch", ErrWebAuthnVerification)
+	}
+	return verifyWebAuthnSignature(alg, credentialKey.Key, signed, sig)
+}
+
+func verifyWebAuthnSignature(alg int64, key crypto.PublicKey, data, sig []byte) error {
+	digest := sha256.Sum256(data)
+	valid := false
+
+	switch alg {
+	case COSEAlgEdDSA:
+		if pub, ok := key.(ed25519.PublicKey); ok {
+			valid = ed25519.Verify(pub, data, sig)
+		}
+	case COSEAlgES256:
+		if pub, ok := key.(*ecdsa.PublicKey); ok {
+			valid = ecdsa.VerifyASN1(pub, digest[:], sig)
+		}
+	case COSEAlgRS256:
+		if pub, ok := key.(*rsa.PublicKey); ok {
+			valid = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+		}
+	}
+
+	if !valid {
+		return fmt.Errorf("%w: bad signature", ErrWebAuthnVerification)
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package util
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+const testRPID = "sampatti.example"
+
+var (
+	testSeed         = bytes.Repeat([]byte{0x42}, ed25519.SeedSize)
+	testCredentialID = []byte("credential-0001")
+	testAAGUID       = bytes.Repeat([]byte{0xaa}, 16)
+	testClientHash   = sha256.Sum256([]byte(`{"type":"webauthn.create"}`))
+)
+
+// noneAttestationVector is a "none" attestation for testCredentialID with the
+// Ed25519 key from testSeed, RP ID testRPID, flags UP|UV|AT and sign count 7
+const noneAttestationVector = "a363666d74646e6f6e656761747453746d74a06861757468446174615870" +
+	"f701791d324fef7e209b12eae54d45bf8202ef672b27027b29e34e4b858fa1f4" + // rpIdHash
+	"45" + "00000007" + // flags, sign count
+	"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" + "000f" + "63726564656e7469616c2d30303031" + // AAGUID, credential ID
+	"a40101032720062158202152f8d19b791d24453242e15f2eab6cb7cffa7b6a5ed30097960e069881db12" // COSE key
+
+func testEd25519Key() (ed25519.PrivateKey, []byte) {
+	private := ed25519.NewKeyFromSeed(testSeed)
+	cose := encodeCBOR(cborMap{
+		{int64(1), int64(1)},
+		{int64(3), COSEAlgEdDSA},
+		{int64(-1), int64(6)},
+		{int64(-2), []byte(private.Public().(ed25519.PublicKey))},
+	})
+	return private, cose
+}
+
+func testES256Key(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x := private.PublicKey.X.FillBytes(make([]byte, 32))
+	y := private.PublicKey.Y.FillBytes(make([]byte, 32))
+	cose := encodeCBOR(cborMap{
+		{int64(1), int64(2)},
+		{int64(3), COSEAlgES256},
+		{int64(-1), int64(1)},
+		{int64(-2), x},
+		{int64(-3), y},
+	})
+	return private, cose
+}
+
+// buildAuthData lays out authenticator data; a nil key leaves out the
+// attested credential data, as in assertions
+func buildAuthData(rpID string, flags byte, signCount uint32, coseKey []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, signCount)
+
+	if coseKey != nil {
+		data = append(data, testAAGUID...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(testCredentialID)))
+		data = append(data, testCredentialID...)
+		data = append(data, coseKey...)
+	}
+	return data
+}
+
+func attestationObject(format string, attStmt cborMap, authData []byte) []byte {
+	return encodeCBOR(cborMap{
+		{"fmt", format},
+		{"attStmt", attStmt},
+		{"authData", authData},
+	})
+}
+
+func signedBy(private ed25519.PrivateKey, authData, clientDataHash []byte) []byte {
+	return ed25519.Sign(private, append(append([]byte(nil), authData...), clientDataHash...))
+}
+
+func TestParseAttestationNoneVector(t *testing.T) {
+	object, err := hex.DecodeString(noneAttestationVector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseAttestation(object, testClientHash[:])
+	if err != nil {
+		t.Fatalf("ParseAttestation: %v", err)
+	}
+
+	if !bytes.Equal(parsed.CredentialID, testCredentialID) || !bytes.Equal(parsed.AAGUID, testAAGUID) || parsed.SignCount != 7 {
+		t.Fatalf("parsed credential = %x / %x / %d", parsed.CredentialID, parsed.AAGUID, parsed.SignCount)
+	}
+	if err := parsed.VerifyRPID(testRPID); err != nil {
+		t.Fatalf("VerifyRPID: %v", err)
+	}
+
+	key, err := ParseCOSEKey(parsed.PublicKey)
+	if err != nil {
+		t.Fatalf("ParseCOSEKey: %v", err)
+	}
+	want := ed25519.NewKeyFromSeed(testSeed).Public().(ed25519.PublicKey)
+	if key.Algorithm != COSEAlgEdDSA || !want.Equal(key.Key) {
+		t.Fatal("credential key does not match the attested key")
+	}
+}
+
+func TestParseAttestationPackedSelfAttestation(t *testing.T) {
+	private, cose := testEd25519Key()
+	authData := buildAuthData(testRPID, authFlagUserPresent|authFlagAttestedData, 0, cose)
+
+	statement := cborMap{{"alg", COSEAlgEdDSA}, {"sig", signedBy(private, authData, testClientHash[:])}}
+	if _, err := ParseAttestation(attestationObject("packed", statement, authData), testClientHash[:]); err != nil {
+		t.Fatalf("ParseAttestation: %v", err)
+	}
+
+	otherHash := sha256.Sum256([]byte("another ceremony"))
+	if _, err := ParseAttestation(attestationObject("packed", statement, authData), otherHash[:]); !errors.Is(err, ErrWebAuthnVerification) {
+		t.Fatalf("signature over another ceremony accepted: %v", err)
+	}
+
+	wrongAlg := cborMap{{"alg", COSEAlgES256}, {"sig", signedBy(private, authData, testClientHash[:])}}
+	if _, err := ParseAttestation(attestationObject("packed", wrongAlg, authData), testClientHash[:]); !errors.Is(err, ErrWebAuthnVerification) {
+		t.Fatalf("algorithm mismatch accepted: %v", err)
+	}
+
+	noSig := cborMap{{"alg", COSEAlgEdDSA}}
+	if _, err := ParseAttestation(attestationObject("packed", noSig, authData), testClientHash[:]); !errors.Is(err, ErrWebAuthnVerification) {
+		t.Fatalf("packed attestation without signature accepted: %v", err)
+	}
+}
+
+func TestParseAttestationRejectsBadObjects(t *testing.T) {
+	_, cose := testEd25519Key()
+	authData := buildAuthData(testRPID, authFlagUserPresent|authFlagAttestedData, 0, cose)
+
+	tests := map[string][]byte{
+		"not CBOR":                   {0xff},
+		"not a map":                  encodeCBOR([]any{int64(1)}),
+		"unsupported format":         attestationObject("tpm", cborMap{}, authData),
+		"none with a statement":      attestationObject("none", cborMap{{"alg", COSEAlgEdDSA}}, authData),
+		"no attested credential":     attestationObject("none", cborMap{}, buildAuthData(testRPID, authFlagUserPresent, 0, nil)),
+		"missing authenticator data": encodeCBOR(cborMap{{"fmt", "none"}, {"attStmt", cborMap{}}}),
+	}
+
+	for name, object := range tests {
+		if _, err := ParseAttestation(object, testClientHash[:]); !errors.Is(err, ErrWebAuthnVerification) {
+			t.Errorf("%s: ParseAttestation error = %v, want ErrWebAuthnVerification", name, err)
+		}
+	}
+}
+
+func TestParseAuthenticatorDataRejectsBadLayouts(t *testing.T) {
+	_, cose := testEd25519Key()
+	valid := buildAuthData(testRPID, authFlagUserPresent|authFlagAttestedData, 0, cose)
+
+	longID := append([]byte(nil), valid...)
+	binary.BigEndian.PutUint16(longID[37+16:], 0xffff)
+
+	tests := map[string][]byte{
+		"too short":               valid[:36],
+		"trailing bytes":          append(append([]byte(nil), valid...), 0x00),
+		"truncated credential":    valid[:37+10],
+		"credential ID past data": longID,
+		"truncated key":           valid[:len(valid)-1],
+		"extensions flag no data": buildAuthData(testRPID, authFlagUserPresent|authFlagExtensions, 0, nil),
+	}
+
+	for name, data := range tests {
+		if _, err := ParseAuthenticatorData(data); !errors.Is(err, ErrWebAuthnVerification) {
+			t.Errorf("%s: ParseAuthenticatorData error = %v, want ErrWebAuthnVerification", name, err)
+		}
+	}
+}
+
+func TestVerifyRPIDRejectsAnotherRelyingParty(t *testing.T) {
+	data, err := ParseAuthenticatorData(buildAuthData("evil.example", authFlagUserPresent, 1, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.VerifyRPID(testRPID); !errors.Is(err, ErrWebAuthnVerification) {
+		t.Fatalf("VerifyRPID accepted another RP ID hash: %v", err)
+	}
+}
+
+func TestVerifyFlags(t *testing.T) {
+	tests := []struct {
+		name            string
+		flags           byte
+		requireVerified bool
+		wantErr         bool
+	}{
+		{"present", authFlagUserPresent, false, false},
+		{"present and verified", authFlagUserPresent | authFlagUserVerified, true, false},
+		{"not present", authFlagUserVerified, false, true},
+		{"nothing set", 0, false, true},
+		{"present but not verified", authFlagUserPresent, true, true},
+	}
+
+	for _, tt := range tests {
+		data, err := ParseAuthenticatorData(buildAuthData(testRPID, tt.flags, 1, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = data.VerifyFlags(tt.requireVerified)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: VerifyFlags error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	edPrivate, edCOSE := testEd25519Key()
+	esPrivate, esCOSE := testES256Key(t)
+
+	sign := map[string]func([]byte) []byte{
+		"EdDSA": func(data []byte) []byte { return ed25519.Sign(edPrivate, data) },
+		"ES256": func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			sig, err := ecdsa.SignASN1(rand.Reader, esPrivate, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sig
+		},
+	}
+	keys := map[string][]byte{"EdDSA": edCOSE, "ES256": esCOSE}
+
+	authData := buildAuthData(testRPID, authFlagUserPresent, 8, nil)
+	clientHash := sha256.Sum256([]byte(`{"type":"webauthn.get"}`))
+	otherHash := sha256.Sum256([]byte(`{"type":"webauthn.get","other":true}`))
+
+	for name, cose := range keys {
+		key, err := ParseCOSEKey(cose)
+		if err != nil {
+			t.Fatalf("%s: ParseCOSEKey: %v", name, err)
+		}
+
+		signature := sign[name](append(append([]byte(nil), authData...), clientHash[:]...))
+		if err := key.VerifyAssertion(authData, clientHash[:], signature); err != nil {
+			t.Errorf("%s: valid assertion rejected: %v", name, err)
+		}
+
+		tampered := append([]byte(nil), authData...)
+		tampered[32] |= authFlagUserVerified
+		if err := key.VerifyAssertion(tampered, clientHash[:], signature); !errors.Is(err, ErrWebAuthnVerification) {
+			t.Errorf("%s: assertion with altered flags accepted", name)
+		}
+
+		if err := key.VerifyAssertion(authData, otherHash[:], signature); !errors.Is(err, ErrWebAuthnVerification) {
+			t.Errorf("%s: assertion for other client data accepted", name)
+		}
+	}
+}
+
+func TestSignCountAdvanced(t *testing.T) {
+	tests := []struct {
+		stored, presented uint32
+		want              bool
+	}{
+		{0, 0, true},
+		{0, 1, true},
+		{5, 6, true},
+		{5, 100, true},
+		{5, 5, false},
+		{5, 4, false},
+		{5, 0, false},
+		{1, 0, false},
+	}
+
+	for _, tt := range tests {
+		if got := SignCountAdvanced(tt.stored, tt.presented); got != tt.want {
+			t.Errorf("SignCountAdvanced(%d, %d) = %v, want %v", tt.stored, tt.presented, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyClientData(t *testing.T) {
+	challenge := []byte("challenge-bytes-0123456789abcdef")
+	encoded := base64.RawURLEncoding.EncodeToString(challenge)
+	origins := []string{"https://sampatti.example"}
+
+	clientData := func(ceremony, challenge, origin string) []byte {
+		return []byte(`{"type":"` + ceremony + `","challenge":"` + challenge + `","origin":"` + origin + `"}`)
+	}
+
+	valid := clientData(WebAuthnGet, encoded, origins[0])
+	hash, err := VerifyClientData(valid, WebAuthnGet, challenge, origins)
+	want := sha256.Sum256(valid)
+	if err != nil || !bytes.Equal(hash, want[:]) {
+		t.Fatalf("VerifyClientData = %x, %v", hash, err)
+	}
+
+	tests := map[string][]byte{
+		"wrong ceremony":  clientData(WebAuthnCreate, encoded, origins[0]),
+		"wrong challenge": clientData(WebAuthnGet, base64.RawURLEncoding.EncodeToString([]byte("other")), origins[0]),
+		"wrong origin":    clientData(WebAuthnGet, encoded, "https://evil.example"),
+		"not JSON":        []byte("{"),
+	}
+
+	for name, data := range tests {
+		if _, err := VerifyClientData(data, WebAuthnGet, challenge, origins); !errors.Is(err, ErrWebAuthnVerification) {
+			t.Errorf("%s: VerifyClientData error = %v, want ErrWebAuthnVerification", name, err)
+		}
+	}
+}
+
+func TestParseCOSEKeyRejectsBadKeys(t *testing.T) {
+	tests := map[string]cborMap{
+		"P-256 point off the curve": {
+			{int64(1), int64(2)}, {int64(3), COSEAlgES256}, {int64(-1), int64(1)},
+			{int64(-2), bytes.Repeat([]byte{1}, 32)}, {int64(-3), bytes.Repeat([]byte{2}, 32)},
+		},
+		"Ed25519 on the wrong curve": {
+			{int64(1), int64(1)}, {int64(3), COSEAlgEdDSA}, {int64(-1), int64(1)},
+			{int64(-2), bytes.Repeat([]byte{1}, 32)},
+		},
+		"short RSA modulus": {
+			{int64(1), int64(3)}, {int64(3), COSEAlgRS256},
+			{int64(-1), bytes.Repeat([]byte{1}, 128)}, {int64(-2), []byte{1, 0, 1}},
+		},
+		"unsupported algorithm": {
+			{int64(1), int64(2)}, {int64(3), int64(-35)},
+		},
+	}
+
+	for name, key := range tests {
+		if _, err := ParseCOSEKey(encodeCBOR(key)); !errors.Is(err, ErrWebAuthnVerification) {
+			t.Errorf("%s: ParseCOSEKey error = %v, want ErrWebAuthnVerification", name, err)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+-- Failed authentication attempts, keyed by what was being guessed
+-- (e.g. "login:account:<email>" or "login:ip:<address>")
+CREATE TABLE auth_attempts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    blocked_until TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_auth_attempts_last_failed_at ON auth_attempts(last_failed_at);
+
+-- Security relevant events such as lockouts
+CREATE TABLE security_events (
+    id UUID PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ip_address VARCHAR(50),
+    details TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_security_events_user_id ON security_events(user_id);
+CREATE INDEX idx_security_events_created_at ON security_events(created_at);
@@ -0,0 +1,28 @@
+-- External OpenID Connect identities linked to users. An identity is the
+-- provider's issuer plus its stable subject identifier.
+CREATE TABLE oidc_identities (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_login_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (issuer, subject)
+);
+
+CREATE INDEX idx_oidc_identities_user_id ON oidc_identities(user_id);
+
+-- Pending authorization requests. The state is stored hashed and deleted on
+-- use; user_id is set when an existing user is linking an identity.
+CREATE TABLE oidc_login_states (
+    id UUID PRIMARY KEY,
+    state_hash VARCHAR(64) UNIQUE NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_oidc_login_states_expires_at ON oidc_login_states(expires_at);
@@ -0,0 +1,22 @@
+-- Dead man's switch: nominee access is only released after the owner has
+-- been inactive for inactivity_days and then ignored every reminder sent
+-- during the grace period.
+CREATE TABLE inactivity_switches (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    inactivity_days INT NOT NULL DEFAULT 90,
+    last_check_in_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    reminders_sent INT NOT NULL DEFAULT 0,
+    last_reminder_at TIMESTAMP WITH TIME ZONE,
+    released_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+ALTER TABLE nominees ADD COLUMN access_eligible_at TIMESTAMP WITH TIME ZONE;
+
+-- Existing owners start their first period now rather than being released
+-- straight away on the strength of an old last_login
+INSERT INTO inactivity_switches (user_id)
+SELECT DISTINCT user_id FROM nominees
+ON CONFLICT (user_id) DO NOTHING;
@@ -0,0 +1,26 @@
+-- Emergency access by a nominee is a request the owner can deny during a
+-- waiting period. Requests move from Pending to Denied or Granted, and
+-- granted access becomes Expired once expires_at passes.
+CREATE TABLE emergency_access_requests (
+    id UUID PRIMARY KEY,
+    nominee_id UUID NOT NULL REFERENCES nominees(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'Pending'
+        CHECK (status IN ('Pending', 'Denied', 'Granted', 'Expired')),
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    grant_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    deny_token_hash VARCHAR(64) UNIQUE NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_emergency_access_requests_nominee_id ON emergency_access_requests(nominee_id, requested_at DESC);
+CREATE INDEX idx_emergency_access_requests_user_id ON emergency_access_requests(user_id);
+CREATE INDEX idx_emergency_access_requests_pending ON emergency_access_requests(grant_at) WHERE status = 'Pending';
+
+-- A nominee has at most one open request at a time
+CREATE UNIQUE INDEX idx_emergency_access_requests_one_pending
+    ON emergency_access_requests(nominee_id) WHERE status = 'Pending';
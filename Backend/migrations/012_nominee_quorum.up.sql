@@ -0,0 +1,22 @@
+-- An owner's quorum policy: Full access is only released once threshold
+-- different nominees have approved within window_hours of each other.
+-- met_at records when that first happened.
+CREATE TABLE nominee_quorum_policies (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    threshold INT NOT NULL CHECK (threshold >= 2),
+    window_hours INT NOT NULL CHECK (window_hours > 0),
+    met_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Every emergency access attempt with a valid code counts as an approval
+CREATE TABLE nominee_quorum_approvals (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    nominee_id UUID NOT NULL REFERENCES nominees(id) ON DELETE CASCADE,
+    approved_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_nominee_quorum_approvals_user_id ON nominee_quorum_approvals(user_id, approved_at);
@@ -0,0 +1,14 @@
+-- How each asset is split between the owner's nominees. The shares of an
+-- asset always add up to 100; the API replaces an asset's allocation as a
+-- whole so it can't be left half edited.
+CREATE TABLE asset_beneficiaries (
+    id UUID PRIMARY KEY,
+    asset_id UUID NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    nominee_id UUID NOT NULL REFERENCES nominees(id) ON DELETE CASCADE,
+    share_percentage NUMERIC(5, 2) NOT NULL CHECK (share_percentage > 0 AND share_percentage <= 100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (asset_id, nominee_id)
+);
+
+CREATE INDEX idx_asset_beneficiaries_nominee_id ON asset_beneficiaries(nominee_id);
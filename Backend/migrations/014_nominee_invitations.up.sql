@@ -0,0 +1,28 @@
+-- Nominees accept an emailed invitation and then sign in with their own
+-- password or a linked Sampatti account, replacing the shared access code.
+ALTER TABLE nominees ADD COLUMN password_hash VARCHAR(255);
+ALTER TABLE nominees ADD COLUMN linked_user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE nominees ADD COLUMN invited_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE nominees ADD COLUMN responded_at TIMESTAMP WITH TIME ZONE;
+
+-- Nominees who only ever had a shared code need a fresh invitation
+UPDATE nominees SET status = 'Invited' WHERE status IN ('Pending', 'Active');
+ALTER TABLE nominees DROP COLUMN emergency_access_code;
+ALTER TABLE nominees ALTER COLUMN status SET DEFAULT 'Invited';
+ALTER TABLE nominees ADD CONSTRAINT nominees_status_check
+    CHECK (status IN ('Invited', 'Accepted', 'Declined', 'Revoked'));
+
+CREATE INDEX idx_nominees_linked_user_id ON nominees(linked_user_id);
+
+-- Invitation links. Only the SHA-256 hash of a token is stored, and sending
+-- a new invitation invalidates the previous one.
+CREATE TABLE nominee_invitations (
+    id UUID PRIMARY KEY,
+    nominee_id UUID NOT NULL REFERENCES nominees(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_nominee_invitations_nominee_id ON nominee_invitations(nominee_id);
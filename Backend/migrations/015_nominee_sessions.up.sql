@@ -0,0 +1,21 @@
+-- How long a nominee stays signed in, chosen by the owner per nominee
+ALTER TABLE nominees ADD COLUMN access_duration_hours INT NOT NULL DEFAULT 24
+    CHECK (access_duration_hours BETWEEN 1 AND 720);
+
+-- Nominee sessions. Every nominee token names one of these rows and is only
+-- accepted while the row is live, so revoking it takes effect immediately.
+CREATE TABLE nominee_sessions (
+    id UUID PRIMARY KEY,
+    nominee_id UUID NOT NULL REFERENCES nominees(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    access_level VARCHAR(20) NOT NULL,
+    ip_address VARCHAR(50) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_nominee_sessions_nominee_id ON nominee_sessions(nominee_id);
+CREATE INDEX idx_nominee_sessions_user_id ON nominee_sessions(user_id);
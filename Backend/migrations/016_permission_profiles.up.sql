@@ -0,0 +1,23 @@
+-- Reusable permission profiles an owner assigns to nominees. A NULL type list
+-- allows every type, an empty one allows none.
+CREATE TABLE permission_profiles (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    asset_types TEXT[],
+    redact_account_numbers BOOLEAN NOT NULL DEFAULT FALSE,
+    redact_institutions BOOLEAN NOT NULL DEFAULT FALSE,
+    redact_notes BOOLEAN NOT NULL DEFAULT FALSE,
+    document_types TEXT[],
+    shared_documents_only BOOLEAN NOT NULL DEFAULT FALSE,
+    allow_downloads BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+CREATE INDEX idx_permission_profiles_user_id ON permission_profiles(user_id);
+
+-- Nominees without a profile fall back to the built-in one for their access level
+ALTER TABLE nominees ADD COLUMN permission_profile_id UUID
+    REFERENCES permission_profiles(id) ON DELETE SET NULL;
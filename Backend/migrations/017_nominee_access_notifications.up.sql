@@ -0,0 +1,23 @@
+-- "This wasn't expected" links sent to owners when a nominee signs in or
+-- views their data. Each one revokes the nominee without signing in, so only
+-- the SHA-256 hash of the token is stored.
+CREATE TABLE nominee_revocation_tokens (
+    id UUID PRIMARY KEY,
+    nominee_id UUID NOT NULL REFERENCES nominees(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_nominee_revocation_tokens_nominee_id ON nominee_revocation_tokens(nominee_id);
+
+-- One webhook per owner for security notifications. Deliveries are signed
+-- with the secret so the receiver can check they came from us.
+CREATE TABLE notification_webhooks (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
@@ -0,0 +1,28 @@
+-- Sealed letters of instruction from owners to their nominees. The subject
+-- and body are encrypted with AES-256-GCM before they reach the database.
+CREATE TABLE nominee_messages (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sealed_content BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_nominee_messages_user_id ON nominee_messages(user_id);
+
+-- Who each letter is addressed to, and when they first opened it
+CREATE TABLE nominee_message_recipients (
+    message_id UUID NOT NULL REFERENCES nominee_messages(id) ON DELETE CASCADE,
+    nominee_id UUID NOT NULL REFERENCES nominees(id) ON DELETE CASCADE,
+    first_opened_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (message_id, nominee_id)
+);
+
+CREATE INDEX idx_nominee_message_recipients_nominee_id ON nominee_message_recipients(nominee_id);
+
+-- Documents attached to a letter
+CREATE TABLE nominee_message_attachments (
+    message_id UUID NOT NULL REFERENCES nominee_messages(id) ON DELETE CASCADE,
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    PRIMARY KEY (message_id, document_id)
+);
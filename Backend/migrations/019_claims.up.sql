@@ -0,0 +1,51 @@
+-- Institution-specific guidance an owner leaves for nominees claiming their
+-- assets. Institutions are matched case-insensitively.
+CREATE TABLE claim_instructions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    institution VARCHAR(255) NOT NULL,
+    instructions TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_claim_instructions_institution ON claim_instructions(user_id, LOWER(institution));
+
+CREATE TABLE claim_instruction_steps (
+    instruction_id UUID NOT NULL REFERENCES claim_instructions(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    required_documents TEXT[] NOT NULL DEFAULT '{}',
+    PRIMARY KEY (instruction_id, position)
+);
+
+-- Each asset's claim checklist, shared by all of the owner's nominees.
+-- It is generated from the owner's instructions the first time a nominee
+-- opens the tracker.
+CREATE TABLE claim_steps (
+    id UUID PRIMARY KEY,
+    asset_id UUID NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    required_documents TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'NotStarted' CHECK (status IN ('NotStarted', 'Submitted', 'Settled')),
+    notes TEXT NOT NULL DEFAULT '',
+    updated_by UUID REFERENCES nominees(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (asset_id, position)
+);
+
+-- Files nominees upload as evidence for a claim step
+CREATE TABLE claim_documents (
+    id UUID PRIMARY KEY,
+    step_id UUID NOT NULL REFERENCES claim_steps(id) ON DELETE CASCADE,
+    nominee_id UUID NOT NULL REFERENCES nominees(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    file_size BIGINT NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    uploaded_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_claim_documents_step_id ON claim_documents(step_id);
@@ -0,0 +1,5 @@
+-- Actions name documents and claim steps, which can run past 100 characters
+ALTER TABLE nominee_access_logs ALTER COLUMN action TYPE TEXT;
+
+-- Serves the owner's access log newest first, one page at a time
+CREATE INDEX idx_nominee_access_logs_nominee_date ON nominee_access_logs(nominee_id, date DESC, id DESC);
@@ -0,0 +1,40 @@
+-- Tamper-evident audit trail. Each owner has a hash chain: an entry's hash
+-- covers its contents and the previous entry's hash, and seq has no gaps.
+-- There are no foreign keys so entries outlive what they describe.
+CREATE TABLE audit_log (
+    owner_id UUID NOT NULL,
+    seq BIGINT NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    actor_type VARCHAR(20) NOT NULL,
+    actor_id UUID,
+    action VARCHAR(100) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL DEFAULT '',
+    entity_id UUID,
+    summary TEXT NOT NULL DEFAULT '',
+    ip_address VARCHAR(50) NOT NULL DEFAULT '',
+    prev_hash VARCHAR(64) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    PRIMARY KEY (owner_id, seq)
+);
+
+-- The latest link of each chain. Appends lock the owner's row, which keeps
+-- seq contiguous, and verification checks the chain still ends here.
+CREATE TABLE audit_heads (
+    owner_id UUID PRIMARY KEY,
+    seq BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL
+);
+
+CREATE FUNCTION audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_changes
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
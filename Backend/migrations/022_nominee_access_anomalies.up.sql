@@ -0,0 +1,33 @@
+-- Nominees suspended after suspicious access can't sign in until the owner
+-- reinstates them
+ALTER TABLE nominees ADD COLUMN suspended_at TIMESTAMP WITH TIME ZONE;
+
+-- How each owner wants nominee access watched. Access outside the hours
+-- allowed_from to allowed_until (exclusive, in timezone, wrapping past
+-- midnight) is suspicious; NULL hours allow any time.
+CREATE TABLE nominee_access_rules (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    allowed_from SMALLINT CHECK (allowed_from BETWEEN 0 AND 23),
+    allowed_until SMALLINT CHECK (allowed_until BETWEEN 0 AND 23),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    auto_suspend BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((allowed_from IS NULL) = (allowed_until IS NULL))
+);
+
+-- Nominee accesses the rules scored as suspicious
+CREATE TABLE nominee_access_anomalies (
+    id UUID PRIMARY KEY,
+    nominee_id UUID NOT NULL REFERENCES nominees(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    access_log_id UUID NOT NULL,
+    score INTEGER NOT NULL,
+    reasons TEXT[] NOT NULL,
+    action TEXT NOT NULL,
+    ip_address VARCHAR(50) NOT NULL DEFAULT '',
+    device_info TEXT NOT NULL DEFAULT '',
+    suspended BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_nominee_access_anomalies_user_id ON nominee_access_anomalies(user_id, created_at DESC);
@@ -0,0 +1,43 @@
+-- Each owner's vault recovery key. Only the X25519 public half is kept; the
+-- private half exists only as Shamir shares held by their nominees, any
+-- threshold of which recover it. Splitting again replaces the vault.
+CREATE TABLE vaults (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    public_key BYTEA NOT NULL,
+    threshold INTEGER NOT NULL CHECK (threshold >= 1),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- One share per nominee. sealed_share holds the share, sealed with the
+-- server key, only until it has been delivered. A nominee's submitted share
+-- is kept, sealed, while emergency access collects enough to unlock, and
+-- cleared when the nominee's access expires, they are revoked or suspended,
+-- or the owner checks in.
+CREATE TABLE vault_shares (
+    vault_id UUID NOT NULL REFERENCES vaults(id) ON DELETE CASCADE,
+    nominee_id UUID NOT NULL REFERENCES nominees(id) ON DELETE CASCADE,
+    share_index SMALLINT NOT NULL,
+    share_hash VARCHAR(64) NOT NULL,
+    sealed_share BYTEA,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    submitted_share BYTEA,
+    submitted_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (vault_id, nominee_id),
+    UNIQUE (vault_id, share_index)
+);
+
+-- Keys of encrypted documents, wrapped twice: with the server key for the
+-- owner, and to the owner's vault for nominees. recovery_wrapped is empty
+-- until the owner has a vault. owner_wrapped means the server key alone
+-- opens every document; the vault shares only gate nominees.
+CREATE TABLE document_keys (
+    document_id UUID PRIMARY KEY REFERENCES documents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    owner_wrapped BYTEA NOT NULL,
+    recovery_wrapped BYTEA,
+    vault_id UUID REFERENCES vaults(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_document_keys_user_id ON document_keys(user_id);
@@ -26,7 +26,7 @@ const formatDate = (dateString) => {
 const EmergencyAccess = () => {
   // Form state
   const [email, setEmail] = useState('');
-  const [accessCode, setAccessCode] = useState('');
+  const [password, setPassword] = useState('');
   const [isLoading, setIsLoading] = useState(false);
   const [error, setError] = useState('');
   
@@ -41,8 +41,8 @@ const EmergencyAccess = () => {
     e.preventDefault();
     setError('');
     
-    if (!email || !accessCode) {
-      setError('Please enter both email and access code');
+    if (!email || !password) {
+      setError('Please enter both email and password');
       return;
     }
     
@@ -57,7 +57,7 @@ const EmergencyAccess = () => {
         },
         body: JSON.stringify({
           email,
-          emergency_access_code: accessCode
+          password
         })
       });
       
@@ -81,7 +81,7 @@ const EmergencyAccess = () => {
       setAccessGranted(true);
     } catch (err) {
       console.error('Emergency access error:', err);
-      setError(err.message || 'Invalid credentials. Please check your email and access code.');
+      setError(err.message || 'Invalid credentials. Please check your email and password.');
     } finally {
       setIsLoading(false);
     }
@@ -95,7 +95,7 @@ const EmergencyAccess = () => {
     setAccessGranted(false);
     setUserData(null);
     setEmail('');
-    setAccessCode('');
+    setPassword('');
   };
   
   // Login form view
@@ -109,7 +109,7 @@ const EmergencyAccess = () => {
             </div>
             <h1 className="text-2xl font-bold text-white mb-2">Emergency Access</h1>
             <p className="text-gray-400">
-              Enter your email and emergency access code to view investment details
+              Enter your email and the password you chose when accepting your invitation
             </p>
           </div>
           
@@ -142,20 +142,21 @@ const EmergencyAccess = () => {
             </div>
             
             <div>
-              <label htmlFor="accessCode" className="block text-sm font-medium mb-2 text-white">
-                Emergency Access Code
+              <label htmlFor="password" className="block text-sm font-medium mb-2 text-white">
+                Password
               </label>
               <div className="relative">
                 <div className="absolute inset-y-0 left-0 pl-3 flex items-center pointer-events-none">
                   <Key size={18} className="text-gray-500" />
                 </div>
                 <input
-                  id="accessCode"
-                  type="text"
-                  value={accessCode}
-                  onChange={(e) => setAccessCode(e.target.value)}
+                  id="password"
+                  type="password"
+                  autoComplete="current-password"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
                   className="block w-full pl-10 pr-3 py-3 border border-white/10 bg-white/5 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent transition-colors text-white placeholder-gray-400"
-                  placeholder="Enter your access code"
+                  placeholder="Enter your password"
                   required
                   disabled={isLoading}
                 />
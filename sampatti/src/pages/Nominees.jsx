@@ -37,8 +37,9 @@ const AccessLevelBadge = ({ level }) => {
 
 const StatusBadge = ({ status }) => {
   const colors = {
-    'Active': 'bg-green-500/20 text-green-400',
-    'Pending': 'bg-yellow-500/20 text-yellow-400',
+    'Accepted': 'bg-green-500/20 text-green-400',
+    'Invited': 'bg-yellow-500/20 text-yellow-400',
+    'Declined': 'bg-gray-500/20 text-gray-400',
     'Revoked': 'bg-red-500/20 text-red-400'
   };
 
@@ -258,13 +259,12 @@ const NomineeFormModal = ({ isOpen, onClose, onSubmit, editingNominee = null })
 const NomineeDetailModal = ({ nominee, isOpen, onClose, onEdit, onDelete, onSendInvite, assets = [] }) => {
   const [isSending, setIsSending] = useState(false);
   const [sendError, setSendError] = useState('');
-  const [inviteCode, setInviteCode] = useState('');
+  const [invitation, setInvitation] = useState(null);
   const [activeTab, setActiveTab] = useState('details');
   
   useEffect(() => {
-    if (isOpen && nominee) {
-      generateAccessCode();
-    }
+    setSendError('');
+    setInvitation(null);
   }, [isOpen, nominee?.id]);
   
   const accessibleAssets = assets.filter(asset => {
@@ -284,27 +284,26 @@ const NomineeDetailModal = ({ nominee, isOpen, onClose, onEdit, onDelete, onSend
     }).format(amount || 0);
   };
   
-  const generateAccessCode = async () => {
+  // Accepted and revoked nominees can't be invited again
+  const canInvite = nominee?.status === 'Invited' || nominee?.status === 'Declined';
+  
+  const resendInvitation = async () => {
     if (!nominee) return;
     
     setIsSending(true);
     setSendError('');
     
     try {
-      const code = await onSendInvite(nominee.id);
-      setInviteCode(code);
+      const sent = await onSendInvite(nominee.id);
+      setInvitation(sent);
     } catch (err) {
-      console.error('Error generating access code:', err);
-      setSendError(err.message || 'Failed to generate access code. Please try again.');
+      console.error('Error sending invitation:', err);
+      setSendError(err.message || 'Failed to send the invitation. Please try again.');
     } finally {
       setIsSending(false);
     }
   };
   
-  const handleRegenerateCode = async () => {
-    await generateAccessCode();
-  };
-  
   if (!isOpen || !nominee) return null;
   
   const formatDate = (dateString) => {
@@ -405,8 +404,9 @@ const NomineeDetailModal = ({ nominee, isOpen, onClose, onEdit, onDelete, onSend
                   <div>
                     <p className="text-gray-400 text-sm">Status</p>
                     <p className={
-                      nominee.status === 'Active' ? 'text-green-400' : 
-                      nominee.status === 'Pending' ? 'text-yellow-400' : 
+                      nominee.status === 'Accepted' ? 'text-green-400' : 
+                      nominee.status === 'Invited' ? 'text-yellow-400' : 
+                      nominee.status === 'Declined' ? 'text-gray-400' : 
                       'text-red-400'
                     }>
                       {nominee.status}
@@ -419,18 +419,20 @@ const NomineeDetailModal = ({ nominee, isOpen, onClose, onEdit, onDelete, onSend
                 </div>
               </div>
               
-              <div className="mt-4">
-                <Button
-                  className="w-full"
-                  variant="primary"
-                  icon={<Send size={18} />}
-                  onClick={handleRegenerateCode}
-                  isLoading={isSending}
-                  disabled={isSending}
-                >
-                  Regenerate Access Code
-                </Button>
-              </div>
+              {canInvite && (
+                <div className="mt-4">
+                  <Button
+                    className="w-full"
+                    variant="primary"
+                    icon={<Send size={18} />}
+                    onClick={resendInvitation}
+                    isLoading={isSending}
+                    disabled={isSending}
+                  >
+                    Resend Invitation
+                  </Button>
+                </div>
+              )}
             </div>
           </div>
           
@@ -457,12 +459,12 @@ const NomineeDetailModal = ({ nominee, isOpen, onClose, onEdit, onDelete, onSend
                     {sendError ? (
                       <ErrorState 
                         message={sendError} 
-                        onRetry={generateAccessCode}
+                        onRetry={resendInvitation}
                       />
                     ) : isSending ? (
                       <div className="p-5 bg-blue-500/10 border border-blue-500/30 rounded-lg text-center">
                         <div className="animate-spin h-8 w-8 border-4 border-blue-500 border-t-transparent rounded-full mx-auto mb-3"></div>
-                        <p className="text-white">Generating access code...</p>
+                        <p className="text-white">Sending invitation...</p>
                       </div>
                     ) : (
                       <div className="p-5 bg-blue-500/10 border border-blue-500/30 rounded-lg">
@@ -471,51 +473,27 @@ const NomineeDetailModal = ({ nominee, isOpen, onClose, onEdit, onDelete, onSend
                           Emergency Access Information
                         </h4>
                         
-                        <div className="mb-4">
-                          <p className="text-gray-300 text-sm mb-2">
-                            Share these details with <span className="text-white font-medium">{nominee.name}</span>:
+                        {invitation ? (
+                          <p className="text-gray-300 text-sm">
+                            A new invitation was emailed to <span className="text-white font-medium">{invitation.nominee_email}</span>. The link expires on {formatDate(invitation.expires_at)}.
                           </p>
-                          
-                          <div className="bg-gray-900 p-4 rounded-lg mb-3">
-                            <div className="mb-3">
-                              <span className="text-gray-400 text-sm block mb-1">Email:</span>
-                              <span className="bg-blue-500/20 p-2 rounded font-mono text-blue-400 text-md block break-all">
-                                {nominee.email}
-                              </span>
-                            </div>
-                            
-                            <div>
-                              <span className="text-gray-400 text-sm block mb-1">Access Code:</span>
-                              {inviteCode ? (
-                                <span className="bg-green-500/20 p-2 rounded font-mono text-green-400 text-lg block">
-                                  {inviteCode}
-                                </span>
-                              ) : (
-                                <span className="bg-red-500/20 p-2 rounded text-red-400 text-sm block">
-                                  Failed to generate code. Click "Regenerate Access Code" to try again.
-                                </span>
-                              )}
-                            </div>
-                          </div>
-                          
-                          <p className="text-gray-400 text-sm">
-                            <strong>Important:</strong> Store this information securely. The nominee will need both their email and this code to access your shared data in case of emergency.
+                        ) : nominee.status === 'Accepted' ? (
+                          <p className="text-gray-300 text-sm">
+                            <span className="text-white font-medium">{nominee.name}</span> accepted your invitation. In an emergency they sign in with the password they chose or their linked Sampatti account, so there is nothing for you to share.
                           </p>
-                        </div>
-                        
-                        <div className="flex justify-end">
-                          <button 
-                            onClick={() => {
-                              const text = `Emergency Access Details for ${nominee.name}:\nEmail: ${nominee.email}\nAccess Code: ${inviteCode}`;
-                              navigator.clipboard.writeText(text);
-                              alert('Access details copied to clipboard');
-                            }}
-                            className="px-3 py-1 bg-blue-600 hover:bg-blue-700 text-white text-sm rounded"
-                            disabled={!inviteCode}
-                          >
-                            Copy to Clipboard
-                          </button>
-                        </div>
+                        ) : nominee.status === 'Invited' ? (
+                          <p className="text-gray-300 text-sm">
+                            An invitation was emailed to <span className="text-white font-medium">{nominee.email}</span>. Once they accept it and choose a password or link their Sampatti account, they can request emergency access. Resend it if it has expired or gone missing.
+                          </p>
+                        ) : nominee.status === 'Declined' ? (
+                          <p className="text-gray-300 text-sm">
+                            <span className="text-white font-medium">{nominee.name}</span> declined your invitation. Resend it if they change their mind.
+                          </p>
+                        ) : (
+                          <p className="text-gray-300 text-sm">
+                            The access of <span className="text-white font-medium">{nominee.name}</span> has been revoked. Remove and add them again to invite them afresh.
+                          </p>
+                        )}
                       </div>
                     )}
                   </div>                  
@@ -774,23 +752,23 @@ const Nominees = () => {
   // Send invitation to a nominee
   const handleSendInvitation = async (nomineeId) => {
     try {
-      const inviteCode = await sendNomineeInvitation(nomineeId);
+      const invitation = await sendNomineeInvitation(nomineeId);
       
       // Update nominee status
       setNominees(prev => 
         prev.map(nominee => 
           nominee.id === nomineeId 
-            ? { ...nominee, status: 'Pending' }
+            ? { ...nominee, status: 'Invited' }
             : nominee
         )
       );
       
       // Update selected nominee if it's currently being viewed
       if (selectedNominee && selectedNominee.id === nomineeId) {
-        setSelectedNominee(prev => ({ ...prev, status: 'Pending' }));
+        setSelectedNominee(prev => ({ ...prev, status: 'Invited' }));
       }
       
-      return inviteCode;
+      return invitation;
     } catch (error) {
       console.error('Error sending invitation:', error);
       throw error;
@@ -2,7 +2,7 @@
 import { create } from 'zustand';
 import { persist } from 'zustand/middleware';
 import { 
-  loginUser, registerUser, refreshTokenApi, getUserProfile,
+  loginUser, logoutUser, registerUser, refreshTokenApi, getUserProfile,
   updateUserProfile, updateUserSettings, changePassword,
   getAssets, createAsset, updateAsset, deleteAsset, getAssetById,
   updateAssetValue, getPortfolioSummary, getAssetHistory,
@@ -108,6 +108,10 @@ const createAuthSlice = (set, get) => ({
       
       if (data && data.access_token) {
         localStorage.setItem('authToken', data.access_token);
+        // Refresh tokens are rotated on every use
+        if (data.refresh_token) {
+          localStorage.setItem('refreshToken', data.refresh_token);
+        }
         return true;
       }
       return false;
@@ -133,6 +137,9 @@ const createAuthSlice = (set, get) => ({
   },
   
   logout: () => {
+    // Revoke the session server-side; local state is cleared either way
+    logoutUser().catch(() => {});
+
     localStorage.removeItem('authToken');
     localStorage.removeItem('refreshToken');
     localStorage.removeItem('isLoggedIn');
@@ -424,19 +431,19 @@ const createNomineesSlice = (set, get) => ({
   sendNomineeInvitation: async (nomineeId) => {
     try {
       set({ nomineesLoading: true, nomineeError: null });
-      const inviteCode = await sendNomineeInvitation(nomineeId);
+      const invitation = await sendNomineeInvitation(nomineeId);
       
       // Update nominee status in store
       set(state => ({
         nominees: state.nominees.map(nominee => 
           nominee.id === nomineeId 
-            ? { ...nominee, status: 'Pending' }
+            ? { ...nominee, status: 'Invited' }
             : nominee
         ),
         nomineesLoading: false
       }));
       
-      return inviteCode;
+      return invitation;
     } catch (error) {
       set({ 
         nomineeError: error.message || 'Failed to send nominee invitation', 
@@ -65,6 +65,10 @@ const refreshAuthToken = async () => {
   const data = await handleApiResponse(response);
   if (data && data.access_token) {
     localStorage.setItem('authToken', data.access_token);
+    // Refresh tokens are rotated on every use
+    if (data.refresh_token) {
+      localStorage.setItem('refreshToken', data.refresh_token);
+    }
     return data.access_token;
   }
   
@@ -208,6 +212,16 @@ export const refreshTokenApi = async () => {
   }
 };
 
+export const logoutUser = async () => {
+  const refreshToken = localStorage.getItem('refreshToken');
+  if (!refreshToken) return;
+
+  return fetchApi('/auth/logout', {
+    method: 'POST',
+    body: JSON.stringify({ refresh_token: refreshToken }),
+  });
+};
+
 export const requestPasswordReset = async (email) => {
   return fetchApi('/auth/forgot-password', {
     method: 'POST',
@@ -222,6 +236,19 @@ export const resetPassword = async (token, newPassword) => {
   });
 };
 
+export const verifyEmail = async (token) => {
+  return fetchApi('/auth/verify-email', {
+    method: 'POST',
+    body: JSON.stringify({ token }),
+  });
+};
+
+export const resendVerificationEmail = async () => {
+  return fetchApi('/users/email-verification', {
+    method: 'POST',
+  });
+};
+
 // User profile functions with built-in error recovery
 export const getUserProfile = async () => {
   try {
@@ -261,6 +288,15 @@ export const changePassword = async (oldPassword, newPassword) => {
   });
 };
 
+export const getAuditLog = async (params = {}) => {
+  const query = new URLSearchParams(params).toString();
+  return fetchApi(`/users/audit-log${query ? `?${query}` : ''}`);
+};
+
+export const verifyAuditLog = async () => {
+  return fetchApi('/users/audit-log/verify');
+};
+
 // Asset/Investment functions
 export const getAssets = async () => {
   try {
@@ -439,24 +475,59 @@ export const deleteNominee = async (nomineeId) => {
   });
 };
 
+// Emails the nominee a fresh invitation link and returns who it went to and
+// when it expires
 export const sendNomineeInvitation = async (nomineeId) => {
-  const response = await fetchApi(`/nominees/${nomineeId}/send-invitation`, {
+  return fetchApi(`/nominees/${nomineeId}/send-invitation`, {
     method: 'POST',
   });
-  
-  return response && response.code ? response.code : 'CODE-NOT-FOUND';
 };
 
-export const getNomineeAccessLogs = async () => {
+export const getNomineeAccessLogs = async (filters = {}) => {
   try {
-    const response = await fetchApi('/nominees/access-log');
-    return response || [];
+    const params = new URLSearchParams(filters).toString();
+    const response = await fetchApi(`/nominees/access-log${params ? `?${params}` : ''}`);
+    return response?.logs || [];
   } catch (error) {
     console.error('Error fetching nominee access logs:', error);
     return [];
   }
 };
 
+export const getNomineeAccessRules = async () => {
+  return fetchApi('/nominees/access-rules');
+};
+
+export const updateNomineeAccessRules = async (rules) => {
+  return fetchApi('/nominees/access-rules', {
+    method: 'PUT',
+    body: JSON.stringify(rules),
+  });
+};
+
+export const getNomineeAnomalies = async () => {
+  return fetchApi('/nominees/anomalies');
+};
+
+export const reinstateNominee = async (nomineeId) => {
+  return fetchApi(`/nominees/${nomineeId}/reinstate`, {
+    method: 'POST',
+  });
+};
+
+// The vault's shares gate nominee access to encrypted documents only; the
+// server keeps its own copy of each document key so owners can open them
+export const getVaultStatus = async () => {
+  return fetchApi('/nominees/vault');
+};
+
+export const splitVault = async (threshold) => {
+  return fetchApi('/nominees/vault/split', {
+    method: 'POST',
+    body: JSON.stringify({ threshold }),
+  });
+};
+
 // Alert functions
 export const getAlerts = async (includeRead = false) => {
   try {
@@ -516,7 +587,7 @@ export const getEmergencyData = async (userId) => {
 
 // src/utils/api.js - Updated emergencyLogin function
 
-export const emergencyLogin = async (email, accessCode) => {
+export const emergencyLogin = async (email, password) => {
   try {
     // Call emergency access endpoint directly
     const response = await fetch('/api/v1/auth/emergency-access', {
@@ -526,7 +597,7 @@ export const emergencyLogin = async (email, accessCode) => {
       },
       body: JSON.stringify({
         email,
-        emergency_access_code: accessCode
+        password
       })
     });
     
@@ -558,6 +629,8 @@ export default {
   registerUser,
   requestPasswordReset,
   resetPassword,
+  verifyEmail,
+  resendVerificationEmail,
   refreshTokenApi,
   
   // User